@@ -0,0 +1,238 @@
+package winprinters
+
+import (
+	"encoding/binary"
+)
+
+//goland:noinspection GoSnakeCaseUsage,GoUnusedConst,SpellCheckingInspection
+const (
+	DC_PAPERS          = 2
+	DC_PAPERSIZE       = 3
+	DC_MINEXTENT       = 4
+	DC_MAXEXTENT       = 5
+	DC_BINS            = 6
+	DC_DUPLEX          = 7
+	DC_BINNAMES        = 12
+	DC_ENUMRESOLUTIONS = 13
+	DC_PAPERNAMES      = 16
+	DC_ORIENTATION     = 17
+	DC_COPIES          = 18
+	DC_COLLATE         = 22
+	DC_COLORDEVICE     = 32
+	DC_NUP             = 33
+	DC_MEDIATYPENAMES  = 34
+	DC_MEDIATYPES      = 35
+
+	// Sizes of the fixed width names DeviceCapabilities returns, in
+	// characters.
+	CCHPAPERNAME     = 64
+	CCHBINNAME       = 24
+	CCHMEDIATYPENAME = 64
+)
+
+// PaperSize is a paper size a printer supports.
+type PaperSize struct {
+	ID   int16 // DMPAPER_* value for DevMode.SetPaperSize
+	Name string
+	Size SIZE // in thousandths of a millimeter, portrait
+}
+
+// PaperBin is a paper source of a printer.
+type PaperBin struct {
+	ID   int16 // DMBIN_* value for the DevMode default source
+	Name string
+}
+
+// Resolution is a printer resolution in dots per inch.
+type Resolution struct {
+	X int32
+	Y int32
+}
+
+// MediaType is a kind of paper a printer supports, such as plain or glossy.
+type MediaType struct {
+	ID   uint32 // DMMEDIA_* value for the DevMode media type
+	Name string
+}
+
+// Capabilities lists what a printer supports, to build print dialogs and
+// validate DevMode settings before printing.
+type Capabilities struct {
+	Papers      []PaperSize
+	Bins        []PaperBin
+	Resolutions []Resolution
+	MediaTypes  []MediaType
+	NUp         []uint32 // pages per sheet
+
+	Duplex    bool
+	Color     bool
+	Collate   bool
+	MaxCopies int
+
+	// Orientation is how many degrees the printer turns portrait pages
+	// to print landscape, 90 or 270, or 0 when it has no landscape.
+	Orientation int
+
+	// MinExtent and MaxExtent bound the custom paper sizes, in thousandths
+	// of a millimeter. Both are zero when they are not known.
+	MinExtent SIZE
+	MaxExtent SIZE
+}
+
+// Paper returns the paper size with the given DMPAPER_* ID.
+func (c *Capabilities) Paper(id int16) (PaperSize, bool) {
+	for _, p := range c.Papers {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return PaperSize{}, false
+}
+
+// PaperByName returns the paper size with the given name.
+func (c *Capabilities) PaperByName(name string) (PaperSize, bool) {
+	for _, p := range c.Papers {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return PaperSize{}, false
+}
+
+// clone returns a copy of c that shares no slices with it.
+func (c *Capabilities) clone() *Capabilities {
+	d := *c
+	d.Papers = append([]PaperSize(nil), c.Papers...)
+	d.Bins = append([]PaperBin(nil), c.Bins...)
+	d.Resolutions = append([]Resolution(nil), c.Resolutions...)
+	d.MediaTypes = append([]MediaType(nil), c.MediaTypes...)
+	d.NUp = append([]uint32(nil), c.NUp...)
+	return &d
+}
+
+// CapabilitiesReader is implemented by PrinterHandles that report what the
+// printer supports.
+type CapabilitiesReader interface {
+	Capabilities() (*Capabilities, error)
+}
+
+// Capabilities returns the paper sizes, bins, resolutions, media types and
+// the other features the printer driver supports.
+func (p *Printer) Capabilities() (*Capabilities, error) {
+	r, ok := p.h.(CapabilitiesReader)
+	if !ok {
+		return nil, &UnsupportedError{Op: "Capabilities"}
+	}
+	return r.Capabilities()
+}
+
+// deviceCapabilitiesFunc calls DeviceCapabilities for one DC_* capability.
+// With a nil out it returns the number of items, or the value of scalar
+// capabilities; otherwise it fills out with the items.
+type deviceCapabilitiesFunc func(capability uint16, out []byte) (int32, error)
+
+// queryCapabilities builds Capabilities from the DC_* capabilities dc
+// returns. Only a failure to list the paper sizes is an error: drivers fail
+// the capabilities they do not know.
+func queryCapabilities(dc deviceCapabilitiesFunc) (*Capabilities, error) {
+	items := func(capability uint16, size int) ([]byte, int, error) {
+		n, err := dc(capability, nil)
+		if err != nil || n <= 0 {
+			return nil, 0, err
+		}
+		b := make([]byte, int(n)*size)
+		m, err := dc(capability, b)
+		if err != nil {
+			return nil, 0, err
+		}
+		if m < n {
+			n = m
+		}
+		return b, int(n), nil
+	}
+	scalar := func(capability uint16) int32 {
+		n, err := dc(capability, nil)
+		if err != nil || n < 0 {
+			return 0
+		}
+		return n
+	}
+	le := binary.LittleEndian
+
+	c := new(Capabilities)
+	ids, n, err := items(DC_PAPERS, 2)
+	if err != nil {
+		return nil, err
+	}
+	names, nNames, _ := items(DC_PAPERNAMES, 2*CCHPAPERNAME)
+	sizes, nSizes, _ := items(DC_PAPERSIZE, 8)
+	for i := 0; i < n; i++ {
+		p := PaperSize{ID: int16(le.Uint16(ids[2*i:]))}
+		if i < nNames {
+			p.Name = fixedString(names[2*CCHPAPERNAME*i:], CCHPAPERNAME)
+		}
+		if i < nSizes {
+			// DC_PAPERSIZE reports tenths of a millimeter.
+			p.Size = SIZE{Width: le.Uint32(sizes[8*i:]) * 100, Height: le.Uint32(sizes[8*i+4:]) * 100}
+		}
+		c.Papers = append(c.Papers, p)
+	}
+
+	bins, n, _ := items(DC_BINS, 2)
+	names, nNames, _ = items(DC_BINNAMES, 2*CCHBINNAME)
+	for i := 0; i < n; i++ {
+		b := PaperBin{ID: int16(le.Uint16(bins[2*i:]))}
+		if i < nNames {
+			b.Name = fixedString(names[2*CCHBINNAME*i:], CCHBINNAME)
+		}
+		c.Bins = append(c.Bins, b)
+	}
+
+	media, n, _ := items(DC_MEDIATYPES, 4)
+	names, nNames, _ = items(DC_MEDIATYPENAMES, 2*CCHMEDIATYPENAME)
+	for i := 0; i < n; i++ {
+		m := MediaType{ID: le.Uint32(media[4*i:])}
+		if i < nNames {
+			m.Name = fixedString(names[2*CCHMEDIATYPENAME*i:], CCHMEDIATYPENAME)
+		}
+		c.MediaTypes = append(c.MediaTypes, m)
+	}
+
+	res, n, _ := items(DC_ENUMRESOLUTIONS, 8)
+	for i := 0; i < n; i++ {
+		c.Resolutions = append(c.Resolutions, Resolution{X: int32(le.Uint32(res[8*i:])), Y: int32(le.Uint32(res[8*i+4:]))})
+	}
+	nup, n, _ := items(DC_NUP, 4)
+	for i := 0; i < n; i++ {
+		c.NUp = append(c.NUp, le.Uint32(nup[4*i:]))
+	}
+
+	c.Duplex = scalar(DC_DUPLEX) == 1
+	c.Color = scalar(DC_COLORDEVICE) == 1
+	c.Collate = scalar(DC_COLLATE) == 1
+	c.MaxCopies = int(scalar(DC_COPIES))
+	c.Orientation = int(scalar(DC_ORIENTATION))
+	c.MinExtent = extent(scalar(DC_MINEXTENT))
+	c.MaxExtent = extent(scalar(DC_MAXEXTENT))
+	return c, nil
+}
+
+// extent converts the POINTS DC_MINEXTENT and DC_MAXEXTENT return, in tenths
+// of a millimeter, to a SIZE.
+func extent(v int32) SIZE {
+	x, y := int16(v), int16(v>>16)
+	if x <= 0 || y <= 0 {
+		return SIZE{}
+	}
+	return SIZE{Width: uint32(x) * 100, Height: uint32(y) * 100}
+}
+
+// fixedString decodes a UTF-16 string of at most n characters, NUL
+// terminated when shorter.
+func fixedString(b []byte, n int) string {
+	s := make([]uint16, n)
+	for i := range s {
+		s[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return utf16ToString(s)
+}
@@ -0,0 +1,168 @@
+package winprinters
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// dcDriver answers DeviceCapabilities like a printer driver, from raw item
+// buffers and scalar values. Capabilities it does not know fail.
+type dcDriver struct {
+	items   map[uint16][][]byte
+	scalars map[uint16]int32
+}
+
+func (d *dcDriver) deviceCapabilities(capability uint16, out []byte) (int32, error) {
+	if v, ok := d.scalars[capability]; ok {
+		return v, nil
+	}
+	items, ok := d.items[capability]
+	if !ok {
+		return -1, errors.New("the parameter is incorrect")
+	}
+	if out != nil {
+		var n int
+		for _, item := range items {
+			n += copy(out[n:], item)
+		}
+	}
+	return int32(len(items)), nil
+}
+
+func words(v ...uint16) [][]byte {
+	var items [][]byte
+	for _, w := range v {
+		item := make([]byte, 2)
+		binary.LittleEndian.PutUint16(item, w)
+		items = append(items, item)
+	}
+	return items
+}
+
+func dwords(v ...uint32) [][]byte {
+	var items [][]byte
+	for _, w := range v {
+		item := make([]byte, 4)
+		binary.LittleEndian.PutUint32(item, w)
+		items = append(items, item)
+	}
+	return items
+}
+
+func points(v ...int32) [][]byte {
+	var items [][]byte
+	for i := 0; i < len(v); i += 2 {
+		item := make([]byte, 8)
+		binary.LittleEndian.PutUint32(item, uint32(v[i]))
+		binary.LittleEndian.PutUint32(item[4:], uint32(v[i+1]))
+		items = append(items, item)
+	}
+	return items
+}
+
+// names encodes s as fixed width names of n characters.
+func names(n int, s ...string) [][]byte {
+	var items [][]byte
+	for _, name := range s {
+		item := make([]byte, 2*n)
+		for i, c := range utf16.Encode([]rune(name)) {
+			binary.LittleEndian.PutUint16(item[2*i:], c)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func TestQueryCapabilities(t *testing.T) {
+	d := &dcDriver{
+		items: map[uint16][][]byte{
+			DC_PAPERS:          words(1, 9, 256),
+			DC_PAPERNAMES:      names(CCHPAPERNAME, "Letter", "A4", "4 x 6 in - a name of exactly sixty-four characters for the tests"),
+			DC_PAPERSIZE:       points(2159, 2794, 2100, 2970, 1016, 1524),
+			DC_BINS:            words(7, 15),
+			DC_BINNAMES:        names(CCHBINNAME, "Automatically Select"),
+			DC_ENUMRESOLUTIONS: points(203, 203, 300, 300),
+			DC_NUP:             dwords(1, 2, 4),
+			DC_MEDIATYPES:      dwords(1, 257),
+			DC_MEDIATYPENAMES:  names(CCHMEDIATYPENAME, "Plain", "Direct Thermal"),
+		},
+		scalars: map[uint16]int32{
+			DC_DUPLEX:      1,
+			DC_COLORDEVICE: 0,
+			DC_COPIES:      999,
+			DC_ORIENTATION: 270,
+			DC_MINEXTENT:   254<<16 | 254,
+			DC_MAXEXTENT:   -1,
+		},
+	}
+	got, err := queryCapabilities(d.deviceCapabilities)
+	if err != nil {
+		t.Fatalf("queryCapabilities failed: %v", err)
+	}
+	want := &Capabilities{
+		Papers: []PaperSize{
+			{ID: 1, Name: "Letter", Size: SIZE{Width: 215900, Height: 279400}},
+			{ID: 9, Name: "A4", Size: SIZE{Width: 210000, Height: 297000}},
+			{ID: 256, Name: "4 x 6 in - a name of exactly sixty-four characters for the tests", Size: SIZE{Width: 101600, Height: 152400}},
+		},
+		Bins:        []PaperBin{{ID: 7, Name: "Automatically Select"}, {ID: 15}},
+		Resolutions: []Resolution{{X: 203, Y: 203}, {X: 300, Y: 300}},
+		MediaTypes:  []MediaType{{ID: 1, Name: "Plain"}, {ID: 257, Name: "Direct Thermal"}},
+		NUp:         []uint32{1, 2, 4},
+		Duplex:      true,
+		MaxCopies:   999,
+		Orientation: 270,
+		MinExtent:   SIZE{Width: 25400, Height: 25400},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("queryCapabilities() = %+v, want %+v", got, want)
+	}
+	if p, ok := got.PaperByName("A4"); !ok || p.ID != 9 {
+		t.Errorf("PaperByName(A4) = %+v, %v", p, ok)
+	}
+	if _, ok := got.Paper(70); ok {
+		t.Errorf("Paper(70) found a paper the driver lacks")
+	}
+
+	delete(d.items, DC_PAPERS)
+	if _, err = queryCapabilities(d.deviceCapabilities); err == nil {
+		t.Errorf("queryCapabilities without paper sizes succeeded")
+	}
+}
+
+func TestPrinter_CapabilitiesFake(t *testing.T) {
+	fake := newTestSpooler(t)
+	caps := &Capabilities{
+		Papers:      []PaperSize{{ID: 256, Name: "4x6", Size: SIZE{Width: 101600, Height: 152400}}},
+		Resolutions: []Resolution{{X: 203, Y: 203}},
+		MaxCopies:   1,
+	}
+	if err := fake.UpdatePrinter("Label", func(p *FakePrinter) { p.Capabilities = caps }); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Open("Label")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closePrinter(p)
+	got, err := p.Capabilities()
+	if err != nil || !reflect.DeepEqual(got, caps) {
+		t.Fatalf("Capabilities() = %+v, %v", got, err)
+	}
+	got.Papers[0].Name = "changed"
+	if again, _ := p.Capabilities(); again.Papers[0].Name != "4x6" {
+		t.Errorf("changing the result changed the fake")
+	}
+
+	office, err := Open("Office")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closePrinter(office)
+	if _, err = office.Capabilities(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Capabilities() of a printer without them: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package winprinters
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// Capabilities implements CapabilitiesReader with DeviceCapabilities, for
+// the default DevMode of the printer.
+func (p *winspoolPrinter) Capabilities() (*Capabilities, error) {
+	info, err := p.PrinterInfo()
+	if err != nil {
+		return nil, err
+	}
+	device, err := windows.UTF16PtrFromString(info.Name)
+	if err != nil {
+		return nil, err
+	}
+	port, err := windows.UTF16PtrFromString(info.PortName)
+	if err != nil {
+		return nil, err
+	}
+	return queryCapabilities(func(capability uint16, out []byte) (int32, error) {
+		var b *byte
+		if len(out) > 0 {
+			b = &out[0]
+		}
+		return DeviceCapabilities(device, port, capability, b, nil)
+	})
+}
@@ -0,0 +1,43 @@
+// ippserver command shares the printers of the local spooler as IPP
+// Everywhere printers at ipp://host:631/printers/<name>.
+//
+// Documents are passed to the printers as they are, so clients must send
+// data the printers understand, such as PCL or PostScript. Use -formats to
+// advertise the document formats the printers accept natively.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/chenxi2015/winprinters"
+)
+
+var (
+	addr    = flag.String("addr", ":631", "address to listen on")
+	formats = flag.String("formats", "", "comma separated document formats accepted besides application/octet-stream")
+	maxReq  = flag.Int64("max-request", DefaultMaxRequestSize, "maximum size in bytes of the attributes of a request, not counting document data")
+)
+
+func main() {
+	flag.Parse()
+	s := NewServer(spoolerBackend{winprinters.CurrentSpooler()})
+	s.MaxRequestSize = *maxReq
+	for _, f := range strings.Split(*formats, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			s.Formats = append(s.Formats, f)
+		}
+	}
+	names, err := s.Backend.ReadNames()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, name := range names {
+		log.Printf("sharing %q at %s%s", name, printersPath, url.PathEscape(name))
+	}
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, s))
+}
@@ -0,0 +1,896 @@
+package main
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chenxi2015/winprinters"
+	"github.com/chenxi2015/winprinters/ipp"
+)
+
+// Backend is the print system published by a Server.
+type Backend interface {
+	// GetDefault returns the name of the default printer.
+	GetDefault() (string, error)
+	// ReadNames returns the names of the published printers.
+	ReadNames() ([]string, error)
+	// Open opens the named printer.
+	Open(name string) (*winprinters.Printer, error)
+	// DevMode returns the default document settings of the named printer.
+	DevMode(p *winprinters.Printer, name string) (*winprinters.DevMode, error)
+}
+
+// spoolerBackend publishes the printers of a winprinters.Spooler.
+type spoolerBackend struct {
+	s winprinters.Spooler
+}
+
+func (b spoolerBackend) GetDefault() (string, error) {
+	return b.s.GetDefault()
+}
+
+func (b spoolerBackend) ReadNames() ([]string, error) {
+	return b.s.ReadNames()
+}
+
+func (b spoolerBackend) Open(name string) (*winprinters.Printer, error) {
+	h, err := b.s.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return winprinters.NewPrinter(h), nil
+}
+
+// DevMode asks the driver for its defaults, which only works on winspool
+// printers.
+func (b spoolerBackend) DevMode(p *winprinters.Printer, name string) (*winprinters.DevMode, error) {
+	return p.DocumentPropertiesGet(name)
+}
+
+const printersPath = "/printers/"
+
+// octetStream is the document format of raw print data, which every printer
+// accepts.
+const octetStream = "application/octet-stream"
+
+// DefaultMaxRequestSize is the limit of the header and attributes of a
+// request used when Server.MaxRequestSize is zero.
+const DefaultMaxRequestSize = 1 << 20
+
+// Server is an IPP Everywhere server publishing every printer of its Backend
+// at /printers/<name>. Documents are passed to the printer as they are, so
+// Formats should list only the formats the printers understand natively.
+type Server struct {
+	Backend Backend
+	Formats []string // document formats accepted besides application/octet-stream
+
+	// MaxRequestSize limits the header and attributes of a request, in
+	// bytes; the document data that follows them is not limited.
+	MaxRequestSize int64
+
+	started time.Time
+}
+
+// NewServer returns a Server publishing the printers of b.
+func NewServer(b Backend) *Server {
+	return &Server{Backend: b, started: time.Now()}
+}
+
+// statusError is an error answered with an IPP status code.
+type statusError struct {
+	status ipp.Status
+	msg    string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+func errorf(status ipp.Status, format string, args ...interface{}) error {
+	return &statusError{status: status, msg: fmt.Sprintf(format, args...)}
+}
+
+// statusOf maps err onto the IPP status answered for it.
+func statusOf(err error) ipp.Status {
+	var se *statusError
+	switch {
+	case errors.As(err, &se):
+		return se.status
+	case errors.Is(err, winprinters.ErrPrinterNotFound), errors.Is(err, winprinters.ErrJobNotFound):
+		return ipp.StatusNotFound
+	case errors.Is(err, winprinters.ErrUnsupported):
+		return ipp.StatusOperationNotSupported
+	}
+	return ipp.StatusInternalError
+}
+
+// request is an IPP request being served.
+type request struct {
+	*ipp.Message
+	http *http.Request
+	body io.Reader
+}
+
+// opAttr returns the operation attribute called name.
+func (r *request) opAttr(name string) ipp.Attribute {
+	a, _ := r.Attr(ipp.TagOperationGroup, name)
+	return a
+}
+
+// printerURI returns the URI of the named printer as seen by the client.
+func (r *request) printerURI(name string) string {
+	scheme := "ipp"
+	if r.http.TLS != nil {
+		scheme = "ipps"
+	}
+	return scheme + "://" + r.http.Host + printersPath + url.PathEscape(name)
+}
+
+func (r *request) jobURI(name string, id uint32) string {
+	return r.printerURI(name) + "/" + strconv.FormatUint(uint64(id), 10)
+}
+
+// target returns the printer name and, for job URIs, the job ID addressed by
+// the request.
+func (r *request) target() (name string, jobID uint32, err error) {
+	path := r.http.URL.EscapedPath()
+	for _, attr := range []string{"printer-uri", "job-uri"} {
+		if uri := r.opAttr(attr).Text(); uri != "" {
+			u, err := url.Parse(uri)
+			if err != nil {
+				return "", 0, errorf(ipp.StatusBadRequest, "bad %s %q", attr, uri)
+			}
+			path = u.EscapedPath()
+			break
+		}
+	}
+	if !strings.HasPrefix(path, printersPath) {
+		return "", 0, errorf(ipp.StatusNotFound, "no printer at %q", path)
+	}
+	parts := strings.Split(strings.TrimPrefix(path, printersPath), "/")
+	if len(parts) > 2 {
+		return "", 0, errorf(ipp.StatusNotFound, "no printer at %q", path)
+	}
+	if name, err = url.PathUnescape(parts[0]); err != nil || name == "" {
+		return "", 0, errorf(ipp.StatusNotFound, "no printer at %q", path)
+	}
+	if len(parts) == 2 {
+		id, err := strconv.ParseUint(parts[1], 10, 32)
+		if err != nil {
+			return "", 0, errorf(ipp.StatusNotFound, "no job at %q", path)
+		}
+		jobID = uint32(id)
+	}
+	return name, jobID, nil
+}
+
+// requested returns the set of requested-attributes, or nil when all
+// attributes are wanted.
+func (r *request) requested(defaults ...string) map[string]bool {
+	names := r.opAttr("requested-attributes").Texts()
+	if len(names) == 0 {
+		names = defaults
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		switch n {
+		case "all", "printer-description", "job-description", "job-template":
+			return nil
+		}
+		set[n] = true
+	}
+	return set
+}
+
+// filter drops the attributes of g that are not in want.
+func filter(g *ipp.Group, want map[string]bool) {
+	if want == nil {
+		return
+	}
+	attrs := g.Attrs[:0]
+	for _, a := range g.Attrs {
+		if want[a.Name] {
+			attrs = append(attrs, a)
+		}
+	}
+	g.Attrs = attrs
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "IPP requests must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "application/ipp" {
+		http.Error(w, "unexpected content type "+ct, http.StatusUnsupportedMediaType)
+		return
+	}
+	limit := s.MaxRequestSize
+	if limit <= 0 {
+		limit = DefaultMaxRequestSize
+	}
+	// The document data is read from r.Body itself, past the limit.
+	var msg ipp.Message
+	if err := msg.Decode(http.MaxBytesReader(w, r.Body, limit)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b, err := s.serve(&request{Message: &msg, http: r, body: r.Body}).MarshalBinary()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/ipp")
+	_, _ = w.Write(b)
+}
+
+// serve answers req, turning handler errors into error responses.
+func (s *Server) serve(req *request) *ipp.Message {
+	resp, err := s.handle(req)
+	if err == nil {
+		return resp
+	}
+	resp = ipp.NewResponse(req.Message, statusOf(err))
+	if resp.Status() == ipp.StatusVersionNotSupported {
+		resp.Version = ipp.Version11
+	}
+	resp.Group(ipp.TagOperationGroup).Add(ipp.MakeAttribute("status-message", ipp.TagText, ipp.String(err.Error())))
+	return resp
+}
+
+func (s *Server) handle(req *request) (*ipp.Message, error) {
+	if major := req.Version >> 8; major != 1 && major != 2 {
+		return nil, errorf(ipp.StatusVersionNotSupported, "IPP/%s is not supported", req.Version)
+	}
+	if req.opAttr("attributes-charset").Text() == "" || req.opAttr("attributes-natural-language").Text() == "" {
+		return nil, errorf(ipp.StatusBadRequest, "missing attributes-charset or attributes-natural-language")
+	}
+	switch req.Op() {
+	case ipp.OpGetPrinterAttributes:
+		return s.getPrinterAttributes(req)
+	case ipp.OpValidateJob:
+		return s.validateJob(req)
+	case ipp.OpPrintJob:
+		return s.printJob(req)
+	case ipp.OpGetJobs:
+		return s.getJobs(req)
+	case ipp.OpGetJobAttributes:
+		return s.getJobAttributes(req)
+	case ipp.OpCancelJob:
+		return s.cancelJob(req)
+	case ipp.OpCupsGetDefault:
+		return s.cupsGetDefault(req)
+	case ipp.OpCupsGetPrinters:
+		return s.cupsGetPrinters(req)
+	}
+	return nil, errorf(ipp.StatusOperationNotSupported, "%s is not supported", req.Op())
+}
+
+// operations are the operations-supported by the server.
+var operations = []ipp.Op{
+	ipp.OpPrintJob, ipp.OpValidateJob, ipp.OpCancelJob, ipp.OpGetJobAttributes,
+	ipp.OpGetJobs, ipp.OpGetPrinterAttributes, ipp.OpCupsGetDefault, ipp.OpCupsGetPrinters,
+}
+
+// open opens the named printer and calls f with it.
+func (s *Server) open(name string, f func(p *winprinters.Printer) error) error {
+	p, err := s.Backend.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = p.Close() }()
+	return f(p)
+}
+
+func (s *Server) getPrinterAttributes(req *request) (*ipp.Message, error) {
+	name, _, err := req.target()
+	if err != nil {
+		return nil, err
+	}
+	g, err := s.printerAttributes(req, name)
+	if err != nil {
+		return nil, err
+	}
+	filter(g, req.requested())
+	resp := ipp.NewResponse(req.Message, ipp.StatusOK)
+	resp.Groups = append(resp.Groups, g)
+	return resp, nil
+}
+
+func (s *Server) cupsGetDefault(req *request) (*ipp.Message, error) {
+	name, err := s.Backend.GetDefault()
+	if err != nil {
+		return nil, err
+	}
+	g, err := s.printerAttributes(req, name)
+	if err != nil {
+		return nil, err
+	}
+	filter(g, req.requested())
+	resp := ipp.NewResponse(req.Message, ipp.StatusOK)
+	resp.Groups = append(resp.Groups, g)
+	return resp, nil
+}
+
+func (s *Server) cupsGetPrinters(req *request) (*ipp.Message, error) {
+	names, err := s.Backend.ReadNames()
+	if err != nil {
+		return nil, err
+	}
+	if limit := req.opAttr("limit").Int(); limit > 0 && limit < len(names) {
+		names = names[:limit]
+	}
+	want := req.requested()
+	resp := ipp.NewResponse(req.Message, ipp.StatusOK)
+	for _, name := range names {
+		g, err := s.printerAttributes(req, name)
+		if errors.Is(err, winprinters.ErrPrinterNotFound) {
+			continue // removed since ReadNames
+		}
+		if err != nil {
+			return nil, err
+		}
+		filter(g, want)
+		resp.Groups = append(resp.Groups, g)
+	}
+	return resp, nil
+}
+
+// printerAttributes describes the named printer from its driver, forms,
+// queue and default DevMode.
+func (s *Server) printerAttributes(req *request, name string) (*ipp.Group, error) {
+	var (
+		di    *winprinters.DriverInfo
+		forms []winprinters.FormInfo
+		jobs  []winprinters.JobInfo
+		dm    *winprinters.DevMode
+	)
+	err := s.open(name, func(p *winprinters.Printer) (err error) {
+		if di, err = p.DriverInfo(); err != nil {
+			return
+		}
+		if forms, err = p.Forms(); err != nil {
+			return
+		}
+		if jobs, err = p.Jobs(); err != nil {
+			return
+		}
+		// Defaults are optional: only winspool printers have a DevMode.
+		dm, _ = s.Backend.DevMode(p, name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state := ipp.PrinterIdle
+	for _, j := range jobs {
+		if j.StatusCode&winprinters.JOB_STATUS_PRINTING != 0 {
+			state = ipp.PrinterProcessing
+		}
+	}
+	ops := make([]ipp.Data, 0, len(operations))
+	for _, op := range operations {
+		ops = append(ops, ipp.Integer(op))
+	}
+	formats := []ipp.Data{ipp.String(octetStream)}
+	for _, f := range s.Formats {
+		formats = append(formats, ipp.String(f))
+	}
+	security := "none"
+	if req.http.TLS != nil {
+		security = "tls"
+	}
+
+	g := &ipp.Group{Tag: ipp.TagPrinterGroup}
+	g.Add(
+		ipp.MakeAttribute("printer-uri-supported", ipp.TagURI, ipp.String(req.printerURI(name))),
+		ipp.MakeAttribute("uri-security-supported", ipp.TagKeyword, ipp.String(security)),
+		ipp.MakeAttribute("uri-authentication-supported", ipp.TagKeyword, ipp.String("requesting-user-name")),
+		ipp.MakeAttribute("printer-name", ipp.TagName, ipp.String(name)),
+		ipp.MakeAttribute("printer-info", ipp.TagText, ipp.String(name)),
+		ipp.MakeAttribute("printer-make-and-model", ipp.TagText, ipp.String(di.Name)),
+		ipp.MakeAttribute("printer-uuid", ipp.TagURI, ipp.String(printerUUID(name))),
+		ipp.MakeAttribute("printer-state", ipp.TagEnum, ipp.Integer(state)),
+		ipp.MakeAttribute("printer-state-reasons", ipp.TagKeyword, ipp.String("none")),
+		ipp.MakeAttribute("printer-is-accepting-jobs", ipp.TagBoolean, ipp.Boolean(true)),
+		ipp.MakeAttribute("queued-job-count", ipp.TagInteger, ipp.Integer(len(jobs))),
+		ipp.MakeAttribute("printer-up-time", ipp.TagInteger, ipp.Integer(time.Since(s.started)/time.Second+1)),
+		ipp.MakeAttribute("ipp-versions-supported", ipp.TagKeyword, ipp.String("1.1"), ipp.String("2.0")),
+		ipp.MakeAttribute("ipp-features-supported", ipp.TagKeyword, ipp.String("ipp-everywhere")),
+		ipp.MakeAttribute("operations-supported", ipp.TagEnum, ops...),
+		ipp.MakeAttribute("charset-configured", ipp.TagCharset, ipp.String("utf-8")),
+		ipp.MakeAttribute("charset-supported", ipp.TagCharset, ipp.String("utf-8")),
+		ipp.MakeAttribute("natural-language-configured", ipp.TagLanguage, ipp.String("en")),
+		ipp.MakeAttribute("generated-natural-language-supported", ipp.TagLanguage, ipp.String("en")),
+		ipp.MakeAttribute("document-format-default", ipp.TagMimeType, ipp.String(octetStream)),
+		ipp.MakeAttribute("document-format-supported", ipp.TagMimeType, formats...),
+		ipp.MakeAttribute("pdl-override-supported", ipp.TagKeyword, ipp.String("attempted")),
+		ipp.MakeAttribute("compression-supported", ipp.TagKeyword, ipp.String("none")),
+		ipp.MakeAttribute("multiple-document-jobs-supported", ipp.TagBoolean, ipp.Boolean(false)),
+		ipp.MakeAttribute("copies-default", ipp.TagInteger, ipp.Integer(1)),
+		ipp.MakeAttribute("copies-supported", ipp.TagRange, ipp.Range{Lower: 1, Upper: 1}),
+		ipp.MakeAttribute("color-supported", ipp.TagBoolean, ipp.Boolean(devModeColor(dm))),
+	)
+	g.Add(mediaAttributes(forms, dm)...)
+	g.Add(devModeAttributes(dm)...)
+	return g, nil
+}
+
+// printerUUID returns a name-based (version 5 style) UUID URN, stable for as
+// long as the printer keeps its name.
+func printerUUID(name string) string {
+	h := sha1.Sum([]byte("winprinters/" + name))
+	h[6] = h[6]&0x0f | 0x50
+	h[8] = h[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
+
+// pwgMediaNames maps Windows form names onto PWG 5101.1 media names.
+var pwgMediaNames = map[string]string{
+	"Letter":       "na_letter_8.5x11in",
+	"Legal":        "na_legal_8.5x14in",
+	"Executive":    "na_executive_7.25x10.5in",
+	"Tabloid":      "na_ledger_11x17in",
+	"Statement":    "na_invoice_5.5x8.5in",
+	"A3":           "iso_a3_297x420mm",
+	"A4":           "iso_a4_210x297mm",
+	"A5":           "iso_a5_148x210mm",
+	"A6":           "iso_a6_105x148mm",
+	"B4 (JIS)":     "jis_b4_257x364mm",
+	"B5 (JIS)":     "jis_b5_182x257mm",
+	"Envelope #10": "na_number-10_4.125x9.5in",
+	"Envelope DL":  "iso_dl_110x220mm",
+	"Envelope C5":  "iso_c5_162x229mm",
+}
+
+// pwgMediaName returns the PWG self-describing name of form f.
+func pwgMediaName(f winprinters.FormInfo) string {
+	if name, ok := pwgMediaNames[f.Name]; ok {
+		return name
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(f.Name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' {
+			b.WriteRune(r)
+		} else if s := b.String(); s != "" && s[len(s)-1] != '-' {
+			b.WriteByte('-')
+		}
+	}
+	return fmt.Sprintf("custom_%s_%gx%gmm", strings.Trim(b.String(), "-"),
+		float64(f.Size.Width)/1000, float64(f.Size.Height)/1000)
+}
+
+// mediaCol describes form f as a media-col collection. Forms measure in
+// thousandths of a millimeter, IPP in hundredths.
+func mediaCol(f winprinters.FormInfo) ipp.Collection {
+	dim := func(v uint32) ipp.Integer { return ipp.Integer(v / 10) }
+	return ipp.Collection{
+		ipp.MakeAttribute("media-key", ipp.TagName, ipp.String(f.Name)),
+		ipp.MakeAttribute("media-size-name", ipp.TagKeyword, ipp.String(pwgMediaName(f))),
+		ipp.MakeAttribute("media-size", ipp.TagBeginCollection, ipp.Collection{
+			ipp.MakeAttribute("x-dimension", ipp.TagInteger, dim(f.Size.Width)),
+			ipp.MakeAttribute("y-dimension", ipp.TagInteger, dim(f.Size.Height)),
+		}),
+		ipp.MakeAttribute("media-left-margin", ipp.TagInteger, dim(f.ImageableArea.Left)),
+		ipp.MakeAttribute("media-top-margin", ipp.TagInteger, dim(f.ImageableArea.Top)),
+		ipp.MakeAttribute("media-right-margin", ipp.TagInteger, dim(f.Size.Width-f.ImageableArea.Right)),
+		ipp.MakeAttribute("media-bottom-margin", ipp.TagInteger, dim(f.Size.Height-f.ImageableArea.Bottom)),
+	}
+}
+
+// mediaAttributes lists forms as media, with the DevMode form as default.
+func mediaAttributes(forms []winprinters.FormInfo, dm *winprinters.DevMode) []ipp.Attribute {
+	if len(forms) == 0 {
+		return nil
+	}
+	def := forms[0]
+	if dm != nil {
+		if name, ok := dm.GetFormName(); ok {
+			for _, f := range forms {
+				if f.Name == name {
+					def = f
+				}
+			}
+		}
+	}
+	names := make([]ipp.Data, 0, len(forms))
+	cols := make([]ipp.Data, 0, len(forms))
+	for _, f := range forms {
+		names = append(names, ipp.String(pwgMediaName(f)))
+		cols = append(cols, mediaCol(f))
+	}
+	return []ipp.Attribute{
+		ipp.MakeAttribute("media-default", ipp.TagKeyword, ipp.String(pwgMediaName(def))),
+		ipp.MakeAttribute("media-supported", ipp.TagKeyword, names...),
+		ipp.MakeAttribute("media-col-default", ipp.TagBeginCollection, mediaCol(def)),
+		ipp.MakeAttribute("media-col-database", ipp.TagBeginCollection, cols...),
+	}
+}
+
+func devModeColor(dm *winprinters.DevMode) bool {
+	if dm == nil {
+		return false
+	}
+	color, ok := dm.GetColor()
+	return ok && color == winprinters.DMCOLOR_COLOR
+}
+
+// devModeAttributes describes the orientation, duplex and color defaults of
+// dm.
+func devModeAttributes(dm *winprinters.DevMode) []ipp.Attribute {
+	if dm == nil {
+		return nil
+	}
+	var attrs []ipp.Attribute
+	if o, ok := dm.GetOrientation(); ok {
+		orientation := 3 // portrait
+		if o == winprinters.DMORIENT_LANDSCAPE {
+			orientation = 4
+		}
+		attrs = append(attrs,
+			ipp.MakeAttribute("orientation-requested-default", ipp.TagEnum, ipp.Integer(orientation)),
+			ipp.MakeAttribute("orientation-requested-supported", ipp.TagEnum, ipp.Integer(3), ipp.Integer(4)),
+		)
+	}
+	if d, ok := dm.GetDuplex(); ok {
+		sides := "one-sided"
+		switch d {
+		case winprinters.DMDUP_VERTICAL:
+			sides = "two-sided-long-edge"
+		case winprinters.DMDUP_HORIZONTAL:
+			sides = "two-sided-short-edge"
+		}
+		attrs = append(attrs,
+			ipp.MakeAttribute("sides-default", ipp.TagKeyword, ipp.String(sides)),
+			ipp.MakeAttribute("sides-supported", ipp.TagKeyword, ipp.String("one-sided"),
+				ipp.String("two-sided-long-edge"), ipp.String("two-sided-short-edge")),
+		)
+	}
+	if _, ok := dm.GetColor(); ok {
+		mode := ipp.MakeAttribute("print-color-mode-supported", ipp.TagKeyword, ipp.String("monochrome"))
+		def := "monochrome"
+		if devModeColor(dm) {
+			mode.Values = append(mode.Values, ipp.Value{Tag: ipp.TagKeyword, Data: ipp.String("color")})
+			def = "color"
+		}
+		attrs = append(attrs, ipp.MakeAttribute("print-color-mode-default", ipp.TagKeyword, ipp.String(def)), mode)
+	}
+	return attrs
+}
+
+// checkJob validates the document format and job template attributes of a
+// Print-Job or Validate-Job request. Raw documents are printed as they are,
+// so job template attributes are ignored, or refused when the client asked
+// for ipp-attribute-fidelity.
+func (s *Server) checkJob(req *request) (format string, ignored []ipp.Attribute, err error) {
+	format = octetStream
+	if f := req.opAttr("document-format").Text(); f != "" {
+		format = f
+	}
+	ok := format == octetStream
+	for _, f := range s.Formats {
+		ok = ok || f == format
+	}
+	if !ok {
+		return "", nil, errorf(ipp.StatusDocumentFormatNotSupported, "document-format %q is not supported", format)
+	}
+	if c := req.opAttr("compression").Text(); c != "" && c != "none" {
+		return "", nil, errorf(ipp.StatusCompressionNotSupported, "compression %q is not supported", c)
+	}
+	for _, g := range req.GroupsOf(ipp.TagJobGroup) {
+		ignored = append(ignored, g.Attrs...)
+	}
+	if len(ignored) > 0 && req.opAttr("ipp-attribute-fidelity").Bool() {
+		return "", nil, errorf(ipp.StatusAttributesOrValuesNotSupported, "job template attributes are not supported")
+	}
+	return format, ignored, nil
+}
+
+// jobResponse returns a successful response, or one reporting the ignored
+// attributes.
+func jobResponse(req *request, ignored []ipp.Attribute) *ipp.Message {
+	if len(ignored) == 0 {
+		return ipp.NewResponse(req.Message, ipp.StatusOK)
+	}
+	resp := ipp.NewResponse(req.Message, ipp.StatusOKIgnoredOrSubstituted)
+	unsupported := resp.Group(ipp.TagUnsupportedGroup)
+	for _, a := range ignored {
+		unsupported.Add(ipp.MakeAttribute(a.Name, ipp.TagUnsupportedValue, ipp.Void{}))
+	}
+	return resp
+}
+
+func (s *Server) validateJob(req *request) (*ipp.Message, error) {
+	name, _, err := req.target()
+	if err != nil {
+		return nil, err
+	}
+	_, ignored, err := s.checkJob(req)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.open(name, func(*winprinters.Printer) error { return nil }); err != nil {
+		return nil, err
+	}
+	return jobResponse(req, ignored), nil
+}
+
+// readErrReader records the error of its reader, to tell the failures of
+// the client sending a document from those of the printer it is copied to.
+type readErrReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *readErrReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+// printJob spools the document data that follows the request as a raw
+// document.
+func (s *Server) printJob(req *request) (*ipp.Message, error) {
+	name, _, err := req.target()
+	if err != nil {
+		return nil, err
+	}
+	_, ignored, err := s.checkJob(req)
+	if err != nil {
+		return nil, err
+	}
+	docName := req.opAttr("job-name").Text()
+	if docName == "" {
+		docName = req.opAttr("document-name").Text()
+	}
+	if docName == "" {
+		docName = "Untitled"
+	}
+	var job winprinters.JobInfo
+	err = s.open(name, func(p *winprinters.Printer) error {
+		doc, err := p.StartRawDocument(docName)
+		if err != nil {
+			return err
+		}
+		body := &readErrReader{r: req.body}
+		if _, err = io.Copy(p, body); err != nil {
+			// Drop the incomplete document rather than print it: closing
+			// the printer without ending the document aborts it.
+			if doc.ID != 0 {
+				_ = p.CancelJob(doc.ID)
+			}
+			if body.err != nil {
+				return errorf(ipp.StatusDocumentAccessError, "reading document data: %v", body.err)
+			}
+			return err
+		}
+		if err = p.EndDocument(); err != nil {
+			return err
+		}
+		job = winprinters.JobInfo{JobID: doc.ID, DocumentName: docName, StatusCode: winprinters.JOB_STATUS_COMPLETE}
+		if jobs, err := p.Jobs(); err == nil {
+			for _, j := range jobs {
+				if j.JobID == doc.ID {
+					job = j
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := jobResponse(req, ignored)
+	g := jobAttributes(req, name, job)
+	filter(g, map[string]bool{"job-id": true, "job-uri": true, "job-state": true, "job-state-reasons": true})
+	resp.Groups = append(resp.Groups, g)
+	return resp, nil
+}
+
+// whichJobs selects the jobs matching the which-jobs and my-jobs operation
+// attributes.
+func whichJobs(req *request, jobs []winprinters.JobInfo) ([]winprinters.JobInfo, error) {
+	which := req.opAttr("which-jobs").Text()
+	if which == "" {
+		which = "not-completed"
+	}
+	if which != "not-completed" && which != "completed" && which != "all" {
+		return nil, errorf(ipp.StatusAttributesOrValuesNotSupported, "which-jobs %q is not supported", which)
+	}
+	user := ""
+	if req.opAttr("my-jobs").Bool() {
+		user = req.opAttr("requesting-user-name").Text()
+	}
+	var selected []winprinters.JobInfo
+	for _, j := range jobs {
+		if user != "" && !strings.EqualFold(j.UserName, user) {
+			continue
+		}
+		if terminal := jobState(j.StatusCode) >= ipp.JobCanceled; which == "all" || terminal == (which == "completed") {
+			selected = append(selected, j)
+		}
+	}
+	return selected, nil
+}
+
+func (s *Server) getJobs(req *request) (*ipp.Message, error) {
+	name, _, err := req.target()
+	if err != nil {
+		return nil, err
+	}
+	var jobs []winprinters.JobInfo
+	err = s.open(name, func(p *winprinters.Printer) (err error) {
+		jobs, err = p.Jobs()
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	if jobs, err = whichJobs(req, jobs); err != nil {
+		return nil, err
+	}
+	if limit := req.opAttr("limit").Int(); limit > 0 && limit < len(jobs) {
+		jobs = jobs[:limit]
+	}
+	want := req.requested("job-id", "job-uri")
+	resp := ipp.NewResponse(req.Message, ipp.StatusOK)
+	for _, j := range jobs {
+		g := jobAttributes(req, name, j)
+		filter(g, want)
+		resp.Groups = append(resp.Groups, g)
+	}
+	return resp, nil
+}
+
+// job returns the job addressed by a job-uri, or by printer-uri and job-id.
+func (s *Server) job(req *request) (string, uint32, error) {
+	name, id, err := req.target()
+	if err != nil {
+		return "", 0, err
+	}
+	if id == 0 {
+		id = uint32(req.opAttr("job-id").Int())
+	}
+	if id == 0 {
+		return "", 0, errorf(ipp.StatusBadRequest, "missing job-id")
+	}
+	return name, id, nil
+}
+
+func (s *Server) getJobAttributes(req *request) (*ipp.Message, error) {
+	name, id, err := s.job(req)
+	if err != nil {
+		return nil, err
+	}
+	var g *ipp.Group
+	err = s.open(name, func(p *winprinters.Printer) error {
+		jobs, err := p.Jobs()
+		if err != nil {
+			return err
+		}
+		for _, j := range jobs {
+			if j.JobID == id {
+				g = jobAttributes(req, name, j)
+				return nil
+			}
+		}
+		return winprinters.ErrJobNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	filter(g, req.requested())
+	resp := ipp.NewResponse(req.Message, ipp.StatusOK)
+	resp.Groups = append(resp.Groups, g)
+	return resp, nil
+}
+
+func (s *Server) cancelJob(req *request) (*ipp.Message, error) {
+	name, id, err := s.job(req)
+	if err != nil {
+		return nil, err
+	}
+	err = s.open(name, func(p *winprinters.Printer) error {
+		return p.CancelJob(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ipp.NewResponse(req.Message, ipp.StatusOK), nil
+}
+
+// jobState maps JOB_STATUS_* flags onto an IPP job-state.
+func jobState(code winprinters.JobStatus) int {
+	switch {
+	case code&(winprinters.JOB_STATUS_DELETED|winprinters.JOB_STATUS_DELETING) != 0:
+		return ipp.JobCanceled
+	case code&(winprinters.JOB_STATUS_PRINTED|winprinters.JOB_STATUS_COMPLETE) != 0:
+		return ipp.JobCompleted
+	case code&winprinters.JOB_STATUS_PRINTING != 0:
+		if code&(winprinters.JOB_STATUS_PAUSED|winprinters.JOB_STATUS_ERROR|winprinters.JOB_STATUS_OFFLINE|
+			winprinters.JOB_STATUS_PAPEROUT|winprinters.JOB_STATUS_USER_INTERVENTION|winprinters.JOB_STATUS_BLOCKED_DEVQ) != 0 {
+			return ipp.JobProcessingStopped
+		}
+		return ipp.JobProcessing
+	case code&winprinters.JOB_STATUS_ERROR != 0:
+		return ipp.JobAborted
+	case code&winprinters.JOB_STATUS_PAUSED != 0:
+		return ipp.JobPendingHeld
+	}
+	return ipp.JobPending
+}
+
+// jobStateReasons maps JOB_STATUS_* flags onto job-state-reasons keywords.
+func jobStateReasons(code winprinters.JobStatus) []ipp.Data {
+	var reasons []ipp.Data
+	for _, r := range []struct {
+		flag   winprinters.JobStatus
+		reason string
+	}{
+		{winprinters.JOB_STATUS_SPOOLING, "job-incoming"},
+		{winprinters.JOB_STATUS_PRINTING, "job-printing"},
+		{winprinters.JOB_STATUS_PAUSED, "job-suspended"},
+		{winprinters.JOB_STATUS_DELETING | winprinters.JOB_STATUS_DELETED, "job-canceled-by-user"},
+		{winprinters.JOB_STATUS_ERROR, "aborted-by-system"},
+		{winprinters.JOB_STATUS_OFFLINE | winprinters.JOB_STATUS_PAPEROUT | winprinters.JOB_STATUS_USER_INTERVENTION |
+			winprinters.JOB_STATUS_BLOCKED_DEVQ, "printer-stopped"},
+		{winprinters.JOB_STATUS_PRINTED | winprinters.JOB_STATUS_COMPLETE, "job-completed-successfully"},
+	} {
+		if code&r.flag != 0 {
+			reasons = append(reasons, ipp.String(r.reason))
+		}
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, ipp.String("none"))
+	}
+	return reasons
+}
+
+// jobAttributes describes job j queued on the named printer.
+func jobAttributes(req *request, name string, j winprinters.JobInfo) *ipp.Group {
+	g := &ipp.Group{Tag: ipp.TagJobGroup}
+	g.Add(
+		ipp.MakeAttribute("job-id", ipp.TagInteger, ipp.Integer(j.JobID)),
+		ipp.MakeAttribute("job-uri", ipp.TagURI, ipp.String(req.jobURI(name, j.JobID))),
+		ipp.MakeAttribute("job-printer-uri", ipp.TagURI, ipp.String(req.printerURI(name))),
+		ipp.MakeAttribute("job-name", ipp.TagName, ipp.String(j.DocumentName)),
+		ipp.MakeAttribute("job-state", ipp.TagEnum, ipp.Integer(jobState(j.StatusCode))),
+		ipp.MakeAttribute("job-state-reasons", ipp.TagKeyword, jobStateReasons(j.StatusCode)...),
+		ipp.MakeAttribute("document-format", ipp.TagMimeType, ipp.String(winprinters.DocumentFormat(j.DataType))),
+		ipp.MakeAttribute("job-impressions", ipp.TagInteger, ipp.Integer(j.TotalPages)),
+		ipp.MakeAttribute("job-impressions-completed", ipp.TagInteger, ipp.Integer(j.PagesPrinted)),
+	)
+	if j.UserName != "" {
+		g.Add(ipp.MakeAttribute("job-originating-user-name", ipp.TagName, ipp.String(j.UserName)))
+	}
+	if j.UserMachineName != "" {
+		g.Add(ipp.MakeAttribute("job-originating-host-name", ipp.TagName, ipp.String(j.UserMachineName)))
+	}
+	if j.Status != "" {
+		g.Add(ipp.MakeAttribute("job-state-message", ipp.TagText, ipp.String(j.Status)))
+	}
+	if j.Priority > 0 {
+		g.Add(ipp.MakeAttribute("job-priority", ipp.TagInteger, ipp.Integer(j.Priority)))
+	}
+	if !j.Submitted.IsZero() {
+		g.Add(
+			ipp.MakeAttribute("time-at-creation", ipp.TagInteger, ipp.Integer(j.Submitted.Unix())),
+			ipp.MakeAttribute("date-time-at-creation", ipp.TagDateTime, ipp.Time{Time: j.Submitted}),
+		)
+	}
+	return g
+}
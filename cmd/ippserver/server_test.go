@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/chenxi2015/winprinters"
+	"github.com/chenxi2015/winprinters/ipp"
+)
+
+// fakeBackend serves a FakeSpooler with DevModes configured by the test.
+type fakeBackend struct {
+	spoolerBackend
+	devModes map[string]*winprinters.DevMode
+}
+
+func (b fakeBackend) DevMode(_ *winprinters.Printer, name string) (*winprinters.DevMode, error) {
+	if dm, ok := b.devModes[name]; ok {
+		return dm, nil
+	}
+	return nil, &winprinters.UnsupportedError{Op: "DevMode"}
+}
+
+func newTestServer(t *testing.T) (*winprinters.FakeSpooler, *httptest.Server) {
+	fake := winprinters.NewFakeSpooler(
+		winprinters.FakePrinter{
+			Name:   "Label",
+			Driver: winprinters.DriverInfo{Name: "ZDesigner GK420d"},
+			Forms: []winprinters.FormInfo{
+				{Name: "4x6", Size: winprinters.SIZE{Width: 101600, Height: 152400},
+					ImageableArea: winprinters.Rect{Right: 101600, Bottom: 152400}},
+			},
+			Jobs: []winprinters.JobInfo{
+				{JobID: 3, DocumentName: "held.zpl", UserName: "bob", StatusCode: winprinters.JOB_STATUS_PAUSED},
+			},
+		},
+		winprinters.FakePrinter{
+			Name:   "Office Printer",
+			Driver: winprinters.DriverInfo{Name: "HP LaserJet"},
+			Forms: []winprinters.FormInfo{
+				{Name: "Letter", Size: winprinters.SIZE{Width: 215900, Height: 279400},
+					ImageableArea: winprinters.Rect{Left: 4230, Top: 4230, Right: 211670, Bottom: 275170}},
+				{Name: "A4", Size: winprinters.SIZE{Width: 210000, Height: 297000},
+					ImageableArea: winprinters.Rect{Left: 4230, Top: 4230, Right: 205770, Bottom: 292770}},
+			},
+		},
+	)
+	dm := new(winprinters.DevMode)
+	dm.SetOrientation(winprinters.DMORIENT_LANDSCAPE)
+	dm.SetDuplex(winprinters.DMDUP_VERTICAL)
+	dm.SetColor(winprinters.DMCOLOR_COLOR)
+	dm.SetFormName("A4")
+	s := NewServer(fakeBackend{
+		spoolerBackend: spoolerBackend{fake},
+		devModes:       map[string]*winprinters.DevMode{"Office Printer": dm},
+	})
+	s.Formats = []string{"application/vnd.hp-PCL"}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return fake, ts
+}
+
+// call posts req to the server and decodes the response.
+func call(t *testing.T, ts *httptest.Server, path string, req *ipp.Message, doc string) *ipp.Message {
+	t.Helper()
+	b, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	r, err := http.Post(ts.URL+path, "application/ipp", bytes.NewReader(append(b, doc...)))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	defer func() { _ = r.Body.Close() }()
+	if r.StatusCode != http.StatusOK {
+		t.Fatalf("POST %s: %s", path, r.Status)
+	}
+	var resp ipp.Message
+	if err = resp.Decode(r.Body); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	return &resp
+}
+
+func newRequest(ts *httptest.Server, op ipp.Op, printer string) *ipp.Message {
+	req := ipp.NewRequest(ipp.Version20, op, 1)
+	if printer != "" {
+		uri := strings.Replace(ts.URL, "http://", "ipp://", 1) + printersPath + printer
+		req.Group(ipp.TagOperationGroup).Add(ipp.MakeAttribute("printer-uri", ipp.TagURI, ipp.String(uri)))
+	}
+	return req
+}
+
+// TestServer_IPPSpooler drives the server through the package's own IPP
+// client, so everything a Printer does over IPP must round trip.
+func TestServer_IPPSpooler(t *testing.T) {
+	fake, ts := newTestServer(t)
+	client := winprinters.NewIPPSpooler(ts.URL)
+
+	names, err := client.ReadNames()
+	if err != nil {
+		t.Fatalf("ReadNames failed: %v", err)
+	}
+	if want := []string{"Label", "Office Printer"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadNames = %q, want %q", names, want)
+	}
+	if def, err := client.GetDefault(); err != nil || def != "Label" {
+		t.Errorf("GetDefault = %q, %v", def, err)
+	}
+
+	h, err := client.Open("Office Printer")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	p := winprinters.NewPrinter(h)
+	defer func() { _ = p.Close() }()
+
+	di, err := p.DriverInfo()
+	if err != nil || di.Name != "HP LaserJet" {
+		t.Errorf("DriverInfo = %+v, %v", di, err)
+	}
+	forms, err := p.Forms()
+	if err != nil {
+		t.Fatalf("Forms failed: %v", err)
+	}
+	want := []winprinters.FormInfo{
+		{Name: "Letter", Size: winprinters.SIZE{Width: 215900, Height: 279400},
+			ImageableArea: winprinters.Rect{Left: 4230, Top: 4230, Right: 211670, Bottom: 275170}},
+		{Name: "A4", Size: winprinters.SIZE{Width: 210000, Height: 297000},
+			ImageableArea: winprinters.Rect{Left: 4230, Top: 4230, Right: 205770, Bottom: 292770}},
+	}
+	if !reflect.DeepEqual(forms, want) {
+		t.Errorf("Forms = %+v, want %+v", forms, want)
+	}
+
+	if _, err = p.StartRawDocument("report.pcl"); err != nil {
+		t.Fatalf("StartRawDocument failed: %v", err)
+	}
+	if _, err = p.Write([]byte("\x1bE report")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err = p.EndDocument(); err != nil {
+		t.Fatalf("EndDocument failed: %v", err)
+	}
+	docs := fake.Documents()
+	if len(docs) != 1 || docs[0].Printer != "Office Printer" || docs[0].Name != "report.pcl" ||
+		docs[0].DataType != "RAW" || string(docs[0].Data) != "\x1bE report" {
+		t.Fatalf("Documents = %+v", docs)
+	}
+
+	jobs, err := p.Jobs()
+	if err != nil {
+		t.Fatalf("Jobs failed: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].JobID != docs[0].JobID || jobs[0].DocumentName != "report.pcl" {
+		t.Fatalf("Jobs = %+v", jobs)
+	}
+	if err = p.CancelJob(jobs[0].JobID); err != nil {
+		t.Fatalf("CancelJob failed: %v", err)
+	}
+	if err = p.CancelJob(jobs[0].JobID); !errors.Is(err, winprinters.ErrJobNotFound) {
+		t.Errorf("second CancelJob = %v, want ErrJobNotFound", err)
+	}
+	if jobs, _ = p.Jobs(); len(jobs) != 0 {
+		t.Errorf("Jobs after CancelJob = %+v", jobs)
+	}
+
+	if _, err = client.Open("Missing"); !errors.Is(err, winprinters.ErrPrinterNotFound) {
+		t.Errorf("Open(Missing) = %v, want ErrPrinterNotFound", err)
+	}
+}
+
+func TestServer_PrinterAttributes(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	resp := call(t, ts, "/", newRequest(ts, ipp.OpGetPrinterAttributes, "Office%20Printer"), "")
+	if resp.Status() != ipp.StatusOK {
+		t.Fatalf("status = %s", resp.Status())
+	}
+	attr := func(name string) string {
+		a, _ := resp.Attr(ipp.TagPrinterGroup, name)
+		return strings.Join(a.Texts(), ",")
+	}
+	for name, want := range map[string]string{
+		"printer-name":                  "Office Printer",
+		"printer-make-and-model":        "HP LaserJet",
+		"printer-state":                 "3",
+		"document-format-supported":     "application/octet-stream,application/vnd.hp-PCL",
+		"media-supported":               "na_letter_8.5x11in,iso_a4_210x297mm",
+		"media-default":                 "iso_a4_210x297mm",
+		"orientation-requested-default": "4",
+		"sides-default":                 "two-sided-long-edge",
+		"print-color-mode-default":      "color",
+		"print-color-mode-supported":    "monochrome,color",
+		"color-supported":               "true",
+		"ipp-versions-supported":        "1.1,2.0",
+	} {
+		if got := attr(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if uri := attr("printer-uri-supported"); !strings.HasSuffix(uri, "/printers/Office%20Printer") {
+		t.Errorf("printer-uri-supported = %q", uri)
+	}
+	if uuid := attr("printer-uuid"); len(uuid) != len("urn:uuid:")+36 {
+		t.Errorf("printer-uuid = %q", uuid)
+	}
+
+	// Without a DevMode, only the driver and forms are described.
+	req := newRequest(ts, ipp.OpGetPrinterAttributes, "Label")
+	req.Group(ipp.TagOperationGroup).Add(ipp.MakeAttribute("requested-attributes", ipp.TagKeyword,
+		ipp.String("media-supported"), ipp.String("queued-job-count"), ipp.String("sides-default")))
+	resp = call(t, ts, "/", req, "")
+	g := resp.Group(ipp.TagPrinterGroup)
+	if len(g.Attrs) != 2 || attr("media-supported") != "custom_4x6_101.6x152.4mm" || attr("queued-job-count") != "1" {
+		t.Errorf("Label attributes = %v", g.Attrs)
+	}
+}
+
+func TestServer_PrintJob(t *testing.T) {
+	fake, ts := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		req    func() *ipp.Message
+		status ipp.Status
+	}{
+		{
+			name: "raw",
+			req: func() *ipp.Message {
+				req := newRequest(ts, ipp.OpPrintJob, "Label")
+				req.Group(ipp.TagOperationGroup).Add(ipp.MakeAttribute("job-name", ipp.TagName, ipp.String("label.zpl")))
+				return req
+			},
+			status: ipp.StatusOK,
+		},
+		{
+			name: "ignored attributes",
+			req: func() *ipp.Message {
+				req := newRequest(ts, ipp.OpPrintJob, "Label")
+				req.Group(ipp.TagJobGroup).Add(ipp.MakeAttribute("copies", ipp.TagInteger, ipp.Integer(2)))
+				return req
+			},
+			status: ipp.StatusOKIgnoredOrSubstituted,
+		},
+		{
+			name: "fidelity",
+			req: func() *ipp.Message {
+				req := newRequest(ts, ipp.OpPrintJob, "Label")
+				req.Group(ipp.TagOperationGroup).Add(ipp.MakeAttribute("ipp-attribute-fidelity", ipp.TagBoolean, ipp.Boolean(true)))
+				req.Group(ipp.TagJobGroup).Add(ipp.MakeAttribute("copies", ipp.TagInteger, ipp.Integer(2)))
+				return req
+			},
+			status: ipp.StatusAttributesOrValuesNotSupported,
+		},
+		{
+			name: "unsupported format",
+			req: func() *ipp.Message {
+				req := newRequest(ts, ipp.OpPrintJob, "Label")
+				req.Group(ipp.TagOperationGroup).Add(ipp.MakeAttribute("document-format", ipp.TagMimeType, ipp.String("application/pdf")))
+				return req
+			},
+			status: ipp.StatusDocumentFormatNotSupported,
+		},
+		{
+			name:   "missing printer",
+			req:    func() *ipp.Message { return newRequest(ts, ipp.OpPrintJob, "Missing") },
+			status: ipp.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(fake.Documents())
+			resp := call(t, ts, "/", tt.req(), "^XA^XZ")
+			if resp.Status() != tt.status {
+				t.Fatalf("status = %s, want %s", resp.Status(), tt.status)
+			}
+			docs := fake.Documents()
+			if !tt.status.IsSuccess() {
+				if len(docs) != before {
+					t.Errorf("failed Print-Job spooled %+v", docs[before:])
+				}
+				return
+			}
+			if len(docs) != before+1 || string(docs[before].Data) != "^XA^XZ" {
+				t.Fatalf("Documents = %+v", docs)
+			}
+			id, _ := resp.Attr(ipp.TagJobGroup, "job-id")
+			if uint32(id.Int()) != docs[before].JobID {
+				t.Errorf("job-id = %d, want %d", id.Int(), docs[before].JobID)
+			}
+			state, _ := resp.Attr(ipp.TagJobGroup, "job-state")
+			if state.Int() != ipp.JobPending {
+				t.Errorf("job-state = %d, want pending", state.Int())
+			}
+		})
+	}
+}
+
+func TestServer_Jobs(t *testing.T) {
+	fake, ts := newTestServer(t)
+	if _, err := fake.AddJob("Label", winprinters.JobInfo{JobID: 7, DocumentName: "a.zpl", UserName: "alice",
+		StatusCode: winprinters.JOB_STATUS_PRINTING}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fake.AddJob("Label", winprinters.JobInfo{JobID: 8, DocumentName: "b.zpl", UserName: "alice",
+		StatusCode: winprinters.JOB_STATUS_PRINTED}); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(resp *ipp.Message) []int {
+		var ids []int
+		for _, g := range resp.GroupsOf(ipp.TagJobGroup) {
+			a, _ := g.Attr("job-id")
+			ids = append(ids, a.Int())
+		}
+		return ids
+	}
+	tests := []struct {
+		name  string
+		attrs []ipp.Attribute
+		want  []int
+	}{
+		{"default", nil, []int{3, 7}},
+		{"completed", []ipp.Attribute{ipp.MakeAttribute("which-jobs", ipp.TagKeyword, ipp.String("completed"))}, []int{8}},
+		{"all", []ipp.Attribute{ipp.MakeAttribute("which-jobs", ipp.TagKeyword, ipp.String("all"))}, []int{3, 7, 8}},
+		{"limit", []ipp.Attribute{ipp.MakeAttribute("limit", ipp.TagInteger, ipp.Integer(1))}, []int{3}},
+		{"my-jobs", []ipp.Attribute{
+			ipp.MakeAttribute("requesting-user-name", ipp.TagName, ipp.String("alice")),
+			ipp.MakeAttribute("my-jobs", ipp.TagBoolean, ipp.Boolean(true)),
+		}, []int{7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest(ts, ipp.OpGetJobs, "Label")
+			req.Group(ipp.TagOperationGroup).Add(tt.attrs...)
+			resp := call(t, ts, "/", req, "")
+			if got := ids(resp); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("job ids = %v, want %v", got, tt.want)
+			}
+			// Get-Jobs defaults to job-id and job-uri.
+			for _, g := range resp.GroupsOf(ipp.TagJobGroup) {
+				if len(g.Attrs) != 2 {
+					t.Errorf("job attributes = %v", g.Attrs)
+				}
+			}
+		})
+	}
+
+	// Get-Job-Attributes and Cancel-Job by job-uri.
+	jobURI := strings.Replace(ts.URL, "http://", "ipp://", 1) + "/printers/Label/7"
+	req := ipp.NewRequest(ipp.Version11, ipp.OpGetJobAttributes, 2)
+	req.Group(ipp.TagOperationGroup).Add(ipp.MakeAttribute("job-uri", ipp.TagURI, ipp.String(jobURI)))
+	resp := call(t, ts, "/", req, "")
+	if state, _ := resp.Attr(ipp.TagJobGroup, "job-state"); state.Int() != ipp.JobProcessing {
+		t.Errorf("job-state = %v", state)
+	}
+	if reasons, _ := resp.Attr(ipp.TagJobGroup, "job-state-reasons"); reasons.Text() != "job-printing" {
+		t.Errorf("job-state-reasons = %v", reasons)
+	}
+	req.Code = uint16(ipp.OpCancelJob)
+	if resp = call(t, ts, "/", req, ""); resp.Status() != ipp.StatusOK {
+		t.Errorf("Cancel-Job status = %s", resp.Status())
+	}
+	req.Code = uint16(ipp.OpGetJobAttributes)
+	if resp = call(t, ts, "/", req, ""); resp.Status() != ipp.StatusNotFound {
+		t.Errorf("Get-Job-Attributes of a canceled job: status = %s", resp.Status())
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	fake, ts := newTestServer(t)
+
+	req := newRequest(ts, ipp.OpPausePrinter, "Label")
+	if resp := call(t, ts, "/", req, ""); resp.Status() != ipp.StatusOperationNotSupported {
+		t.Errorf("Pause-Printer status = %s", resp.Status())
+	}
+	req = newRequest(ts, ipp.OpGetPrinterAttributes, "Label")
+	req.Version = 0x0300
+	resp := call(t, ts, "/", req, "")
+	if resp.Status() != ipp.StatusVersionNotSupported || resp.Version != ipp.Version11 {
+		t.Errorf("IPP/3.0 answered with %s %s", resp.Version, resp.Status())
+	}
+	req = &ipp.Message{Version: ipp.Version11, Code: uint16(ipp.OpGetPrinterAttributes), RequestID: 1}
+	if resp = call(t, ts, "/printers/Label", req, ""); resp.Status() != ipp.StatusBadRequest {
+		t.Errorf("request without charset: status = %s", resp.Status())
+	}
+	// The request path addresses the printer when printer-uri is missing.
+	req = ipp.NewRequest(ipp.Version11, ipp.OpGetPrinterAttributes, 1)
+	if resp = call(t, ts, "/printers/Label", req, ""); resp.Status() != ipp.StatusOK {
+		t.Errorf("Get-Printer-Attributes by path: status = %s", resp.Status())
+	}
+
+	fake.InjectError("Label", "Write", errors.New("device offline"))
+	if resp = call(t, ts, "/", newRequest(ts, ipp.OpPrintJob, "Label"), "^XA^XZ"); resp.Status() != ipp.StatusInternalError {
+		t.Errorf("Print-Job with a failing printer: status = %s", resp.Status())
+	}
+	fake.InjectError("Label", "Write", nil)
+	if docs := fake.Documents(); len(docs) != 0 {
+		t.Errorf("failed Print-Job spooled %+v", docs)
+	}
+	if jobs := call(t, ts, "/", newRequest(ts, ipp.OpGetJobs, "Label"), ""); len(jobs.Groups) != 2 {
+		t.Errorf("failed Print-Job left %d jobs, want the held one", len(jobs.Groups)-1)
+	}
+
+	// A client failing to send the document is a client error.
+	b, err := newRequest(ts, ipp.OpPrintJob, "Label").MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hr := httptest.NewRequest(http.MethodPost, "/", io.MultiReader(bytes.NewReader(b), iotest.ErrReader(errors.New("connection reset"))))
+	hr.Header.Set("Content-Type", "application/ipp")
+	rec := httptest.NewRecorder()
+	ts.Config.Handler.ServeHTTP(rec, hr)
+	if err = resp.Decode(rec.Body); err != nil || resp.Status() != ipp.StatusDocumentAccessError {
+		t.Errorf("Print-Job with a failing client: status = %s, %v", resp.Status(), err)
+	}
+
+	r, err := http.Get(ts.URL + "/printers/Label")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = r.Body.Close()
+	if r.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %s", r.Status)
+	}
+}
+
+func TestServer_MaxRequestSize(t *testing.T) {
+	fake, ts := newTestServer(t)
+	ts.Config.Handler.(*Server).MaxRequestSize = 512
+
+	// The document data is not limited.
+	doc := strings.Repeat("^XA^FDlabel^FS^XZ", 100)
+	call(t, ts, "/", newRequest(ts, ipp.OpPrintJob, "Label"), doc)
+	if docs := fake.Documents(); len(docs) != 1 || string(docs[0].Data) != doc {
+		t.Fatalf("Documents = %d, want the %d byte document", len(docs), len(doc))
+	}
+
+	req := newRequest(ts, ipp.OpPrintJob, "Label")
+	req.Group(ipp.TagOperationGroup).Add(ipp.MakeAttribute("job-name", ipp.TagName, ipp.String(strings.Repeat("x", 1000))))
+	b, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := http.Post(ts.URL, "application/ipp", bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = r.Body.Close()
+	if r.StatusCode != http.StatusBadRequest {
+		t.Errorf("oversized request status = %s", r.Status)
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/chenxi2015/winprinters"
+	"github.com/chenxi2015/winprinters/lpd"
+)
+
+// Queue is an LPD queue forwarding to a printer of the spooler.
+type Queue struct {
+	Name    string       // LPD queue name
+	Printer string       // spooler printer name
+	Allow   []*net.IPNet // clients allowed to use the queue; any client when empty
+}
+
+// allowed reports whether the client at addr may use q.
+func (q *Queue) allowed(addr net.Addr) bool {
+	return len(q.Allow) == 0 || inNets(q.Allow, addr)
+}
+
+// inNets reports whether addr is in one of nets.
+func inNets(nets []*net.IPNet, addr net.Addr) bool {
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip = a.IP
+	default:
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return false
+		}
+		ip = net.ParseIP(host)
+	}
+	for _, n := range nets {
+		if ip != nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAllow parses a comma separated list of IP addresses and CIDR
+// networks.
+func ParseAllow(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		if !strings.Contains(f, "/") {
+			ip := net.ParseIP(f)
+			if ip == nil {
+				return nil, fmt.Errorf("bad address %q", f)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(f)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// owner is the LPD user and host that submitted a job. The spooler records
+// the account of the gateway as the owner of every job.
+type owner struct {
+	user, host string
+}
+
+// Gateway is an lpd.Handler forwarding the jobs received on its queues to
+// the printers of a spooler, the way the Windows LPD service did.
+type Gateway struct {
+	Spooler winprinters.Spooler
+
+	// Admins are the clients whose agent root removes the jobs of any
+	// user. Root is an ordinary user for other clients, and for every
+	// client when Admins is empty.
+	Admins []*net.IPNet
+
+	mu     sync.Mutex
+	queues map[string]*Queue
+	owners map[string]map[uint32]owner // by printer and job ID
+}
+
+// NewGateway returns a Gateway serving queues on the printers of s.
+func NewGateway(s winprinters.Spooler, queues ...Queue) *Gateway {
+	g := &Gateway{Spooler: s, queues: make(map[string]*Queue), owners: make(map[string]map[uint32]owner)}
+	for i := range queues {
+		g.queues[queues[i].Name] = &queues[i]
+	}
+	return g
+}
+
+// errPermission is reported to clients outside the allow-list of a queue.
+var errPermission = errors.New("permission denied")
+
+func (g *Gateway) queue(name string) (*Queue, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	q, ok := g.queues[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown printer", name)
+	}
+	return q, nil
+}
+
+func (g *Gateway) Accept(r *lpd.Request) error {
+	q, err := g.queue(r.Queue)
+	if err != nil {
+		return err
+	}
+	if !q.allowed(r.Remote) {
+		return fmt.Errorf("%s: %w", r.Queue, errPermission)
+	}
+	return nil
+}
+
+// open opens the printer of queue and calls f with it.
+func (g *Gateway) open(queue string, f func(p *winprinters.Printer, q *Queue) error) error {
+	q, err := g.queue(queue)
+	if err != nil {
+		return err
+	}
+	h, err := g.Spooler.Open(q.Printer)
+	if err != nil {
+		return err
+	}
+	p := winprinters.NewPrinter(h)
+	defer func() { _ = p.Close() }()
+	return f(p, q)
+}
+
+// PrintWaiting does nothing: the spooler prints jobs as soon as they are
+// queued.
+func (g *Gateway) PrintWaiting(*lpd.Request) error {
+	return nil
+}
+
+// ReceiveJob prints every file of job as a document of its own, repeated
+// as many times as the control file asks.
+func (g *Gateway) ReceiveJob(r *lpd.Request, job *lpd.ReceivedJob) error {
+	return g.open(r.Queue, func(p *winprinters.Printer, q *Queue) error {
+		for _, f := range job.Control.Files {
+			name := job.Control.Job
+			if name == "" {
+				name = f.Source
+			}
+			if name == "" {
+				name = f.Name
+			}
+			var doc *winprinters.Job
+			var err error
+			if f.Format == lpd.FormatText {
+				doc, err = p.StartDocument(name, "TEXT")
+			} else {
+				doc, err = p.StartRawDocument(name)
+			}
+			if err != nil {
+				return err
+			}
+			id := doc.ID
+			if id != 0 {
+				g.setOwner(q.Printer, id, owner{job.Control.User, job.Control.Host})
+			}
+			// Empty data files are valid LPD but nothing to write.
+			data := job.Data[f.Name]
+			for i := 0; i < f.Copies && len(data) > 0; i++ {
+				if _, err = p.Write(data); err != nil {
+					// Drop the incomplete document rather than print it:
+					// open closes the printer without ending the
+					// document, which aborts it.
+					if id != 0 {
+						_ = p.CancelJob(id)
+					}
+					return err
+				}
+			}
+			if err = p.EndDocument(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (g *Gateway) setOwner(printer string, jobID uint32, o owner) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.owners[printer] == nil {
+		g.owners[printer] = make(map[uint32]owner)
+	}
+	g.owners[printer][jobID] = o
+}
+
+// queued returns the jobs of printer with the LPD user and host that
+// submitted them, forgetting the owners of jobs that left the queue.
+func (g *Gateway) queued(p *winprinters.Printer, printer string) ([]winprinters.JobInfo, error) {
+	jobs, err := p.Jobs()
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	owners := make(map[uint32]owner)
+	for i, j := range jobs {
+		if o, ok := g.owners[printer][j.JobID]; ok {
+			jobs[i].UserName, jobs[i].UserMachineName = o.user, o.host
+			owners[j.JobID] = o
+		}
+	}
+	g.owners[printer] = owners
+	return jobs, nil
+}
+
+// selected reports whether j is in list, user names or job numbers, or list
+// is empty.
+func selected(j winprinters.JobInfo, list []string) bool {
+	for _, s := range list {
+		if s == j.UserName || s == strconv.FormatUint(uint64(j.JobID), 10) {
+			return true
+		}
+	}
+	return len(list) == 0
+}
+
+func (g *Gateway) QueueState(w io.Writer, r *lpd.Request) error {
+	return g.open(r.Queue, func(p *winprinters.Printer, q *Queue) error {
+		jobs, err := g.queued(p, q.Printer)
+		if err != nil {
+			return err
+		}
+		var entries []lpd.QueueEntry
+		for i, j := range jobs {
+			if !selected(j, r.List) {
+				continue
+			}
+			entries = append(entries, lpd.QueueEntry{
+				Rank:  lpd.Rank(i),
+				Owner: j.UserName,
+				Job:   int(j.JobID),
+				Host:  j.UserMachineName,
+				Files: []string{j.DocumentName},
+			})
+		}
+		return lpd.WriteQueue(w, entries, r.Command == lpd.CmdQueueLong)
+	})
+}
+
+// RemoveJobs cancels the jobs in r.List that belong to r.Agent, or any job
+// when the agent is root on one of the Admins hosts. An empty list removes
+// the first job of the agent.
+func (g *Gateway) RemoveJobs(w io.Writer, r *lpd.Request) error {
+	admin := r.Agent == "root" && len(g.Admins) > 0 && inNets(g.Admins, r.Remote)
+	return g.open(r.Queue, func(p *winprinters.Printer, q *Queue) error {
+		jobs, err := g.queued(p, q.Printer)
+		if err != nil {
+			return err
+		}
+		for _, j := range jobs {
+			mine := j.UserName == r.Agent || admin
+			if !mine || !selected(j, r.List) {
+				continue
+			}
+			if err = p.CancelJob(j.JobID); err != nil && !errors.Is(err, winprinters.ErrJobNotFound) {
+				return err
+			}
+			if _, err = fmt.Fprintf(w, "%s dequeued\n", j.DocumentName); err != nil {
+				return err
+			}
+			if len(r.List) == 0 {
+				break
+			}
+		}
+		return nil
+	})
+}
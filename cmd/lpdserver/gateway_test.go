@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chenxi2015/winprinters"
+	"github.com/chenxi2015/winprinters/lpd"
+)
+
+func mustAllow(t *testing.T, s string) []*net.IPNet {
+	t.Helper()
+	nets, err := ParseAllow(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nets
+}
+
+func newTestServer(t *testing.T, queues ...Queue) (*winprinters.FakeSpooler, *lpd.Client) {
+	fake := winprinters.NewFakeSpooler(
+		winprinters.FakePrinter{Name: "Office Printer"},
+		winprinters.FakePrinter{Name: "Zebra"},
+	)
+	return fake, serve(t, NewGateway(fake, queues...))
+}
+
+// serve serves g and returns a client of it.
+func serve(t *testing.T, g *Gateway) *lpd.Client {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	srv := &lpd.Server{Handler: g}
+	go func() { _ = srv.Serve(ln) }()
+	return &lpd.Client{Addr: ln.Addr().String(), Timeout: 5 * time.Second}
+}
+
+func TestGateway_Print(t *testing.T) {
+	fake, c := newTestServer(t, Queue{Name: "lp", Printer: "Office Printer", Allow: mustAllow(t, "127.0.0.0/8")})
+
+	job := &lpd.Job{Number: 12, Host: "unix1", User: "alice", Name: "report", Source: "report.ps",
+		Format: lpd.FormatRaw, Copies: 2}
+	if err := c.Print("lp", job, strings.NewReader("%!PS\n"), 5); err != nil {
+		t.Fatalf("Print failed: %v", err)
+	}
+	docs := fake.Documents()
+	if len(docs) != 1 || docs[0].Printer != "Office Printer" || docs[0].Name != "report" ||
+		docs[0].DataType != "RAW" || string(docs[0].Data) != "%!PS\n%!PS\n" {
+		t.Fatalf("spooler received %+v", docs)
+	}
+
+	text := &lpd.Job{Number: 13, Host: "unix2", User: "bob", Source: "notes.txt", Format: lpd.FormatText}
+	if err := c.Print("lp", text, strings.NewReader("hello\n"), 6); err != nil {
+		t.Fatalf("Print failed: %v", err)
+	}
+	if docs = fake.Documents(); len(docs) != 2 || docs[1].Name != "notes.txt" || docs[1].DataType != "TEXT" {
+		t.Errorf("spooler received %+v", docs)
+	}
+
+	entries, err := c.Queue("lp")
+	if err != nil {
+		t.Fatalf("Queue failed: %v", err)
+	}
+	want := []lpd.QueueEntry{
+		{Rank: "active", Owner: "alice", Job: int(docs[0].JobID), Files: []string{"report"}},
+		{Rank: "1st", Owner: "bob", Job: int(docs[1].JobID), Files: []string{"notes.txt"}},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Queue =\n%+v\nwant\n%+v", entries, want)
+	}
+	if entries, err = c.Queue("lp", "bob"); err != nil || len(entries) != 1 || entries[0].Owner != "bob" {
+		t.Errorf("Queue(bob) = %+v, %v", entries, err)
+	}
+	long, err := c.QueueState("lp", true)
+	if err != nil {
+		t.Fatalf("QueueState failed: %v", err)
+	}
+	if entries, err = lpd.ParseLongQueue(long); err != nil || len(entries) != 2 || entries[0].Host != "unix1" {
+		t.Errorf("long QueueState = %q", long)
+	}
+}
+
+func TestGateway_EmptyDataFile(t *testing.T) {
+	fake, c := newTestServer(t, Queue{Name: "lp", Printer: "Office Printer"})
+	// Writing nothing would crash winspool, so the write must be skipped.
+	fake.InjectError("Office Printer", "Write", errors.New("empty write"))
+	job := &lpd.Job{Number: 5, Host: "unix1", User: "alice", Name: "empty", Copies: 2}
+	if err := c.Print("lp", job, strings.NewReader(""), 0); err != nil {
+		t.Fatalf("Print of an empty file failed: %v", err)
+	}
+	if docs := fake.Documents(); len(docs) != 1 || docs[0].Name != "empty" || len(docs[0].Data) != 0 {
+		t.Errorf("spooler received %+v", docs)
+	}
+}
+
+func TestGateway_Remove(t *testing.T) {
+	fake, c := newTestServer(t, Queue{Name: "lp", Printer: "Office Printer"})
+	for i, user := range []string{"alice", "bob", "alice"} {
+		job := &lpd.Job{Number: i, Host: "unix1", User: user, Name: user + "-doc"}
+		if err := c.Print("lp", job, strings.NewReader("x"), 1); err != nil {
+			t.Fatalf("Print failed: %v", err)
+		}
+	}
+	jobs := func() []string {
+		entries, err := c.Queue("lp")
+		if err != nil {
+			t.Fatalf("Queue failed: %v", err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Files[0])
+		}
+		return names
+	}
+	docs := fake.Documents()
+	bobJob := strconv.FormatUint(uint64(docs[1].JobID), 10)
+
+	// Jobs of other users are left alone.
+	if out, err := c.Remove("lp", "alice", bobJob); err != nil || out != "" {
+		t.Errorf("Remove of another user's job = %q, %v", out, err)
+	}
+	if out, err := c.Remove("lp", "bob", bobJob); err != nil || out != "bob-doc dequeued\n" {
+		t.Errorf("Remove = %q, %v", out, err)
+	}
+	if got := jobs(); !reflect.DeepEqual(got, []string{"alice-doc", "alice-doc"}) {
+		t.Errorf("queue after Remove = %q", got)
+	}
+	// An empty list removes the first job of the agent only.
+	if _, err := c.Remove("lp", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if got := jobs(); len(got) != 1 {
+		t.Errorf("queue after Remove = %q", got)
+	}
+	// Root is an ordinary user unless its host is an admin one.
+	if out, err := c.Remove("lp", "root", "alice"); err != nil || out != "" {
+		t.Errorf("root Remove without admins = %q, %v", out, err)
+	}
+	// Other gateways did not receive the job, so only its number selects it.
+	last := strconv.FormatUint(uint64(docs[2].JobID), 10)
+	g := NewGateway(fake, Queue{Name: "lp", Printer: "Office Printer"})
+	g.Admins = mustAllow(t, "10.0.0.0/8")
+	if out, err := serve(t, g).Remove("lp", "root", last); err != nil || out != "" {
+		t.Errorf("root Remove from another host = %q, %v", out, err)
+	}
+	g = NewGateway(fake, Queue{Name: "lp", Printer: "Office Printer"})
+	g.Admins = mustAllow(t, "127.0.0.1")
+	if _, err := serve(t, g).Remove("lp", "root", last); err != nil {
+		t.Fatal(err)
+	}
+	if got := jobs(); len(got) != 0 {
+		t.Errorf("queue after admin root Remove = %q", got)
+	}
+}
+
+func TestGateway_Refused(t *testing.T) {
+	fake, c := newTestServer(t,
+		Queue{Name: "lp", Printer: "Office Printer", Allow: mustAllow(t, "10.0.0.0/8,192.168.1.7")},
+		Queue{Name: "gone", Printer: "Missing"},
+		Queue{Name: "label", Printer: "Zebra"},
+	)
+	job := &lpd.Job{Number: 1, Host: "unix1", User: "mallory"}
+	for _, queue := range []string{"lp", "unknown"} {
+		if err := c.Print(queue, job, strings.NewReader("x"), 1); !errors.Is(err, lpd.ErrRejected) {
+			t.Errorf("Print to %s = %v, want lpd.ErrRejected", queue, err)
+		}
+	}
+	if out, err := c.QueueState("lp", false); err != nil || !strings.Contains(out, "permission denied") {
+		t.Errorf("QueueState of a refused queue = %q, %v", out, err)
+	}
+	if err := c.Print("gone", job, strings.NewReader("x"), 1); !errors.Is(err, lpd.ErrRejected) {
+		t.Errorf("Print to a missing printer = %v, want lpd.ErrRejected", err)
+	}
+
+	fake.InjectError("Zebra", "Write", errors.New("device offline"))
+	if err := c.Print("label", job, strings.NewReader("x"), 1); !errors.Is(err, lpd.ErrRejected) {
+		t.Errorf("Print with a failing spooler = %v, want lpd.ErrRejected", err)
+	}
+	if len(fake.Documents()) != 0 {
+		t.Errorf("spooler received %+v", fake.Documents())
+	}
+	if entries, err := c.Queue("label"); err != nil || len(entries) != 0 {
+		t.Errorf("failed Print left %+v, %v", entries, err)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	fake := winprinters.NewFakeSpooler(
+		winprinters.FakePrinter{Name: "Office Printer"},
+		winprinters.FakePrinter{Name: "Zebra"},
+	)
+	qs, err := configure(fake, nil, []string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(qs) != 1 || qs[0].Name != "Zebra" || qs[0].Printer != "Zebra" || len(qs[0].Allow) != 1 {
+		t.Errorf("configure shared %+v", qs)
+	}
+
+	qs, err = configure(fake, []string{"lp=Office Printer", "label=Zebra"}, []string{"10.0.0.0/8", "label=192.168.1.7,::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := func(q Queue, ip string) bool {
+		return q.allowed(&net.TCPAddr{IP: net.ParseIP(ip), Port: 721})
+	}
+	if qs[0].Name != "lp" || qs[0].Printer != "Office Printer" || !allowed(qs[0], "10.1.2.3") || allowed(qs[0], "192.168.1.7") {
+		t.Errorf("queue lp = %+v", qs[0])
+	}
+	if qs[1].Name != "label" || allowed(qs[1], "10.1.2.3") || !allowed(qs[1], "192.168.1.7") || !allowed(qs[1], "::1") {
+		t.Errorf("queue label = %+v", qs[1])
+	}
+
+	for _, bad := range [][2][]string{
+		{{"lp"}, nil},
+		{{"=Zebra"}, nil},
+		{{"my queue=Zebra"}, nil},
+		{nil, {"10.0.0.0/33"}},
+		{nil, {"printbox.local"}},
+		{nil, {"unknown=10.0.0.1"}},
+	} {
+		if _, err = configure(fake, bad[0], bad[1]); err == nil {
+			t.Errorf("configure(%q, %q) succeeded", bad[0], bad[1])
+		}
+	}
+}
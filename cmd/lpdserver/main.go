@@ -0,0 +1,128 @@
+// lpdserver command accepts LPD (RFC 1179) jobs from lpr clients and prints
+// them on the printers of the local spooler, replacing the deprecated
+// Windows LPD service.
+//
+// Every printer whose name has no spaces is shared under its own name
+// unless queues are given with -queue:
+//
+//	lpdserver -queue "lp=Office Printer" -queue label=Zebra -allow 10.1.0.0/16 -allow label=10.1.2.7
+//
+// -allow limits the clients of every queue, or of one queue when prefixed
+// with its name, which replaces the common list for that queue. Queues
+// without an allow-list accept any client.
+//
+// Clients remove their own jobs only. -admin lists the hosts whose root
+// user removes the jobs of every user.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/chenxi2015/winprinters"
+	"github.com/chenxi2015/winprinters/lpd"
+)
+
+// listFlag is a flag that may be repeated.
+type listFlag []string
+
+func (f *listFlag) String() string {
+	return strings.Join(*f, " ")
+}
+
+func (f *listFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+var (
+	addr   = flag.String("addr", ":"+lpd.DefaultPort, "address to listen on")
+	admins = flag.String("admin", "", "comma separated IP addresses and CIDR networks whose root user may remove any job")
+	queues listFlag
+	allows listFlag
+)
+
+func init() {
+	flag.Var(&queues, "queue", "`name=printer` queue forwarding to a spooler printer; may be repeated")
+	flag.Var(&allows, "allow", "`[queue=]addresses` comma separated IP addresses and CIDR networks allowed to print; may be repeated")
+}
+
+// configure builds the queues from the -queue and -allow flags, sharing
+// every printer of s when no queue is given.
+func configure(s winprinters.Spooler, queueFlags, allowFlags []string) ([]Queue, error) {
+	var qs []Queue
+	for _, f := range queueFlags {
+		i := strings.IndexByte(f, '=')
+		if i <= 0 || i == len(f)-1 || strings.ContainsAny(f[:i], " \t") {
+			return nil, fmt.Errorf("bad -queue %q, want name=printer", f)
+		}
+		qs = append(qs, Queue{Name: f[:i], Printer: f[i+1:]})
+	}
+	if len(qs) == 0 {
+		names, err := s.ReadNames()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if !strings.ContainsAny(name, " \t") {
+				qs = append(qs, Queue{Name: name, Printer: name})
+			}
+		}
+	}
+
+	var common []*net.IPNet
+	perQueue := make(map[string][]*net.IPNet)
+	for _, f := range allowFlags {
+		queue := ""
+		if i := strings.IndexByte(f, '='); i >= 0 {
+			queue, f = f[:i], f[i+1:]
+		}
+		nets, err := ParseAllow(f)
+		if err != nil {
+			return nil, fmt.Errorf("bad -allow: %v", err)
+		}
+		if queue == "" {
+			common = append(common, nets...)
+		} else {
+			perQueue[queue] = append(perQueue[queue], nets...)
+		}
+	}
+	for i := range qs {
+		if nets, ok := perQueue[qs[i].Name]; ok {
+			qs[i].Allow = nets
+			delete(perQueue, qs[i].Name)
+		} else {
+			qs[i].Allow = common
+		}
+	}
+	for name := range perQueue {
+		return nil, fmt.Errorf("-allow for unknown queue %q", name)
+	}
+	return qs, nil
+}
+
+func main() {
+	flag.Parse()
+	s := winprinters.CurrentSpooler()
+	qs, err := configure(s, queues, allows)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, q := range qs {
+		log.Printf("queue %s prints on %q", q.Name, q.Printer)
+	}
+	g := NewGateway(s, qs...)
+	if g.Admins, err = ParseAllow(*admins); err != nil {
+		log.Fatalf("bad -admin: %v", err)
+	}
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("listening on %s", l.Addr())
+	srv := &lpd.Server{Handler: g, ErrorLog: log.Printf}
+	log.Fatal(srv.Serve(l))
+}
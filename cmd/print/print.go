@@ -79,7 +79,7 @@ func printOneDocument(printerName, documentName string, lines []string) error {
 		_ = p.Close()
 	}(p)
 
-	err = p.StartRawDocument(documentName)
+	_, err = p.StartRawDocument(documentName)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,272 @@
+package winprinters
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConnectionManager is implemented by Spoolers that manage the printer
+// connections of the user: shared printers of print servers, like
+// \\printsrv01\Labels, mapped for the user rather than installed.
+type ConnectionManager interface {
+	// Connections returns the names of the printer connections of the
+	// user.
+	Connections() ([]string, error)
+	// AddConnection connects the user to the shared printer.
+	AddConnection(name string) error
+	// DeleteConnection removes the printer connection of the user.
+	DeleteConnection(name string) error
+}
+
+func connectionManager(op string) (ConnectionManager, error) {
+	if m, ok := CurrentSpooler().(ConnectionManager); ok {
+		return m, nil
+	}
+	return nil, &UnsupportedError{Op: op}
+}
+
+// Connections returns the names of the printer connections of the user.
+func Connections() ([]string, error) {
+	m, err := connectionManager("Connections")
+	if err != nil {
+		return nil, err
+	}
+	return m.Connections()
+}
+
+// AddPrinterConnection connects the user to the shared printer name, such
+// as \\printsrv01\Labels, installing its driver from the server when
+// needed. Connecting to a connected printer succeeds.
+func AddPrinterConnection(name string) error {
+	m, err := connectionManager("AddPrinterConnection")
+	if err != nil {
+		return err
+	}
+	if err = checkConnectionName(name); err != nil {
+		return err
+	}
+	return m.AddConnection(name)
+}
+
+// DeletePrinterConnection removes the printer connection name of the user.
+func DeletePrinterConnection(name string) error {
+	m, err := connectionManager("DeletePrinterConnection")
+	if err != nil {
+		return err
+	}
+	return m.DeleteConnection(name)
+}
+
+// checkConnectionName checks that name is a shared printer like
+// \\printsrv01\Labels.
+func checkConnectionName(name string) error {
+	if strings.HasPrefix(name, `\\`) {
+		if i := strings.Index(name[2:], `\`); i > 0 && 2+i+1 < len(name) {
+			return nil
+		}
+	}
+	return fmt.Errorf(`winprinters: printer connection %q is not named like \\server\printer`, name)
+}
+
+// PrinterList separates the printers of the user by kind.
+type PrinterList struct {
+	Local       []string // queues installed on this computer
+	Connections []string // connections to shared printers of print servers
+}
+
+// ListPrinters returns the printers ReadNames lists, split into local
+// queues and printer connections, each in the order of ReadNames.
+func ListPrinters() (*PrinterList, error) {
+	m, err := connectionManager("ListPrinters")
+	if err != nil {
+		return nil, err
+	}
+	names, err := ReadNames()
+	if err != nil {
+		return nil, err
+	}
+	conns, err := m.Connections()
+	if err != nil {
+		return nil, err
+	}
+	connected := foldSet(conns)
+	list := new(PrinterList)
+	for _, name := range names {
+		if connected[strings.ToLower(name)] {
+			list.Connections = append(list.Connections, name)
+		} else {
+			list.Local = append(list.Local, name)
+		}
+	}
+	return list, nil
+}
+
+// foldSet returns the set of names, which Windows compares without case.
+func foldSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[strings.ToLower(name)] = true
+	}
+	return set
+}
+
+// ConnectionOptions tunes PlanConnections.
+type ConnectionOptions struct {
+	// Default is the printer to make the default of the user, one of the
+	// connections or an installed printer. When empty, the default is
+	// left alone.
+	Default string
+	// Prune selects the connections missing from the list to delete,
+	// such as those of a retired print server. When nil, no connection is
+	// deleted.
+	Prune func(name string) bool
+}
+
+// ConnectionPlan lists the changes that make the printer connections of
+// the user match a list: connections to add in the order of the list,
+// connections to delete by name, then the default printer to set.
+type ConnectionPlan struct {
+	Add     []string `json:",omitempty"`
+	Delete  []string `json:",omitempty"`
+	Default string   `json:",omitempty"` // empty when the default stays
+}
+
+// Empty reports whether the connections already match the list.
+func (p *ConnectionPlan) Empty() bool {
+	return len(p.Add) == 0 && len(p.Delete) == 0 && p.Default == ""
+}
+
+func (p *ConnectionPlan) String() string {
+	if p.Empty() {
+		return "no changes"
+	}
+	var lines []string
+	for _, name := range p.Add {
+		lines = append(lines, fmt.Sprintf("connect %q", name))
+	}
+	for _, name := range p.Delete {
+		lines = append(lines, fmt.Sprintf("disconnect %q", name))
+	}
+	if p.Default != "" {
+		lines = append(lines, fmt.Sprintf("default %q", p.Default))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// planConnections compares the connections of the user, among all of the
+// printers names, and the default printer current with the wanted
+// connections.
+func planConnections(names, connections []string, current string, want []string, opts ConnectionOptions) (*ConnectionPlan, error) {
+	connected := foldSet(connections)
+	wanted := make(map[string]bool, len(want))
+	plan := new(ConnectionPlan)
+	for _, name := range want {
+		if err := checkConnectionName(name); err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(name)
+		if wanted[key] {
+			return nil, fmt.Errorf("winprinters: printer connection %q is listed twice", name)
+		}
+		wanted[key] = true
+		if !connected[key] {
+			plan.Add = append(plan.Add, name)
+		}
+	}
+	deleted := make(map[string]bool)
+	if opts.Prune != nil {
+		for _, name := range connections {
+			if key := strings.ToLower(name); !wanted[key] && opts.Prune(name) {
+				plan.Delete = append(plan.Delete, name)
+				deleted[key] = true
+			}
+		}
+	}
+	if opts.Default != "" {
+		key := strings.ToLower(opts.Default)
+		switch {
+		case deleted[key]:
+			return nil, fmt.Errorf("winprinters: default printer %q is a connection to delete", opts.Default)
+		case !wanted[key] && !foldSet(names)[key]:
+			return nil, fmt.Errorf("winprinters: default printer %q is neither listed nor installed", opts.Default)
+		case !strings.EqualFold(opts.Default, current):
+			plan.Default = opts.Default
+		}
+	}
+	return plan, nil
+}
+
+// PlanConnections compares the printer connections of the user with want,
+// names like \\printsrv01\Labels, and returns the changes that make them
+// match, without applying them. The plan sets opts.Default whenever it is
+// not already the default printer of the user, replacing any other default.
+func PlanConnections(want []string, opts ConnectionOptions) (*ConnectionPlan, error) {
+	m, err := connectionManager("PlanConnections")
+	if err != nil {
+		return nil, err
+	}
+	names, err := ReadNames()
+	if err != nil {
+		return nil, err
+	}
+	conns, err := m.Connections()
+	if err != nil {
+		return nil, err
+	}
+	var current string
+	if opts.Default != "" {
+		// GetDefault fails when the user has no default printer yet.
+		current, _ = GetDefault()
+	}
+	return planConnections(names, conns, current, want, opts)
+}
+
+// ConnectionError is returned by ConnectionPlan.Apply for the step that
+// failed, "connect", "disconnect" or "default". The steps before it were
+// applied.
+type ConnectionError struct {
+	Op   string
+	Name string
+	Err  error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("winprinters: %s %q: %v", e.Op, e.Name, e.Err)
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+// Apply makes the changes of the plan with the current Spooler, stopping
+// at the first one that fails with a *ConnectionError.
+func (p *ConnectionPlan) Apply() error {
+	for _, name := range p.Add {
+		if err := AddPrinterConnection(name); err != nil {
+			return &ConnectionError{Op: "connect", Name: name, Err: err}
+		}
+	}
+	for _, name := range p.Delete {
+		if err := DeletePrinterConnection(name); err != nil {
+			return &ConnectionError{Op: "disconnect", Name: name, Err: err}
+		}
+	}
+	if p.Default != "" {
+		if err := SetDefault(p.Default); err != nil {
+			return &ConnectionError{Op: "default", Name: p.Default, Err: err}
+		}
+	}
+	return nil
+}
+
+// EnsureConnections connects the user to the printers of want, deletes
+// the connections opts.Prune selects and sets opts.Default as the default
+// printer, doing nothing that is already done, and returns the plan it
+// applied. It is meant to run at every login.
+func EnsureConnections(want []string, opts ConnectionOptions) (*ConnectionPlan, error) {
+	plan, err := PlanConnections(want, opts)
+	if err != nil {
+		return nil, err
+	}
+	return plan, plan.Apply()
+}
@@ -0,0 +1,133 @@
+package winprinters
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanConnections(t *testing.T) {
+	names := []string{"Label", "Microsoft Print to PDF", `\\printsrv01\Labels`, `\\oldsrv\Office`}
+	conns := []string{`\\printsrv01\Labels`, `\\oldsrv\Office`}
+	pruneOld := func(name string) bool { return strings.HasPrefix(strings.ToLower(name), `\\oldsrv\`) }
+	tests := []struct {
+		name    string
+		want    []string
+		opts    ConnectionOptions
+		current string
+		plan    string
+		err     string
+	}{
+		{name: "connected", want: []string{`\\printsrv01\Labels`}, plan: "no changes"},
+		{name: "case", want: []string{`\\PRINTSRV01\labels`}, plan: "no changes"},
+		{
+			name: "add",
+			want: []string{`\\printsrv01\Office`, `\\printsrv01\Labels`, `\\printsrv02\Receipts`},
+			plan: `connect "\\\\printsrv01\\Office"` + "\n" + `connect "\\\\printsrv02\\Receipts"`,
+		},
+		{
+			name: "prune",
+			want: []string{`\\printsrv01\Labels`},
+			opts: ConnectionOptions{Prune: pruneOld},
+			plan: `disconnect "\\\\oldsrv\\Office"`,
+		},
+		{name: "keep listed", want: []string{`\\oldsrv\Office`}, opts: ConnectionOptions{Prune: pruneOld}, plan: "no changes"},
+		{
+			name:    "default",
+			want:    []string{`\\printsrv01\Office`},
+			opts:    ConnectionOptions{Default: `\\printsrv01\Office`},
+			current: "Label",
+			plan:    `connect "\\\\printsrv01\\Office"` + "\n" + `default "\\\\printsrv01\\Office"`,
+		},
+		{name: "default already", opts: ConnectionOptions{Default: "label"}, current: "Label", plan: "no changes"},
+		{name: "no default yet", opts: ConnectionOptions{Default: "Microsoft Print to PDF"}, plan: `default "Microsoft Print to PDF"`},
+		{name: "unknown default", opts: ConnectionOptions{Default: "Plotter"}, err: "neither listed nor installed"},
+		{
+			name: "default pruned",
+			opts: ConnectionOptions{Default: `\\oldsrv\Office`, Prune: pruneOld},
+			err:  "connection to delete",
+		},
+		{name: "not a connection", want: []string{"Label"}, err: `not named like \\server\printer`},
+		{name: "no printer", want: []string{`\\printsrv01\`}, err: "not named like"},
+		{name: "twice", want: []string{`\\printsrv01\Office`, `\\PrintSrv01\Office`}, err: "listed twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planConnections(names, conns, tt.current, tt.want, tt.opts)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("planConnections() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("planConnections failed: %v", err)
+			}
+			if plan.String() != tt.plan {
+				t.Errorf("plan:\n%s\nwant:\n%s", plan, tt.plan)
+			}
+		})
+	}
+}
+
+func TestEnsureConnections(t *testing.T) {
+	fake := newTestSpooler(t)
+	fake.AddServer(FakeServer{Name: `\\printsrv01`})
+	for _, name := range []string{`\\printsrv01\Labels`, `\\printsrv01\Office`, `\\printsrv01\Retired`} {
+		fake.AddPrinter(FakePrinter{Name: name})
+	}
+	if err := AddPrinterConnection(`\\printsrv01\Retired`); err != nil {
+		t.Fatalf("AddPrinterConnection failed: %v", err)
+	}
+	if err := AddPrinterConnection(`\\printsrv02\Labels`); !errors.Is(err, ErrPrinterNotFound) {
+		t.Errorf("connecting to an unknown printer: %v", err)
+	}
+	if err := AddPrinterConnection("Label"); err == nil {
+		t.Errorf("connecting to a local queue succeeded")
+	}
+
+	want := []string{`\\printsrv01\Labels`, `\\printsrv01\Office`}
+	opts := ConnectionOptions{
+		Default: `\\printsrv01\Office`,
+		Prune:   func(name string) bool { return strings.HasPrefix(name, `\\printsrv01\`) },
+	}
+	fake.InjectError(`\\printsrv01\Office`, "AddConnection", errors.New("driver not installed"))
+	var ce *ConnectionError
+	if _, err := EnsureConnections(want, opts); !errors.As(err, &ce) || ce.Op != "connect" || ce.Name != `\\printsrv01\Office` {
+		t.Fatalf("EnsureConnections with a failing connection: %v", err)
+	}
+	fake.InjectError(`\\printsrv01\Office`, "AddConnection", nil)
+
+	plan, err := EnsureConnections(want, opts)
+	if err != nil {
+		t.Fatalf("EnsureConnections failed: %v", err)
+	}
+	if !reflect.DeepEqual(plan.Add, []string{`\\printsrv01\Office`}) || !reflect.DeepEqual(plan.Delete, []string{`\\printsrv01\Retired`}) || plan.Default != opts.Default {
+		t.Errorf("EnsureConnections after a partial run:\n%s", plan)
+	}
+	if def, _ := GetDefault(); def != opts.Default {
+		t.Errorf("GetDefault() = %q", def)
+	}
+	if plan, err = EnsureConnections(want, opts); err != nil || !plan.Empty() {
+		t.Errorf("EnsureConnections again = %s, %v", plan, err)
+	}
+
+	list, err := ListPrinters()
+	if err != nil {
+		t.Fatalf("ListPrinters failed: %v", err)
+	}
+	if !reflect.DeepEqual(list, &PrinterList{Local: []string{"Label", "Office"}, Connections: want}) {
+		t.Errorf("ListPrinters() = %+v", list)
+	}
+
+	if err = DeletePrinterConnection(`\\printsrv01\Office`); err != nil {
+		t.Fatalf("DeletePrinterConnection failed: %v", err)
+	}
+	if _, err = GetDefault(); err == nil {
+		t.Errorf("deleting the default connection kept it the default")
+	}
+	if err = DeletePrinterConnection(`\\printsrv01\Office`); !errors.Is(err, ErrPrinterNotFound) {
+		t.Errorf("deleting a deleted connection: %v", err)
+	}
+}
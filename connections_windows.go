@@ -0,0 +1,41 @@
+package winprinters
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// Connections implements ConnectionManager with EnumPrinters level 1 of
+// PRINTER_ENUM_CONNECTIONS.
+func (winspool) Connections() ([]string, error) {
+	sb, count, err := enumSpooler(func(buf *byte, bufN uint32, needed, returned *uint32) error {
+		return EnumPrinters(PRINTER_ENUM_CONNECTIONS, nil, 1, buf, bufN, needed, returned)
+	})
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	entries, err := decodePrinterInfo1(sb, count)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	return names, nil
+}
+
+func (winspool) AddConnection(name string) error {
+	n, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	return addPrinterConnection(n)
+}
+
+func (winspool) DeleteConnection(name string) error {
+	n, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	return deletePrinterConnection(n)
+}
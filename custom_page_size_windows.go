@@ -3,6 +3,7 @@ package winprinters
 import (
 	"errors"
 	"fmt"
+	"syscall"
 
 	"golang.org/x/sys/windows"
 )
@@ -16,10 +17,6 @@ import (
 //
 //goland:noinspection GoSnakeCaseUsage
 func AddCustomPaperSize(printerName, paperName string, widthMM, heightMM, leftMM, topMM uint32) (err error) {
-	const (
-		PRINTER_ACCESS_USE        uint32 = 0x00000008
-		PRINTER_ACCESS_ADMINISTER uint32 = 0x00000004
-	)
 	defaults := &PrinterDefaults{
 		pDevMode:      new(DevMode),
 		DesiredAccess: PRINTER_ACCESS_ADMINISTER | PRINTER_ACCESS_USE,
@@ -32,9 +29,13 @@ func AddCustomPaperSize(printerName, paperName string, widthMM, heightMM, leftMM
 	defer func() {
 		_ = p.Close()
 	}()
+	var h syscall.Handle
+	if h, err = p.winspoolHandle("AddCustomPaperSize"); err != nil {
+		return
+	}
 	pFormName, _ := windows.UTF16FromString(paperName)
 	paper := &pFormName[0]
-	_ = DeleteForm(p.h, paper) // 删除已存在的同名自定义纸张大小
+	_ = DeleteForm(h, paper) // 删除已存在的同名自定义纸张大小
 
 	pageSize := SIZE{
 		Width:  widthMM * 1000,
@@ -51,13 +52,13 @@ func AddCustomPaperSize(printerName, paperName string, widthMM, heightMM, leftMM
 			Bottom: pageSize.Height,
 		},
 	}
-	if err = AddForm(p.h, 1, &formInfo); err != nil {
+	if err = AddForm(h, 1, &formInfo); err != nil {
 		err = fmt.Errorf("向打印机 [%s] 添加自定义纸张大小 [%s] 失败！错误：%s", printerName, paperName, err.Error())
 	}
 
 	pDeviceName, _ := windows.UTF16PtrFromString(printerName)
 	printerInfo := &PRINTER_INFO_9{}
-	err = DocumentProperties(0, p.h, pDeviceName, printerInfo.pDevMode, printerInfo.pDevMode, DM_MODIFY|DM_COPY)
+	err = DocumentProperties(0, h, pDeviceName, printerInfo.pDevMode, printerInfo.pDevMode, DM_MODIFY|DM_COPY)
 	if err != nil {
 		return // 无法为打印机设定打印方向
 	}
@@ -89,10 +90,14 @@ func DeleteCustomPaperSize(printerName, paperName string) (err error) {
 	defer func() {
 		_ = p.Close()
 	}()
+	var h syscall.Handle
+	if h, err = p.winspoolHandle("DeleteCustomPaperSize"); err != nil {
+		return
+	}
 
 	pFormName, _ := windows.UTF16FromString(paperName)
 	pName := &(pFormName)[0]
-	if err = DeleteForm(p.h, pName); errors.Is(err, windows.ERROR_INVALID_FORM_NAME) {
+	if err = DeleteForm(h, pName); errors.Is(err, windows.ERROR_INVALID_FORM_NAME) {
 		err = nil
 	}
 	return
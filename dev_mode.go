@@ -2,9 +2,8 @@ package winprinters
 
 import (
 	"fmt"
-	"reflect"
 	"strings"
-	"syscall"
+	"unicode/utf16"
 	"unsafe"
 )
 
@@ -281,6 +280,20 @@ func (dm *DevMode) SetCollate(collate int16) {
 	dm.dmFields |= DM_COLLATE
 }
 
+func (dm *DevMode) GetFormName() (string, bool) {
+	return utf16PtrToStringSize(&dm.dmFormName, CCHFORMNAME*2), dm.dmFields&DM_FORMNAME != 0
+}
+
+// SetFormName sets the form name, truncated to CCHFORMNAME-1 characters.
+func (dm *DevMode) SetFormName(name string) {
+	s := unsafe.Slice(&dm.dmFormName, CCHFORMNAME)
+	n := copy(s[:CCHFORMNAME-1], utf16.Encode([]rune(name)))
+	for i := n; i < len(s); i++ {
+		s[i] = 0
+	}
+	dm.dmFields |= DM_FORMNAME
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 //UTF functions:
 ///////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -292,14 +305,18 @@ func utf16PtrToStringSize(s *uint16, bytes uint32) string {
 		return ""
 	}
 
-	hdr := reflect.SliceHeader{
-		Data: uintptr(unsafe.Pointer(s)),
-		Len:  int(bytes / 2),
-		Cap:  int(bytes / 2),
-	}
-	c := *(*[]uint16)(unsafe.Pointer(&hdr))
+	return utf16ToString(unsafe.Slice(s, bytes/2))
+}
 
-	return syscall.UTF16ToString(c)
+// utf16ToString is syscall.UTF16ToString, which only exists on Windows.
+func utf16ToString(s []uint16) string {
+	for i, v := range s {
+		if v == 0 {
+			s = s[:i]
+			break
+		}
+	}
+	return string(utf16.Decode(s))
 }
 
 func utf16PtrToString(s *uint16) string {
@@ -0,0 +1,335 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Browser browses printer services with multicast DNS. It sends one-shot
+// queries from an ephemeral port (RFC 6762 section 5.1), so it works next
+// to the mDNS responder of the system, and asks for the service, text and
+// address records responders leave out of their answers.
+type Browser struct {
+	// Services are the service types browsed; Services when empty.
+	Services []string
+
+	// Domain is the browse domain; "local." when empty.
+	Domain string
+
+	// Interface is the network interface queries are sent on, selected
+	// by binding to its IPv4 address. Every multicast interface that is
+	// up is used when nil.
+	Interface *net.Interface
+
+	// Addr is the multicast group queries are sent to; 224.0.0.251:5353
+	// when nil.
+	Addr *net.UDPAddr
+
+	// Interval is the pause between repeated queries; 1 second when
+	// zero.
+	Interval time.Duration
+}
+
+// Browse browses services, or Services when none are given, until ctx is
+// done, using the default Browser settings.
+func Browse(ctx context.Context, services ...string) ([]DiscoveredPrinter, error) {
+	return (&Browser{Services: services}).Browse(ctx)
+}
+
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// interfaceAddrs returns the IPv4 addresses to send queries from. An empty
+// result means sending from the unspecified address.
+func (b *Browser) interfaceAddrs() ([]net.IP, error) {
+	ifs := []net.Interface{}
+	if b.Interface != nil {
+		ifs = append(ifs, *b.Interface)
+	} else {
+		all, err := net.Interfaces()
+		if err != nil {
+			return nil, err
+		}
+		for _, ifi := range all {
+			if ifi.Flags&net.FlagUp != 0 && ifi.Flags&net.FlagMulticast != 0 && ifi.Flags&net.FlagLoopback == 0 {
+				ifs = append(ifs, ifi)
+			}
+		}
+	}
+	var ips []net.IP
+	for i := range ifs {
+		addrs, err := ifs[i].Addrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			if n, ok := a.(*net.IPNet); ok && n.IP.To4() != nil {
+				ips = append(ips, n.IP.To4())
+				break
+			}
+		}
+	}
+	return ips, nil
+}
+
+// Browse sends queries and collects the answers until ctx is done, then
+// returns the printers whose service record arrived, sorted by service and
+// instance name. Ending ctx is not an error.
+func (b *Browser) Browse(ctx context.Context) ([]DiscoveredPrinter, error) {
+	ips, err := b.interfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		ips = []net.IP{nil}
+	}
+	var conns []*net.UDPConn
+	defer func() {
+		for _, c := range conns {
+			_ = c.Close()
+		}
+	}()
+	for _, ip := range ips {
+		c, err := net.ListenUDP("udp4", &net.UDPAddr{IP: ip})
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, c)
+	}
+
+	st := newBrowseState(b)
+	packets := make(chan packet)
+	var wg sync.WaitGroup
+	for _, c := range conns {
+		wg.Add(1)
+		go func(c *net.UDPConn) {
+			defer wg.Done()
+			buf := make([]byte, 9000)
+			for {
+				n, from, err := c.ReadFromUDP(buf)
+				if err != nil {
+					return
+				}
+				select {
+				case packets <- packet{append([]byte(nil), buf[:n]...), from.IP}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(c)
+	}
+	defer wg.Wait()
+
+	send := func(qs []question) {
+		if len(qs) == 0 {
+			return
+		}
+		m := &message{Questions: qs}
+		msg, err := m.MarshalBinary()
+		if err != nil {
+			return
+		}
+		for _, c := range conns {
+			_, _ = c.WriteToUDP(msg, b.group())
+		}
+	}
+
+	interval := b.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	send(st.browseQuestions())
+	for {
+		select {
+		case <-ctx.Done():
+			for _, c := range conns {
+				_ = c.Close()
+			}
+			return st.printers(), nil
+		case <-tick.C:
+			send(st.browseQuestions())
+			st.asked = make(map[question]bool)
+			send(st.missing())
+		case p := <-packets:
+			var m message
+			if m.UnmarshalBinary(p.data) != nil || m.Flags&flagResponse == 0 {
+				continue
+			}
+			st.add(&m, p.from)
+			send(st.missing())
+		}
+	}
+}
+
+func (b *Browser) group() *net.UDPAddr {
+	if b.Addr != nil {
+		return b.Addr
+	}
+	return mdnsGroup
+}
+
+type packet struct {
+	data []byte
+	from net.IP
+}
+
+// browseState is the cache of records received by Browse. Names are kept
+// lower cased, as DNS names compare case-insensitively.
+type browseState struct {
+	services map[string]string          // service name, e.g. "_ipp._tcp.local.", to service type
+	domain   string                     // browse domain
+	inst     map[string]string          // instance name to service type
+	srv      map[string]record          // by instance name
+	txt      map[string][]string        // by instance name
+	from     map[string]net.IP          // by instance name, sender of its SRV record
+	addrs    map[string]map[string]bool // by host name
+	asked    map[question]bool
+}
+
+func newBrowseState(b *Browser) *browseState {
+	st := &browseState{
+		services: make(map[string]string),
+		domain:   b.Domain,
+		inst:     make(map[string]string),
+		srv:      make(map[string]record),
+		txt:      make(map[string][]string),
+		from:     make(map[string]net.IP),
+		addrs:    make(map[string]map[string]bool),
+		asked:    make(map[question]bool),
+	}
+	if st.domain == "" {
+		st.domain = "local."
+	}
+	if !strings.HasSuffix(st.domain, ".") {
+		st.domain += "."
+	}
+	services := b.Services
+	if len(services) == 0 {
+		services = Services
+	}
+	for _, s := range services {
+		st.services[strings.ToLower(strings.TrimSuffix(s, ".")+"."+st.domain)] = s
+	}
+	return st
+}
+
+func (st *browseState) browseQuestions() []question {
+	var qs []question
+	for name := range st.services {
+		qs = append(qs, question{Name: name, Type: typePTR})
+	}
+	sort.Slice(qs, func(i, j int) bool { return qs[i].Name < qs[j].Name })
+	return qs
+}
+
+// add caches the records of m, sent by from.
+func (st *browseState) add(m *message, from net.IP) {
+	for _, r := range append(append([]record(nil), m.Answers...), m.Additionals...) {
+		if r.Class&classMask != classIN {
+			continue
+		}
+		name := strings.ToLower(r.Name)
+		switch r.Type {
+		case typePTR:
+			service, ok := st.services[name]
+			if !ok {
+				continue
+			}
+			target := strings.ToLower(r.Target)
+			if r.TTL == 0 {
+				// A goodbye packet: the service is gone.
+				delete(st.inst, target)
+				continue
+			}
+			if strings.HasSuffix(target, "."+name) {
+				st.inst[target] = service
+			}
+		case typeSRV:
+			st.srv[name] = r
+			st.from[name] = from
+		case typeTXT:
+			st.txt[name] = r.Text
+		case typeA, typeAAAA:
+			if st.addrs[name] == nil {
+				st.addrs[name] = make(map[string]bool)
+			}
+			st.addrs[name][r.IP.String()] = true
+		}
+	}
+}
+
+// missing returns the questions for the records still missing, each asked
+// once until the next browse query.
+func (st *browseState) missing() []question {
+	var qs []question
+	ask := func(q question) {
+		if !st.asked[q] {
+			st.asked[q] = true
+			qs = append(qs, q)
+		}
+	}
+	for name := range st.inst {
+		srv, ok := st.srv[name]
+		if !ok {
+			ask(question{Name: name, Type: typeSRV})
+		}
+		if _, ok := st.txt[name]; !ok {
+			ask(question{Name: name, Type: typeTXT})
+		}
+		if ok && len(st.addrs[strings.ToLower(srv.Target)]) == 0 {
+			ask(question{Name: strings.ToLower(srv.Target), Type: typeA})
+		}
+	}
+	sort.Slice(qs, func(i, j int) bool {
+		if qs[i].Name != qs[j].Name {
+			return qs[i].Name < qs[j].Name
+		}
+		return qs[i].Type < qs[j].Type
+	})
+	return qs
+}
+
+// printers returns the instances whose SRV record arrived.
+func (st *browseState) printers() []DiscoveredPrinter {
+	var ps []DiscoveredPrinter
+	for name, service := range st.inst {
+		srv, ok := st.srv[name]
+		if !ok {
+			continue
+		}
+		d := DiscoveredPrinter{
+			Service: service,
+			Domain:  st.domain,
+			Host:    srv.Target,
+			Port:    int(srv.Port),
+		}
+		if labels := splitName(srv.Name); len(labels) > 0 {
+			d.Instance = labels[0]
+		}
+		d.parseTXT(st.txt[name])
+		var addrs []string
+		for a := range st.addrs[strings.ToLower(srv.Target)] {
+			addrs = append(addrs, a)
+		}
+		sort.Strings(addrs)
+		for _, a := range addrs {
+			d.Addrs = append(d.Addrs, net.ParseIP(a))
+		}
+		if len(d.Addrs) == 0 && st.from[name] != nil {
+			d.Addrs = []net.IP{st.from[name]}
+		}
+		ps = append(ps, d)
+	}
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].Service != ps[j].Service {
+			return ps[i].Service < ps[j].Service
+		}
+		return ps[i].Instance < ps[j].Instance
+	})
+	return ps
+}
@@ -0,0 +1,188 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// responder is an mDNS responder on the loopback interface. Instances of
+// services in minimal get their PTR record only, so the browser has to ask
+// for the rest.
+type responder struct {
+	conn    *net.UDPConn
+	records []record
+	minimal map[string]bool
+}
+
+func loopback(t *testing.T) *net.Interface {
+	ifs, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range ifs {
+		if ifs[i].Flags&net.FlagLoopback != 0 && ifs[i].Flags&net.FlagUp != 0 {
+			return &ifs[i]
+		}
+	}
+	t.Skip("no loopback interface")
+	return nil
+}
+
+func newResponder(t *testing.T, ifi *net.Interface, records []record, minimal ...string) *responder {
+	c, err := net.ListenMulticastUDP("udp4", ifi, &net.UDPAddr{IP: mdnsGroup.IP})
+	if err != nil {
+		t.Skipf("multicast is not available: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	r := &responder{conn: c, records: records, minimal: make(map[string]bool)}
+	for _, s := range minimal {
+		r.minimal[s+".local."] = true
+	}
+	go r.serve()
+	return r
+}
+
+func (r *responder) group() *net.UDPAddr {
+	return &net.UDPAddr{IP: mdnsGroup.IP, Port: r.conn.LocalAddr().(*net.UDPAddr).Port}
+}
+
+// lookup returns the records called name of type typ.
+func (r *responder) lookup(name string, typ uint16) []record {
+	var rs []record
+	for _, rr := range r.records {
+		if strings.EqualFold(rr.Name, name) && (typ == typeANY || rr.Type == typ) {
+			rs = append(rs, rr)
+		}
+	}
+	return rs
+}
+
+func (r *responder) serve() {
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		var q message
+		if q.UnmarshalBinary(buf[:n]) != nil || q.Flags&flagResponse != 0 {
+			continue
+		}
+		resp := &message{ID: q.ID, Flags: flagResponse | flagAA}
+		for _, qq := range q.Questions {
+			answers := r.lookup(qq.Name, qq.Type)
+			resp.Answers = append(resp.Answers, answers...)
+			for _, a := range answers {
+				if a.Type != typePTR || r.minimal[strings.ToLower(a.Name)] {
+					continue
+				}
+				resp.Additionals = append(resp.Additionals, r.lookup(a.Target, typeANY)...)
+				for _, srv := range r.lookup(a.Target, typeSRV) {
+					resp.Additionals = append(resp.Additionals, r.lookup(srv.Target, typeA)...)
+				}
+			}
+		}
+		if len(resp.Answers) == 0 {
+			continue
+		}
+		b, err := resp.MarshalBinary()
+		if err != nil {
+			panic(err)
+		}
+		_, _ = r.conn.WriteToUDP(b, from)
+	}
+}
+
+var testRecords = []record{
+	{Name: "_ipp._tcp.local.", Type: typePTR, Class: classIN, TTL: 4500, Target: `Office MFP\. 2nd floor._ipp._tcp.local.`},
+	{Name: `Office MFP\. 2nd floor._ipp._tcp.local.`, Type: typeSRV, Class: classIN | 0x8000, TTL: 120, Port: 631, Target: "MFP-1A2B.local."},
+	{Name: `Office MFP\. 2nd floor._ipp._tcp.local.`, Type: typeTXT, Class: classIN | 0x8000, TTL: 4500, Text: []string{
+		"txtvers=1", "rp=ipp/print", "ty=Brother MFC-L8900CDW", "product=(Brother MFC-L8900CDW)",
+		"pdl=application/octet-stream,image/urf,image/pwg-raster,application/pdf",
+		"Color=T", "Duplex=T", "UUID=e3248000-80ce-11db-8000-30055c773bcf",
+		"adminurl=http://MFP-1A2B.local./", "TY=ignored duplicate",
+	}},
+	{Name: "MFP-1A2B.local.", Type: typeA, Class: classIN | 0x8000, TTL: 120, IP: net.IPv4(192, 168, 1, 30)},
+
+	{Name: "_pdl-datastream._tcp.local.", Type: typePTR, Class: classIN, TTL: 4500, Target: "Zebra ZT410._pdl-datastream._tcp.local."},
+	{Name: "Zebra ZT410._pdl-datastream._tcp.local.", Type: typeSRV, Class: classIN, TTL: 120, Port: 9100, Target: "zt410.local."},
+	{Name: "Zebra ZT410._pdl-datastream._tcp.local.", Type: typeTXT, Class: classIN, TTL: 4500, Text: []string{"ty=Zebra ZT410", "pdl=application/vnd.zebra-zpl"}},
+	{Name: "zt410.local.", Type: typeA, Class: classIN, TTL: 120, IP: net.IPv4(192, 168, 1, 40)},
+
+	{Name: "_printer._tcp.local.", Type: typePTR, Class: classIN, TTL: 4500, Target: "Zebra ZT410._printer._tcp.local."},
+	{Name: "Zebra ZT410._printer._tcp.local.", Type: typeSRV, Class: classIN, TTL: 120, Port: 515, Target: "zt410.local."},
+	{Name: "Zebra ZT410._printer._tcp.local.", Type: typeTXT, Class: classIN, TTL: 4500, Text: nil},
+
+	// A service that is not browsed.
+	{Name: "_http._tcp.local.", Type: typePTR, Class: classIN, TTL: 4500, Target: "Router._http._tcp.local."},
+}
+
+func TestBrowse(t *testing.T) {
+	ifi := loopback(t)
+	r := newResponder(t, ifi, testRecords, ServicePDL, ServiceLPD)
+	b := &Browser{Interface: ifi, Addr: r.group(), Interval: 100 * time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	printers, err := b.Browse(ctx)
+	if err != nil {
+		t.Fatalf("Browse failed: %v", err)
+	}
+	if len(printers) != 3 {
+		t.Fatalf("Browse found %d printers: %v", len(printers), printers)
+	}
+
+	mfp := printers[0]
+	want := DiscoveredPrinter{
+		Instance:     "Office MFP. 2nd floor",
+		Service:      ServiceIPP,
+		Domain:       "local.",
+		Host:         "MFP-1A2B.local.",
+		Port:         631,
+		Addrs:        []net.IP{net.IPv4(192, 168, 1, 30)},
+		Type:         "Brother MFC-L8900CDW",
+		Product:      "Brother MFC-L8900CDW",
+		PDL:          []string{"application/octet-stream", "image/urf", "image/pwg-raster", "application/pdf"},
+		Color:        true,
+		Duplex:       true,
+		UUID:         "e3248000-80ce-11db-8000-30055c773bcf",
+		AdminURL:     "http://MFP-1A2B.local./",
+		ResourcePath: "ipp/print",
+	}
+	want.TXT = mfp.TXT
+	if !reflect.DeepEqual(mfp, want) {
+		t.Errorf("IPP printer =\n%+v\nwant\n%+v", mfp, want)
+	}
+	if mfp.TXT["ty"] != "Brother MFC-L8900CDW" || mfp.TXT["txtvers"] != "1" {
+		t.Errorf("TXT = %v", mfp.TXT)
+	}
+
+	// The PDL and LPD services were resolved with follow-up queries.
+	wantURIs := []string{"ipp://192.168.1.30:631/ipp/print", "socket://192.168.1.40:9100", "lpd://192.168.1.40/auto"}
+	for i, p := range printers {
+		if got := p.URI(); got != wantURIs[i] {
+			t.Errorf("%s: URI = %q, want %q", p.Instance, got, wantURIs[i])
+		}
+	}
+	if zpl := printers[1]; zpl.Instance != "Zebra ZT410" || zpl.Type != "Zebra ZT410" || zpl.Color {
+		t.Errorf("PDL printer = %+v", zpl)
+	}
+}
+
+func TestBrowse_Services(t *testing.T) {
+	ifi := loopback(t)
+	r := newResponder(t, ifi, testRecords)
+	b := &Browser{Services: []string{ServiceLPD}, Interface: ifi, Addr: r.group()}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	printers, err := b.Browse(ctx)
+	if err != nil {
+		t.Fatalf("Browse failed: %v", err)
+	}
+	if len(printers) != 1 || printers[0].Service != ServiceLPD || printers[0].Port != 515 {
+		t.Errorf("Browse(%s) = %v", ServiceLPD, printers)
+	}
+}
@@ -0,0 +1,332 @@
+package discovery
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DNS record types used by DNS-SD.
+const (
+	typeA    uint16 = 1
+	typePTR  uint16 = 12
+	typeTXT  uint16 = 16
+	typeAAAA uint16 = 28
+	typeSRV  uint16 = 33
+	typeANY  uint16 = 255
+)
+
+const (
+	classIN = 1
+	// classMask clears the mDNS cache-flush and unicast-response bits.
+	classMask = 0x7fff
+
+	flagResponse = 0x8000
+	flagAA       = 0x0400
+)
+
+var errShort = errors.New("discovery: truncated DNS message")
+
+// question is a DNS question.
+type question struct {
+	Name string
+	Type uint16
+}
+
+// record is a DNS resource record with the rdata of the types DNS-SD uses
+// decoded.
+type record struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	TTL   uint32
+
+	Target   string   // PTR and SRV
+	Port     uint16   // SRV
+	Priority uint16   // SRV
+	Weight   uint16   // SRV
+	Text     []string // TXT
+	IP       net.IP   // A and AAAA
+}
+
+// message is a DNS message.
+type message struct {
+	ID          uint16
+	Flags       uint16
+	Questions   []question
+	Answers     []record
+	Additionals []record // authority records are merged in when decoding
+}
+
+// Names are dotted strings ending with a dot. Dots and backslashes inside
+// labels, which service instance names may contain, are escaped with a
+// backslash.
+
+// splitName splits an escaped name into its labels.
+func splitName(name string) []string {
+	var (
+		labels []string
+		cur    strings.Builder
+	)
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case c == '\\' && i+1 < len(name):
+			i++
+			cur.WriteByte(name[i])
+		case c == '.':
+			labels = append(labels, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	if cur.Len() > 0 {
+		labels = append(labels, cur.String())
+	}
+	return labels
+}
+
+// escapeLabel escapes a label for use in a dotted name.
+func escapeLabel(l string) string {
+	return strings.NewReplacer(`\`, `\\`, `.`, `\.`).Replace(l)
+}
+
+// joinName returns the escaped name made of labels.
+func joinName(labels ...string) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(escapeLabel(l))
+		b.WriteByte('.')
+	}
+	return b.String()
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendName(b []byte, name string) ([]byte, error) {
+	for _, l := range splitName(name) {
+		if len(l) == 0 || len(l) > 63 {
+			return nil, fmt.Errorf("discovery: bad label %q in %q", l, name)
+		}
+		b = append(b, byte(len(l)))
+		b = append(b, l...)
+	}
+	return append(b, 0), nil
+}
+
+func appendRecord(b []byte, r record) ([]byte, error) {
+	b, err := appendName(b, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	b = appendUint16(b, r.Type)
+	b = appendUint16(b, r.Class)
+	b = appendUint32(b, r.TTL)
+	var data []byte
+	switch r.Type {
+	case typePTR:
+		data, err = appendName(nil, r.Target)
+	case typeSRV:
+		data = appendUint16(data, r.Priority)
+		data = appendUint16(data, r.Weight)
+		data = appendUint16(data, r.Port)
+		data, err = appendName(data, r.Target)
+	case typeTXT:
+		for _, s := range r.Text {
+			if len(s) > 255 {
+				return nil, fmt.Errorf("discovery: TXT string of %d bytes", len(s))
+			}
+			data = append(data, byte(len(s)))
+			data = append(data, s...)
+		}
+		if len(data) == 0 {
+			data = []byte{0}
+		}
+	case typeA:
+		data = r.IP.To4()
+	case typeAAAA:
+		data = r.IP.To16()
+	}
+	if err != nil {
+		return nil, err
+	}
+	b = appendUint16(b, uint16(len(data)))
+	return append(b, data...), nil
+}
+
+// MarshalBinary encodes m without name compression.
+func (m *message) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, 512)
+	b = appendUint16(b, m.ID)
+	b = appendUint16(b, m.Flags)
+	b = appendUint16(b, uint16(len(m.Questions)))
+	b = appendUint16(b, uint16(len(m.Answers)))
+	b = appendUint16(b, 0)
+	b = appendUint16(b, uint16(len(m.Additionals)))
+	var err error
+	for _, q := range m.Questions {
+		if b, err = appendName(b, q.Name); err != nil {
+			return nil, err
+		}
+		b = appendUint16(b, q.Type)
+		b = appendUint16(b, classIN)
+	}
+	for _, rs := range [][]record{m.Answers, m.Additionals} {
+		for _, r := range rs {
+			if b, err = appendRecord(b, r); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b, nil
+}
+
+// readName reads the possibly compressed name at off in msg and returns it
+// with the offset following it.
+func readName(msg []byte, off int) (string, int, error) {
+	var (
+		b    strings.Builder
+		end  = -1
+		hops = 0
+	)
+	for {
+		if off >= len(msg) {
+			return "", 0, errShort
+		}
+		n := int(msg[off])
+		switch n & 0xc0 {
+		case 0x00:
+			if n == 0 {
+				if end < 0 {
+					end = off + 1
+				}
+				if b.Len() == 0 {
+					b.WriteByte('.')
+				}
+				return b.String(), end, nil
+			}
+			if off+1+n > len(msg) {
+				return "", 0, errShort
+			}
+			b.WriteString(escapeLabel(string(msg[off+1 : off+1+n])))
+			b.WriteByte('.')
+			off += 1 + n
+		case 0xc0:
+			if off+2 > len(msg) {
+				return "", 0, errShort
+			}
+			if hops++; hops > 64 {
+				return "", 0, errors.New("discovery: DNS name compression loop")
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+		default:
+			return "", 0, fmt.Errorf("discovery: bad DNS label length 0x%02x", n)
+		}
+	}
+}
+
+func readRecord(msg []byte, off int) (record, int, error) {
+	var r record
+	name, off, err := readName(msg, off)
+	if err != nil {
+		return r, 0, err
+	}
+	if off+10 > len(msg) {
+		return r, 0, errShort
+	}
+	r.Name = name
+	r.Type = binary.BigEndian.Uint16(msg[off:])
+	r.Class = binary.BigEndian.Uint16(msg[off+2:])
+	r.TTL = binary.BigEndian.Uint32(msg[off+4:])
+	n := int(binary.BigEndian.Uint16(msg[off+8:]))
+	off += 10
+	if off+n > len(msg) {
+		return r, 0, errShort
+	}
+	data := msg[off : off+n]
+	switch r.Type {
+	case typePTR:
+		r.Target, _, err = readName(msg, off)
+	case typeSRV:
+		if n < 7 {
+			return r, 0, errShort
+		}
+		r.Priority = binary.BigEndian.Uint16(data)
+		r.Weight = binary.BigEndian.Uint16(data[2:])
+		r.Port = binary.BigEndian.Uint16(data[4:])
+		r.Target, _, err = readName(msg, off+6)
+	case typeTXT:
+		for i := 0; i < len(data); {
+			l := int(data[i])
+			if i+1+l > len(data) {
+				return r, 0, errShort
+			}
+			if l > 0 {
+				r.Text = append(r.Text, string(data[i+1:i+1+l]))
+			}
+			i += 1 + l
+		}
+	case typeA:
+		if n != net.IPv4len {
+			return r, 0, fmt.Errorf("discovery: A record of %d bytes", n)
+		}
+		r.IP = net.IP(append([]byte(nil), data...))
+	case typeAAAA:
+		if n != net.IPv6len {
+			return r, 0, fmt.Errorf("discovery: AAAA record of %d bytes", n)
+		}
+		r.IP = net.IP(append([]byte(nil), data...))
+	}
+	if err != nil {
+		return r, 0, err
+	}
+	return r, off + n, nil
+}
+
+// UnmarshalBinary decodes a DNS message.
+func (m *message) UnmarshalBinary(msg []byte) error {
+	if len(msg) < 12 {
+		return errShort
+	}
+	*m = message{ID: binary.BigEndian.Uint16(msg), Flags: binary.BigEndian.Uint16(msg[2:])}
+	counts := [4]int{}
+	for i := range counts {
+		counts[i] = int(binary.BigEndian.Uint16(msg[4+2*i:]))
+	}
+	off := 12
+	for i := 0; i < counts[0]; i++ {
+		name, next, err := readName(msg, off)
+		if err != nil {
+			return err
+		}
+		if next+4 > len(msg) {
+			return errShort
+		}
+		m.Questions = append(m.Questions, question{Name: name, Type: binary.BigEndian.Uint16(msg[next:])})
+		off = next + 4
+	}
+	for i := 0; i < counts[1]+counts[2]+counts[3]; i++ {
+		r, next, err := readRecord(msg, off)
+		if err != nil {
+			return err
+		}
+		if i < counts[1] {
+			m.Answers = append(m.Answers, r)
+		} else {
+			m.Additionals = append(m.Additionals, r)
+		}
+		off = next
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Package discovery finds network printers with DNS Service Discovery over
+// multicast DNS (RFC 6762 and RFC 6763), as advertised by IPP Everywhere,
+// AirPrint and Bonjour printers.
+package discovery
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Printer services browsed by default.
+const (
+	ServiceIPP  = "_ipp._tcp"            // IPP, ipp://
+	ServiceIPPS = "_ipps._tcp"           // IPP over TLS, ipps://
+	ServicePDL  = "_pdl-datastream._tcp" // raw TCP, usually port 9100, socket://
+	ServiceLPD  = "_printer._tcp"        // LPD (RFC 1179), lpd://
+)
+
+// Services are the printer services browsed when none are given.
+var Services = []string{ServiceIPP, ServiceIPPS, ServicePDL, ServiceLPD}
+
+// DiscoveredPrinter is a printer service instance found on the network.
+type DiscoveredPrinter struct {
+	Instance string   // service instance name, e.g. "HP LaserJet 400 M401dn"
+	Service  string   // one of the Service constants
+	Domain   string   // browse domain, "local."
+	Host     string   // host name of the printer, e.g. "NPI1A2B3C.local."
+	Port     int      // service port
+	Addrs    []net.IP // addresses of Host
+
+	// Decoded TXT record keys of the Bonjour Printing specification.
+	Type         string   // ty: make and model
+	Product      string   // product: PostScript product name, without the parentheses
+	PDL          []string // pdl: accepted document formats, as MIME types
+	Color        bool     // Color=T
+	Duplex       bool     // Duplex=T
+	UUID         string   // UUID, shared by the services of one device
+	AdminURL     string   // adminurl: web configuration page
+	ResourcePath string   // rp: IPP resource path or LPD queue name
+
+	// TXT holds every TXT record key, lower cased, with its value.
+	TXT map[string]string
+}
+
+// parseTXT decodes the key=value strings of a TXT record into d.
+func (d *DiscoveredPrinter) parseTXT(text []string) {
+	d.TXT = make(map[string]string)
+	for _, s := range text {
+		key, value := s, ""
+		if i := strings.IndexByte(s, '='); i >= 0 {
+			key, value = s[:i], s[i+1:]
+		}
+		key = strings.ToLower(key)
+		if _, dup := d.TXT[key]; dup || key == "" {
+			// RFC 6763 section 6.4: only the first occurrence counts.
+			continue
+		}
+		d.TXT[key] = value
+	}
+	d.Type = d.TXT["ty"]
+	d.Product = strings.TrimSuffix(strings.TrimPrefix(d.TXT["product"], "("), ")")
+	d.PDL = nil
+	for _, f := range strings.Split(d.TXT["pdl"], ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			d.PDL = append(d.PDL, f)
+		}
+	}
+	d.Color = strings.EqualFold(d.TXT["color"], "T")
+	d.Duplex = strings.EqualFold(d.TXT["duplex"], "T")
+	d.UUID = d.TXT["uuid"]
+	d.AdminURL = d.TXT["adminurl"]
+	d.ResourcePath = strings.TrimPrefix(d.TXT["rp"], "/")
+}
+
+// host returns the address to reach d at: its first IPv4 address, any
+// address, or its host name.
+func (d *DiscoveredPrinter) host() string {
+	for _, ip := range d.Addrs {
+		if ip.To4() != nil {
+			return ip.String()
+		}
+	}
+	if len(d.Addrs) > 0 {
+		return d.Addrs[0].String()
+	}
+	return strings.TrimSuffix(d.Host, ".")
+}
+
+// URI returns the printer URI of d, which the winprinters IPP, socket and
+// LPR backends all accept as a printer name: ipp:// and ipps:// URIs with
+// the rp path, socket://host:port, or lpd://host/queue. The queue of an
+// LPD service without rp is "auto", which Bonjour printers take as their
+// default queue.
+func (d *DiscoveredPrinter) URI() string {
+	hostPort := net.JoinHostPort(d.host(), strconv.Itoa(d.Port))
+	switch d.Service {
+	case ServiceIPP:
+		return "ipp://" + hostPort + "/" + d.ResourcePath
+	case ServiceIPPS:
+		return "ipps://" + hostPort + "/" + d.ResourcePath
+	case ServicePDL:
+		return "socket://" + hostPort
+	case ServiceLPD:
+		queue := d.ResourcePath
+		if queue == "" {
+			queue = "auto"
+		}
+		if d.Port != 0 && d.Port != 515 {
+			return "lpd://" + hostPort + "/" + queue
+		}
+		host := d.host()
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		return "lpd://" + host + "/" + queue
+	}
+	return ""
+}
+
+func (d *DiscoveredPrinter) String() string {
+	return fmt.Sprintf("%s (%s) %s", d.Instance, d.Service, d.URI())
+}
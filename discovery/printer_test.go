@@ -0,0 +1,85 @@
+package discovery
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+	m := &message{
+		ID:        7,
+		Flags:     flagResponse | flagAA,
+		Questions: []question{{Name: "_ipp._tcp.local.", Type: typePTR}},
+		Answers: []record{
+			{Name: "_ipp._tcp.local.", Type: typePTR, Class: classIN, TTL: 4500, Target: `A\.B._ipp._tcp.local.`},
+			{Name: `A\.B._ipp._tcp.local.`, Type: typeSRV, Class: classIN, TTL: 120, Priority: 1, Weight: 2, Port: 631, Target: "host.local."},
+			{Name: `A\.B._ipp._tcp.local.`, Type: typeTXT, Class: classIN, TTL: 4500, Text: []string{"rp=ipp/print", "Color=T"}},
+		},
+		Additionals: []record{
+			{Name: "host.local.", Type: typeA, Class: classIN, TTL: 120, IP: net.IPv4(10, 0, 0, 1).To4()},
+			{Name: "host.local.", Type: typeAAAA, Class: classIN, TTL: 120, IP: net.ParseIP("fe80::1")},
+		},
+	}
+	b, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	var got message
+	if err = got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	if !reflect.DeepEqual(&got, m) {
+		t.Errorf("UnmarshalBinary =\n%+v\nwant\n%+v", got, *m)
+	}
+	if labels := splitName(m.Answers[0].Target); !reflect.DeepEqual(labels, []string{"A.B", "_ipp", "_tcp", "local"}) {
+		t.Errorf("splitName = %q", labels)
+	}
+
+	// A response using name compression, as every responder sends.
+	compressed := []byte{
+		0, 0, 0x84, 0, 0, 0, 0, 1, 0, 0, 0, 0,
+		// _ipp._tcp.local. PTR Printer._ipp._tcp.local.
+		4, '_', 'i', 'p', 'p', 4, '_', 't', 'c', 'p', 5, 'l', 'o', 'c', 'a', 'l', 0,
+		0, 12, 0, 1, 0, 0, 0x11, 0x94, 0, 10,
+		7, 'P', 'r', 'i', 'n', 't', 'e', 'r', 0xc0, 12,
+	}
+	if err = got.UnmarshalBinary(compressed); err != nil {
+		t.Fatalf("UnmarshalBinary of a compressed message failed: %v", err)
+	}
+	if len(got.Answers) != 1 || got.Answers[0].Target != "Printer._ipp._tcp.local." {
+		t.Errorf("compressed message = %+v", got)
+	}
+
+	loop := append([]byte(nil), compressed...)
+	loop[len(loop)-1] = byte(len(loop) - 2)
+	for _, bad := range [][]byte{compressed[:20], compressed[:len(compressed)-1], loop} {
+		if err = got.UnmarshalBinary(bad); err == nil {
+			t.Errorf("UnmarshalBinary(% x) succeeded", bad)
+		}
+	}
+}
+
+func TestDiscoveredPrinter_URI(t *testing.T) {
+	for _, tt := range []struct {
+		d    DiscoveredPrinter
+		want string
+	}{
+		{DiscoveredPrinter{Service: ServiceIPPS, Host: "p.local.", Port: 443, ResourcePath: "ipp/print"}, "ipps://p.local:443/ipp/print"},
+		{DiscoveredPrinter{Service: ServiceIPP, Port: 631, Addrs: []net.IP{net.ParseIP("fe80::1"), net.ParseIP("10.0.0.2")}}, "ipp://10.0.0.2:631/"},
+		{DiscoveredPrinter{Service: ServicePDL, Port: 9100, Addrs: []net.IP{net.ParseIP("fe80::1")}}, "socket://[fe80::1]:9100"},
+		{DiscoveredPrinter{Service: ServiceLPD, Port: 515, Host: "box.local.", ResourcePath: "lp1"}, "lpd://box.local/lp1"},
+		{DiscoveredPrinter{Service: ServiceLPD, Port: 5515, Host: "box.local."}, "lpd://box.local:5515/auto"},
+		{DiscoveredPrinter{Service: "_http._tcp", Port: 80}, ""},
+	} {
+		if got := tt.d.URI(); got != tt.want {
+			t.Errorf("URI(%+v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+
+	var d DiscoveredPrinter
+	d.parseTXT([]string{"product=(GPL Ghostscript)", "pdl=", "Duplex=F", "color=t", "noValue"})
+	if d.Product != "GPL Ghostscript" || d.PDL != nil || d.Duplex || !d.Color || d.TXT["novalue"] != "" {
+		t.Errorf("parseTXT = %+v", d)
+	}
+}
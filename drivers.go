@@ -0,0 +1,318 @@
+package winprinters
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DriverVersion is the version of a driver, major.minor.build.revision in
+// 16 bit parts from the most significant.
+type DriverVersion uint64
+
+func (v DriverVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d.%d", uint16(v>>48), uint16(v>>32), uint16(v>>16), uint16(v))
+}
+
+// MarshalText encodes v as its String, for JSON.
+func (v DriverVersion) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// clone returns a copy of d that shares no slices with it.
+func (d DriverInfo) clone() DriverInfo {
+	for _, l := range []*[]string{&d.DependentFiles, &d.PreviousNames, &d.ColorProfiles, &d.CoreDriverDependencies} {
+		if *l != nil {
+			*l = append([]string(nil), *l...)
+		}
+	}
+	return d
+}
+
+// driverInfo8Layout holds the offsets in a DRIVER_INFO_8: cVersion, ten
+// string pointers from pName to pszzPreviousNames, ftDriverDate,
+// dwlDriverVersion, eight string pointers from pszMfgName to pszInfPath,
+// dwPrinterDriverAttributes, pszzCoreDriverDependencies,
+// ftMinInboxDriverVerDate and dwlMinInboxDriverVerVersion. The DWORDLONGs
+// are 8 byte aligned for 4 byte pointers too.
+type driverInfo8Layout struct {
+	names, date, version, more, attributes, core, minDate, minVersion, size int
+}
+
+func newDriverInfo8Layout(sb spoolBuffer) driverInfo8Layout {
+	align8 := func(n int) int { return (n + 7) &^ 7 }
+	var l driverInfo8Layout
+	l.names = sb.align(4)
+	l.date = l.names + 10*sb.ptr
+	l.version = align8(l.date + 8)
+	l.more = l.version + 8
+	l.attributes = l.more + 8*sb.ptr
+	l.core = sb.align(l.attributes + 4)
+	l.minDate = l.core + sb.ptr
+	l.minVersion = align8(l.minDate + 8)
+	l.size = l.minVersion + 8
+	return l
+}
+
+// decodeDriverInfo8 decodes count DRIVER_INFO_8 structures from sb.
+func decodeDriverInfo8(sb spoolBuffer, count int) ([]DriverInfo, error) {
+	l := newDriverInfo8Layout(sb)
+	if err := sb.check(0, count*l.size); err != nil {
+		return nil, err
+	}
+	drivers := make([]DriverInfo, 0, count)
+	for i := 0; i < count; i++ {
+		off := i * l.size
+		d := DriverInfo{
+			Version:               sb.uint32(off),
+			Date:                  sb.fileTime(off + l.date),
+			DriverVersion:         DriverVersion(sb.uint64(off + l.version)),
+			Attributes:            sb.uint32(off + l.attributes),
+			MinInboxDriverDate:    sb.fileTime(off + l.minDate),
+			MinInboxDriverVersion: DriverVersion(sb.uint64(off + l.minVersion)),
+		}
+		strs := []struct {
+			off   int
+			s     *string
+			multi *[]string
+		}{
+			{off: l.names, s: &d.Name},
+			{off: l.names + sb.ptr, s: &d.Environment},
+			{off: l.names + 2*sb.ptr, s: &d.DriverPath},
+			{off: l.names + 3*sb.ptr, s: &d.DataFile},
+			{off: l.names + 4*sb.ptr, s: &d.ConfigFile},
+			{off: l.names + 5*sb.ptr, s: &d.HelpFile},
+			{off: l.names + 6*sb.ptr, multi: &d.DependentFiles},
+			{off: l.names + 7*sb.ptr, s: &d.MonitorName},
+			{off: l.names + 8*sb.ptr, s: &d.DefaultDataType},
+			{off: l.names + 9*sb.ptr, multi: &d.PreviousNames},
+			{off: l.more, s: &d.Manufacturer},
+			{off: l.more + sb.ptr, s: &d.OEMURL},
+			{off: l.more + 2*sb.ptr, s: &d.HardwareID},
+			{off: l.more + 3*sb.ptr, s: &d.Provider},
+			{off: l.more + 4*sb.ptr, s: &d.PrintProcessor},
+			{off: l.more + 5*sb.ptr, s: &d.VendorSetup},
+			{off: l.more + 6*sb.ptr, multi: &d.ColorProfiles},
+			{off: l.more + 7*sb.ptr, s: &d.InfPath},
+			{off: l.core, multi: &d.CoreDriverDependencies},
+		}
+		for _, f := range strs {
+			var err error
+			if f.s != nil {
+				*f.s, err = sb.string(off + f.off)
+			} else {
+				*f.multi, err = sb.multiString(off + f.off)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		drivers = append(drivers, d)
+	}
+	return drivers, nil
+}
+
+// decodeNameInfo1 decodes count structures holding only a name pointer,
+// such as PRINTPROCESSOR_INFO_1 and DATATYPES_INFO_1, from sb.
+func decodeNameInfo1(sb spoolBuffer, count int) ([]string, error) {
+	if err := sb.check(0, count*sb.ptr); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		s, err := sb.string(i * sb.ptr)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, s)
+	}
+	return names, nil
+}
+
+// DriverInventory is implemented by Spoolers that list the printer drivers
+// and print processors installed on the print server.
+type DriverInventory interface {
+	// Drivers returns the drivers installed for environment, such as
+	// "Windows x64". The empty environment is the one of this process and
+	// "all" is every environment.
+	Drivers(environment string) ([]DriverInfo, error)
+	// PrintProcessors returns the names of the print processors installed
+	// for environment.
+	PrintProcessors(environment string) ([]string, error)
+	// PrintProcessorDataTypes returns the data types the print processor
+	// accepts, such as "RAW" and "NT EMF 1.008".
+	PrintProcessorDataTypes(processor string) ([]string, error)
+}
+
+func driverInventory(op string) (DriverInventory, error) {
+	if inv, ok := CurrentSpooler().(DriverInventory); ok {
+		return inv, nil
+	}
+	return nil, &UnsupportedError{Op: op}
+}
+
+// Drivers returns the printer drivers installed for environment, such as
+// "Windows x64". The empty environment is the one of this process and "all"
+// is every environment.
+func Drivers(environment string) ([]DriverInfo, error) {
+	inv, err := driverInventory("Drivers")
+	if err != nil {
+		return nil, err
+	}
+	return inv.Drivers(environment)
+}
+
+// PrintProcessors returns the names of the print processors installed for
+// environment, such as "winprint".
+func PrintProcessors(environment string) ([]string, error) {
+	inv, err := driverInventory("PrintProcessors")
+	if err != nil {
+		return nil, err
+	}
+	return inv.PrintProcessors(environment)
+}
+
+// PrintProcessorDataTypes returns the data types the print processor
+// accepts, to pick the PrinterSpec.DataType of a queue.
+func PrintProcessorDataTypes(processor string) ([]string, error) {
+	inv, err := driverInventory("PrintProcessorDataTypes")
+	if err != nil {
+		return nil, err
+	}
+	return inv.PrintProcessorDataTypes(processor)
+}
+
+// DriverKind is the driver model of a print queue.
+type DriverKind int
+
+const (
+	// DriverUnknown is a driver whose model is not reported, such as the
+	// drivers of spoolers other than winspool or of printers that could
+	// not be read.
+	DriverUnknown DriverKind = iota
+	// DriverV3 is a version 3 GDI driver.
+	DriverV3
+	// DriverV3XPS is a version 3 XPSDrv driver.
+	DriverV3XPS
+	// DriverV4 is a version 4 driver, which is XPS based.
+	DriverV4
+)
+
+func (k DriverKind) String() string {
+	switch k {
+	case DriverV3:
+		return "v3"
+	case DriverV3XPS:
+		return "v3 XPS"
+	case DriverV4:
+		return "v4"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes k as its String, for JSON.
+func (k DriverKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// classifyDriver returns the model of d from its version and attributes.
+func classifyDriver(d *DriverInfo) DriverKind {
+	switch {
+	case d.Version == 4:
+		return DriverV4
+	case d.Version == 3 && d.Attributes&PRINTER_DRIVER_XPS != 0:
+		return DriverV3XPS
+	case d.Version == 3:
+		return DriverV3
+	}
+	return DriverUnknown
+}
+
+// QueueDriver is the driver of a print queue in a DriverReport.
+type QueueDriver struct {
+	Printer     string
+	Driver      string
+	Environment string
+	Kind        DriverKind
+	Version     DriverVersion
+
+	// Err is the failure to open the printer or read its driver, which
+	// makes the kind unknown.
+	Err error `json:"-"`
+}
+
+// DriverReport lists the driver models of print queues, to find the queues
+// still on version 3 drivers before moving them to version 4 or XPS ones.
+type DriverReport []QueueDriver
+
+// Printers returns the names of the printers whose driver is one of kinds.
+func (r DriverReport) Printers(kinds ...DriverKind) []string {
+	var names []string
+	for _, q := range r {
+		for _, k := range kinds {
+			if q.Kind == k {
+				names = append(names, q.Printer)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// String lists the printers of each driver model, one line per model, such
+// as:
+//
+//	v3: Label (ZDesigner GK420d)
+//	v4: Office (Microsoft PS Class Driver)
+func (r DriverReport) String() string {
+	var lines []string
+	for _, k := range []DriverKind{DriverV3, DriverV3XPS, DriverV4, DriverUnknown} {
+		var queues []string
+		for _, q := range r {
+			if q.Kind != k {
+				continue
+			}
+			switch {
+			case q.Err != nil:
+				queues = append(queues, fmt.Sprintf("%s (%v)", q.Printer, q.Err))
+			case q.Driver != "":
+				queues = append(queues, fmt.Sprintf("%s (%s)", q.Printer, q.Driver))
+			default:
+				queues = append(queues, q.Printer)
+			}
+		}
+		if len(queues) > 0 {
+			lines = append(lines, k.String()+": "+strings.Join(queues, ", "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DriverCompatibility returns the driver model of every printer ReadNames
+// lists, in the same order. The printers are opened in parallel, a few at a
+// time; the kind of one that cannot be read is unknown. Only a failure to
+// list the printers is returned as an error.
+func DriverCompatibility() (DriverReport, error) {
+	names, err := ReadNames()
+	if err != nil {
+		return nil, err
+	}
+	report := make(DriverReport, len(names))
+	parallel(len(names), func(i int) {
+		q := &report[i]
+		q.Printer = names[i]
+		p, err := Open(q.Printer)
+		if err != nil {
+			q.Err = err
+			return
+		}
+		defer p.Close()
+		d, err := p.DriverInfo()
+		if err != nil {
+			q.Err = err
+			return
+		}
+		q.Driver, q.Environment, q.Version = d.Name, d.Environment, d.DriverVersion
+		q.Kind = classifyDriver(d)
+	})
+	return report, nil
+}
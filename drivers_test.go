@@ -0,0 +1,166 @@
+package winprinters
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeDriverInfo8(t *testing.T) {
+	want := []DriverInfo{
+		{
+			Name:                   "ZDesigner GK420d",
+			Environment:            "Windows x64",
+			DriverPath:             `C:\Windows\System32\DriverStore\FileRepository\zdesigner.inf_amd64\ZDesignerUI.dll`,
+			DataFile:               "ZDGK420d.gpd",
+			ConfigFile:             "ZDesignerUI.dll",
+			HelpFile:               "ZDesigner.hlp",
+			DependentFiles:         []string{"ZDesignerLM.dll", "ZDesignerRes.dll"},
+			MonitorName:            "ZDesigner Language Monitor",
+			DefaultDataType:        "RAW",
+			PreviousNames:          []string{"ZDesigner GK420d (EPL)"},
+			Date:                   time.Date(2021, 6, 21, 0, 0, 0, 0, time.UTC),
+			DriverVersion:          8<<48 | 6<<32 | 4<<16 | 12345,
+			Version:                3,
+			Manufacturer:           "ZDesigner",
+			OEMURL:                 "https://www.zebra.com",
+			HardwareID:             "zebra_technologiesztc_gk420d",
+			Provider:               "Zebra Technologies",
+			PrintProcessor:         "winprint",
+			ColorProfiles:          []string{"sRGB Color Space Profile.icm"},
+			InfPath:                `C:\Windows\System32\DriverStore\FileRepository\zdesigner.inf_amd64\zdesigner.inf`,
+			Attributes:             PRINTER_DRIVER_PACKAGE_AWARE,
+			CoreDriverDependencies: []string{"{D20EA372-DD35-4950-9ED8-A6335AFE79F0}"},
+			MinInboxDriverDate:     time.Date(2006, 6, 21, 0, 0, 0, 0, time.UTC),
+			MinInboxDriverVersion:  6<<48 | 1<<32 | 7600<<16 | 16385,
+		},
+		{Name: "Microsoft PS Class Driver", Version: 4, Attributes: PRINTER_DRIVER_XPS | PRINTER_DRIVER_CLASS},
+	}
+	for _, ptr := range []int{4, 8} {
+		l := newDriverInfo8Layout(spoolBuffer{ptr: ptr})
+		if size := map[int]int{4: 120, 8: 200}[ptr]; l.size != size {
+			t.Errorf("%d-byte pointers: DRIVER_INFO_8 is %d bytes, want %d", ptr, l.size, size)
+		}
+		w := newSpoolWriter(ptr, len(want)*l.size)
+		for i, d := range want {
+			off := i * l.size
+			w.uint32(off, d.Version)
+			for n, s := range []string{d.Name, d.Environment, d.DriverPath, d.DataFile, d.ConfigFile, d.HelpFile} {
+				w.string(off+l.names+n*ptr, s)
+			}
+			if d.DependentFiles != nil {
+				w.multiString(off+l.names+6*ptr, d.DependentFiles...)
+			}
+			w.string(off+l.names+7*ptr, d.MonitorName)
+			w.string(off+l.names+8*ptr, d.DefaultDataType)
+			if d.PreviousNames != nil {
+				w.multiString(off+l.names+9*ptr, d.PreviousNames...)
+			}
+			if !d.Date.IsZero() {
+				w.fileTime(off+l.date, d.Date)
+				w.fileTime(off+l.minDate, d.MinInboxDriverDate)
+			}
+			w.uint32(off+l.version, uint32(d.DriverVersion))
+			w.uint32(off+l.version+4, uint32(d.DriverVersion>>32))
+			for n, s := range []string{d.Manufacturer, d.OEMURL, d.HardwareID, d.Provider, d.PrintProcessor, d.VendorSetup} {
+				w.string(off+l.more+n*ptr, s)
+			}
+			if d.ColorProfiles != nil {
+				w.multiString(off+l.more+6*ptr, d.ColorProfiles...)
+			}
+			w.string(off+l.more+7*ptr, d.InfPath)
+			w.uint32(off+l.attributes, d.Attributes)
+			if d.CoreDriverDependencies != nil {
+				w.multiString(off+l.core, d.CoreDriverDependencies...)
+			}
+			w.uint32(off+l.minVersion, uint32(d.MinInboxDriverVersion))
+			w.uint32(off+l.minVersion+4, uint32(d.MinInboxDriverVersion>>32))
+		}
+		got, err := decodeDriverInfo8(w.sb, len(want))
+		if err != nil {
+			t.Fatalf("%d-byte pointers: decodeDriverInfo8 failed: %v", ptr, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%d-byte pointers: decodeDriverInfo8() =\n%+v\nwant\n%+v", ptr, got, want)
+		}
+		if _, err = decodeDriverInfo8(w.sb, len(want)+1); err == nil {
+			t.Errorf("%d-byte pointers: decoding past the buffer succeeded", ptr)
+		}
+	}
+	if v := want[0].DriverVersion.String(); v != "8.6.4.12345" {
+		t.Errorf("DriverVersion.String() = %q", v)
+	}
+}
+
+func TestDecodeNameInfo1(t *testing.T) {
+	for _, ptr := range []int{4, 8} {
+		w := newSpoolWriter(ptr, 2*ptr)
+		w.string(0, "RAW")
+		w.string(ptr, "NT EMF 1.008")
+		got, err := decodeNameInfo1(w.sb, 2)
+		if err != nil || !reflect.DeepEqual(got, []string{"RAW", "NT EMF 1.008"}) {
+			t.Errorf("%d-byte pointers: decodeNameInfo1() = %q, %v", ptr, got, err)
+		}
+	}
+}
+
+func TestDriverCompatibility(t *testing.T) {
+	fake := newTestSpooler(t)
+	fake.AddPrinter(FakePrinter{Name: "Store 12 Receipts", Driver: DriverInfo{Name: "EPSON TM-T88V", Version: 3, Attributes: PRINTER_DRIVER_XPS}})
+	fake.AddPrinter(FakePrinter{Name: "Plotter", Driver: DriverInfo{Name: "HP DesignJet"}})
+	for name, v := range map[string]uint32{"Label": 3, "Office": 4} {
+		v := v
+		if err := fake.UpdatePrinter(name, func(p *FakePrinter) { p.Driver.Version = v }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fake.InjectError("Plotter", "Open", errors.New("access denied"))
+
+	report, err := DriverCompatibility()
+	if err != nil {
+		t.Fatalf("DriverCompatibility failed: %v", err)
+	}
+	kinds := []DriverKind{DriverV3, DriverV4, DriverV3XPS, DriverUnknown}
+	for i, q := range report {
+		if q.Kind != kinds[i] {
+			t.Errorf("%s: kind %v, want %v", q.Printer, q.Kind, kinds[i])
+		}
+	}
+	if report[3].Err == nil {
+		t.Errorf("the printer that failed to open has no error")
+	}
+	if got := report.Printers(DriverV3XPS, DriverV4); !reflect.DeepEqual(got, []string{"Office", "Store 12 Receipts"}) {
+		t.Errorf("Printers(v3 XPS, v4) = %q", got)
+	}
+	want := `v3: Label (ZDesigner)
+v3 XPS: Store 12 Receipts (EPSON TM-T88V)
+v4: Office (Microsoft PS Class Driver)
+unknown: Plotter (access denied)`
+	if report.String() != want {
+		t.Errorf("report:\n%s\nwant:\n%s", report, want)
+	}
+}
+
+func TestDriverInventoryFake(t *testing.T) {
+	newTestSpooler(t)
+	drivers, err := Drivers("Windows x64")
+	if err != nil || len(drivers) != 2 || drivers[0].Name != "ZDesigner" {
+		t.Errorf("Drivers() = %+v, %v", drivers, err)
+	}
+	if drivers, _ = Drivers("Windows NT x86"); len(drivers) != 1 || drivers[0].Name != "Microsoft PS Class Driver" {
+		t.Errorf("Drivers(x86) = %+v", drivers)
+	}
+	processors, err := PrintProcessors("")
+	if err != nil || !reflect.DeepEqual(processors, []string{"winprint"}) {
+		t.Errorf("PrintProcessors() = %q, %v", processors, err)
+	}
+	types, err := PrintProcessorDataTypes("winprint")
+	if err != nil || types[0] != "RAW" {
+		t.Errorf("PrintProcessorDataTypes() = %q, %v", types, err)
+	}
+	if _, err = PrintProcessorDataTypes("hpcpp"); err == nil || !strings.Contains(err.Error(), "hpcpp") {
+		t.Errorf("PrintProcessorDataTypes() of an unknown processor: %v", err)
+	}
+}
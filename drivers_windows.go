@@ -0,0 +1,106 @@
+package winprinters
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// Drivers implements DriverInventory with EnumPrinterDrivers level 8.
+func (winspool) Drivers(environment string) ([]DriverInfo, error) {
+	return enumDrivers("", environment)
+}
+
+// PrintProcessors implements DriverInventory with EnumPrintProcessors.
+func (winspool) PrintProcessors(environment string) ([]string, error) {
+	return enumPrintProcessors("", environment)
+}
+
+// PrintProcessorDataTypes implements DriverInventory with
+// EnumPrintProcessorDatatypes.
+func (winspool) PrintProcessorDataTypes(processor string) ([]string, error) {
+	return enumDataTypes("", processor)
+}
+
+// enumDrivers lists the drivers of the print server, the local one when
+// server is empty.
+func enumDrivers(server, environment string) ([]DriverInfo, error) {
+	name, err := optionalUTF16(server)
+	if err != nil {
+		return nil, err
+	}
+	env, err := optionalUTF16(environment)
+	if err != nil {
+		return nil, err
+	}
+	sb, count, err := enumSpooler(func(buf *byte, bufN uint32, needed, returned *uint32) error {
+		return EnumPrinterDrivers(name, env, 8, buf, bufN, needed, returned)
+	})
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return decodeDriverInfo8(sb, count)
+}
+
+// enumPrintProcessors lists the print processors of the print server.
+func enumPrintProcessors(server, environment string) ([]string, error) {
+	name, err := optionalUTF16(server)
+	if err != nil {
+		return nil, err
+	}
+	env, err := optionalUTF16(environment)
+	if err != nil {
+		return nil, err
+	}
+	sb, count, err := enumSpooler(func(buf *byte, bufN uint32, needed, returned *uint32) error {
+		return EnumPrintProcessors(name, env, 1, buf, bufN, needed, returned)
+	})
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return decodeNameInfo1(sb, count)
+}
+
+// enumDataTypes lists the data types of a print processor of the print
+// server.
+func enumDataTypes(server, processor string) ([]string, error) {
+	name, err := optionalUTF16(server)
+	if err != nil {
+		return nil, err
+	}
+	p, err := windows.UTF16PtrFromString(processor)
+	if err != nil {
+		return nil, err
+	}
+	sb, count, err := enumSpooler(func(buf *byte, bufN uint32, needed, returned *uint32) error {
+		return EnumPrintProcessorDatatypes(name, p, 1, buf, bufN, needed, returned)
+	})
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return decodeNameInfo1(sb, count)
+}
+
+// optionalUTF16 returns s as a UTF-16 string, or nil when it is empty.
+func optionalUTF16(s string) (*uint16, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return windows.UTF16PtrFromString(s)
+}
+
+// enumSpooler calls the winspool enumeration enum with a buffer grown until
+// it fits, and returns the buffer and the count of structures in it.
+func enumSpooler(enum func(buf *byte, bufN uint32, needed, returned *uint32) error) (spoolBuffer, int, error) {
+	var needed, returned uint32
+	buf := make([]byte, 1)
+	for {
+		err := enum(&buf[0], uint32(len(buf)), &needed, &returned)
+		if err == nil {
+			break
+		}
+		if err != windows.ERROR_INSUFFICIENT_BUFFER || needed <= uint32(len(buf)) {
+			return spoolBuffer{}, 0, err
+		}
+		buf = make([]byte, needed)
+	}
+	return newSpoolBuffer(buf), int(returned), nil
+}
@@ -0,0 +1,892 @@
+package winprinters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	errFakeClosed     = errors.New("winprinters: fake printer handle is closed")
+	errFakeNoDocument = errors.New("winprinters: no document started")
+	errFakeDocument   = errors.New("winprinters: document already started")
+)
+
+// FakePrinter configures a printer served by a FakeSpooler.
+type FakePrinter struct {
+	Name   string
+	Status PrinterStatus
+	Driver DriverInfo
+	Forms  []FormInfo
+	Jobs   []JobInfo
+
+	// Info holds the rest of the PRINTER_INFO_2 information. Its Name,
+	// Status, DriverName and Jobs are taken from the fields above.
+	Info PrinterInfo
+
+	// Capabilities is what Printer.Capabilities returns. When nil, the
+	// printer does not report its capabilities.
+	Capabilities *Capabilities
+}
+
+// FakeDocument is a document written to a FakeSpooler.
+type FakeDocument struct {
+	Printer  string
+	JobID    uint32
+	Name     string
+	DataType string
+	Pages    int
+	Data     []byte
+
+	// OutputFile is the path passed to Printer.StartDocumentToFile. The
+	// fake records it but writes no file.
+	OutputFile string
+}
+
+// FakeSpooler is an in-memory Spooler. It serves configurable printers,
+// forms, drivers and queued jobs, records the documents written to it and
+// can be told to fail any operation, so code using this package can be
+// tested without winspool:
+//
+//	fake := winprinters.NewFakeSpooler(winprinters.FakePrinter{Name: "Label"})
+//	defer winprinters.SetSpooler(winprinters.SetSpooler(fake))
+type FakeSpooler struct {
+	mu        sync.Mutex
+	def       string
+	printers  []*FakePrinter
+	docs      []FakeDocument
+	errs      map[string]error
+	nextJobID uint32
+	watches   []*fakeWatch
+	ports     []fakePort
+	servers   []*FakeServer
+	conns     []string
+}
+
+// fakePort is a port of a FakeSpooler, with the configuration of Standard
+// TCP/IP ports.
+type fakePort struct {
+	info   PortInfo
+	config *PortConfig
+}
+
+// NewFakeSpooler returns a FakeSpooler serving printers. The first printer,
+// if any, is the default one.
+func NewFakeSpooler(printers ...FakePrinter) *FakeSpooler {
+	s := &FakeSpooler{errs: make(map[string]error), nextJobID: 1}
+	for _, p := range printers {
+		s.AddPrinter(p)
+	}
+	if len(printers) > 0 {
+		s.def = printers[0].Name
+	}
+	return s
+}
+
+// AddPrinter adds p, replacing any printer with the same name. Jobs without
+// a JobID are given one, and all jobs their queue position.
+func (s *FakeSpooler) AddPrinter(p FakePrinter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p.Driver = p.Driver.clone()
+	p.Forms = append([]FormInfo(nil), p.Forms...)
+	p.Jobs = append([]JobInfo(nil), p.Jobs...)
+	if p.Capabilities != nil {
+		p.Capabilities = p.Capabilities.clone()
+	}
+	for i := range p.Jobs {
+		s.assignJobID(&p.Jobs[i])
+		p.Jobs[i].Position = uint32(i + 1)
+	}
+	for i, q := range s.printers {
+		if q.Name == p.Name {
+			s.printers[i] = &p
+			s.notify(p.Name, watchAll, p.Jobs...)
+			return
+		}
+	}
+	s.printers = append(s.printers, &p)
+}
+
+// UpdatePrinter changes the named printer with update, such as to change
+// its status, forms or driver, and notifies its watchers.
+func (s *FakeSpooler) UpdatePrinter(name string, update func(p *FakePrinter)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.printer(name)
+	if p == nil {
+		return ErrPrinterNotFound
+	}
+	update(p)
+	for i := range p.Jobs {
+		s.assignJobID(&p.Jobs[i])
+	}
+	s.notify(name, watchAll, p.Jobs...)
+	return nil
+}
+
+// RemovePrinter removes the named printer. Removing the default printer
+// leaves the spooler without one, as Windows does.
+func (s *FakeSpooler) RemovePrinter(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, q := range s.printers {
+		if q.Name == name {
+			s.printers = append(s.printers[:i], s.printers[i+1:]...)
+			if s.def == name {
+				s.def = ""
+			}
+			return
+		}
+	}
+}
+
+// CreatePrinter implements PrinterAdmin, adding a printer with the driver
+// and settings of spec.
+func (s *FakeSpooler) CreatePrinter(spec PrinterSpec) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected(spec.Name, "CreatePrinter"); err != nil {
+		return err
+	}
+	if s.printer(spec.Name) != nil {
+		return fmt.Errorf("winprinters: printer %q already exists", spec.Name)
+	}
+	s.printers = append(s.printers, &FakePrinter{
+		Name:   spec.Name,
+		Driver: DriverInfo{Name: spec.DriverName},
+		Info:   *spec.info(),
+	})
+	return nil
+}
+
+// DeletePrinter implements PrinterAdmin.
+func (s *FakeSpooler) DeletePrinter(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected(name, "DeletePrinter"); err != nil {
+		return err
+	}
+	for i, q := range s.printers {
+		if q.Name == name {
+			s.printers = append(s.printers[:i], s.printers[i+1:]...)
+			if s.def == name {
+				s.def = ""
+			}
+			return nil
+		}
+	}
+	return ErrPrinterNotFound
+}
+
+// Ports implements PortAdmin.
+func (s *FakeSpooler) Ports() ([]PortInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected("", "Ports"); err != nil {
+		return nil, err
+	}
+	var ports []PortInfo
+	for _, p := range s.ports {
+		ports = append(ports, p.info)
+	}
+	return ports, nil
+}
+
+// AddTCPPort implements PortAdmin, checking that the port does not exist.
+func (s *FakeSpooler) AddTCPPort(c PortConfig) error {
+	info := PortInfo{Name: c.Name, MonitorName: StandardTCPPortMonitor, Description: "Standard TCP/IP Port", Type: PORT_TYPE_WRITE | PORT_TYPE_READ}
+	return s.addPort(info, &c)
+}
+
+// AddLocalPort implements PortAdmin, checking that the port does not exist.
+func (s *FakeSpooler) AddLocalPort(name string) error {
+	return s.addPort(PortInfo{Name: name, MonitorName: LocalPortMonitor, Description: "Local Port", Type: PORT_TYPE_WRITE}, nil)
+}
+
+func (s *FakeSpooler) addPort(info PortInfo, c *PortConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected(info.Name, "AddPort"); err != nil {
+		return err
+	}
+	if s.port(info.Name) != nil {
+		return fmt.Errorf("winprinters: port %q already exists", info.Name)
+	}
+	s.ports = append(s.ports, fakePort{info: info, config: c})
+	return nil
+}
+
+// ConfigureTCPPort implements PortAdmin.
+func (s *FakeSpooler) ConfigureTCPPort(c PortConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected(c.Name, "ConfigureTCPPort"); err != nil {
+		return err
+	}
+	p := s.port(c.Name)
+	if p == nil || p.config == nil {
+		return fmt.Errorf("winprinters: no Standard TCP/IP port %q", c.Name)
+	}
+	p.config = &c
+	return nil
+}
+
+// TCPPortConfig implements PortAdmin.
+func (s *FakeSpooler) TCPPortConfig(name string) (*PortConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected(name, "TCPPortConfig"); err != nil {
+		return nil, err
+	}
+	p := s.port(name)
+	if p == nil || p.config == nil {
+		return nil, fmt.Errorf("winprinters: no Standard TCP/IP port %q", name)
+	}
+	c := *p.config
+	return &c, nil
+}
+
+// DeletePort implements PortAdmin.
+func (s *FakeSpooler) DeletePort(monitor, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected(name, "DeletePort"); err != nil {
+		return err
+	}
+	for i, p := range s.ports {
+		if p.info.Name == name && p.info.MonitorName == monitor {
+			s.ports = append(s.ports[:i], s.ports[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("winprinters: no %s port %q", monitor, name)
+}
+
+// port returns the named port or nil. The caller holds s.mu.
+func (s *FakeSpooler) port(name string) *fakePort {
+	for i := range s.ports {
+		if s.ports[i].info.Name == name {
+			return &s.ports[i]
+		}
+	}
+	return nil
+}
+
+// fakeDataTypes are the data types of the winprint print processor.
+var fakeDataTypes = []string{"RAW", "RAW [FF appended]", "RAW [FF auto]", "NT EMF 1.008", "TEXT", "XPS2GDI"}
+
+// Drivers implements DriverInventory, listing the distinct drivers of the
+// local printers. Drivers without an environment belong to every
+// environment.
+func (s *FakeSpooler) Drivers(environment string) ([]DriverInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected("", "Drivers"); err != nil {
+		return nil, err
+	}
+	return s.drivers("", environment), nil
+}
+
+// drivers lists the distinct drivers of the printers of server for
+// environment. The caller holds s.mu.
+func (s *FakeSpooler) drivers(server, environment string) []DriverInfo {
+	var drivers []DriverInfo
+	seen := make(map[[2]string]bool)
+	for _, p := range s.printers {
+		d := p.Driver
+		key := [2]string{d.Name, d.Environment}
+		if s.serverOf(p.Name) != server || seen[key] ||
+			environment != "" && environment != "all" && d.Environment != "" && d.Environment != environment {
+			continue
+		}
+		seen[key] = true
+		drivers = append(drivers, d.clone())
+	}
+	return drivers
+}
+
+// PrintProcessors implements DriverInventory with the winprint processor
+// alone.
+func (s *FakeSpooler) PrintProcessors(string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected("", "PrintProcessors"); err != nil {
+		return nil, err
+	}
+	return []string{"winprint"}, nil
+}
+
+// PrintProcessorDataTypes implements DriverInventory with the data types of
+// winprint.
+func (s *FakeSpooler) PrintProcessorDataTypes(processor string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected("", "PrintProcessorDataTypes"); err != nil {
+		return nil, err
+	}
+	return fakeProcessorDataTypes(processor)
+}
+
+func fakeProcessorDataTypes(processor string) ([]string, error) {
+	if processor != "winprint" {
+		return nil, fmt.Errorf("winprinters: unknown print processor %q", processor)
+	}
+	return append([]string(nil), fakeDataTypes...), nil
+}
+
+// AddJob queues job on the named printer and returns its ID, which is
+// assigned when job.JobID is zero.
+func (s *FakeSpooler) AddJob(printer string, job JobInfo) (uint32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.printer(printer)
+	if p == nil {
+		return 0, ErrPrinterNotFound
+	}
+	s.assignJobID(&job)
+	job.Position = uint32(len(p.Jobs) + 1)
+	p.Jobs = append(p.Jobs, job)
+	s.notify(printer, watchJobs, job)
+	return job.JobID, nil
+}
+
+// RemoveJob removes a job from the named printer queue.
+func (s *FakeSpooler) RemoveJob(printer string, jobID uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p := s.printer(printer); p != nil && p.removeJob(jobID) {
+		s.notify(printer, watchJobs)
+	}
+}
+
+// removeJob removes a job from p and renumbers the queue positions. The
+// caller holds the spooler lock.
+func (p *FakePrinter) removeJob(jobID uint32) bool {
+	for i, j := range p.Jobs {
+		if j.JobID == jobID {
+			p.Jobs = append(p.Jobs[:i], p.Jobs[i+1:]...)
+			for k := range p.Jobs {
+				p.Jobs[k].Position = uint32(k + 1)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// job returns the queued job with the given ID or nil. The caller holds
+// the spooler lock.
+func (p *FakePrinter) job(jobID uint32) *JobInfo {
+	for i := range p.Jobs {
+		if p.Jobs[i].JobID == jobID {
+			return &p.Jobs[i]
+		}
+	}
+	return nil
+}
+
+// Documents returns the documents ended on any printer, oldest first.
+func (s *FakeSpooler) Documents() []FakeDocument {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	docs := make([]FakeDocument, len(s.docs))
+	copy(docs, s.docs)
+	return docs
+}
+
+// InjectError makes operation op fail with err on the named printer, or on
+// every printer when printer is empty. Op is the name of the Spooler or
+// PrinterHandle method, such as "Open", "Jobs" or "Write". A nil err removes
+// the injected failure.
+func (s *FakeSpooler) InjectError(printer, op string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := op + "/" + printer
+	if err == nil {
+		delete(s.errs, key)
+		return
+	}
+	s.errs[key] = err
+}
+
+// injected returns the error injected for op on printer. The caller holds s.mu.
+func (s *FakeSpooler) injected(printer, op string) error {
+	if err, ok := s.errs[op+"/"+printer]; ok {
+		return err
+	}
+	return s.errs[op+"/"]
+}
+
+// notify tells the watchers of the named printer which parts of its state
+// changed, and which jobs it concerns. The caller holds s.mu.
+func (s *FakeSpooler) notify(printer string, kinds watchKinds, jobs ...JobInfo) {
+	for _, w := range s.watches {
+		if w.printer != printer {
+			continue
+		}
+		w.pending.kinds |= kinds
+		w.pending.jobs = append(w.pending.jobs, jobs...)
+		select {
+		case w.signal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// printer returns the named printer or nil. The caller holds s.mu.
+func (s *FakeSpooler) printer(name string) *FakePrinter {
+	for _, p := range s.printers {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// assignJobID gives job the next free ID when it has none. The caller holds s.mu.
+func (s *FakeSpooler) assignJobID(job *JobInfo) {
+	if job.JobID == 0 {
+		job.JobID = s.nextJobID
+	}
+	if job.JobID >= s.nextJobID {
+		s.nextJobID = job.JobID + 1
+	}
+}
+
+func (s *FakeSpooler) GetDefault() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected("", "GetDefault"); err != nil {
+		return "", err
+	}
+	if s.def == "" {
+		return "", ErrPrinterNotFound
+	}
+	return s.def, nil
+}
+
+func (s *FakeSpooler) SetDefault(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected(name, "SetDefault"); err != nil {
+		return err
+	}
+	if s.printer(name) == nil {
+		return ErrPrinterNotFound
+	}
+	s.def = name
+	return nil
+}
+
+// ReadNames lists the printers, leaving out those of the servers added
+// with AddServer, then the printer connections.
+func (s *FakeSpooler) ReadNames() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected("", "ReadNames"); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(s.printers)+len(s.conns))
+	for _, p := range s.printers {
+		if s.serverOf(p.Name) == "" {
+			names = append(names, p.Name)
+		}
+	}
+	return append(names, s.conns...), nil
+}
+
+func (s *FakeSpooler) Open(name string) (PrinterHandle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected(name, "Open"); err != nil {
+		return nil, err
+	}
+	if s.printer(name) == nil {
+		return nil, ErrPrinterNotFound
+	}
+	return &fakeHandle{s: s, name: name}, nil
+}
+
+// fakeHandle is the PrinterHandle returned by FakeSpooler.Open.
+type fakeHandle struct {
+	s      *FakeSpooler
+	name   string
+	doc    *FakeDocument
+	closed bool
+}
+
+// begin locks the spooler and checks that op may run on h. On success the
+// caller must unlock h.s.mu.
+func (h *fakeHandle) begin(op string) (*FakePrinter, error) {
+	h.s.mu.Lock()
+	if h.closed {
+		h.s.mu.Unlock()
+		return nil, errFakeClosed
+	}
+	if err := h.s.injected(h.name, op); err != nil {
+		h.s.mu.Unlock()
+		return nil, err
+	}
+	p := h.s.printer(h.name)
+	if p == nil {
+		h.s.mu.Unlock()
+		return nil, ErrPrinterNotFound
+	}
+	return p, nil
+}
+
+func (h *fakeHandle) Jobs() ([]JobInfo, error) {
+	p, err := h.begin("Jobs")
+	if err != nil {
+		return nil, err
+	}
+	defer h.s.mu.Unlock()
+	if len(p.Jobs) == 0 {
+		return nil, nil
+	}
+	jobs := make([]JobInfo, len(p.Jobs))
+	copy(jobs, p.Jobs)
+	return jobs, nil
+}
+
+func (h *fakeHandle) Forms() ([]FormInfo, error) {
+	p, err := h.begin("Forms")
+	if err != nil {
+		return nil, err
+	}
+	defer h.s.mu.Unlock()
+	if len(p.Forms) == 0 {
+		return nil, nil
+	}
+	forms := make([]FormInfo, len(p.Forms))
+	copy(forms, p.Forms)
+	return forms, nil
+}
+
+func (h *fakeHandle) DriverInfo() (*DriverInfo, error) {
+	p, err := h.begin("DriverInfo")
+	if err != nil {
+		return nil, err
+	}
+	defer h.s.mu.Unlock()
+	di := p.Driver.clone()
+	return &di, nil
+}
+
+func (h *fakeHandle) CancelJob(jobID uint32) error {
+	p, err := h.begin("CancelJob")
+	if err != nil {
+		return err
+	}
+	defer h.s.mu.Unlock()
+	if !p.removeJob(jobID) {
+		return ErrJobNotFound
+	}
+	h.s.notify(h.name, watchJobs)
+	return nil
+}
+
+// ControlJob changes the status bits of a job the way winspool does. Cancel
+// and delete remove the job; restart clears the pages printed so far.
+func (h *fakeHandle) ControlJob(jobID, command uint32) error {
+	p, err := h.begin("ControlJob")
+	if err != nil {
+		return err
+	}
+	defer h.s.mu.Unlock()
+	j := p.job(jobID)
+	if j == nil {
+		return ErrJobNotFound
+	}
+	switch command {
+	case JOB_CONTROL_PAUSE:
+		j.StatusCode |= JOB_STATUS_PAUSED
+	case JOB_CONTROL_RESUME:
+		j.StatusCode &^= JOB_STATUS_PAUSED
+	case JOB_CONTROL_CANCEL, JOB_CONTROL_DELETE:
+		p.removeJob(jobID)
+	case JOB_CONTROL_RESTART:
+		j.StatusCode = j.StatusCode&^(JOB_STATUS_PRINTED|JOB_STATUS_COMPLETE|JOB_STATUS_ERROR) | JOB_STATUS_RESTART
+		j.PagesPrinted = 0
+	case JOB_CONTROL_RETAIN:
+		j.StatusCode |= JOB_STATUS_RETAINED
+	case JOB_CONTROL_RELEASE:
+		j.StatusCode &^= JOB_STATUS_RETAINED
+	default:
+		return fmt.Errorf("winprinters: unknown job control command %d", command)
+	}
+	h.s.notify(h.name, watchJobs)
+	return nil
+}
+
+func (h *fakeHandle) SetJobPriority(jobID, priority uint32) error {
+	p, err := h.begin("SetJobPriority")
+	if err != nil {
+		return err
+	}
+	defer h.s.mu.Unlock()
+	j := p.job(jobID)
+	if j == nil {
+		return ErrJobNotFound
+	}
+	j.Priority = priority
+	h.s.notify(h.name, watchJobs)
+	return nil
+}
+
+// SetJobPosition moves a job in the queue. Positions past the end move it
+// last.
+func (h *fakeHandle) SetJobPosition(jobID, position uint32) error {
+	p, err := h.begin("SetJobPosition")
+	if err != nil {
+		return err
+	}
+	defer h.s.mu.Unlock()
+	j := p.job(jobID)
+	if j == nil {
+		return ErrJobNotFound
+	}
+	job := *j
+	p.removeJob(jobID)
+	i := int(position) - 1
+	if i > len(p.Jobs) {
+		i = len(p.Jobs)
+	}
+	p.Jobs = append(p.Jobs, JobInfo{})
+	copy(p.Jobs[i+1:], p.Jobs[i:])
+	p.Jobs[i] = job
+	for k := range p.Jobs {
+		p.Jobs[k].Position = uint32(k + 1)
+	}
+	h.s.notify(h.name, watchJobs)
+	return nil
+}
+
+func (h *fakeHandle) PrinterInfo() (*PrinterInfo, error) {
+	p, err := h.begin("PrinterInfo")
+	if err != nil {
+		return nil, err
+	}
+	defer h.s.mu.Unlock()
+	info := p.Info
+	info.Name = p.Name
+	info.Status = p.Status
+	info.DriverName = p.Driver.Name
+	info.Jobs = uint32(len(p.Jobs))
+	if info.DevMode != nil {
+		dm := *info.DevMode
+		info.DevMode = &dm
+	}
+	return &info, nil
+}
+
+// UpdatePrinter stores info, renaming the printer when its Name changes
+// and changing the driver name. Status, Jobs and AveragePPM are read-only.
+func (h *fakeHandle) UpdatePrinter(info *PrinterInfo) error {
+	p, err := h.begin("UpdatePrinter")
+	if err != nil {
+		return err
+	}
+	defer h.s.mu.Unlock()
+	if info.Name != "" && info.Name != p.Name {
+		if h.s.printer(info.Name) != nil {
+			return fmt.Errorf("winprinters: printer %q already exists", info.Name)
+		}
+		if h.s.def == p.Name {
+			h.s.def = info.Name
+		}
+		for _, w := range h.s.watches {
+			if w.printer == p.Name {
+				w.printer = info.Name
+			}
+		}
+		p.Name, h.name = info.Name, info.Name
+	}
+	if info.DriverName != "" {
+		p.Driver.Name = info.DriverName
+	}
+	stored := *info
+	stored.Status, stored.Jobs, stored.AveragePPM = 0, 0, p.Info.AveragePPM
+	if stored.DevMode != nil {
+		dm := *stored.DevMode
+		stored.DevMode = &dm
+	}
+	p.Info = stored
+	h.s.notify(h.name, watchAll)
+	return nil
+}
+
+func (h *fakeHandle) Capabilities() (*Capabilities, error) {
+	p, err := h.begin("Capabilities")
+	if err != nil {
+		return nil, err
+	}
+	defer h.s.mu.Unlock()
+	if p.Capabilities == nil {
+		return nil, &UnsupportedError{Op: "Capabilities"}
+	}
+	return p.Capabilities.clone(), nil
+}
+
+func (h *fakeHandle) PrinterStatus() (PrinterStatus, error) {
+	p, err := h.begin("PrinterStatus")
+	if err != nil {
+		return 0, err
+	}
+	defer h.s.mu.Unlock()
+	return p.Status, nil
+}
+
+// Watch implements Watcher with the change notifications of the fake, which
+// like winspool ones report jobs that come and go between two refreshes.
+func (h *fakeHandle) Watch(ctx context.Context) (<-chan PrinterEvent, error) {
+	if _, err := h.begin("Watch"); err != nil {
+		return nil, err
+	}
+	fw := &fakeWatch{s: h.s, printer: h.name, signal: make(chan struct{}, 1)}
+	h.s.watches = append(h.s.watches, fw)
+	h.s.mu.Unlock()
+
+	w, err := newWatcher(h)
+	if err != nil {
+		fw.stop()
+		return nil, err
+	}
+	go func() {
+		defer fw.stop()
+		w.run(ctx, fw.next)
+	}()
+	return w.events, nil
+}
+
+func (h *fakeHandle) StartDocument(name, datatype string) (uint32, error) {
+	return h.StartFileDocument(name, datatype, "")
+}
+
+func (h *fakeHandle) StartFileDocument(name, datatype, path string) (uint32, error) {
+	p, err := h.begin("StartDocument")
+	if err != nil {
+		return 0, err
+	}
+	defer h.s.mu.Unlock()
+	if h.doc != nil {
+		return 0, errFakeDocument
+	}
+	job := JobInfo{
+		DocumentName: name,
+		DataType:     datatype,
+		StatusCode:   JOB_STATUS_SPOOLING,
+		Position:     uint32(len(p.Jobs) + 1),
+		Submitted:    time.Now().UTC(),
+	}
+	h.s.assignJobID(&job)
+	p.Jobs = append(p.Jobs, job)
+	h.doc = &FakeDocument{Printer: h.name, JobID: job.JobID, Name: name, DataType: datatype, OutputFile: path}
+	h.s.notify(h.name, watchJobs, job)
+	return job.JobID, nil
+}
+
+func (h *fakeHandle) Write(b []byte) (int, error) {
+	if _, err := h.begin("Write"); err != nil {
+		return 0, err
+	}
+	defer h.s.mu.Unlock()
+	if h.doc == nil {
+		return 0, errFakeNoDocument
+	}
+	h.doc.Data = append(h.doc.Data, b...)
+	return len(b), nil
+}
+
+func (h *fakeHandle) EndDocument() error {
+	p, err := h.begin("EndDocument")
+	if err != nil {
+		return err
+	}
+	defer h.s.mu.Unlock()
+	if h.doc == nil {
+		return errFakeNoDocument
+	}
+	if j := p.job(h.doc.JobID); j != nil {
+		j.StatusCode &^= JOB_STATUS_SPOOLING
+		j.TotalPages = uint32(h.doc.Pages)
+		h.s.notify(h.name, watchJobs, *j)
+	}
+	h.s.docs = append(h.s.docs, *h.doc)
+	h.doc = nil
+	return nil
+}
+
+func (h *fakeHandle) StartPage() error {
+	if _, err := h.begin("StartPage"); err != nil {
+		return err
+	}
+	defer h.s.mu.Unlock()
+	if h.doc == nil {
+		return errFakeNoDocument
+	}
+	return nil
+}
+
+func (h *fakeHandle) EndPage() error {
+	if _, err := h.begin("EndPage"); err != nil {
+		return err
+	}
+	defer h.s.mu.Unlock()
+	if h.doc == nil {
+		return errFakeNoDocument
+	}
+	h.doc.Pages++
+	return nil
+}
+
+func (h *fakeHandle) Close() error {
+	h.s.mu.Lock()
+	defer h.s.mu.Unlock()
+	if h.closed {
+		return errFakeClosed
+	}
+	if err := h.s.injected(h.name, "Close"); err != nil {
+		return err
+	}
+	h.closed = true
+	h.doc = nil
+	return nil
+}
+
+// fakeWatch receives the change notifications of one printer of a
+// FakeSpooler.
+type fakeWatch struct {
+	s       *FakeSpooler
+	printer string
+	signal  chan struct{}
+	pending watchChange // guarded by s.mu
+}
+
+// next waits for changes and returns them, merged since the last call.
+func (w *fakeWatch) next(ctx context.Context) (watchChange, error) {
+	select {
+	case <-ctx.Done():
+		return watchChange{}, ctx.Err()
+	case <-w.signal:
+	}
+	w.s.mu.Lock()
+	defer w.s.mu.Unlock()
+	c := w.pending
+	w.pending = watchChange{}
+	return c, nil
+}
+
+func (w *fakeWatch) stop() {
+	w.s.mu.Lock()
+	defer w.s.mu.Unlock()
+	for i, x := range w.s.watches {
+		if x == w {
+			w.s.watches = append(w.s.watches[:i], w.s.watches[i+1:]...)
+			return
+		}
+	}
+}
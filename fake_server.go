@@ -0,0 +1,296 @@
+package winprinters
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FakeServer configures a print server a FakeSpooler serves to OpenServer.
+// The printers of a remote server are the FakePrinters named like
+// \\printsrv01\Labels, which ReadNames of the FakeSpooler leaves out.
+type FakeServer struct {
+	Name       string // such as \\printsrv01, or empty for the local server
+	Comment    string // listed with the server by PRINTER_ENUM_REMOTE
+	Forms      []FormInfo
+	Ports      []PortInfo // of a remote server; the local one has the ports of PortAdmin
+	Properties ServerProperties
+}
+
+// AddServer adds srv, replacing any server with the same name. Without an
+// added local server, the local one has no forms and zero properties.
+func (s *FakeSpooler) AddServer(srv FakeServer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name, err := serverName(srv.Name); err == nil {
+		srv.Name = name
+	}
+	srv.Forms = append([]FormInfo(nil), srv.Forms...)
+	srv.Ports = append([]PortInfo(nil), srv.Ports...)
+	for i, q := range s.servers {
+		if strings.EqualFold(q.Name, srv.Name) {
+			s.servers[i] = &srv
+			return
+		}
+	}
+	s.servers = append(s.servers, &srv)
+}
+
+// server returns the named server, or nil. The caller holds s.mu.
+func (s *FakeSpooler) server(name string) *FakeServer {
+	for _, srv := range s.servers {
+		if strings.EqualFold(srv.Name, name) {
+			return srv
+		}
+	}
+	return nil
+}
+
+// serverOf returns the name of the added remote server the named printer
+// is on, or empty for a local printer. The caller holds s.mu.
+func (s *FakeSpooler) serverOf(printer string) string {
+	if !strings.HasPrefix(printer, `\\`) {
+		return ""
+	}
+	i := strings.Index(printer[2:], `\`)
+	if i < 0 {
+		return ""
+	}
+	if srv := s.server(printer[:2+i]); srv != nil && srv.Name != "" {
+		return srv.Name
+	}
+	return ""
+}
+
+// Connections implements ConnectionManager.
+func (s *FakeSpooler) Connections() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected("", "Connections"); err != nil {
+		return nil, err
+	}
+	return append([]string(nil), s.conns...), nil
+}
+
+// AddConnection implements ConnectionManager, connecting to a printer of a
+// server added with AddServer. Connecting twice succeeds.
+func (s *FakeSpooler) AddConnection(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected(name, "AddConnection"); err != nil {
+		return err
+	}
+	if s.connection(name) >= 0 {
+		return nil
+	}
+	if s.printer(name) == nil || s.serverOf(name) == "" {
+		return ErrPrinterNotFound
+	}
+	s.conns = append(s.conns, name)
+	return nil
+}
+
+// DeleteConnection implements ConnectionManager. Deleting the default
+// printer leaves the user without one.
+func (s *FakeSpooler) DeleteConnection(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected(name, "DeleteConnection"); err != nil {
+		return err
+	}
+	i := s.connection(name)
+	if i < 0 {
+		return ErrPrinterNotFound
+	}
+	if strings.EqualFold(s.def, s.conns[i]) {
+		s.def = ""
+	}
+	s.conns = append(s.conns[:i], s.conns[i+1:]...)
+	return nil
+}
+
+// connection returns the index of the named connection, or -1. The caller
+// holds s.mu.
+func (s *FakeSpooler) connection(name string) int {
+	for i, c := range s.conns {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// OpenServer implements ServerOpener. Remote servers must have been added
+// with AddServer.
+func (s *FakeSpooler) OpenServer(name string) (ServerHandle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected(name, "OpenServer"); err != nil {
+		return nil, err
+	}
+	if name != "" && s.server(name) == nil {
+		return nil, fmt.Errorf("winprinters: print server %s not found", name)
+	}
+	return &fakeServer{s: s, name: name}, nil
+}
+
+// fakeServer is the ServerHandle returned by FakeSpooler.OpenServer.
+type fakeServer struct {
+	s      *FakeSpooler
+	name   string
+	closed bool
+}
+
+// begin locks the spooler and checks that op may run on h, like
+// fakeHandle.begin. On success the caller must unlock h.s.mu.
+func (h *fakeServer) begin(op string) (*FakeServer, error) {
+	h.s.mu.Lock()
+	if h.closed {
+		h.s.mu.Unlock()
+		return nil, errFakeClosed
+	}
+	if err := h.s.injected(h.name, op); err != nil {
+		h.s.mu.Unlock()
+		return nil, err
+	}
+	if srv := h.s.server(h.name); srv != nil {
+		return srv, nil
+	}
+	if h.name == "" {
+		return &FakeServer{}, nil
+	}
+	h.s.mu.Unlock()
+	return nil, fmt.Errorf("winprinters: print server %s not found", h.name)
+}
+
+// EnumPrinters lists the local printers for PRINTER_ENUM_LOCAL, the
+// printer connections for PRINTER_ENUM_CONNECTIONS, those of the server for
+// PRINTER_ENUM_NAME, and the remote servers with their shared printers for
+// PRINTER_ENUM_REMOTE and PRINTER_ENUM_NETWORK.
+func (h *fakeServer) EnumPrinters(flags uint32) ([]PrinterEntry, error) {
+	if _, err := h.begin("EnumPrinters"); err != nil {
+		return nil, err
+	}
+	defer h.s.mu.Unlock()
+	var entries []PrinterEntry
+	seen := make(map[string]bool)
+	add := func(e PrinterEntry) {
+		if !seen[e.Name] {
+			seen[e.Name] = true
+			entries = append(entries, e)
+		}
+	}
+	addPrinter := func(p *FakePrinter) {
+		add(PrinterEntry{
+			Flags:       PRINTER_ENUM_ICON8,
+			Description: p.Name + "," + p.Driver.Name + "," + p.Info.Location,
+			Name:        p.Name,
+			Comment:     p.Info.Comment,
+		})
+	}
+	printers := func(server string, shared bool) {
+		for _, p := range h.s.printers {
+			if h.s.serverOf(p.Name) == server && (!shared || p.Info.Attributes.Shared()) {
+				addPrinter(p)
+			}
+		}
+	}
+	shared := flags&PRINTER_ENUM_SHARED != 0
+	if flags&PRINTER_ENUM_LOCAL != 0 {
+		printers("", shared)
+	}
+	if flags&PRINTER_ENUM_CONNECTIONS != 0 {
+		for _, name := range h.s.conns {
+			if p := h.s.printer(name); p != nil {
+				addPrinter(p)
+			}
+		}
+	}
+	if flags&PRINTER_ENUM_NAME != 0 {
+		printers(h.name, shared)
+	}
+	if flags&(PRINTER_ENUM_REMOTE|PRINTER_ENUM_NETWORK) != 0 {
+		for _, srv := range h.s.servers {
+			if srv.Name == "" {
+				continue
+			}
+			if flags&PRINTER_ENUM_REMOTE != 0 {
+				add(PrinterEntry{Flags: PRINTER_ENUM_CONTAINER, Description: srv.Name + "," + srv.Comment, Name: srv.Name, Comment: srv.Comment})
+			}
+			printers(srv.Name, true)
+		}
+	}
+	return entries, nil
+}
+
+func (h *fakeServer) Forms() ([]FormInfo, error) {
+	srv, err := h.begin("Forms")
+	if err != nil {
+		return nil, err
+	}
+	defer h.s.mu.Unlock()
+	return append([]FormInfo(nil), srv.Forms...), nil
+}
+
+func (h *fakeServer) Ports() ([]PortInfo, error) {
+	srv, err := h.begin("Ports")
+	if err != nil {
+		return nil, err
+	}
+	defer h.s.mu.Unlock()
+	if h.name != "" {
+		return append([]PortInfo(nil), srv.Ports...), nil
+	}
+	var ports []PortInfo
+	for _, p := range h.s.ports {
+		ports = append(ports, p.info)
+	}
+	return ports, nil
+}
+
+func (h *fakeServer) Drivers(environment string) ([]DriverInfo, error) {
+	if _, err := h.begin("Drivers"); err != nil {
+		return nil, err
+	}
+	defer h.s.mu.Unlock()
+	return h.s.drivers(h.name, environment), nil
+}
+
+func (h *fakeServer) PrintProcessors(string) ([]string, error) {
+	if _, err := h.begin("PrintProcessors"); err != nil {
+		return nil, err
+	}
+	defer h.s.mu.Unlock()
+	return []string{"winprint"}, nil
+}
+
+func (h *fakeServer) PrintProcessorDataTypes(processor string) ([]string, error) {
+	if _, err := h.begin("PrintProcessorDataTypes"); err != nil {
+		return nil, err
+	}
+	defer h.s.mu.Unlock()
+	return fakeProcessorDataTypes(processor)
+}
+
+// PrinterData returns the values of the server Properties.
+func (h *fakeServer) PrinterData(value string) (uint32, []byte, error) {
+	srv, err := h.begin("PrinterData")
+	if err != nil {
+		return 0, nil, err
+	}
+	defer h.s.mu.Unlock()
+	typ, data, ok := serverPropertyData(&srv.Properties, value)
+	if !ok {
+		return 0, nil, fmt.Errorf("winprinters: print server value %q not found", value)
+	}
+	return typ, data, nil
+}
+
+func (h *fakeServer) Close() error {
+	h.s.mu.Lock()
+	defer h.s.mu.Unlock()
+	if h.closed {
+		return errFakeClosed
+	}
+	h.closed = true
+	return nil
+}
@@ -0,0 +1,326 @@
+package winprinters
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func newTestSpooler(t *testing.T) *FakeSpooler {
+	fake := NewFakeSpooler(
+		FakePrinter{
+			Name:   "Label",
+			Driver: DriverInfo{Name: "ZDesigner", Environment: "Windows x64"},
+			Forms:  []FormInfo{{Name: "4x6", Size: SIZE{Width: 101600, Height: 152400}}},
+			Jobs:   []JobInfo{{DocumentName: "queued", StatusCode: JOB_STATUS_PAUSED}},
+		},
+		FakePrinter{
+			Name:   "Office",
+			Driver: DriverInfo{Name: "Microsoft PS Class Driver", Attributes: PRINTER_DRIVER_XPS},
+		},
+	)
+	previous := SetSpooler(fake)
+	t.Cleanup(func() {
+		SetSpooler(previous)
+	})
+	return fake
+}
+
+func closePrinter(p *Printer) {
+	if p == nil {
+		return
+	}
+	_ = p.Close()
+}
+
+func TestFakeSpooler_Names(t *testing.T) {
+	fake := newTestSpooler(t)
+
+	names, err := ReadNames()
+	if err != nil {
+		t.Fatalf("ReadNames failed: %v", err)
+	}
+	if want := []string{"Label", "Office"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadNames() = %q, want %q", names, want)
+	}
+
+	name, err := GetDefault()
+	if err != nil || name != "Label" {
+		t.Errorf("GetDefault() = %q, %v, want %q", name, err, "Label")
+	}
+	if err = SetDefault("Office"); err != nil {
+		t.Fatalf("SetDefault failed: %v", err)
+	}
+	if name, _ = GetDefault(); name != "Office" {
+		t.Errorf("GetDefault() after SetDefault = %q, want %q", name, "Office")
+	}
+	if err = SetDefault("Missing"); !errors.Is(err, ErrPrinterNotFound) {
+		t.Errorf("SetDefault(Missing) error = %v, want %v", err, ErrPrinterNotFound)
+	}
+	if _, err = Open("Missing"); !errors.Is(err, ErrPrinterNotFound) {
+		t.Errorf("Open(Missing) error = %v, want %v", err, ErrPrinterNotFound)
+	}
+
+	fake.RemovePrinter("Label")
+	if name, err = GetDefault(); err != nil || name != "Office" {
+		t.Errorf("GetDefault() after removing another printer = %q, %v", name, err)
+	}
+	fake.RemovePrinter("Office")
+	if name, err = GetDefault(); !errors.Is(err, ErrPrinterNotFound) {
+		t.Errorf("GetDefault() after removing the default = %q, %v, want %v", name, err, ErrPrinterNotFound)
+	}
+}
+
+func TestFakeSpooler_Printer(t *testing.T) {
+	newTestSpooler(t)
+
+	p, err := Open("Label")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer closePrinter(p)
+
+	forms, err := p.Forms()
+	if err != nil || len(forms) != 1 || forms[0].Name != "4x6" {
+		t.Errorf("Forms() = %+v, %v", forms, err)
+	}
+	di, err := p.DriverInfo()
+	if err != nil || di.Name != "ZDesigner" {
+		t.Errorf("DriverInfo() = %+v, %v", di, err)
+	}
+	jobs, err := p.Jobs()
+	if err != nil || len(jobs) != 1 || jobs[0].JobID == 0 || jobs[0].DocumentName != "queued" {
+		t.Errorf("Jobs() = %+v, %v", jobs, err)
+	}
+}
+
+func TestFakeSpooler_Document(t *testing.T) {
+	fake := newTestSpooler(t)
+
+	p, err := Open("Office")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer closePrinter(p)
+
+	if _, err = p.Write([]byte("early")); err == nil {
+		t.Errorf("Write before StartDocument succeeded")
+	}
+	job, err := p.StartRawDocument("report")
+	if err != nil {
+		t.Fatalf("StartRawDocument failed: %v", err)
+	}
+	jobs, _ := p.Jobs()
+	if len(jobs) != 1 || jobs[0].JobID != job.ID || jobs[0].StatusCode&JOB_STATUS_SPOOLING == 0 {
+		t.Errorf("Jobs() while spooling = %+v", jobs)
+	}
+	for i := 0; i < 2; i++ {
+		if err = p.StartPage(); err != nil {
+			t.Fatalf("StartPage failed: %v", err)
+		}
+		_, _ = fmt.Fprintf(p, "page %d\n", i+1)
+		if err = p.EndPage(); err != nil {
+			t.Fatalf("EndPage failed: %v", err)
+		}
+	}
+	if err = p.EndDocument(); err != nil {
+		t.Fatalf("EndDocument failed: %v", err)
+	}
+
+	docs := fake.Documents()
+	want := FakeDocument{
+		Printer:  "Office",
+		JobID:    jobs[0].JobID,
+		Name:     "report",
+		DataType: "XPS_PASS",
+		Pages:    2,
+		Data:     []byte("page 1\npage 2\n"),
+	}
+	if len(docs) != 1 || !reflect.DeepEqual(docs[0], want) {
+		t.Errorf("Documents() = %+v, want %+v", docs, want)
+	}
+	jobs, _ = p.Jobs()
+	if len(jobs) != 1 || jobs[0].StatusCode&JOB_STATUS_SPOOLING != 0 || jobs[0].TotalPages != 2 {
+		t.Errorf("Jobs() after EndDocument = %+v", jobs)
+	}
+}
+
+func TestFakeSpooler_InjectError(t *testing.T) {
+	fake := newTestSpooler(t)
+	errBoom := errors.New("boom")
+
+	fake.InjectError("Office", "Jobs", errBoom)
+	fake.InjectError("", "ReadNames", errBoom)
+
+	if _, err := ReadNames(); err != errBoom {
+		t.Errorf("ReadNames() error = %v, want %v", err, errBoom)
+	}
+	label, err := Open("Label")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer closePrinter(label)
+	if _, err = label.Jobs(); err != nil {
+		t.Errorf("Label Jobs() error = %v, want nil", err)
+	}
+	office, err := Open("Office")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer closePrinter(office)
+	if _, err = office.Jobs(); err != errBoom {
+		t.Errorf("Office Jobs() error = %v, want %v", err, errBoom)
+	}
+
+	fake.InjectError("Office", "Jobs", nil)
+	if _, err = office.Jobs(); err != nil {
+		t.Errorf("Office Jobs() after clearing error = %v, want nil", err)
+	}
+}
+
+func TestFakeSpooler_Close(t *testing.T) {
+	newTestSpooler(t)
+
+	p, err := Open("Label")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err = p.Jobs(); err == nil {
+		t.Errorf("Jobs() on closed printer succeeded")
+	}
+}
+
+func TestFakeSpooler_JobControl(t *testing.T) {
+	fake := newTestSpooler(t)
+	var ids []uint32
+	for _, name := range []string{"a", "b", "c"} {
+		id, err := fake.AddJob("Office", JobInfo{DocumentName: name, Priority: DEF_PRIORITY})
+		if err != nil {
+			t.Fatalf("AddJob failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	p, err := Open("Office")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer closePrinter(p)
+
+	status := func(id uint32) JobStatus {
+		jobs, _ := p.Jobs()
+		for _, j := range jobs {
+			if j.JobID == id {
+				return j.StatusCode
+			}
+		}
+		t.Fatalf("job %d is not queued", id)
+		return 0
+	}
+	for _, tt := range []struct {
+		op   string
+		f    func(uint32) error
+		want JobStatus
+	}{
+		{"PauseJob", p.PauseJob, JOB_STATUS_PAUSED},
+		{"RetainJob", p.RetainJob, JOB_STATUS_PAUSED | JOB_STATUS_RETAINED},
+		{"ResumeJob", p.ResumeJob, JOB_STATUS_RETAINED},
+		{"ReleaseJob", p.ReleaseJob, 0},
+		{"RestartJob", p.RestartJob, JOB_STATUS_RESTART},
+	} {
+		if err = tt.f(ids[0]); err != nil {
+			t.Errorf("%s failed: %v", tt.op, err)
+		}
+		if got := status(ids[0]); got != tt.want {
+			t.Errorf("status after %s = %#x, want %#x", tt.op, got, tt.want)
+		}
+	}
+
+	if err = p.SetJobPriority(ids[1], MAX_PRIORITY); err != nil {
+		t.Errorf("SetJobPriority failed: %v", err)
+	}
+	if err = p.SetJobPosition(ids[2], 1); err != nil {
+		t.Errorf("SetJobPosition failed: %v", err)
+	}
+	jobs, _ := p.Jobs()
+	var order []string
+	for _, j := range jobs {
+		order = append(order, fmt.Sprintf("%d:%s:%d", j.Position, j.DocumentName, j.Priority))
+	}
+	if want := []string{"1:c:1", "2:a:1", "3:b:99"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("queue = %q, want %q", order, want)
+	}
+
+	if err = p.DeleteJob(ids[0]); err != nil {
+		t.Errorf("DeleteJob failed: %v", err)
+	}
+	if err = p.CancelJob(ids[1]); err != nil {
+		t.Errorf("CancelJob failed: %v", err)
+	}
+	if jobs, _ = p.Jobs(); len(jobs) != 1 || jobs[0].JobID != ids[2] || jobs[0].Position != 1 {
+		t.Errorf("Jobs() after DeleteJob and CancelJob = %+v", jobs)
+	}
+}
+
+func TestFakeSpooler_JobErrors(t *testing.T) {
+	fake := newTestSpooler(t)
+	p, err := Open("Label")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer closePrinter(p)
+	jobs, _ := p.Jobs()
+	id := jobs[0].JobID
+
+	var je *JobError
+	err = p.PauseJob(1000)
+	if !errors.As(err, &je) || je.Op != "PauseJob" || je.JobID != 1000 || !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("PauseJob of a missing job = %#v, want *JobError for job 1000", err)
+	}
+	if got, want := err.Error(), "winprinters: PauseJob 1000: job not found"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	for _, err = range []error{
+		p.SetJobPriority(id, 0),
+		p.SetJobPriority(id, MAX_PRIORITY+1),
+		p.SetJobPosition(id, JOB_POSITION_UNSPECIFIED),
+	} {
+		if !errors.As(err, &je) || je.JobID != id {
+			t.Errorf("out of range SetJob error = %v, want *JobError for job %d", err, id)
+		}
+	}
+	fake.InjectError("Label", "ControlJob", errors.New("access denied"))
+	if err = p.ResumeJob(id); !errors.As(err, &je) || je.JobID != id || je.Op != "ResumeJob" {
+		t.Errorf("ResumeJob with an injected error = %v", err)
+	}
+
+	socket := NewPrinter(&socketPrinter{})
+	if err = socket.PauseJob(id); !errors.Is(err, ErrUnsupported) || errors.As(err, &je) {
+		t.Errorf("PauseJob on a socket printer = %v, want a plain *UnsupportedError", err)
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	fake := newTestSpooler(t)
+	id, err := fake.AddJob("Office", JobInfo{DocumentName: "report"})
+	if err != nil {
+		t.Fatalf("AddJob failed: %v", err)
+	}
+	if err = CancelJob(id); err != nil {
+		t.Fatalf("CancelJob failed: %v", err)
+	}
+	p, err := Open("Office")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer closePrinter(p)
+	if jobs, _ := p.Jobs(); len(jobs) != 0 {
+		t.Errorf("Jobs() after CancelJob = %+v", jobs)
+	}
+	if err = CancelJob(id); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("second CancelJob = %v, want %v", err, ErrJobNotFound)
+	}
+}
@@ -0,0 +1,292 @@
+package winprinters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileDocument is the metadata of a document printed by a FileSpooler,
+// stored as JSON next to the document data in <data file>.json.
+type FileDocument struct {
+	Printer   string    `json:"printer"`
+	JobID     uint32    `json:"jobId"`
+	Name      string    `json:"document"`
+	DataType  string    `json:"dataType"`
+	Pages     int       `json:"pages"`
+	Size      int64     `json:"size"`
+	Submitted time.Time `json:"submitted"`
+	Completed time.Time `json:"completed"`
+	DataFile  string    `json:"dataFile"`
+}
+
+// FileSpooler is a Spooler printing every document to a file in Dir, so
+// printing code can be run end to end without a printer. The document data
+// is written as it is, followed by a JSON sidecar holding its FileDocument.
+type FileSpooler struct {
+	// Dir is the directory documents are written to.
+	Dir string
+
+	mu       sync.Mutex
+	printers []string
+	def      string
+	nextJob  uint32
+}
+
+// NewFileSpooler returns a FileSpooler writing to dir, which is created if
+// needed, and serving printers. The first printer, if any, is the default
+// one.
+func NewFileSpooler(dir string, printers ...string) (*FileSpooler, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &FileSpooler{Dir: dir, printers: append([]string(nil), printers...), nextJob: 1}
+	if len(printers) > 0 {
+		s.def = printers[0]
+	}
+	return s, nil
+}
+
+func (s *FileSpooler) GetDefault() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.def == "" {
+		return "", ErrPrinterNotFound
+	}
+	return s.def, nil
+}
+
+func (s *FileSpooler) SetDefault(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.has(name) {
+		return ErrPrinterNotFound
+	}
+	s.def = name
+	return nil
+}
+
+func (s *FileSpooler) ReadNames() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.printers...), nil
+}
+
+// has reports whether name is one of the printers. The caller holds s.mu.
+func (s *FileSpooler) has(name string) bool {
+	for _, p := range s.printers {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *FileSpooler) Open(name string) (PrinterHandle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.has(name) {
+		return nil, ErrPrinterNotFound
+	}
+	return &filePrinter{s: s, name: name}, nil
+}
+
+// Documents reads the metadata of the documents printed to Dir, oldest
+// first.
+func (s *FileSpooler) Documents() ([]FileDocument, error) {
+	paths, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var docs []FileDocument
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var d FileDocument
+		if err = json.Unmarshal(b, &d); err != nil {
+			return nil, fmt.Errorf("winprinters: %s: %w", path, err)
+		}
+		docs = append(docs, d)
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		if !docs[i].Submitted.Equal(docs[j].Submitted) {
+			return docs[i].Submitted.Before(docs[j].Submitted)
+		}
+		return docs[i].JobID < docs[j].JobID
+	})
+	return docs, nil
+}
+
+// create creates the data file of a new job, skipping the job IDs whose
+// files already exist, e.g. from an earlier run.
+func (s *FileSpooler) create(name string) (*os.File, uint32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		id := s.nextJob
+		s.nextJob++
+		path := filepath.Join(s.Dir, fmt.Sprintf("%06d-%s.prn", id, fileNamePart(name)))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		return f, id, err
+	}
+}
+
+// fileNamePart turns a document name into something usable in a file name.
+func fileNamePart(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	name = truncate(name, 64)
+	if name == "" {
+		return "document"
+	}
+	return name
+}
+
+// filePrinter is the PrinterHandle returned by FileSpooler.Open.
+type filePrinter struct {
+	s    *FileSpooler
+	name string
+	f    *os.File
+	doc  *FileDocument
+}
+
+var errFileNoDocument = errors.New("winprinters: no document started")
+
+// Jobs returns the document being written, if any.
+func (p *filePrinter) Jobs() ([]JobInfo, error) {
+	if p.doc == nil {
+		return nil, nil
+	}
+	return []JobInfo{{
+		JobID:        p.doc.JobID,
+		DocumentName: p.doc.Name,
+		DataType:     p.doc.DataType,
+		StatusCode:   JOB_STATUS_SPOOLING,
+		Position:     1,
+		TotalPages:   uint32(p.doc.Pages),
+		Submitted:    p.doc.Submitted,
+	}}, nil
+}
+
+func (p *filePrinter) Forms() ([]FormInfo, error) {
+	return nil, &UnsupportedError{Op: "Forms"}
+}
+
+func (p *filePrinter) DriverInfo() (*DriverInfo, error) {
+	return &DriverInfo{Name: "File", Environment: "file", DriverPath: p.s.Dir}, nil
+}
+
+// StartDocument creates a data file named after the job ID and the
+// document in the spooler directory.
+func (p *filePrinter) StartDocument(name, datatype string) (uint32, error) {
+	if p.doc != nil {
+		return 0, errors.New("winprinters: document already started")
+	}
+	f, id, err := p.s.create(name)
+	if err != nil {
+		return 0, err
+	}
+	p.start(f, id, name, datatype)
+	return id, nil
+}
+
+// StartFileDocument writes the document to path, replacing any file there,
+// and its metadata to path + ".json".
+func (p *filePrinter) StartFileDocument(name, datatype, path string) (uint32, error) {
+	if p.doc != nil {
+		return 0, errors.New("winprinters: document already started")
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	p.s.mu.Lock()
+	id := p.s.nextJob
+	p.s.nextJob++
+	p.s.mu.Unlock()
+	p.start(f, id, name, datatype)
+	return id, nil
+}
+
+func (p *filePrinter) start(f *os.File, id uint32, name, datatype string) {
+	p.f = f
+	p.doc = &FileDocument{
+		Printer:   p.name,
+		JobID:     id,
+		Name:      name,
+		DataType:  datatype,
+		Submitted: time.Now().UTC(),
+		DataFile:  f.Name(),
+	}
+}
+
+func (p *filePrinter) Write(b []byte) (int, error) {
+	if p.doc == nil {
+		return 0, errFileNoDocument
+	}
+	n, err := p.f.Write(b)
+	p.doc.Size += int64(n)
+	return n, err
+}
+
+// EndDocument closes the data file and writes the metadata sidecar.
+func (p *filePrinter) EndDocument() error {
+	if p.doc == nil {
+		return errFileNoDocument
+	}
+	doc := p.doc
+	err := p.f.Close()
+	p.f, p.doc = nil, nil
+	if err != nil {
+		return err
+	}
+	doc.Completed = time.Now().UTC()
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(doc.DataFile+".json", append(b, '\n'), 0o644)
+}
+
+func (p *filePrinter) StartPage() error {
+	if p.doc == nil {
+		return errFileNoDocument
+	}
+	return nil
+}
+
+// EndPage counts the pages recorded in the metadata.
+func (p *filePrinter) EndPage() error {
+	if p.doc == nil {
+		return errFileNoDocument
+	}
+	p.doc.Pages++
+	return nil
+}
+
+// Close removes the data file of a document that was started but not
+// ended.
+func (p *filePrinter) Close() error {
+	if p.doc == nil {
+		return nil
+	}
+	path := p.doc.DataFile
+	_ = p.f.Close()
+	p.f, p.doc = nil, nil
+	return os.Remove(path)
+}
@@ -0,0 +1,189 @@
+package winprinters
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSpooler(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	s, err := NewFileSpooler(dir, "Label", "Office")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetSpooler(SetSpooler(s))
+
+	p, err := Open("Label")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer closePrinter(p)
+	if _, err = p.StartRawDocument("ship/label 1"); err != nil {
+		t.Fatalf("StartRawDocument failed: %v", err)
+	}
+	for _, page := range []string{"^XA^FDone^XZ", "^XA^FDtwo^XZ"} {
+		if err = p.StartPage(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = p.Write([]byte(page)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if err = p.EndPage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if jobs, _ := p.Jobs(); len(jobs) != 1 || jobs[0].TotalPages != 2 {
+		t.Errorf("Jobs while printing = %+v", jobs)
+	}
+	if err = p.EndDocument(); err != nil {
+		t.Fatalf("EndDocument failed: %v", err)
+	}
+
+	// An abandoned document leaves no file behind.
+	q, err := Open("Office")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = q.StartDocument("draft", "RAW"); err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	docs, err := s.Documents()
+	if err != nil {
+		t.Fatalf("Documents failed: %v", err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("Documents = %+v", docs)
+	}
+	d := docs[0]
+	if d.Printer != "Label" || d.JobID != 1 || d.Name != "ship/label 1" || d.DataType != "RAW" ||
+		d.Pages != 2 || d.Size != 24 || d.Completed.Before(d.Submitted) ||
+		d.DataFile != filepath.Join(dir, "000001-ship_label 1.prn") {
+		t.Errorf("Documents()[0] = %+v", d)
+	}
+	if b, err := os.ReadFile(d.DataFile); err != nil || string(b) != "^XA^FDone^XZ^XA^FDtwo^XZ" {
+		t.Errorf("data file = %q, %v", b, err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 2 {
+		t.Errorf("spooler directory holds %q", files)
+	}
+
+	// A new spooler on the same directory does not overwrite earlier jobs.
+	s2, _ := NewFileSpooler(dir, "Label")
+	h, _ := s2.Open("Label")
+	p2 := NewPrinter(h)
+	defer closePrinter(p2)
+	if _, err = p2.StartDocument("ship/label 1", "RAW"); err != nil {
+		t.Fatal(err)
+	}
+	if err = p2.EndDocument(); err != nil {
+		t.Fatal(err)
+	}
+	if docs, _ = s2.Documents(); len(docs) != 2 || docs[1].JobID != 2 {
+		t.Errorf("Documents after a restart = %+v", docs)
+	}
+
+	if _, err = Open("Missing"); !errors.Is(err, ErrPrinterNotFound) {
+		t.Errorf("Open(Missing) = %v, want ErrPrinterNotFound", err)
+	}
+}
+
+func TestFileNamePart(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{" Q3: report/draft? ", "Q3_ report_draft_"},
+		{"", "document"},
+		{strings.Repeat("x", 100), strings.Repeat("x", 64)},
+		// 63 bytes, then a 3 byte rune that does not fit.
+		{strings.Repeat("x", 63) + "报告", strings.Repeat("x", 63)},
+	}
+	for _, tt := range tests {
+		if got := fileNamePart(tt.name); got != tt.want {
+			t.Errorf("fileNamePart(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStartDocumentToFile(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileSpooler(filepath.Join(dir, "spool"), "Microsoft Print to PDF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, _ := s.Open("Microsoft Print to PDF")
+	p := NewPrinter(h)
+	defer closePrinter(p)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	job, err := p.StartDocumentToFile("invoice", "RAW", "invoice.pdf")
+	if err != nil {
+		t.Fatalf("StartDocumentToFile failed: %v", err)
+	}
+	path := job.OutputFile
+	if want := filepath.Join(dir, "invoice.pdf"); path != want {
+		t.Errorf("StartDocumentToFile path = %q, want %q", path, want)
+	}
+	if _, err = p.Write([]byte("%PDF-1.7")); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.EndDocument(); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != "%PDF-1.7" {
+		t.Errorf("output file = %q, %v", b, err)
+	}
+	if _, err = os.Stat(path + ".json"); err != nil {
+		t.Errorf("no metadata next to the output file: %v", err)
+	}
+
+	for _, bad := range []string{"", filepath.Join(dir, "missing", "out.pdf"), dir, filepath.Join(path, "out.pdf")} {
+		if _, err = p.StartDocumentToFile("bad", "RAW", bad); err == nil {
+			t.Errorf("StartDocumentToFile(%q) succeeded", bad)
+			_ = p.EndDocument()
+		}
+	}
+}
+
+func TestStartDocumentToFile_Fake(t *testing.T) {
+	fake := newTestSpooler(t)
+	p, err := Open("Office")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closePrinter(p)
+	out := filepath.Join(t.TempDir(), "page.xps")
+	job, err := p.StartDocumentToFile("page", "XPS_PASS", out)
+	if err != nil || job.OutputFile != out {
+		t.Fatalf("StartDocumentToFile = %+v, %v", job, err)
+	}
+	if err = p.EndDocument(); err != nil {
+		t.Fatal(err)
+	}
+	if docs := fake.Documents(); len(docs) != 1 || docs[0].OutputFile != out {
+		t.Errorf("fake recorded %+v", docs)
+	}
+
+	h, err := NewSocketSpooler().Open("127.0.0.1:9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewPrinter(h).StartDocumentToFile("page", "RAW", out)
+	if !errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), "StartDocumentToFile") {
+		t.Errorf("StartDocumentToFile on a socket printer = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,165 @@
+package winprinters
+
+import (
+	"sync"
+)
+
+// HealthState classifies whether a printer can take jobs.
+type HealthState int
+
+const (
+	// HealthReady is a printer that prints, possibly busy or warming up.
+	HealthReady HealthState = iota
+	// HealthDegraded is a printer that prints but needs attention soon,
+	// such as for low toner or a full output bin.
+	HealthDegraded
+	// HealthUnavailable is a printer whose jobs wait, such as when it is
+	// offline, paused, jammed or out of paper, or whose state is unknown.
+	HealthUnavailable
+)
+
+func (s HealthState) String() string {
+	switch s {
+	case HealthReady:
+		return "ready"
+	case HealthDegraded:
+		return "degraded"
+	case HealthUnavailable:
+		return "unavailable"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes s as its String, for JSON.
+func (s HealthState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+const (
+	// unavailableStatus holds the PRINTER_STATUS_* flags that stop jobs
+	// from printing until someone acts.
+	unavailableStatus = PRINTER_STATUS_PAUSED | PRINTER_STATUS_ERROR | PRINTER_STATUS_PENDING_DELETION |
+		PRINTER_STATUS_PAPER_JAM | PRINTER_STATUS_PAPER_OUT | PRINTER_STATUS_MANUAL_FEED |
+		PRINTER_STATUS_OFFLINE | PRINTER_STATUS_NOT_AVAILABLE | PRINTER_STATUS_NO_TONER |
+		PRINTER_STATUS_PAGE_PUNT | PRINTER_STATUS_USER_INTERVENTION | PRINTER_STATUS_OUT_OF_MEMORY |
+		PRINTER_STATUS_DOOR_OPEN | PRINTER_STATUS_SERVER_UNKNOWN | PRINTER_STATUS_SERVER_OFFLINE
+
+	// degradedStatus holds the PRINTER_STATUS_* flags of a printer that
+	// still prints but needs attention. The remaining flags, such as busy,
+	// printing or warming up, are part of normal operation.
+	degradedStatus = PRINTER_STATUS_PAPER_PROBLEM | PRINTER_STATUS_OUTPUT_BIN_FULL |
+		PRINTER_STATUS_TONER_LOW | PRINTER_STATUS_DRIVER_UPDATE_NEEDED
+)
+
+// PrinterHealth tells whether a printer is usable, and why not.
+type PrinterHealth struct {
+	Printer string // printer name, when known
+	State   HealthState
+	Status  PrinterStatus // PRINTER_STATUS_* flags the state was decided from
+
+	// Reasons describe in English the flags that made the printer
+	// degraded or unavailable, worst first, such as "Paper Jam".
+	Reasons []string
+
+	// Err is the failure to read the printer state, which makes the
+	// printer unavailable.
+	Err error `json:"-"`
+}
+
+// classifyHealth decides the health of a printer from its status flags and
+// attributes.
+func classifyHealth(status PrinterStatus, attrs PrinterAttributes) PrinterHealth {
+	h := PrinterHealth{State: HealthReady, Status: status}
+	if attrs.WorkOffline() {
+		h.State = HealthUnavailable
+		h.Reasons = append(h.Reasons, "Work Offline")
+	}
+	for _, mask := range []PrinterStatus{unavailableStatus, degradedStatus} {
+		for _, f := range (status & mask).Flags() {
+			h.Reasons = append(h.Reasons, f.String())
+		}
+	}
+	switch {
+	case status&unavailableStatus != 0:
+		h.State = HealthUnavailable
+	case status&degradedStatus != 0 && h.State == HealthReady:
+		h.State = HealthDegraded
+	}
+	return h
+}
+
+// Health classifies the printer as ready, degraded or unavailable from its
+// PRINTER_STATUS_* flags and its work offline attribute. Printers whose
+// state cannot be read are unavailable, with the failure in Err.
+func (p *Printer) Health() PrinterHealth {
+	if r, ok := p.h.(PrinterInfoReader); ok {
+		info, err := r.PrinterInfo()
+		if err != nil {
+			return unavailable("", err)
+		}
+		h := classifyHealth(info.Status, info.Attributes)
+		h.Printer = info.Name
+		return h
+	}
+	if r, ok := p.h.(PrinterStatusReader); ok {
+		status, err := r.PrinterStatus()
+		if err != nil {
+			return unavailable("", err)
+		}
+		return classifyHealth(status, 0)
+	}
+	return unavailable("", &UnsupportedError{Op: "Health"})
+}
+
+// maxParallel bounds the printers open at once when printers are read in
+// parallel, which a print server with hundreds of queues would not welcome
+// all at once.
+const maxParallel = 8
+
+// parallel calls f for every index below n, from at most maxParallel
+// goroutines, and returns when all the calls have.
+func parallel(n int, f func(i int)) {
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < maxParallel && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+}
+
+func unavailable(name string, err error) PrinterHealth {
+	return PrinterHealth{Printer: name, State: HealthUnavailable, Reasons: []string{err.Error()}, Err: err}
+}
+
+// HealthAll returns the health of every printer ReadNames lists, in the same
+// order. The printers are opened in parallel, a few at a time; one that
+// cannot be opened is unavailable. Only a failure to list the printers is
+// returned as an error.
+func HealthAll() ([]PrinterHealth, error) {
+	names, err := ReadNames()
+	if err != nil {
+		return nil, err
+	}
+	health := make([]PrinterHealth, len(names))
+	parallel(len(names), func(i int) {
+		p, err := Open(names[i])
+		if err != nil {
+			health[i] = unavailable(names[i], err)
+			return
+		}
+		defer p.Close()
+		health[i] = p.Health()
+		health[i].Printer = names[i]
+	})
+	return health, nil
+}
@@ -0,0 +1,128 @@
+package winprinters
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClassifyHealth(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  PrinterStatus
+		attrs   PrinterAttributes
+		state   HealthState
+		reasons []string
+	}{
+		{name: "idle", state: HealthReady},
+		{name: "printing", status: PRINTER_STATUS_PRINTING | PRINTER_STATUS_BUSY | PRINTER_STATUS_WARMING_UP, state: HealthReady},
+		{name: "toner low", status: PRINTER_STATUS_TONER_LOW | PRINTER_STATUS_PRINTING, state: HealthDegraded, reasons: []string{"Toner Low"}},
+		{
+			name:    "jammed",
+			status:  PRINTER_STATUS_TONER_LOW | PRINTER_STATUS_PAPER_JAM | PRINTER_STATUS_USER_INTERVENTION,
+			state:   HealthUnavailable,
+			reasons: []string{"Paper Jam", "User Intervention Required", "Toner Low"},
+		},
+		{name: "offline", status: PRINTER_STATUS_OFFLINE, state: HealthUnavailable, reasons: []string{"Offline"}},
+		{name: "paused", status: PRINTER_STATUS_PAUSED, state: HealthUnavailable, reasons: []string{"Paused"}},
+		{name: "door open", status: PRINTER_STATUS_DOOR_OPEN, state: HealthUnavailable, reasons: []string{"Door Open"}},
+		{name: "not available", status: PRINTER_STATUS_NOT_AVAILABLE, state: HealthUnavailable, reasons: []string{"Not Available"}},
+		{
+			name:    "work offline",
+			status:  PRINTER_STATUS_OUTPUT_BIN_FULL,
+			attrs:   PRINTER_ATTRIBUTE_WORK_OFFLINE | PRINTER_ATTRIBUTE_LOCAL,
+			state:   HealthUnavailable,
+			reasons: []string{"Work Offline", "Output Bin Full"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := classifyHealth(tt.status, tt.attrs)
+			if h.State != tt.state || !reflect.DeepEqual(h.Reasons, tt.reasons) || h.Status != tt.status {
+				t.Errorf("classifyHealth(%#x, %#x) = %+v, want %v %q", uint32(tt.status), uint32(tt.attrs), h, tt.state, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestHealthAll(t *testing.T) {
+	fake := newTestSpooler(t)
+	fake.AddPrinter(FakePrinter{Name: "Plotter", Status: PRINTER_STATUS_TONER_LOW})
+	fake.AddPrinter(FakePrinter{Name: "Gone"})
+	if err := fake.UpdatePrinter("Label", func(p *FakePrinter) {
+		p.Status = PRINTER_STATUS_PAPER_OUT
+	}); err != nil {
+		t.Fatal(err)
+	}
+	fake.InjectError("Gone", "Open", ErrPrinterNotFound)
+	fake.InjectError("Office", "PrinterInfo", errors.New("access denied"))
+
+	health, err := HealthAll()
+	if err != nil {
+		t.Fatalf("HealthAll failed: %v", err)
+	}
+	want := []struct {
+		name  string
+		state HealthState
+	}{
+		{"Label", HealthUnavailable},
+		{"Office", HealthUnavailable},
+		{"Plotter", HealthDegraded},
+		{"Gone", HealthUnavailable},
+	}
+	if len(health) != len(want) {
+		t.Fatalf("HealthAll() = %+v", health)
+	}
+	for i, w := range want {
+		if health[i].Printer != w.name || health[i].State != w.state {
+			t.Errorf("health[%d] = %+v, want %s %v", i, health[i], w.name, w.state)
+		}
+	}
+	if !reflect.DeepEqual(health[0].Reasons, []string{"Out of Paper"}) {
+		t.Errorf("Label reasons = %q", health[0].Reasons)
+	}
+	if !errors.Is(health[3].Err, ErrPrinterNotFound) || health[1].Err == nil {
+		t.Errorf("errors = %v, %v", health[3].Err, health[1].Err)
+	}
+
+	b, err := json.Marshal(health[2])
+	if err != nil || string(b) != `{"Printer":"Plotter","State":"degraded","Status":["toner-low"],"Reasons":["Toner Low"]}` {
+		t.Errorf("Marshal = %s, %v", b, err)
+	}
+
+	fake.InjectError("", "ReadNames", errors.New("spooler stopped"))
+	if _, err = HealthAll(); err == nil {
+		t.Errorf("HealthAll succeeded without the printer names")
+	}
+}
+
+func TestParallel(t *testing.T) {
+	var mu sync.Mutex
+	running, most := 0, 0
+	called := make([]int, 50)
+	parallel(len(called), func(i int) {
+		mu.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		called[i]++
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+	for i, n := range called {
+		if n != 1 {
+			t.Errorf("f(%d) called %d times", i, n)
+		}
+	}
+	if most > maxParallel {
+		t.Errorf("%d calls ran at once, want at most %d", most, maxParallel)
+	}
+	parallel(0, func(int) { t.Error("f called for n = 0") })
+}
@@ -0,0 +1,301 @@
+package ipp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// wire builds message bytes the way RFC 8010 section 4 lays them out: single
+// bytes for tags, two-byte lengths before names and values.
+type wire struct {
+	bytes.Buffer
+}
+
+func (w *wire) header(version Version, code uint16, id uint32) *wire {
+	var b [8]byte
+	binary.BigEndian.PutUint16(b[0:], uint16(version))
+	binary.BigEndian.PutUint16(b[2:], code)
+	binary.BigEndian.PutUint32(b[4:], id)
+	w.Write(b[:])
+	return w
+}
+
+func (w *wire) tag(t Tag) *wire {
+	w.WriteByte(byte(t))
+	return w
+}
+
+func (w *wire) attr(t Tag, name string, value []byte) *wire {
+	w.WriteByte(byte(t))
+	w.field([]byte(name))
+	w.field(value)
+	return w
+}
+
+func (w *wire) field(b []byte) {
+	var n [2]byte
+	binary.BigEndian.PutUint16(n[:], uint16(len(b)))
+	w.Write(n[:])
+	w.Write(b)
+}
+
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+const pinetree = "ipp://printer.example.com/ipp/print/pinetree"
+
+// rfcMessage returns a message with the charset and language used by the
+// RFC 8010 examples.
+func rfcMessage(version Version, code uint16, id uint32) *Message {
+	m := &Message{Version: version, Code: code, RequestID: id}
+	m.Group(TagOperationGroup).Add(
+		MakeAttribute("attributes-charset", TagCharset, String("utf-8")),
+		MakeAttribute("attributes-natural-language", TagLanguage, String("en-us")),
+	)
+	return m
+}
+
+func rfcHeader(version Version, code uint16, id uint32) *wire {
+	w := new(wire).header(version, code, id).tag(TagOperationGroup)
+	w.attr(TagCharset, "attributes-charset", []byte("utf-8"))
+	w.attr(TagLanguage, "attributes-natural-language", []byte("en-us"))
+	return w
+}
+
+func TestMessage_Golden(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  func() *Message
+		wire func() []byte
+	}{
+		{
+			name: "RFC 8010 4.1 Print-Job request",
+			msg: func() *Message {
+				m := rfcMessage(Version11, uint16(OpPrintJob), 1)
+				m.Group(TagOperationGroup).Add(
+					MakeAttribute("printer-uri", TagURI, String(pinetree)),
+					MakeAttribute("job-name", TagName, String("foobar")),
+					MakeAttribute("ipp-attribute-fidelity", TagBoolean, Boolean(true)),
+				)
+				m.Group(TagJobGroup).Add(
+					MakeAttribute("copies", TagInteger, Integer(20)),
+					MakeAttribute("sides", TagKeyword, String("two-sided-long-edge")),
+				)
+				return m
+			},
+			wire: func() []byte {
+				w := rfcHeader(Version11, 0x0002, 1)
+				w.attr(TagURI, "printer-uri", []byte(pinetree))
+				w.attr(TagName, "job-name", []byte("foobar"))
+				w.attr(TagBoolean, "ipp-attribute-fidelity", []byte{0x01})
+				w.tag(TagJobGroup)
+				w.attr(TagInteger, "copies", u32(0x14))
+				w.attr(TagKeyword, "sides", []byte("two-sided-long-edge"))
+				return w.tag(TagEnd).Bytes()
+			},
+		},
+		{
+			name: "RFC 8010 4.2 Print-Job response (successful)",
+			msg: func() *Message {
+				m := rfcMessage(Version11, uint16(StatusOK), 1)
+				m.Group(TagOperationGroup).Add(
+					MakeAttribute("status-message", TagText, String("successful-ok")),
+				)
+				m.Group(TagJobGroup).Add(
+					MakeAttribute("job-id", TagInteger, Integer(147)),
+					MakeAttribute("job-uri", TagURI, String(pinetree+"/147")),
+					MakeAttribute("job-state", TagEnum, Integer(JobPending)),
+				)
+				return m
+			},
+			wire: func() []byte {
+				w := rfcHeader(Version11, 0x0000, 1)
+				w.attr(TagText, "status-message", []byte("successful-ok"))
+				w.tag(TagJobGroup)
+				w.attr(TagInteger, "job-id", u32(147))
+				w.attr(TagURI, "job-uri", []byte(pinetree+"/147"))
+				w.attr(TagEnum, "job-state", u32(3))
+				return w.tag(TagEnd).Bytes()
+			},
+		},
+		{
+			name: "RFC 8010 4.3 Print-Job response (failure)",
+			msg: func() *Message {
+				m := rfcMessage(Version11, uint16(StatusAttributesOrValuesNotSupported), 1)
+				m.Group(TagOperationGroup).Add(
+					MakeAttribute("status-message", TagText, String("client-error-attributes-or-values-not-supported")),
+				)
+				m.Group(TagUnsupportedGroup).Add(
+					MakeAttribute("copies", TagInteger, Integer(20)),
+					MakeAttribute("sides", TagUnsupportedValue, Void{}),
+				)
+				return m
+			},
+			wire: func() []byte {
+				w := rfcHeader(Version11, 0x040b, 1)
+				w.attr(TagText, "status-message", []byte("client-error-attributes-or-values-not-supported"))
+				w.tag(TagUnsupportedGroup)
+				w.attr(TagInteger, "copies", u32(0x14))
+				w.attr(TagUnsupportedValue, "sides", nil)
+				return w.tag(TagEnd).Bytes()
+			},
+		},
+		{
+			name: "RFC 8010 4.9 Get-Jobs request",
+			msg: func() *Message {
+				m := rfcMessage(Version11, uint16(OpGetJobs), 0x123)
+				m.Group(TagOperationGroup).Add(
+					MakeAttribute("printer-uri", TagURI, String(pinetree)),
+					MakeAttribute("limit", TagInteger, Integer(50)),
+					MakeAttribute("requested-attributes", TagKeyword,
+						String("job-id"), String("job-name"), String("document-format")),
+				)
+				return m
+			},
+			wire: func() []byte {
+				w := rfcHeader(Version11, 0x000a, 0x123)
+				w.attr(TagURI, "printer-uri", []byte(pinetree))
+				w.attr(TagInteger, "limit", u32(0x32))
+				w.attr(TagKeyword, "requested-attributes", []byte("job-id"))
+				w.attr(TagKeyword, "", []byte("job-name"))
+				w.attr(TagKeyword, "", []byte("document-format"))
+				return w.tag(TagEnd).Bytes()
+			},
+		},
+		{
+			name: "value syntaxes",
+			msg: func() *Message {
+				m := &Message{Version: Version20, Code: uint16(StatusOK), RequestID: 9}
+				m.Group(TagPrinterGroup).Add(
+					MakeAttribute("printer-current-time", TagDateTime,
+						Time{time.Date(2026, 10, 17, 9, 30, 15, 5e8, time.FixedZone("", 8*3600))}),
+					MakeAttribute("printer-resolution-supported", TagResolution,
+						Resolution{600, 300, UnitsDPI}, Resolution{118, 118, UnitsDPCM}),
+					MakeAttribute("copies-supported", TagRange, Range{1, 999}),
+					MakeAttribute("printer-info", TagTextLang, TextWithLang{Lang: "zh-cn", Text: "打印机"}),
+					MakeAttribute("printer-location", TagNoValue, Void{}),
+					MakeAttribute("printer-more-info", TagUnknown, Void{}),
+				)
+				return m
+			},
+			wire: func() []byte {
+				w := new(wire).header(Version20, 0, 9).tag(TagPrinterGroup)
+				w.attr(TagDateTime, "printer-current-time",
+					[]byte{0x07, 0xea, 10, 17, 9, 30, 15, 5, '+', 8, 0})
+				w.attr(TagResolution, "printer-resolution-supported",
+					[]byte{0, 0, 0x02, 0x58, 0, 0, 0x01, 0x2c, 3})
+				w.attr(TagResolution, "", []byte{0, 0, 0, 118, 0, 0, 0, 118, 4})
+				w.attr(TagRange, "copies-supported", []byte{0, 0, 0, 1, 0, 0, 0x03, 0xe7})
+				w.attr(TagTextLang, "printer-info",
+					append([]byte{0, 5, 'z', 'h', '-', 'c', 'n', 0, 9}, "打印机"...))
+				w.attr(TagNoValue, "printer-location", nil)
+				w.attr(TagUnknown, "printer-more-info", nil)
+				return w.tag(TagEnd).Bytes()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.wire()
+			b, err := tt.msg().MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary failed: %v", err)
+			}
+			if !bytes.Equal(b, want) {
+				t.Errorf("MarshalBinary =\n% x\nwant\n% x", b, want)
+			}
+			var got Message
+			if err = got.UnmarshalBinary(want); err != nil {
+				t.Fatalf("UnmarshalBinary failed: %v", err)
+			}
+			if msg := tt.msg(); !equalMessages(&got, msg) {
+				t.Errorf("UnmarshalBinary =\n%+v\nwant\n%+v", got, msg)
+			}
+		})
+	}
+}
+
+// equalMessages compares messages, comparing dateTime values as instants.
+func equalMessages(a, b *Message) bool {
+	if a.Version != b.Version || a.Code != b.Code || a.RequestID != b.RequestID ||
+		len(a.Groups) != len(b.Groups) {
+		return false
+	}
+	for i := range a.Groups {
+		ga, gb := a.Groups[i], b.Groups[i]
+		if ga.Tag != gb.Tag || len(ga.Attrs) != len(gb.Attrs) {
+			return false
+		}
+		for j := range ga.Attrs {
+			if !equalAttrs(ga.Attrs[j], gb.Attrs[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func equalAttrs(a, b Attribute) bool {
+	if a.Name != b.Name || len(a.Values) != len(b.Values) {
+		return false
+	}
+	for i, va := range a.Values {
+		vb := b.Values[i]
+		if ta, ok := va.Data.(Time); ok {
+			tb, ok := vb.Data.(Time)
+			if !ok || !ta.Equal(tb.Time) {
+				return false
+			}
+			continue
+		}
+		if !reflect.DeepEqual(va, vb) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestValue_DecodeErrors(t *testing.T) {
+	tests := []struct {
+		tag Tag
+		b   []byte
+	}{
+		{TagInteger, []byte{0, 1}},
+		{TagBoolean, nil},
+		{TagRange, []byte{0, 0, 0, 1}},
+		{TagResolution, make([]byte, 8)},
+		{TagDateTime, make([]byte, 11)},
+		{TagTextLang, []byte{0, 5, 'e', 'n'}},
+		{TagNameLang, []byte{0, 2, 'e', 'n', 0, 4, 'x'}},
+	}
+	for _, tt := range tests {
+		if v, err := decodeData(tt.tag, tt.b); err == nil {
+			t.Errorf("decodeData(%s, % x) = %v, want error", tt.tag, tt.b, v)
+		}
+	}
+}
+
+func TestValue_String(t *testing.T) {
+	tests := []struct {
+		v    Data
+		want string
+	}{
+		{Range{1, 5}, "1-5"},
+		{Resolution{300, 300, UnitsDPI}, "300dpi"},
+		{Resolution{600, 300, UnitsDPCM}, "600x300dpcm"},
+		{TextWithLang{Lang: "en", Text: "Office"}, "Office"},
+		{Time{time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}, "2026-01-02T03:04:05Z"},
+		{Void{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.v.String(); got != tt.want {
+			t.Errorf("%#v.String() = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
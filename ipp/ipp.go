@@ -0,0 +1,200 @@
+// Package ipp encodes and decodes Internet Printing Protocol messages as
+// defined by RFC 8010 and RFC 8011.
+package ipp
+
+import "fmt"
+
+// Version is an IPP protocol version, major number in the high byte.
+type Version uint16
+
+// IPP versions.
+const (
+	Version11 Version = 0x0101
+	Version20 Version = 0x0200
+)
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d", v>>8, v&0xff)
+}
+
+// Tag is a delimiter or value tag.
+type Tag byte
+
+// Delimiter tags.
+const (
+	TagOperationGroup    Tag = 0x01
+	TagJobGroup          Tag = 0x02
+	TagEnd               Tag = 0x03
+	TagPrinterGroup      Tag = 0x04
+	TagUnsupportedGroup  Tag = 0x05
+	TagSubscriptionGroup Tag = 0x06
+	TagEventGroup        Tag = 0x07
+	TagResourceGroup     Tag = 0x08
+	TagDocumentGroup     Tag = 0x09
+	TagSystemGroup       Tag = 0x0a
+)
+
+// Out-of-band value tags. Their values are always empty.
+const (
+	TagUnsupportedValue Tag = 0x10
+	TagDefault          Tag = 0x11
+	TagUnknown          Tag = 0x12
+	TagNoValue          Tag = 0x13
+	TagNotSettable      Tag = 0x15
+	TagDeleteAttribute  Tag = 0x16
+	TagAdminDefine      Tag = 0x17
+)
+
+// Value tags.
+const (
+	TagInteger         Tag = 0x21
+	TagBoolean         Tag = 0x22
+	TagEnum            Tag = 0x23
+	TagOctetString     Tag = 0x30
+	TagDateTime        Tag = 0x31
+	TagResolution      Tag = 0x32
+	TagRange           Tag = 0x33
+	TagBeginCollection Tag = 0x34
+	TagTextLang        Tag = 0x35
+	TagNameLang        Tag = 0x36
+	TagEndCollection   Tag = 0x37
+	TagText            Tag = 0x41
+	TagName            Tag = 0x42
+	TagKeyword         Tag = 0x44
+	TagURI             Tag = 0x45
+	TagURIScheme       Tag = 0x46
+	TagCharset         Tag = 0x47
+	TagLanguage        Tag = 0x48
+	TagMimeType        Tag = 0x49
+	TagMemberName      Tag = 0x4a
+	TagExtension       Tag = 0x7f
+)
+
+// IsDelimiter reports whether t starts an attribute group or ends the
+// attributes.
+func (t Tag) IsDelimiter() bool {
+	return t < 0x10
+}
+
+// IsOutOfBand reports whether t is an out-of-band value tag such as
+// unknown or no-value.
+func (t Tag) IsOutOfBand() bool {
+	return t >= 0x10 && t < 0x20
+}
+
+// Op is an operation id.
+type Op uint16
+
+// Operation ids of RFC 8011 and the CUPS extensions.
+const (
+	OpPrintJob                  Op = 0x0002
+	OpPrintURI                  Op = 0x0003
+	OpValidateJob               Op = 0x0004
+	OpCreateJob                 Op = 0x0005
+	OpSendDocument              Op = 0x0006
+	OpSendURI                   Op = 0x0007
+	OpCancelJob                 Op = 0x0008
+	OpGetJobAttributes          Op = 0x0009
+	OpGetJobs                   Op = 0x000a
+	OpGetPrinterAttributes      Op = 0x000b
+	OpHoldJob                   Op = 0x000c
+	OpReleaseJob                Op = 0x000d
+	OpRestartJob                Op = 0x000e
+	OpPausePrinter              Op = 0x0010
+	OpResumePrinter             Op = 0x0011
+	OpPurgeJobs                 Op = 0x0012
+	OpSetPrinterAttributes      Op = 0x0013
+	OpSetJobAttributes          Op = 0x0014
+	OpGetPrinterSupportedValues Op = 0x0015
+	OpCancelJobs                Op = 0x0038
+	OpCancelMyJobs              Op = 0x0039
+	OpCloseJob                  Op = 0x003b
+	OpIdentifyPrinter           Op = 0x003c
+	OpCupsGetDefault            Op = 0x4001
+	OpCupsGetPrinters           Op = 0x4002
+	OpCupsAddModifyPrinter      Op = 0x4003
+	OpCupsDeletePrinter         Op = 0x4004
+	OpCupsGetClasses            Op = 0x4005
+	OpCupsAcceptJobs            Op = 0x4008
+	OpCupsRejectJobs            Op = 0x4009
+	OpCupsSetDefault            Op = 0x400a
+	OpCupsMoveJob               Op = 0x400d
+	OpCupsGetPPD                Op = 0x400f
+)
+
+// Status is a response status code.
+type Status uint16
+
+// Status codes of RFC 8011.
+const (
+	StatusOK                               Status = 0x0000
+	StatusOKIgnoredOrSubstituted           Status = 0x0001
+	StatusOKConflicting                    Status = 0x0002
+	StatusBadRequest                       Status = 0x0400
+	StatusForbidden                        Status = 0x0401
+	StatusNotAuthenticated                 Status = 0x0402
+	StatusNotAuthorized                    Status = 0x0403
+	StatusNotPossible                      Status = 0x0404
+	StatusTimeout                          Status = 0x0405
+	StatusNotFound                         Status = 0x0406
+	StatusGone                             Status = 0x0407
+	StatusRequestEntityTooLarge            Status = 0x0408
+	StatusRequestValueTooLong              Status = 0x0409
+	StatusDocumentFormatNotSupported       Status = 0x040a
+	StatusAttributesOrValuesNotSupported   Status = 0x040b
+	StatusURISchemeNotSupported            Status = 0x040c
+	StatusCharsetNotSupported              Status = 0x040d
+	StatusConflictingAttributes            Status = 0x040e
+	StatusCompressionNotSupported          Status = 0x040f
+	StatusCompressionError                 Status = 0x0410
+	StatusDocumentFormatError              Status = 0x0411
+	StatusDocumentAccessError              Status = 0x0412
+	StatusInternalError                    Status = 0x0500
+	StatusOperationNotSupported            Status = 0x0501
+	StatusServiceUnavailable               Status = 0x0502
+	StatusVersionNotSupported              Status = 0x0503
+	StatusDeviceError                      Status = 0x0504
+	StatusTemporaryError                   Status = 0x0505
+	StatusNotAcceptingJobs                 Status = 0x0506
+	StatusBusy                             Status = 0x0507
+	StatusJobCanceled                      Status = 0x0508
+	StatusMultipleDocumentJobsNotSupported Status = 0x0509
+)
+
+// IsSuccess reports whether s is one of the successful-ok status codes.
+func (s Status) IsSuccess() bool {
+	return s < 0x0100
+}
+
+// Job states of the "job-state" attribute.
+const (
+	JobPending           = 3
+	JobPendingHeld       = 4
+	JobProcessing        = 5
+	JobProcessingStopped = 6
+	JobCanceled          = 7
+	JobAborted           = 8
+	JobCompleted         = 9
+)
+
+// Printer states of the "printer-state" attribute.
+const (
+	PrinterIdle       = 3
+	PrinterProcessing = 4
+	PrinterStopped    = 5
+)
+
+// StatusError is a response whose status code is not successful.
+type StatusError struct {
+	Op      Op
+	Status  Status
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	s := fmt.Sprintf("ipp: operation 0x%04x failed: %s", uint16(e.Op), e.Status)
+	if e.Message != "" {
+		s += ": " + e.Message
+	}
+	return s
+}
@@ -0,0 +1,445 @@
+package ipp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Value is an attribute value together with its value tag.
+type Value struct {
+	Tag  Tag
+	Data Data
+}
+
+func (v Value) String() string {
+	return v.Data.String()
+}
+
+// Attribute is a named attribute with one or more values.
+type Attribute struct {
+	Name   string
+	Values []Value
+}
+
+// MakeAttribute returns an attribute whose values all have tag.
+func MakeAttribute(name string, tag Tag, values ...Data) Attribute {
+	a := Attribute{Name: name, Values: make([]Value, 0, len(values))}
+	for _, v := range values {
+		a.Values = append(a.Values, Value{Tag: tag, Data: v})
+	}
+	return a
+}
+
+func (a Attribute) String() string {
+	s := make([]string, 0, len(a.Values))
+	for _, v := range a.Values {
+		s = append(s, v.String())
+	}
+	return a.Name + "=" + strings.Join(s, ",")
+}
+
+// Int returns the first value as an integer, or 0.
+func (a Attribute) Int() int {
+	if len(a.Values) > 0 {
+		if v, ok := a.Values[0].Data.(Integer); ok {
+			return int(v)
+		}
+	}
+	return 0
+}
+
+// Bool returns the first value as a boolean, or false.
+func (a Attribute) Bool() bool {
+	if len(a.Values) > 0 {
+		if v, ok := a.Values[0].Data.(Boolean); ok {
+			return bool(v)
+		}
+	}
+	return false
+}
+
+// Text returns the first value as a string, or "".
+func (a Attribute) Text() string {
+	if len(a.Values) > 0 {
+		return a.Values[0].String()
+	}
+	return ""
+}
+
+// Texts returns all values as strings.
+func (a Attribute) Texts() []string {
+	s := make([]string, 0, len(a.Values))
+	for _, v := range a.Values {
+		s = append(s, v.String())
+	}
+	return s
+}
+
+// Group is an attribute group.
+type Group struct {
+	Tag   Tag
+	Attrs []Attribute
+}
+
+// Attr returns the attribute called name.
+func (g *Group) Attr(name string) (Attribute, bool) {
+	for _, a := range g.Attrs {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Attribute{}, false
+}
+
+// Add appends attributes to g.
+func (g *Group) Add(attrs ...Attribute) {
+	g.Attrs = append(g.Attrs, attrs...)
+}
+
+// Message is an IPP request or response. Code is an Op in requests and a
+// Status in responses. Document data that follows the attributes is not
+// part of the Message.
+type Message struct {
+	Version   Version
+	Code      uint16
+	RequestID uint32
+	Groups    []*Group
+}
+
+// NewRequest returns a request for op with the attributes-charset and
+// attributes-natural-language operation attributes already set.
+func NewRequest(version Version, op Op, requestID uint32) *Message {
+	m := &Message{Version: version, Code: uint16(op), RequestID: requestID}
+	m.addCharsetAndLanguage()
+	return m
+}
+
+// NewResponse returns a response to req with the given status.
+func NewResponse(req *Message, status Status) *Message {
+	m := &Message{Version: req.Version, Code: uint16(status), RequestID: req.RequestID}
+	m.addCharsetAndLanguage()
+	return m
+}
+
+func (m *Message) addCharsetAndLanguage() {
+	m.Group(TagOperationGroup).Add(
+		MakeAttribute("attributes-charset", TagCharset, String("utf-8")),
+		MakeAttribute("attributes-natural-language", TagLanguage, String("en")),
+	)
+}
+
+// Op returns the operation id of a request.
+func (m *Message) Op() Op {
+	return Op(m.Code)
+}
+
+// Status returns the status code of a response.
+func (m *Message) Status() Status {
+	return Status(m.Code)
+}
+
+// Group returns the first group with tag, appending a new one when m has
+// none.
+func (m *Message) Group(tag Tag) *Group {
+	for _, g := range m.Groups {
+		if g.Tag == tag {
+			return g
+		}
+	}
+	g := &Group{Tag: tag}
+	m.Groups = append(m.Groups, g)
+	return g
+}
+
+// GroupsOf returns all groups with tag, in message order.
+func (m *Message) GroupsOf(tag Tag) []*Group {
+	var gs []*Group
+	for _, g := range m.Groups {
+		if g.Tag == tag {
+			gs = append(gs, g)
+		}
+	}
+	return gs
+}
+
+// Attr returns the attribute called name from the first group with tag.
+func (m *Message) Attr(tag Tag, name string) (Attribute, bool) {
+	for _, g := range m.Groups {
+		if g.Tag == tag {
+			return g.Attr(name)
+		}
+	}
+	return Attribute{}, false
+}
+
+// MarshalBinary encodes m.
+func (m *Message) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := m.Encode(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes m from b, which must hold nothing but the message.
+func (m *Message) UnmarshalBinary(b []byte) error {
+	r := bytes.NewReader(b)
+	if err := m.Decode(r); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("ipp: %d bytes after end-of-attributes", r.Len())
+	}
+	return nil
+}
+
+// Encode writes m to w.
+func (m *Message) Encode(w io.Writer) error {
+	e := encoder{}
+	e.u16(uint16(m.Version))
+	e.u16(m.Code)
+	e.u32(m.RequestID)
+	for _, g := range m.Groups {
+		if !g.Tag.IsDelimiter() || g.Tag == TagEnd {
+			return fmt.Errorf("ipp: invalid group tag %s", g.Tag)
+		}
+		e.buf.WriteByte(byte(g.Tag))
+		for _, a := range g.Attrs {
+			if err := e.attr(a.Name, a.Values); err != nil {
+				return err
+			}
+		}
+	}
+	e.buf.WriteByte(byte(TagEnd))
+	_, err := w.Write(e.buf.Bytes())
+	return err
+}
+
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *encoder) u16(v uint16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) u32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) field(b []byte) error {
+	if len(b) > 0xffff {
+		return fmt.Errorf("ipp: field of %d bytes is too long", len(b))
+	}
+	e.u16(uint16(len(b)))
+	e.buf.Write(b)
+	return nil
+}
+
+// attr writes an attribute; additional values get an empty name.
+func (e *encoder) attr(name string, values []Value) error {
+	if len(values) == 0 {
+		return fmt.Errorf("ipp: attribute %q has no values", name)
+	}
+	for i, v := range values {
+		if i > 0 {
+			name = ""
+		}
+		if err := e.value(name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) value(name string, v Value) error {
+	if v.Tag.IsDelimiter() {
+		return fmt.Errorf("ipp: attribute %q has delimiter tag %s", name, v.Tag)
+	}
+	e.buf.WriteByte(byte(v.Tag))
+	if err := e.field([]byte(name)); err != nil {
+		return err
+	}
+	if v.Tag == TagBeginCollection {
+		c, ok := v.Data.(Collection)
+		if !ok {
+			return fmt.Errorf("ipp: attribute %q: %s value is %T", name, v.Tag, v.Data)
+		}
+		return e.collection(c)
+	}
+	if v.Data == nil {
+		return e.field(nil)
+	}
+	b, err := v.Data.encode()
+	if err != nil {
+		return fmt.Errorf("ipp: attribute %q: %v", name, err)
+	}
+	return e.field(b)
+}
+
+// collection writes the members of c after an empty begCollection value.
+func (e *encoder) collection(c Collection) error {
+	e.u16(0)
+	for _, a := range c {
+		e.buf.WriteByte(byte(TagMemberName))
+		e.u16(0)
+		if err := e.field([]byte(a.Name)); err != nil {
+			return err
+		}
+		for _, v := range a.Values {
+			if err := e.value("", v); err != nil {
+				return err
+			}
+		}
+	}
+	e.buf.WriteByte(byte(TagEndCollection))
+	e.u16(0)
+	e.u16(0)
+	return nil
+}
+
+// ErrTruncated is returned when a message ends before end-of-attributes.
+var ErrTruncated = errors.New("ipp: truncated message")
+
+// Decode reads m from r, stopping right after the end-of-attributes tag so
+// that document data can be read from r afterwards.
+func (m *Message) Decode(r io.Reader) error {
+	d := decoder{r: r}
+	var hdr [8]byte
+	if err := d.read(hdr[:]); err != nil {
+		return err
+	}
+	*m = Message{
+		Version:   Version(binary.BigEndian.Uint16(hdr[0:])),
+		Code:      binary.BigEndian.Uint16(hdr[2:]),
+		RequestID: binary.BigEndian.Uint32(hdr[4:]),
+	}
+	var g *Group
+	for {
+		tag, err := d.tag()
+		if err != nil {
+			return err
+		}
+		if tag == TagEnd {
+			return nil
+		}
+		if tag.IsDelimiter() {
+			g = &Group{Tag: tag}
+			m.Groups = append(m.Groups, g)
+			continue
+		}
+		if g == nil {
+			return fmt.Errorf("ipp: attribute before first group tag")
+		}
+		name, v, err := d.value(tag)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			if len(g.Attrs) == 0 {
+				return fmt.Errorf("ipp: additional value without attribute")
+			}
+			last := &g.Attrs[len(g.Attrs)-1]
+			last.Values = append(last.Values, v)
+			continue
+		}
+		g.Attrs = append(g.Attrs, Attribute{Name: name, Values: []Value{v}})
+	}
+}
+
+type decoder struct {
+	r     io.Reader
+	depth int // of the collection being read
+}
+
+// maxCollectionDepth bounds the nesting of collections, which are decoded
+// recursively.
+const maxCollectionDepth = 32
+
+func (d *decoder) read(b []byte) error {
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrTruncated
+		}
+		return err
+	}
+	return nil
+}
+
+func (d *decoder) tag() (Tag, error) {
+	var b [1]byte
+	err := d.read(b[:])
+	return Tag(b[0]), err
+}
+
+func (d *decoder) field() ([]byte, error) {
+	var n [2]byte
+	if err := d.read(n[:]); err != nil {
+		return nil, err
+	}
+	b := make([]byte, binary.BigEndian.Uint16(n[:]))
+	if err := d.read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// value reads the name and value that follow tag.
+func (d *decoder) value(tag Tag) (string, Value, error) {
+	name, err := d.field()
+	if err != nil {
+		return "", Value{}, err
+	}
+	raw, err := d.field()
+	if err != nil {
+		return "", Value{}, err
+	}
+	if tag == TagBeginCollection {
+		if d.depth == maxCollectionDepth {
+			return "", Value{}, fmt.Errorf("ipp: collections nested deeper than %d levels", maxCollectionDepth)
+		}
+		d.depth++
+		c, err := d.collection()
+		d.depth--
+		return string(name), Value{Tag: tag, Data: c}, err
+	}
+	data, err := decodeData(tag, raw)
+	return string(name), Value{Tag: tag, Data: data}, err
+}
+
+// collection reads collection members up to the matching endCollection.
+func (d *decoder) collection() (Collection, error) {
+	var c Collection
+	for {
+		tag, err := d.tag()
+		if err != nil {
+			return nil, err
+		}
+		if tag.IsDelimiter() {
+			return nil, fmt.Errorf("ipp: delimiter tag %s inside collection", tag)
+		}
+		_, v, err := d.value(tag)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case tag == TagEndCollection:
+			return c, nil
+		case tag == TagMemberName:
+			c = append(c, Attribute{Name: v.String()})
+		case len(c) == 0:
+			return nil, fmt.Errorf("ipp: collection value without member name")
+		default:
+			last := &c[len(c)-1]
+			last.Values = append(last.Values, v)
+		}
+	}
+}
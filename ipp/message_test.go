@@ -0,0 +1,95 @@
+package ipp
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMessage_RoundTrip(t *testing.T) {
+	req := NewRequest(Version20, OpPrintJob, 7)
+	op := req.Group(TagOperationGroup)
+	op.Add(
+		MakeAttribute("printer-uri", TagURI, String("ipp://localhost/printers/Label")),
+		MakeAttribute("requesting-user-name", TagName, String("alice")),
+		MakeAttribute("document-format", TagMimeType, String("application/octet-stream")),
+	)
+	job := req.Group(TagJobGroup)
+	job.Add(
+		MakeAttribute("copies", TagInteger, Integer(2)),
+		MakeAttribute("job-hold-until-specified", TagBoolean, Boolean(false)),
+		MakeAttribute("finishings", TagEnum, Integer(3), Integer(4)),
+		MakeAttribute("media-col", TagBeginCollection, Collection{
+			MakeAttribute("media-size", TagBeginCollection, Collection{
+				MakeAttribute("x-dimension", TagInteger, Integer(21000)),
+				MakeAttribute("y-dimension", TagInteger, Integer(29700)),
+			}),
+			MakeAttribute("media-type", TagKeyword, String("stationery")),
+		}),
+	)
+
+	b, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	var got Message
+	if err = got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	if !reflect.DeepEqual(&got, req) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", got, req)
+	}
+	media, _ := got.Attr(TagJobGroup, "media-col")
+	size, _ := media.Values[0].Data.(Collection).Member("media-size")
+	if x, _ := size.Values[0].Data.(Collection).Member("x-dimension"); x.Int() != 21000 {
+		t.Errorf("media-size x-dimension = %d, want 21000", x.Int())
+	}
+}
+
+func TestMessage_DecodeLeavesData(t *testing.T) {
+	req := NewRequest(Version11, OpPrintJob, 1)
+	b, _ := req.MarshalBinary()
+	r := bytes.NewReader(append(b, "%!PS"...))
+	var m Message
+	if err := m.Decode(r); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if rest := r.Len(); rest != 4 {
+		t.Errorf("Decode left %d bytes, want 4", rest)
+	}
+}
+
+func TestMessage_DecodeTruncated(t *testing.T) {
+	req := NewRequest(Version11, OpGetJobs, 1)
+	b, _ := req.MarshalBinary()
+	var m Message
+	for n := 0; n < len(b); n++ {
+		if err := m.UnmarshalBinary(b[:n]); err == nil {
+			t.Fatalf("UnmarshalBinary of %d/%d bytes succeeded", n, len(b))
+		}
+	}
+	if err := m.Decode(strings.NewReader(string(b[:5]))); err != ErrTruncated {
+		t.Errorf("Decode error = %v, want %v", err, ErrTruncated)
+	}
+}
+
+func TestMessage_DecodeNestedCollections(t *testing.T) {
+	nested := func(depth int) []byte {
+		b := []byte{1, 1, 0, 2, 0, 0, 0, 1, byte(TagOperationGroup), byte(TagBeginCollection), 0, 1, 'a', 0, 0}
+		for i := 1; i < depth; i++ {
+			b = append(b, byte(TagMemberName), 0, 0, 0, 1, 'm', byte(TagBeginCollection), 0, 0, 0, 0)
+		}
+		for i := 0; i < depth; i++ {
+			b = append(b, byte(TagEndCollection), 0, 0, 0, 0)
+		}
+		return append(b, byte(TagEnd))
+	}
+	var m Message
+	if err := m.UnmarshalBinary(nested(maxCollectionDepth)); err != nil {
+		t.Errorf("UnmarshalBinary of %d nested collections failed: %v", maxCollectionDepth, err)
+	}
+	if err := m.UnmarshalBinary(nested(100000)); err == nil || !strings.Contains(err.Error(), "nested deeper") {
+		t.Errorf("UnmarshalBinary of 100000 nested collections: %v", err)
+	}
+}
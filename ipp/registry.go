@@ -0,0 +1,298 @@
+package ipp
+
+import "fmt"
+
+var tagNames = map[Tag]string{
+	TagOperationGroup:    "operation-attributes-tag",
+	TagJobGroup:          "job-attributes-tag",
+	TagEnd:               "end-of-attributes-tag",
+	TagPrinterGroup:      "printer-attributes-tag",
+	TagUnsupportedGroup:  "unsupported-attributes-tag",
+	TagSubscriptionGroup: "subscription-attributes-tag",
+	TagEventGroup:        "event-notification-attributes-tag",
+	TagResourceGroup:     "resource-attributes-tag",
+	TagDocumentGroup:     "document-attributes-tag",
+	TagSystemGroup:       "system-attributes-tag",
+	TagUnsupportedValue:  "unsupported",
+	TagDefault:           "default",
+	TagUnknown:           "unknown",
+	TagNoValue:           "no-value",
+	TagNotSettable:       "not-settable",
+	TagDeleteAttribute:   "delete-attribute",
+	TagAdminDefine:       "admin-define",
+	TagInteger:           "integer",
+	TagBoolean:           "boolean",
+	TagEnum:              "enum",
+	TagOctetString:       "octetString",
+	TagDateTime:          "dateTime",
+	TagResolution:        "resolution",
+	TagRange:             "rangeOfInteger",
+	TagBeginCollection:   "collection",
+	TagTextLang:          "textWithLanguage",
+	TagNameLang:          "nameWithLanguage",
+	TagEndCollection:     "endCollection",
+	TagText:              "textWithoutLanguage",
+	TagName:              "nameWithoutLanguage",
+	TagKeyword:           "keyword",
+	TagURI:               "uri",
+	TagURIScheme:         "uriScheme",
+	TagCharset:           "charset",
+	TagLanguage:          "naturalLanguage",
+	TagMimeType:          "mimeMediaType",
+	TagMemberName:        "memberAttrName",
+	TagExtension:         "extension",
+}
+
+func (t Tag) String() string {
+	if s, ok := tagNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("tag-0x%02x", byte(t))
+}
+
+var opNames = map[Op]string{
+	OpPrintJob:                  "Print-Job",
+	OpPrintURI:                  "Print-URI",
+	OpValidateJob:               "Validate-Job",
+	OpCreateJob:                 "Create-Job",
+	OpSendDocument:              "Send-Document",
+	OpSendURI:                   "Send-URI",
+	OpCancelJob:                 "Cancel-Job",
+	OpGetJobAttributes:          "Get-Job-Attributes",
+	OpGetJobs:                   "Get-Jobs",
+	OpGetPrinterAttributes:      "Get-Printer-Attributes",
+	OpHoldJob:                   "Hold-Job",
+	OpReleaseJob:                "Release-Job",
+	OpRestartJob:                "Restart-Job",
+	OpPausePrinter:              "Pause-Printer",
+	OpResumePrinter:             "Resume-Printer",
+	OpPurgeJobs:                 "Purge-Jobs",
+	OpSetPrinterAttributes:      "Set-Printer-Attributes",
+	OpSetJobAttributes:          "Set-Job-Attributes",
+	OpGetPrinterSupportedValues: "Get-Printer-Supported-Values",
+	OpCancelJobs:                "Cancel-Jobs",
+	OpCancelMyJobs:              "Cancel-My-Jobs",
+	OpCloseJob:                  "Close-Job",
+	OpIdentifyPrinter:           "Identify-Printer",
+	OpCupsGetDefault:            "CUPS-Get-Default",
+	OpCupsGetPrinters:           "CUPS-Get-Printers",
+	OpCupsAddModifyPrinter:      "CUPS-Add-Modify-Printer",
+	OpCupsDeletePrinter:         "CUPS-Delete-Printer",
+	OpCupsGetClasses:            "CUPS-Get-Classes",
+	OpCupsAcceptJobs:            "CUPS-Accept-Jobs",
+	OpCupsRejectJobs:            "CUPS-Reject-Jobs",
+	OpCupsSetDefault:            "CUPS-Set-Default",
+	OpCupsMoveJob:               "CUPS-Move-Job",
+	OpCupsGetPPD:                "CUPS-Get-PPD",
+}
+
+func (o Op) String() string {
+	if s, ok := opNames[o]; ok {
+		return s
+	}
+	return fmt.Sprintf("operation-0x%04x", uint16(o))
+}
+
+// LookupOp returns the operation called name, such as "Print-Job".
+func LookupOp(name string) (Op, bool) {
+	for op, s := range opNames {
+		if s == name {
+			return op, true
+		}
+	}
+	return 0, false
+}
+
+var statusNames = map[Status]string{
+	StatusOK:                               "successful-ok",
+	StatusOKIgnoredOrSubstituted:           "successful-ok-ignored-or-substituted-attributes",
+	StatusOKConflicting:                    "successful-ok-conflicting-attributes",
+	StatusBadRequest:                       "client-error-bad-request",
+	StatusForbidden:                        "client-error-forbidden",
+	StatusNotAuthenticated:                 "client-error-not-authenticated",
+	StatusNotAuthorized:                    "client-error-not-authorized",
+	StatusNotPossible:                      "client-error-not-possible",
+	StatusTimeout:                          "client-error-timeout",
+	StatusNotFound:                         "client-error-not-found",
+	StatusGone:                             "client-error-gone",
+	StatusRequestEntityTooLarge:            "client-error-request-entity-too-large",
+	StatusRequestValueTooLong:              "client-error-request-value-too-long",
+	StatusDocumentFormatNotSupported:       "client-error-document-format-not-supported",
+	StatusAttributesOrValuesNotSupported:   "client-error-attributes-or-values-not-supported",
+	StatusURISchemeNotSupported:            "client-error-uri-scheme-not-supported",
+	StatusCharsetNotSupported:              "client-error-charset-not-supported",
+	StatusConflictingAttributes:            "client-error-conflicting-attributes",
+	StatusCompressionNotSupported:          "client-error-compression-not-supported",
+	StatusCompressionError:                 "client-error-compression-error",
+	StatusDocumentFormatError:              "client-error-document-format-error",
+	StatusDocumentAccessError:              "client-error-document-access-error",
+	StatusInternalError:                    "server-error-internal-error",
+	StatusOperationNotSupported:            "server-error-operation-not-supported",
+	StatusServiceUnavailable:               "server-error-service-unavailable",
+	StatusVersionNotSupported:              "server-error-version-not-supported",
+	StatusDeviceError:                      "server-error-device-error",
+	StatusTemporaryError:                   "server-error-temporary-error",
+	StatusNotAcceptingJobs:                 "server-error-not-accepting-jobs",
+	StatusBusy:                             "server-error-busy",
+	StatusJobCanceled:                      "server-error-job-canceled",
+	StatusMultipleDocumentJobsNotSupported: "server-error-multiple-document-jobs-not-supported",
+}
+
+func (s Status) String() string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("status-0x%04x", uint16(s))
+}
+
+// AttrDef describes the syntax of a registered attribute.
+type AttrDef struct {
+	Name  string
+	Tag   Tag  // value tag of the attribute syntax
+	SetOf bool // 1setOf: the attribute may have several values
+}
+
+var attrDefs = make(map[string]AttrDef)
+
+func init() {
+	for _, set := range []struct {
+		tag   Tag
+		setOf bool
+		names []string
+	}{
+		{TagCharset, false, []string{"attributes-charset", "charset-configured"}},
+		{TagCharset, true, []string{"charset-supported"}},
+		{TagLanguage, false, []string{"attributes-natural-language", "document-natural-language", "natural-language-configured"}},
+		{TagLanguage, true, []string{"generated-natural-language-supported"}},
+		{TagURI, false, []string{
+			"printer-uri", "job-uri", "document-uri", "job-printer-uri", "job-more-info",
+			"printer-more-info", "printer-driver-installer", "printer-more-info-manufacturer", "printer-uuid",
+		}},
+		{TagURI, true, []string{"printer-uri-supported", "printer-icons"}},
+		{TagURIScheme, true, []string{"reference-uri-schemes-supported"}},
+		{TagName, false, []string{
+			"requesting-user-name", "job-name", "document-name", "job-originating-user-name",
+			"job-originating-host-name", "output-device-assigned", "printer-name",
+		}},
+		{TagName, true, []string{"marker-names", "marker-colors"}},
+		{TagText, false, []string{
+			"status-message", "detailed-status-message", "job-state-message", "job-message-from-operator",
+			"printer-location", "printer-info", "printer-make-and-model", "printer-state-message",
+			"printer-message-from-operator", "printer-device-id",
+		}},
+		{TagMimeType, false, []string{"document-format", "document-format-default"}},
+		{TagMimeType, true, []string{"document-format-supported"}},
+		{TagBoolean, false, []string{
+			"ipp-attribute-fidelity", "last-document", "my-jobs", "multiple-document-jobs-supported",
+			"printer-is-accepting-jobs", "color-supported",
+		}},
+		{TagInteger, false, []string{
+			"job-id", "job-k-octets", "job-impressions", "job-media-sheets", "limit", "first-index",
+			"job-priority", "copies", "number-up", "number-of-documents", "time-at-creation",
+			"time-at-processing", "time-at-completed", "job-printer-up-time", "number-of-intervening-jobs",
+			"job-k-octets-processed", "job-impressions-completed", "job-media-sheets-completed",
+			"queued-job-count", "printer-up-time", "multiple-operation-time-out", "pages-per-minute",
+			"pages-per-minute-color", "copies-default", "job-priority-default",
+		}},
+		{TagInteger, true, []string{"marker-levels"}},
+		{TagEnum, false, []string{"job-state", "printer-state", "orientation-requested", "print-quality"}},
+		{TagEnum, true, []string{"finishings", "operations-supported", "finishings-supported",
+			"orientation-requested-supported", "print-quality-supported"}},
+		{TagKeyword, false, []string{
+			"compression", "which-jobs", "job-hold-until", "job-sheets", "multiple-document-handling",
+			"sides", "media", "print-color-mode", "output-bin", "pdl-override-supported", "sides-default",
+			"media-default", "uri-security-supported", "uri-authentication-supported",
+		}},
+		{TagKeyword, true, []string{
+			"requested-attributes", "job-state-reasons", "printer-state-reasons", "ipp-versions-supported",
+			"compression-supported", "sides-supported", "media-supported", "media-ready",
+			"print-color-mode-supported", "printer-kind", "marker-types",
+		}},
+		{TagRange, false, []string{"job-k-octets-supported", "job-impressions-supported", "job-media-sheets-supported", "copies-supported"}},
+		{TagRange, true, []string{"page-ranges"}},
+		{TagResolution, false, []string{"printer-resolution", "printer-resolution-default"}},
+		{TagResolution, true, []string{"printer-resolution-supported"}},
+		{TagDateTime, false, []string{"date-time-at-creation", "date-time-at-processing", "date-time-at-completed", "printer-current-time"}},
+		{TagBeginCollection, false, []string{"media-col", "media-col-default", "media-size"}},
+		{TagBeginCollection, true, []string{"media-col-database", "media-col-ready"}},
+	} {
+		for _, name := range set.names {
+			attrDefs[name] = AttrDef{Name: name, Tag: set.tag, SetOf: set.setOf}
+		}
+	}
+}
+
+// LookupAttr returns the registered definition of the attribute called name.
+func LookupAttr(name string) (AttrDef, bool) {
+	def, ok := attrDefs[name]
+	return def, ok
+}
+
+// NewAttr returns a registered attribute, taking the value tag from its
+// definition.
+func NewAttr(name string, values ...Data) (Attribute, error) {
+	def, ok := LookupAttr(name)
+	if !ok {
+		return Attribute{}, fmt.Errorf("ipp: attribute %q is not registered", name)
+	}
+	if len(values) > 1 && !def.SetOf {
+		return Attribute{}, fmt.Errorf("ipp: attribute %q takes a single value", name)
+	}
+	return MakeAttribute(name, def.Tag, values...), nil
+}
+
+var enumNames = map[string]map[int]string{
+	"job-state": {
+		JobPending: "pending", JobPendingHeld: "pending-held", JobProcessing: "processing",
+		JobProcessingStopped: "processing-stopped", JobCanceled: "canceled", JobAborted: "aborted",
+		JobCompleted: "completed",
+	},
+	"printer-state": {
+		PrinterIdle: "idle", PrinterProcessing: "processing", PrinterStopped: "stopped",
+	},
+	"orientation-requested": {
+		3: "portrait", 4: "landscape", 5: "reverse-landscape", 6: "reverse-portrait", 7: "none",
+	},
+	"print-quality": {
+		3: "draft", 4: "normal", 5: "high",
+	},
+	"finishings": {
+		3: "none", 4: "staple", 5: "punch", 6: "cover", 7: "bind", 8: "saddle-stitch", 9: "edge-stitch",
+		10: "fold", 11: "trim", 12: "bale", 13: "booklet-maker", 14: "jog-offset",
+	},
+}
+
+func init() {
+	for attr, base := range map[string]string{
+		"orientation-requested-supported": "orientation-requested",
+		"print-quality-supported":         "print-quality",
+		"finishings-supported":            "finishings",
+	} {
+		enumNames[attr] = enumNames[base]
+	}
+	ops := make(map[int]string, len(opNames))
+	for op, name := range opNames {
+		ops[int(op)] = name
+	}
+	enumNames["operations-supported"] = ops
+}
+
+// EnumName returns the keyword of value v of the enum attribute attr, such
+// as "pending" for job-state 3.
+func EnumName(attr string, v int) string {
+	if s, ok := enumNames[attr][v]; ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// EnumValue returns the value of the keyword name of the enum attribute
+// attr.
+func EnumValue(attr, name string) (int, bool) {
+	for v, s := range enumNames[attr] {
+		if s == name {
+			return v, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,85 @@
+package ipp
+
+import "testing"
+
+func TestRegistry_Names(t *testing.T) {
+	if got := TagTextLang.String(); got != "textWithLanguage" {
+		t.Errorf("TagTextLang = %q", got)
+	}
+	if got := Tag(0x60).String(); got != "tag-0x60" {
+		t.Errorf("Tag(0x60) = %q", got)
+	}
+	if got := StatusAttributesOrValuesNotSupported.String(); got != "client-error-attributes-or-values-not-supported" {
+		t.Errorf("Status 0x040b = %q", got)
+	}
+	if got := Status(0x0777).String(); got != "status-0x0777" {
+		t.Errorf("Status 0x0777 = %q", got)
+	}
+	if got := OpCupsGetPrinters.String(); got != "CUPS-Get-Printers" {
+		t.Errorf("OpCupsGetPrinters = %q", got)
+	}
+	if op, ok := LookupOp("Get-Jobs"); !ok || op != OpGetJobs {
+		t.Errorf("LookupOp(Get-Jobs) = %v, %v", op, ok)
+	}
+	if _, ok := LookupOp("Print-Everything"); ok {
+		t.Error("LookupOp(Print-Everything) succeeded")
+	}
+}
+
+func TestRegistry_Attrs(t *testing.T) {
+	tests := []struct {
+		name  string
+		tag   Tag
+		setOf bool
+	}{
+		{"printer-uri", TagURI, false},
+		{"requested-attributes", TagKeyword, true},
+		{"job-state", TagEnum, false},
+		{"page-ranges", TagRange, true},
+		{"printer-resolution-default", TagResolution, false},
+		{"date-time-at-creation", TagDateTime, false},
+		{"media-col-database", TagBeginCollection, true},
+	}
+	for _, tt := range tests {
+		def, ok := LookupAttr(tt.name)
+		if !ok || def.Name != tt.name || def.Tag != tt.tag || def.SetOf != tt.setOf {
+			t.Errorf("LookupAttr(%q) = %+v, %v", tt.name, def, ok)
+		}
+	}
+
+	a, err := NewAttr("copies", Integer(2))
+	if err != nil || a.Values[0].Tag != TagInteger || a.Int() != 2 {
+		t.Errorf("NewAttr(copies) = %v, %v", a, err)
+	}
+	if _, err = NewAttr("copies", Integer(1), Integer(2)); err == nil {
+		t.Error("NewAttr accepted two copies values")
+	}
+	if _, err = NewAttr("x-vendor-thing", String("x")); err == nil {
+		t.Error("NewAttr accepted an unregistered attribute")
+	}
+}
+
+func TestRegistry_Enums(t *testing.T) {
+	tests := []struct {
+		attr string
+		v    int
+		name string
+	}{
+		{"job-state", JobProcessingStopped, "processing-stopped"},
+		{"printer-state", PrinterStopped, "stopped"},
+		{"orientation-requested", 4, "landscape"},
+		{"finishings-supported", 4, "staple"},
+		{"operations-supported", int(OpGetPrinterAttributes), "Get-Printer-Attributes"},
+	}
+	for _, tt := range tests {
+		if got := EnumName(tt.attr, tt.v); got != tt.name {
+			t.Errorf("EnumName(%q, %d) = %q, want %q", tt.attr, tt.v, got, tt.name)
+		}
+		if v, ok := EnumValue(tt.attr, tt.name); !ok || v != tt.v {
+			t.Errorf("EnumValue(%q, %q) = %d, %v", tt.attr, tt.name, v, ok)
+		}
+	}
+	if got := EnumName("job-state", 42); got != "42" {
+		t.Errorf("EnumName(job-state, 42) = %q", got)
+	}
+}
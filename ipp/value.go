@@ -0,0 +1,279 @@
+package ipp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Data is the decoded form of an attribute value.
+type Data interface {
+	fmt.Stringer
+	encode() ([]byte, error)
+}
+
+// Integer is an integer or enum value.
+type Integer int32
+
+func (v Integer) String() string {
+	return fmt.Sprint(int32(v))
+}
+
+func (v Integer) encode() ([]byte, error) {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(v))
+	return b, nil
+}
+
+// Boolean is a boolean value.
+type Boolean bool
+
+func (v Boolean) String() string {
+	return fmt.Sprint(bool(v))
+}
+
+func (v Boolean) encode() ([]byte, error) {
+	if v {
+		return []byte{1}, nil
+	}
+	return []byte{0}, nil
+}
+
+// String is a text, name, keyword, uri, charset, naturalLanguage,
+// mimeMediaType or other character string value.
+type String string
+
+func (v String) String() string {
+	return string(v)
+}
+
+func (v String) encode() ([]byte, error) {
+	return []byte(v), nil
+}
+
+// Binary holds the raw bytes of values with no richer decoded form.
+type Binary []byte
+
+func (v Binary) String() string {
+	return fmt.Sprintf("%x", []byte(v))
+}
+
+func (v Binary) encode() ([]byte, error) {
+	return v, nil
+}
+
+// Range is a rangeOfInteger value.
+type Range struct {
+	Lower, Upper int32
+}
+
+func (v Range) String() string {
+	return fmt.Sprintf("%d-%d", v.Lower, v.Upper)
+}
+
+func (v Range) encode() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, uint32(v.Lower))
+	binary.BigEndian.PutUint32(b[4:], uint32(v.Upper))
+	return b, nil
+}
+
+// Units of a Resolution.
+const (
+	UnitsDPI  = 3 // dots per inch
+	UnitsDPCM = 4 // dots per centimeter
+)
+
+// Resolution is a resolution value.
+type Resolution struct {
+	X, Y  int32
+	Units int8
+}
+
+func (v Resolution) String() string {
+	units := "dpi"
+	if v.Units == UnitsDPCM {
+		units = "dpcm"
+	}
+	if v.X == v.Y {
+		return fmt.Sprintf("%d%s", v.X, units)
+	}
+	return fmt.Sprintf("%dx%d%s", v.X, v.Y, units)
+}
+
+func (v Resolution) encode() ([]byte, error) {
+	b := make([]byte, 9)
+	binary.BigEndian.PutUint32(b, uint32(v.X))
+	binary.BigEndian.PutUint32(b[4:], uint32(v.Y))
+	b[8] = byte(v.Units)
+	return b, nil
+}
+
+// Time is a dateTime value. It is encoded with a precision of a tenth of a
+// second and a whole-minute UTC offset.
+type Time struct {
+	time.Time
+}
+
+func (v Time) String() string {
+	return v.Format(time.RFC3339)
+}
+
+// encode writes the RFC 2579 DateAndTime octets.
+func (v Time) encode() ([]byte, error) {
+	_, off := v.Zone()
+	sign := byte('+')
+	if off < 0 {
+		sign, off = '-', -off
+	}
+	off /= 60
+	b := make([]byte, 11)
+	binary.BigEndian.PutUint16(b, uint16(v.Year()))
+	b[2] = byte(v.Month())
+	b[3] = byte(v.Day())
+	b[4] = byte(v.Hour())
+	b[5] = byte(v.Minute())
+	b[6] = byte(v.Second())
+	b[7] = byte(v.Nanosecond() / 1e8)
+	b[8] = sign
+	b[9] = byte(off / 60)
+	b[10] = byte(off % 60)
+	return b, nil
+}
+
+func decodeTime(b []byte) (Time, error) {
+	if b[8] != '+' && b[8] != '-' {
+		return Time{}, fmt.Errorf("ipp: dateTime has UTC direction %q", b[8])
+	}
+	off := (int(b[9])*60 + int(b[10])) * 60
+	if b[8] == '-' {
+		off = -off
+	}
+	loc := time.UTC
+	if off != 0 {
+		loc = time.FixedZone("", off)
+	}
+	return Time{time.Date(int(binary.BigEndian.Uint16(b)), time.Month(b[2]), int(b[3]),
+		int(b[4]), int(b[5]), int(b[6]), int(b[7])*1e8, loc)}, nil
+}
+
+// TextWithLang is a textWithLanguage or nameWithLanguage value.
+type TextWithLang struct {
+	Lang string
+	Text string
+}
+
+func (v TextWithLang) String() string {
+	return v.Text
+}
+
+func (v TextWithLang) encode() ([]byte, error) {
+	if len(v.Lang) > 0xffff || len(v.Text) > 0xffff {
+		return nil, fmt.Errorf("%q is too long", v.Text)
+	}
+	b := make([]byte, 4+len(v.Lang)+len(v.Text))
+	binary.BigEndian.PutUint16(b, uint16(len(v.Lang)))
+	n := 2 + copy(b[2:], v.Lang)
+	binary.BigEndian.PutUint16(b[n:], uint16(len(v.Text)))
+	copy(b[n+2:], v.Text)
+	return b, nil
+}
+
+func decodeTextWithLang(b []byte) (TextWithLang, bool) {
+	if len(b) < 2 {
+		return TextWithLang{}, false
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 4+n {
+		return TextWithLang{}, false
+	}
+	lang, b := b[2:2+n], b[2+n:]
+	n = int(binary.BigEndian.Uint16(b))
+	if len(b) != 2+n {
+		return TextWithLang{}, false
+	}
+	return TextWithLang{Lang: string(lang), Text: string(b[2:])}, true
+}
+
+// Void is the empty value of out-of-band tags such as unsupported, unknown
+// and no-value.
+type Void struct{}
+
+func (Void) String() string {
+	return ""
+}
+
+func (Void) encode() ([]byte, error) {
+	return nil, nil
+}
+
+// Collection is a collection value; its members are attributes.
+type Collection []Attribute
+
+func (v Collection) String() string {
+	s := make([]string, 0, len(v))
+	for _, a := range v {
+		s = append(s, a.String())
+	}
+	return "{" + strings.Join(s, " ") + "}"
+}
+
+// encode is never called: collections are written member by member.
+func (v Collection) encode() ([]byte, error) {
+	return nil, nil
+}
+
+// Member returns the collection member called name.
+func (v Collection) Member(name string) (Attribute, bool) {
+	for _, a := range v {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Attribute{}, false
+}
+
+// decodeData converts the raw bytes of a non-collection value.
+func decodeData(tag Tag, b []byte) (Data, error) {
+	switch tag {
+	case TagInteger, TagEnum:
+		if len(b) != 4 {
+			return nil, fmt.Errorf("ipp: %s value has %d bytes, want 4", tag, len(b))
+		}
+		return Integer(binary.BigEndian.Uint32(b)), nil
+	case TagBoolean:
+		if len(b) != 1 {
+			return nil, fmt.Errorf("ipp: %s value has %d bytes, want 1", tag, len(b))
+		}
+		return Boolean(b[0] != 0), nil
+	case TagRange:
+		if len(b) != 8 {
+			return nil, fmt.Errorf("ipp: %s value has %d bytes, want 8", tag, len(b))
+		}
+		return Range{int32(binary.BigEndian.Uint32(b)), int32(binary.BigEndian.Uint32(b[4:]))}, nil
+	case TagResolution:
+		if len(b) != 9 {
+			return nil, fmt.Errorf("ipp: %s value has %d bytes, want 9", tag, len(b))
+		}
+		return Resolution{int32(binary.BigEndian.Uint32(b)), int32(binary.BigEndian.Uint32(b[4:])), int8(b[8])}, nil
+	case TagDateTime:
+		if len(b) != 11 {
+			return nil, fmt.Errorf("ipp: %s value has %d bytes, want 11", tag, len(b))
+		}
+		return decodeTime(b)
+	case TagTextLang, TagNameLang:
+		v, ok := decodeTextWithLang(b)
+		if !ok {
+			return nil, fmt.Errorf("ipp: malformed %s value", tag)
+		}
+		return v, nil
+	case TagOctetString, TagText, TagName, TagKeyword, TagURI, TagURIScheme,
+		TagCharset, TagLanguage, TagMimeType, TagMemberName:
+		return String(b), nil
+	}
+	if tag.IsOutOfBand() {
+		return Void{}, nil
+	}
+	return Binary(append([]byte(nil), b...)), nil
+}
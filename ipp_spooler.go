@@ -0,0 +1,631 @@
+package winprinters
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chenxi2015/winprinters/ipp"
+)
+
+// IPPSpooler is a Spooler for a CUPS server or any other IPP printer or
+// server. It speaks IPP/2.0 and falls back to IPP/1.1 when the server does
+// not support 2.0.
+//
+// Printer names are CUPS queue names, resolved through CUPS-Get-Printers,
+// or full ipp:// and ipps:// printer URIs.
+type IPPSpooler struct {
+	// URL is the server or printer URL, e.g. "ipp://cups.local:631" or
+	// "http://192.168.1.20/ipp/print".
+	URL string
+
+	// Client sends the requests; when nil, a client bounded by Timeout is
+	// used.
+	Client *http.Client
+
+	// Timeout bounds every request of the default client, including
+	// sending the document; 5 minutes when zero.
+	Timeout time.Duration
+
+	// UserName is sent as requesting-user-name.
+	UserName string
+
+	mu        sync.Mutex
+	version   ipp.Version
+	requestID uint32
+	uris      map[string]string
+}
+
+// NewIPPSpooler returns an IPPSpooler for the server or printer at url.
+func NewIPPSpooler(url string) *IPPSpooler {
+	return &IPPSpooler{URL: url}
+}
+
+// ippJobAttributes are the job attributes decoded into JobInfo.
+var ippJobAttributes = []string{
+	"job-id", "job-name", "job-originating-user-name", "job-originating-host-name",
+	"job-state", "job-state-reasons", "job-state-message", "job-priority",
+	"document-format", "job-impressions", "job-impressions-completed",
+	"time-at-creation",
+}
+
+// ippURI returns the printer-uri form of rawURL, mapping http to ipp and
+// https to ipps. The port of an http or https URL without one, 80 or 443,
+// is written out, since ipp and ipps default to 631.
+func ippURI(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	port := ""
+	switch u.Scheme {
+	case "http":
+		u.Scheme, port = "ipp", "80"
+	case "https":
+		u.Scheme, port = "ipps", "443"
+	case "ipp", "ipps":
+	default:
+		return "", fmt.Errorf("winprinters: unsupported IPP URL scheme %q", u.Scheme)
+	}
+	if port != "" && u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return u.String(), nil
+}
+
+// httpURL returns the HTTP URL that requests for the ipp:// or ipps://
+// printer URI are posted to, on port 631 unless the URI has one. Http and
+// https URLs are used as they are.
+func httpURL(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "ipp":
+		u.Scheme = "http"
+	case "ipps":
+		u.Scheme = "https"
+	case "http", "https":
+		return u.String(), nil
+	default:
+		return "", fmt.Errorf("winprinters: unsupported IPP URL scheme %q", u.Scheme)
+	}
+	if u.Port() == "" {
+		u.Host += ":631"
+	}
+	return u.String(), nil
+}
+
+// printerURI returns the printer-uri for a printer name.
+func (s *IPPSpooler) printerURI(name string) (string, error) {
+	if strings.Contains(name, "://") {
+		return ippURI(name)
+	}
+	s.mu.Lock()
+	uri, ok := s.uris[name]
+	s.mu.Unlock()
+	if ok {
+		return uri, nil
+	}
+	base, err := ippURI(s.URL)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(base, "/") + "/printers/" + url.PathEscape(name), nil
+}
+
+// newRequest returns a request for op addressed to the printer-uri uri,
+// or to the server when uri is empty.
+func (s *IPPSpooler) newRequest(op ipp.Op, uri string) *ipp.Message {
+	s.mu.Lock()
+	if s.version == 0 {
+		s.version = ipp.Version20
+	}
+	s.requestID++
+	req := ipp.NewRequest(s.version, op, s.requestID)
+	s.mu.Unlock()
+	g := req.Group(ipp.TagOperationGroup)
+	if uri != "" {
+		g.Add(ipp.MakeAttribute("printer-uri", ipp.TagURI, ipp.String(uri)))
+	}
+	if s.UserName != "" {
+		g.Add(ipp.MakeAttribute("requesting-user-name", ipp.TagName, ipp.String(s.UserName)))
+	}
+	return req
+}
+
+// post sends req followed by doc to target and decodes the response. It
+// does not look at the response status.
+func (s *IPPSpooler) post(target string, req *ipp.Message, doc io.Reader) (*ipp.Message, error) {
+	u, err := httpURL(target)
+	if err != nil {
+		return nil, err
+	}
+	b, err := req.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	var body io.Reader = bytes.NewReader(b)
+	if doc != nil {
+		body = io.MultiReader(body, doc)
+	}
+	hr, err := http.NewRequest(http.MethodPost, u, body)
+	if err != nil {
+		return nil, err
+	}
+	hr.Header.Set("Content-Type", "application/ipp")
+	client := s.Client
+	if client == nil {
+		timeout := s.Timeout
+		if timeout <= 0 {
+			timeout = 5 * time.Minute
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+	resp, err := client.Do(hr)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("winprinters: IPP request to %s failed: %s", u, resp.Status)
+	}
+	var m ipp.Message
+	if err = m.Decode(resp.Body); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// do sends a request without document data and checks the response status,
+// retrying with IPP/1.1 once if the server rejects IPP/2.0.
+func (s *IPPSpooler) do(target string, req *ipp.Message) (*ipp.Message, error) {
+	resp, err := s.post(target, req, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status() == ipp.StatusVersionNotSupported && req.Version != ipp.Version11 {
+		s.mu.Lock()
+		s.version = ipp.Version11
+		s.mu.Unlock()
+		req.Version = ipp.Version11
+		if resp, err = s.post(target, req, nil); err != nil {
+			return nil, err
+		}
+	}
+	return resp, ippStatusError(req.Op(), resp)
+}
+
+// ippStatusError returns an *ipp.StatusError for unsuccessful responses.
+func ippStatusError(op ipp.Op, resp *ipp.Message) error {
+	if resp.Status().IsSuccess() {
+		return nil
+	}
+	msg, _ := resp.Attr(ipp.TagOperationGroup, "status-message")
+	return &ipp.StatusError{Op: op, Status: resp.Status(), Message: msg.Text()}
+}
+
+// isIPPStatus reports whether err is an *ipp.StatusError with status.
+func isIPPStatus(err error, status ipp.Status) bool {
+	var se *ipp.StatusError
+	return errors.As(err, &se) && se.Status == status
+}
+
+// isOperationNotSupported reports whether err says the server does not
+// implement the operation, as plain IPP printers answer CUPS operations.
+func isOperationNotSupported(err error) bool {
+	return isIPPStatus(err, ipp.StatusOperationNotSupported)
+}
+
+// printerAttributes sends Get-Printer-Attributes for uri.
+func (s *IPPSpooler) printerAttributes(uri string, names ...string) (*ipp.Group, error) {
+	req := s.newRequest(ipp.OpGetPrinterAttributes, uri)
+	req.Group(ipp.TagOperationGroup).Add(ipp.MakeAttribute("requested-attributes", ipp.TagKeyword, ippKeywords(names)...))
+	resp, err := s.do(uri, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Group(ipp.TagPrinterGroup), nil
+}
+
+func ippKeywords(names []string) []ipp.Data {
+	values := make([]ipp.Data, 0, len(names))
+	for _, n := range names {
+		values = append(values, ipp.String(n))
+	}
+	return values
+}
+
+// GetDefault returns the CUPS default printer, or the printer-name of URL
+// when the server is a plain IPP printer.
+func (s *IPPSpooler) GetDefault() (string, error) {
+	resp, err := s.do(s.URL, s.newRequest(ipp.OpCupsGetDefault, ""))
+	if isOperationNotSupported(err) {
+		return s.selfName()
+	}
+	if err != nil {
+		return "", err
+	}
+	name, _ := resp.Attr(ipp.TagPrinterGroup, "printer-name")
+	return name.Text(), nil
+}
+
+// SetDefault makes the named printer the CUPS default printer.
+func (s *IPPSpooler) SetDefault(name string) error {
+	uri, err := s.printerURI(name)
+	if err != nil {
+		return err
+	}
+	_, err = s.do(s.URL, s.newRequest(ipp.OpCupsSetDefault, uri))
+	if isOperationNotSupported(err) {
+		return &UnsupportedError{Op: "SetDefault"}
+	}
+	return err
+}
+
+// ReadNames returns the CUPS queue names, or the printer-name of URL when
+// the server is a plain IPP printer.
+func (s *IPPSpooler) ReadNames() ([]string, error) {
+	req := s.newRequest(ipp.OpCupsGetPrinters, "")
+	req.Group(ipp.TagOperationGroup).Add(ipp.MakeAttribute("requested-attributes", ipp.TagKeyword,
+		ipp.String("printer-name"), ipp.String("printer-uri-supported")))
+	resp, err := s.do(s.URL, req)
+	if isOperationNotSupported(err) {
+		name, err := s.selfName()
+		if err != nil {
+			return nil, err
+		}
+		return []string{name}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	uris := make(map[string]string)
+	var names []string
+	for _, g := range resp.GroupsOf(ipp.TagPrinterGroup) {
+		name, ok := g.Attr("printer-name")
+		if !ok {
+			continue
+		}
+		names = append(names, name.Text())
+		if uri, ok := g.Attr("printer-uri-supported"); ok {
+			uris[name.Text()] = uri.Text()
+		}
+	}
+	s.mu.Lock()
+	s.uris = uris
+	s.mu.Unlock()
+	return names, nil
+}
+
+// selfName returns the printer-name of URL and remembers URL as its URI.
+func (s *IPPSpooler) selfName() (string, error) {
+	uri, err := ippURI(s.URL)
+	if err != nil {
+		return "", err
+	}
+	attrs, err := s.printerAttributes(uri, "printer-name")
+	if err != nil {
+		return "", err
+	}
+	name, _ := attrs.Attr("printer-name")
+	s.mu.Lock()
+	if s.uris == nil {
+		s.uris = make(map[string]string)
+	}
+	s.uris[name.Text()] = uri
+	s.mu.Unlock()
+	return name.Text(), nil
+}
+
+// Open opens the named printer after checking that the server knows it.
+func (s *IPPSpooler) Open(name string) (PrinterHandle, error) {
+	uri, err := s.printerURI(name)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := s.printerAttributes(uri, "printer-name", "printer-make-and-model")
+	if isIPPStatus(err, ipp.StatusNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrPrinterNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	model, _ := attrs.Attr("printer-make-and-model")
+	return &ippPrinter{s: s, uri: uri, model: model.Text()}, nil
+}
+
+// ippPrinter is the PrinterHandle returned by IPPSpooler.Open.
+type ippPrinter struct {
+	s     *IPPSpooler
+	uri   string
+	model string
+	doc   *ippDocument
+	job   uint32 // job-id of the last Print-Job response
+}
+
+// ippDocument is a Print-Job request whose document data is streamed from
+// Write calls.
+type ippDocument struct {
+	w     *io.PipeWriter
+	done  chan error
+	jobID uint32
+}
+
+func (p *ippPrinter) Jobs() ([]JobInfo, error) {
+	req := p.s.newRequest(ipp.OpGetJobs, p.uri)
+	req.Group(ipp.TagOperationGroup).Add(
+		ipp.MakeAttribute("which-jobs", ipp.TagKeyword, ipp.String("not-completed")),
+		ipp.MakeAttribute("requested-attributes", ipp.TagKeyword, ippKeywords(ippJobAttributes)...),
+	)
+	resp, err := p.s.do(p.uri, req)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []JobInfo
+	for i, g := range resp.GroupsOf(ipp.TagJobGroup) {
+		job := ippJobInfo(g)
+		job.Position = uint32(i + 1)
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// ippJobInfo decodes a job attributes group.
+func ippJobInfo(g *ipp.Group) JobInfo {
+	attr := func(name string) ipp.Attribute {
+		a, _ := g.Attr(name)
+		return a
+	}
+	job := JobInfo{
+		JobID:           uint32(attr("job-id").Int()),
+		UserMachineName: attr("job-originating-host-name").Text(),
+		UserName:        attr("job-originating-user-name").Text(),
+		DocumentName:    attr("job-name").Text(),
+		DataType:        attr("document-format").Text(),
+		Status:          attr("job-state-message").Text(),
+		StatusCode:      ippJobStatus(attr("job-state").Int()),
+		Priority:        uint32(attr("job-priority").Int()),
+		TotalPages:      uint32(attr("job-impressions").Int()),
+		PagesPrinted:    uint32(attr("job-impressions-completed").Int()),
+	}
+	if job.Status == "" {
+		job.Status = strings.Join(attr("job-state-reasons").Texts(), ", ")
+	}
+	if t := attr("time-at-creation").Int(); t > 0 {
+		job.Submitted = time.Unix(int64(t), 0).UTC()
+	}
+	return job
+}
+
+// ippJobStatus maps an IPP job-state onto JOB_STATUS_* flags.
+func ippJobStatus(state int) JobStatus {
+	switch state {
+	case ipp.JobPendingHeld:
+		return JOB_STATUS_PAUSED
+	case ipp.JobProcessing:
+		return JOB_STATUS_PRINTING
+	case ipp.JobProcessingStopped:
+		return JOB_STATUS_PRINTING | JOB_STATUS_PAUSED
+	case ipp.JobCanceled:
+		return JOB_STATUS_DELETED
+	case ipp.JobAborted:
+		return JOB_STATUS_ERROR
+	case ipp.JobCompleted:
+		return JOB_STATUS_PRINTED | JOB_STATUS_COMPLETE
+	}
+	return 0
+}
+
+// Forms maps media-col-database entries onto FormInfo, falling back to the
+// sizes encoded in PWG self-describing media-supported names.
+func (p *ippPrinter) Forms() ([]FormInfo, error) {
+	attrs, err := p.s.printerAttributes(p.uri, "media-col-database", "media-supported")
+	if err != nil {
+		return nil, err
+	}
+	var forms []FormInfo
+	seen := make(map[string]bool)
+	add := func(f FormInfo) {
+		if f.Name != "" && !seen[f.Name] {
+			seen[f.Name] = true
+			forms = append(forms, f)
+		}
+	}
+	if db, ok := attrs.Attr("media-col-database"); ok {
+		for _, v := range db.Values {
+			if c, ok := v.Data.(ipp.Collection); ok {
+				add(ippMediaForm(c))
+			}
+		}
+	}
+	if len(forms) == 0 {
+		media, _ := attrs.Attr("media-supported")
+		for _, name := range media.Texts() {
+			if size, ok := pwgMediaSize(name); ok {
+				add(FormInfo{Name: name, Size: size, ImageableArea: Rect{Right: size.Width, Bottom: size.Height}})
+			}
+		}
+	}
+	return forms, nil
+}
+
+// ippMediaForm converts a media-col collection, whose dimensions are in
+// hundredths of millimeters, to a FormInfo in thousandths of millimeters.
+func ippMediaForm(c ipp.Collection) FormInfo {
+	member := func(c ipp.Collection, name string) ipp.Attribute {
+		a, _ := c.Member(name)
+		return a
+	}
+	var size ipp.Collection
+	if a := member(c, "media-size"); len(a.Values) > 0 {
+		size, _ = a.Values[0].Data.(ipp.Collection)
+	}
+	f := FormInfo{
+		Size: SIZE{
+			Width:  uint32(member(size, "x-dimension").Int() * 10),
+			Height: uint32(member(size, "y-dimension").Int() * 10),
+		},
+	}
+	f.ImageableArea = Rect{
+		Left:   uint32(member(c, "media-left-margin").Int() * 10),
+		Top:    uint32(member(c, "media-top-margin").Int() * 10),
+		Right:  f.Size.Width - uint32(member(c, "media-right-margin").Int()*10),
+		Bottom: f.Size.Height - uint32(member(c, "media-bottom-margin").Int()*10),
+	}
+	for _, name := range []string{"media-key", "media-size-name"} {
+		if f.Name = member(c, name).Text(); f.Name != "" {
+			break
+		}
+	}
+	if f.Name == "" && f.Size.Width > 0 && f.Size.Height > 0 {
+		f.Name = fmt.Sprintf("custom_%gx%gmm", float64(f.Size.Width)/1000, float64(f.Size.Height)/1000)
+	}
+	return f
+}
+
+// pwgMediaSize parses the size of a PWG 5101.1 self-describing media name
+// such as "iso_a4_210x297mm" or "na_letter_8.5x11in".
+func pwgMediaSize(name string) (SIZE, bool) {
+	i := strings.LastIndexByte(name, '_')
+	if i < 0 {
+		return SIZE{}, false
+	}
+	dim := name[i+1:]
+	var unit float64
+	switch {
+	case strings.HasSuffix(dim, "mm"):
+		unit, dim = 1000, strings.TrimSuffix(dim, "mm")
+	case strings.HasSuffix(dim, "in"):
+		unit, dim = 25400, strings.TrimSuffix(dim, "in")
+	default:
+		return SIZE{}, false
+	}
+	var w, h float64
+	if n, err := fmt.Sscanf(dim, "%gx%g", &w, &h); err != nil || n != 2 {
+		return SIZE{}, false
+	}
+	return SIZE{Width: uint32(w*unit + 0.5), Height: uint32(h*unit + 0.5)}, true
+}
+
+func (p *ippPrinter) DriverInfo() (*DriverInfo, error) {
+	return &DriverInfo{Name: p.model, Environment: "IPP", DriverPath: p.uri}, nil
+}
+
+func (p *ippPrinter) CancelJob(jobID uint32) error {
+	req := p.s.newRequest(ipp.OpCancelJob, p.uri)
+	req.Group(ipp.TagOperationGroup).Add(ipp.MakeAttribute("job-id", ipp.TagInteger, ipp.Integer(jobID)))
+	_, err := p.s.do(p.uri, req)
+	if isIPPStatus(err, ipp.StatusNotFound) {
+		return ErrJobNotFound
+	}
+	return err
+}
+
+// DocumentFormat maps a winspool data type onto an IPP document-format MIME
+// type, application/octet-stream for RAW and unknown ones. Data types that
+// already are MIME types are passed through.
+func DocumentFormat(datatype string) string {
+	switch {
+	case strings.Contains(datatype, "/"):
+		return datatype
+	case strings.EqualFold(datatype, "TEXT"):
+		return "text/plain"
+	case strings.EqualFold(datatype, "XPS_PASS"):
+		return "application/oxps"
+	}
+	return "application/octet-stream"
+}
+
+// StartDocument sends a Print-Job request whose document data is streamed
+// from the following Write calls until EndDocument.
+// The server assigns the job ID in its response, see LastJobID.
+func (p *ippPrinter) StartDocument(name, datatype string) (uint32, error) {
+	if p.doc != nil {
+		return 0, errors.New("winprinters: document already started")
+	}
+	req := p.s.newRequest(ipp.OpPrintJob, p.uri)
+	req.Group(ipp.TagOperationGroup).Add(
+		ipp.MakeAttribute("job-name", ipp.TagName, ipp.String(name)),
+		ipp.MakeAttribute("document-format", ipp.TagMimeType, ipp.String(DocumentFormat(datatype))),
+	)
+	r, w := io.Pipe()
+	doc := &ippDocument{w: w, done: make(chan error, 1)}
+	go func() {
+		resp, err := p.s.post(p.uri, req, r)
+		if err == nil {
+			err = ippStatusError(req.Op(), resp)
+		}
+		if err == nil {
+			if a, ok := resp.Attr(ipp.TagJobGroup, "job-id"); ok {
+				doc.jobID = uint32(a.Int())
+			}
+		}
+		// Fail further writes if the server answered before reading all
+		// the document data.
+		if err != nil {
+			_ = r.CloseWithError(err)
+		} else {
+			_ = r.CloseWithError(errors.New("winprinters: Print-Job already answered"))
+		}
+		doc.done <- err
+	}()
+	p.doc = doc
+	return 0, nil
+}
+
+func (p *ippPrinter) Write(b []byte) (int, error) {
+	if p.doc == nil {
+		return 0, errors.New("winprinters: no document started")
+	}
+	return p.doc.w.Write(b)
+}
+
+// EndDocument ends the document data and waits for the Print-Job response.
+func (p *ippPrinter) EndDocument() error {
+	if p.doc == nil {
+		return errors.New("winprinters: no document started")
+	}
+	doc := p.doc
+	p.doc = nil
+	_ = doc.w.Close()
+	err := <-doc.done
+	p.job = doc.jobID
+	return err
+}
+
+// LastJobID returns the job-id of the last Print-Job response.
+func (p *ippPrinter) LastJobID() uint32 {
+	return p.job
+}
+
+// StartPage does nothing: IPP documents are not split into pages by the
+// client.
+func (p *ippPrinter) StartPage() error {
+	return nil
+}
+
+// EndPage does nothing, see StartPage.
+func (p *ippPrinter) EndPage() error {
+	return nil
+}
+
+// Close aborts a document that was started but not ended.
+func (p *ippPrinter) Close() error {
+	if p.doc != nil {
+		_ = p.doc.w.CloseWithError(errors.New("winprinters: printer closed"))
+		<-p.doc.done
+		p.doc = nil
+	}
+	return nil
+}
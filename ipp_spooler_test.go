@@ -0,0 +1,430 @@
+package winprinters
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chenxi2015/winprinters/ipp"
+)
+
+// ippStandIn is a minimal CUPS-like IPP server.
+type ippStandIn struct {
+	mu       sync.Mutex
+	plain    bool // answer CUPS operations with operation-not-supported
+	only11   bool // answer IPP/2.0 requests with version-not-supported
+	printers []*standInPrinter
+	def      string
+	nextID   int
+	versions []ipp.Version
+}
+
+type standInPrinter struct {
+	name  string
+	model string
+	media []ipp.Attribute
+	jobs  []standInJob
+}
+
+type standInJob struct {
+	id     int
+	name   string
+	user   string
+	format string
+	state  int
+	data   []byte
+}
+
+func (s *ippStandIn) printer(r *http.Request, req *ipp.Message) *standInPrinter {
+	if s.plain {
+		return s.printers[0]
+	}
+	uri, _ := req.Attr(ipp.TagOperationGroup, "printer-uri")
+	u, err := url.Parse(uri.Text())
+	if err != nil {
+		return nil
+	}
+	name := strings.TrimPrefix(u.Path, "/printers/")
+	for _, p := range s.printers {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *ippStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req ipp.Message
+	if err := req.Decode(r.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.versions = append(s.versions, req.Version)
+	resp := s.handle(r, &req, data)
+	w.Header().Set("Content-Type", "application/ipp")
+	_ = resp.Encode(w)
+}
+
+func (s *ippStandIn) handle(r *http.Request, req *ipp.Message, data []byte) *ipp.Message {
+	if s.only11 && req.Version != ipp.Version11 {
+		return ipp.NewResponse(req, ipp.StatusVersionNotSupported)
+	}
+	switch req.Op() {
+	case ipp.OpCupsGetPrinters, ipp.OpCupsGetDefault, ipp.OpCupsSetDefault:
+		if s.plain {
+			return ipp.NewResponse(req, ipp.StatusOperationNotSupported)
+		}
+	}
+	resp := ipp.NewResponse(req, ipp.StatusOK)
+	switch req.Op() {
+	case ipp.OpCupsGetPrinters:
+		for _, p := range s.printers {
+			g := &ipp.Group{Tag: ipp.TagPrinterGroup}
+			g.Add(
+				ipp.MakeAttribute("printer-name", ipp.TagName, ipp.String(p.name)),
+				ipp.MakeAttribute("printer-uri-supported", ipp.TagURI, ipp.String("ipp://"+r.Host+"/printers/"+p.name)),
+			)
+			resp.Groups = append(resp.Groups, g)
+		}
+		return resp
+	case ipp.OpCupsGetDefault:
+		resp.Group(ipp.TagPrinterGroup).Add(ipp.MakeAttribute("printer-name", ipp.TagName, ipp.String(s.def)))
+		return resp
+	}
+	p := s.printer(r, req)
+	if p == nil {
+		return ipp.NewResponse(req, ipp.StatusNotFound)
+	}
+	switch req.Op() {
+	case ipp.OpCupsSetDefault:
+		s.def = p.name
+	case ipp.OpGetPrinterAttributes:
+		g := resp.Group(ipp.TagPrinterGroup)
+		g.Add(
+			ipp.MakeAttribute("printer-name", ipp.TagName, ipp.String(p.name)),
+			ipp.MakeAttribute("printer-make-and-model", ipp.TagText, ipp.String(p.model)),
+		)
+		g.Add(p.media...)
+	case ipp.OpPrintJob:
+		attr := func(name string) string {
+			a, _ := req.Attr(ipp.TagOperationGroup, name)
+			return a.Text()
+		}
+		s.nextID++
+		p.jobs = append(p.jobs, standInJob{
+			id:     s.nextID,
+			name:   attr("job-name"),
+			user:   attr("requesting-user-name"),
+			format: attr("document-format"),
+			state:  ipp.JobPending,
+			data:   data,
+		})
+		resp.Group(ipp.TagJobGroup).Add(
+			ipp.MakeAttribute("job-id", ipp.TagInteger, ipp.Integer(s.nextID)),
+			ipp.MakeAttribute("job-state", ipp.TagEnum, ipp.Integer(ipp.JobPending)),
+		)
+	case ipp.OpGetJobs:
+		for _, j := range p.jobs {
+			g := &ipp.Group{Tag: ipp.TagJobGroup}
+			g.Add(
+				ipp.MakeAttribute("job-id", ipp.TagInteger, ipp.Integer(j.id)),
+				ipp.MakeAttribute("job-name", ipp.TagName, ipp.String(j.name)),
+				ipp.MakeAttribute("job-originating-user-name", ipp.TagName, ipp.String(j.user)),
+				ipp.MakeAttribute("document-format", ipp.TagMimeType, ipp.String(j.format)),
+				ipp.MakeAttribute("job-state", ipp.TagEnum, ipp.Integer(j.state)),
+				ipp.MakeAttribute("job-state-reasons", ipp.TagKeyword, ipp.String("job-incoming"), ipp.String("job-printing")),
+				ipp.MakeAttribute("time-at-creation", ipp.TagInteger, ipp.Integer(1700000000)),
+			)
+			resp.Groups = append(resp.Groups, g)
+		}
+	case ipp.OpCancelJob:
+		id, _ := req.Attr(ipp.TagOperationGroup, "job-id")
+		for i, j := range p.jobs {
+			if j.id == id.Int() {
+				p.jobs = append(p.jobs[:i], p.jobs[i+1:]...)
+				return resp
+			}
+		}
+		return ipp.NewResponse(req, ipp.StatusNotFound)
+	default:
+		return ipp.NewResponse(req, ipp.StatusOperationNotSupported)
+	}
+	return resp
+}
+
+func mediaCol(key string, x, y, margin int) ipp.Data {
+	c := ipp.Collection{
+		ipp.MakeAttribute("media-size", ipp.TagBeginCollection, ipp.Collection{
+			ipp.MakeAttribute("x-dimension", ipp.TagInteger, ipp.Integer(x)),
+			ipp.MakeAttribute("y-dimension", ipp.TagInteger, ipp.Integer(y)),
+		}),
+	}
+	for _, side := range []string{"bottom", "left", "right", "top"} {
+		c = append(c, ipp.MakeAttribute("media-"+side+"-margin", ipp.TagInteger, ipp.Integer(margin)))
+	}
+	if key != "" {
+		c = append(c, ipp.MakeAttribute("media-key", ipp.TagKeyword, ipp.String(key)))
+	}
+	return c
+}
+
+func newIPPStandIn(t *testing.T) (*ippStandIn, *IPPSpooler) {
+	standIn := &ippStandIn{
+		def: "Office",
+		printers: []*standInPrinter{
+			{
+				name:  "Office",
+				model: "HP LaserJet M404",
+				media: []ipp.Attribute{
+					ipp.MakeAttribute("media-col-database", ipp.TagBeginCollection,
+						mediaCol("iso_a4_210x297mm", 21000, 29700, 423),
+						mediaCol("", 10160, 15240, 0),
+					),
+				},
+			},
+			{
+				name:  "Label",
+				model: "Zebra ZD421",
+				media: []ipp.Attribute{
+					ipp.MakeAttribute("media-supported", ipp.TagKeyword,
+						ipp.String("oe_4x6-label_4x6in"), ipp.String("custom_min_25.4x25.4mm"), ipp.String("auto")),
+				},
+			},
+		},
+	}
+	srv := httptest.NewServer(standIn)
+	t.Cleanup(srv.Close)
+	s := NewIPPSpooler(srv.URL)
+	s.UserName = "alice"
+	return standIn, s
+}
+
+func TestIPPSpooler_Names(t *testing.T) {
+	_, s := newIPPStandIn(t)
+
+	names, err := s.ReadNames()
+	if err != nil {
+		t.Fatalf("ReadNames failed: %v", err)
+	}
+	if want := []string{"Office", "Label"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadNames() = %q, want %q", names, want)
+	}
+	if name, err := s.GetDefault(); err != nil || name != "Office" {
+		t.Errorf("GetDefault() = %q, %v, want %q", name, err, "Office")
+	}
+	if err = s.SetDefault("Label"); err != nil {
+		t.Fatalf("SetDefault failed: %v", err)
+	}
+	if name, _ := s.GetDefault(); name != "Label" {
+		t.Errorf("GetDefault() after SetDefault = %q, want %q", name, "Label")
+	}
+	if _, err = s.Open("Missing"); !errors.Is(err, ErrPrinterNotFound) {
+		t.Errorf("Open(Missing) error = %v, want %v", err, ErrPrinterNotFound)
+	}
+}
+
+func TestIPPSpooler_Print(t *testing.T) {
+	standIn, s := newIPPStandIn(t)
+	previous := SetSpooler(s)
+	defer SetSpooler(previous)
+
+	p, err := Open("Office")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer closePrinter(p)
+
+	di, err := p.DriverInfo()
+	if err != nil || di.Name != "HP LaserJet M404" {
+		t.Errorf("DriverInfo() = %+v, %v", di, err)
+	}
+	job, err := p.StartDocument("invoice.pdf", "application/pdf")
+	if err != nil {
+		t.Fatalf("StartDocument failed: %v", err)
+	}
+	for _, chunk := range []string{"%PDF-1.7\n", "%%EOF\n"} {
+		if _, err = p.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err = p.EndDocument(); err != nil {
+		t.Fatalf("EndDocument failed: %v", err)
+	}
+
+	got := standIn.printers[0].jobs
+	if len(got) != 1 || got[0].name != "invoice.pdf" || got[0].user != "alice" ||
+		got[0].format != "application/pdf" || string(got[0].data) != "%PDF-1.7\n%%EOF\n" {
+		t.Fatalf("server jobs = %+v", got)
+	}
+	if job.ID != uint32(got[0].id) {
+		t.Errorf("Job ID after EndDocument = %d, want %d", job.ID, got[0].id)
+	}
+
+	jobs, err := p.Jobs()
+	if err != nil {
+		t.Fatalf("Jobs failed: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].JobID != uint32(got[0].id) || jobs[0].DocumentName != "invoice.pdf" ||
+		jobs[0].UserName != "alice" || jobs[0].Position != 1 || jobs[0].Status != "job-incoming, job-printing" ||
+		jobs[0].Submitted.Unix() != 1700000000 {
+		t.Errorf("Jobs() = %+v", jobs)
+	}
+
+	if err = p.CancelJob(jobs[0].JobID); err != nil {
+		t.Fatalf("CancelJob failed: %v", err)
+	}
+	if err = p.CancelJob(jobs[0].JobID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("second CancelJob error = %v, want %v", err, ErrJobNotFound)
+	}
+	if jobs, _ = p.Jobs(); len(jobs) != 0 {
+		t.Errorf("Jobs() after CancelJob = %+v", jobs)
+	}
+}
+
+func TestIPPSpooler_Forms(t *testing.T) {
+	_, s := newIPPStandIn(t)
+
+	h, err := s.Open("Office")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	forms, err := h.Forms()
+	if err != nil {
+		t.Fatalf("Forms failed: %v", err)
+	}
+	want := []FormInfo{
+		{
+			Name:          "iso_a4_210x297mm",
+			Size:          SIZE{Width: 210000, Height: 297000},
+			ImageableArea: Rect{Left: 4230, Top: 4230, Right: 210000 - 4230, Bottom: 297000 - 4230},
+		},
+		{
+			Name:          "custom_101.6x152.4mm",
+			Size:          SIZE{Width: 101600, Height: 152400},
+			ImageableArea: Rect{Right: 101600, Bottom: 152400},
+		},
+	}
+	if !reflect.DeepEqual(forms, want) {
+		t.Errorf("Forms() = %+v, want %+v", forms, want)
+	}
+
+	h, err = s.Open("Label")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if forms, err = h.Forms(); err != nil {
+		t.Fatalf("Forms failed: %v", err)
+	}
+	want = []FormInfo{
+		{
+			Name:          "oe_4x6-label_4x6in",
+			Size:          SIZE{Width: 101600, Height: 152400},
+			ImageableArea: Rect{Right: 101600, Bottom: 152400},
+		},
+		{
+			Name:          "custom_min_25.4x25.4mm",
+			Size:          SIZE{Width: 25400, Height: 25400},
+			ImageableArea: Rect{Right: 25400, Bottom: 25400},
+		},
+	}
+	if !reflect.DeepEqual(forms, want) {
+		t.Errorf("Forms() = %+v, want %+v", forms, want)
+	}
+}
+
+func TestIPPSpooler_PlainPrinter(t *testing.T) {
+	standIn, s := newIPPStandIn(t)
+	standIn.plain = true
+	standIn.only11 = true
+	s.URL += "/ipp/print"
+
+	names, err := s.ReadNames()
+	if err != nil {
+		t.Fatalf("ReadNames failed: %v", err)
+	}
+	if want := []string{"Office"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadNames() = %q, want %q", names, want)
+	}
+	if name, err := s.GetDefault(); err != nil || name != "Office" {
+		t.Errorf("GetDefault() = %q, %v, want %q", name, err, "Office")
+	}
+	if err = s.SetDefault("Office"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetDefault() error = %v, want %v", err, ErrUnsupported)
+	}
+	if _, err = s.Open("Office"); err != nil {
+		t.Errorf("Open failed: %v", err)
+	}
+	if first, last := standIn.versions[0], standIn.versions[len(standIn.versions)-1]; first != ipp.Version20 || last != ipp.Version11 {
+		t.Errorf("request versions = %v, want 2.0 first and 1.1 last", standIn.versions)
+	}
+}
+
+func TestIPPSpooler_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-release
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+	s := NewIPPSpooler(srv.URL)
+	s.Timeout = 50 * time.Millisecond
+	if _, err := s.ReadNames(); err == nil {
+		t.Error("ReadNames of a stalled server succeeded")
+	}
+}
+
+func TestIPPURLs(t *testing.T) {
+	tests := []struct {
+		url, uri, post string
+	}{
+		{"http://192.168.1.20/ipp/print", "ipp://192.168.1.20:80/ipp/print", "http://192.168.1.20:80/ipp/print"},
+		{"https://h/ipp/print", "ipps://h:443/ipp/print", "https://h:443/ipp/print"},
+		{"http://cups.local:631", "ipp://cups.local:631", "http://cups.local:631"},
+		{"http://[fe80::1]/ipp", "ipp://[fe80::1]:80/ipp", "http://[fe80::1]:80/ipp"},
+		{"ipp://cups.local/printers/Office", "ipp://cups.local/printers/Office", "http://cups.local:631/printers/Office"},
+		{"ipps://cups.local/printers/Office", "ipps://cups.local/printers/Office", "https://cups.local:631/printers/Office"},
+		{"ipp://cups.local:8631/printers/Office", "ipp://cups.local:8631/printers/Office", "http://cups.local:8631/printers/Office"},
+	}
+	for _, tt := range tests {
+		uri, err := ippURI(tt.url)
+		if err != nil || uri != tt.uri {
+			t.Errorf("ippURI(%q) = %q, %v, want %q", tt.url, uri, err, tt.uri)
+			continue
+		}
+		if post, err := httpURL(uri); err != nil || post != tt.post {
+			t.Errorf("httpURL(%q) = %q, %v, want %q", uri, post, err, tt.post)
+		}
+	}
+	if post, err := httpURL("http://192.168.1.20/ipp/print"); err != nil || post != "http://192.168.1.20/ipp/print" {
+		t.Errorf("httpURL of an http URL = %q, %v", post, err)
+	}
+	for _, bad := range []string{"lpd://h/q", "socket://h:9100"} {
+		if _, err := ippURI(bad); err == nil {
+			t.Errorf("ippURI(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestDocumentFormat(t *testing.T) {
+	tests := map[string]string{
+		"RAW":             "application/octet-stream",
+		"XPS_PASS":        "application/oxps",
+		"text":            "text/plain",
+		"application/pdf": "application/pdf",
+	}
+	for datatype, want := range tests {
+		if got := DocumentFormat(datatype); got != want {
+			t.Errorf("DocumentFormat(%q) = %q, want %q", datatype, got, want)
+		}
+	}
+}
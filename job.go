@@ -0,0 +1,142 @@
+package winprinters
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultJobPollInterval is how often Job.Wait polls the spooler when
+// Job.PollInterval is zero.
+const DefaultJobPollInterval = 500 * time.Millisecond
+
+// Job is a document submitted with Printer.StartDocument.
+type Job struct {
+	// ID is the ID of the job in the printer queue, as reported by
+	// Printer.Jobs. It is 0 until EndDocument returns when the spooler
+	// assigns it only once the document is complete.
+	ID         uint32
+	Name       string
+	DataType   string
+	OutputFile string // set by Printer.StartDocumentToFile
+
+	// PollInterval is how often Wait polls the spooler for the job
+	// status, or DefaultJobPollInterval when zero.
+	PollInterval time.Duration
+
+	p *Printer
+}
+
+// JobResult tells how a job ended.
+type JobResult int
+
+const (
+	// JobPending means Wait returned before the job ended.
+	JobPending JobResult = iota
+	// JobPrinted means the job printed or was sent to the printer.
+	JobPrinted
+	// JobDeleted means the job was deleted or canceled.
+	JobDeleted
+	// JobFailed means the job is stuck in an error, such as a driver
+	// error, until the user fixes it or deletes the job.
+	JobFailed
+	// JobGone means the job left the queue without Wait seeing how it
+	// ended. Spoolers that do not keep jobs once sent, such as raw socket
+	// printers, report all their jobs this way.
+	JobGone
+)
+
+func (r JobResult) String() string {
+	switch r {
+	case JobPending:
+		return "pending"
+	case JobPrinted:
+		return "printed"
+	case JobDeleted:
+		return "deleted"
+	case JobFailed:
+		return "failed"
+	case JobGone:
+		return "gone"
+	}
+	return "unknown"
+}
+
+// JobOutcome is the final state of a job returned by Job.Wait.
+type JobOutcome struct {
+	JobID        uint32
+	Result       JobResult
+	Status       JobStatus // last status seen
+	PagesPrinted uint32
+	TotalPages   uint32
+
+	// History holds every status Wait saw, in order and without repeats.
+	History []JobStatus
+}
+
+// Wait polls the spooler until the job ends or ctx is done, and returns its
+// outcome. A job in error, or blocked by a driver error, ends Wait with
+// JobFailed; Wait may be called again to keep tracking it once the error is
+// cleared. When ctx is done, Wait returns the outcome so far, with
+// JobPending, and ctx.Err().
+func (j *Job) Wait(ctx context.Context) (*JobOutcome, error) {
+	out := &JobOutcome{JobID: j.ID}
+	if j.ID == 0 {
+		return out, &JobError{Op: "Wait", Err: errors.New("job ID not known before EndDocument")}
+	}
+	interval := j.PollInterval
+	if interval <= 0 {
+		interval = DefaultJobPollInterval
+	}
+	seen := false
+	for {
+		d, err := j.p.Job(j.ID)
+		switch {
+		case errors.Is(err, ErrJobNotFound):
+			out.Result = goneResult(out.Status, seen)
+			return out, nil
+		case err != nil:
+			return out, err
+		}
+		s := d.StatusCode
+		if !seen || s != out.Status {
+			out.History = append(out.History, s)
+		}
+		seen = true
+		out.Status = s
+		out.PagesPrinted = d.PagesPrinted
+		out.TotalPages = d.TotalPages
+		switch {
+		case s.Printed() || s.Complete():
+			out.Result = JobPrinted
+			return out, nil
+		case s.Deleted():
+			out.Result = JobDeleted
+			return out, nil
+		case (s.InError() || s.BlockedDevQ()) && !s.Deleting():
+			out.Result = JobFailed
+			return out, nil
+		}
+		t := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return out, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// goneResult guesses how a job that left the queue ended from the last
+// status it was seen in.
+func goneResult(last JobStatus, seen bool) JobResult {
+	switch {
+	case !seen:
+		return JobGone
+	case last.Deleting():
+		return JobDeleted
+	case last.Printing() || last.Printed() || last.Complete():
+		return JobPrinted
+	}
+	return JobGone
+}
@@ -0,0 +1,198 @@
+package winprinters
+
+import (
+	"strings"
+	"time"
+)
+
+// JobDetails stores the JOB_INFO_2 and JOB_INFO_4 information about a print
+// job, for accounting and auditing.
+type JobDetails struct {
+	JobInfo
+	PrinterName    string
+	NotifyName     string // user notified when the job completes
+	PrintProcessor string
+	Parameters     string // print processor parameters
+	DriverName     string
+
+	// DevMode holds the document settings of the job, such as copies,
+	// duplex and color, or is nil when the spooler reports none.
+	DevMode *DevMode
+
+	// StartTime and UntilTime bound the time of day, in UTC, when the job
+	// may print. Both are zero when it may print at any time.
+	StartTime time.Duration
+	UntilTime time.Duration
+
+	Size    uint64        // size of the job in bytes
+	Elapsed time.Duration // time since the job started printing
+}
+
+// Copies returns the number of copies requested by the job DevMode, or 1.
+func (j *JobDetails) Copies() int {
+	if j.DevMode != nil {
+		if n, ok := j.DevMode.GetCopies(); ok && n > 0 {
+			return int(n)
+		}
+	}
+	return 1
+}
+
+// jobInfo1Stride returns the size of a JOB_INFO_1 structure in a buffer of
+// sb: the JobId DWORD, aligned to a pointer, six pointers (pPrinterName,
+// pMachineName, pUserName, pDocument, pDatatype and pStatus), then Status,
+// Priority, Position, TotalPages, PagesPrinted and the Submitted SYSTEMTIME.
+func jobInfo1Stride(sb spoolBuffer) int {
+	return sb.align(sb.ptr + 6*sb.ptr + 5*4 + 16)
+}
+
+// decodeJobInfo1 decodes count JOB_INFO_1 structures from sb.
+func decodeJobInfo1(sb spoolBuffer, count int) ([]JobInfo, error) {
+	stride := jobInfo1Stride(sb)
+	if err := sb.check(0, count*stride); err != nil {
+		return nil, err
+	}
+	jobs := make([]JobInfo, 0, count)
+	for i := 0; i < count; i++ {
+		off := i * stride
+		ptr := func(n int) int { return off + sb.ptr + n*sb.ptr }
+		d := ptr(6)
+		j := JobInfo{
+			JobID:        sb.uint32(off),
+			StatusCode:   JobStatus(sb.uint32(d)),
+			Priority:     sb.uint32(d + 4),
+			Position:     sb.uint32(d + 8),
+			TotalPages:   sb.uint32(d + 12),
+			PagesPrinted: sb.uint32(d + 16),
+			Submitted:    sb.systemTime(d + 20),
+		}
+		for n, s := range []*string{&j.UserMachineName, &j.UserName, &j.DocumentName, &j.DataType, &j.Status} {
+			v, err := sb.string(ptr(n + 1))
+			if err != nil {
+				return nil, err
+			}
+			*s = v
+		}
+		if strings.TrimSpace(j.Status) == "" {
+			j.Status = j.StatusCode.String()
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+// jobInfoStride returns the size of a JOB_INFO_2 or JOB_INFO_4 structure in
+// a buffer of sb, level 4 adding the SizeHigh field.
+//
+// Both start with the JobId DWORD, aligned to a pointer, followed by
+// twelve pointers (pPrinterName, pMachineName, pUserName, pDocument,
+// pNotifyName, pDatatype, pPrintProcessor, pParameters, pDriverName,
+// pDevMode, pStatus and pSecurityDescriptor) and then Status, Priority,
+// Position, StartTime, UntilTime, TotalPages, Size, the Submitted
+// SYSTEMTIME, Time, PagesPrinted and, for level 4, SizeHigh.
+func jobInfoStride(sb spoolBuffer, level int) int {
+	n := sb.ptr + 12*sb.ptr + 7*4 + 16 + 2*4
+	if level == 4 {
+		n += 4
+	}
+	return sb.align(n)
+}
+
+// decodeJobInfo decodes count JOB_INFO_2 or JOB_INFO_4 structures from sb.
+func decodeJobInfo(sb spoolBuffer, level, count int) ([]JobDetails, error) {
+	stride := jobInfoStride(sb, level)
+	if err := sb.check(0, count*stride); err != nil {
+		return nil, err
+	}
+	jobs := make([]JobDetails, 0, count)
+	for i := 0; i < count; i++ {
+		off := i * stride
+		ptr := func(n int) int { return off + sb.ptr + n*sb.ptr }
+		d := ptr(12)
+		j := JobDetails{
+			JobInfo: JobInfo{
+				JobID:        sb.uint32(off),
+				StatusCode:   JobStatus(sb.uint32(d)),
+				Priority:     sb.uint32(d + 4),
+				Position:     sb.uint32(d + 8),
+				TotalPages:   sb.uint32(d + 20),
+				PagesPrinted: sb.uint32(d + 48),
+				Submitted:    sb.systemTime(d + 28),
+			},
+			StartTime: time.Duration(sb.uint32(d+12)) * time.Minute,
+			UntilTime: time.Duration(sb.uint32(d+16)) * time.Minute,
+			Size:      uint64(sb.uint32(d + 24)),
+			Elapsed:   time.Duration(sb.uint32(d+44)) * time.Millisecond,
+		}
+		if level == 4 {
+			j.Size |= uint64(sb.uint32(d+52)) << 32
+		}
+		for _, f := range []struct {
+			n int
+			s *string
+		}{
+			{0, &j.PrinterName},
+			{1, &j.UserMachineName},
+			{2, &j.UserName},
+			{3, &j.DocumentName},
+			{4, &j.NotifyName},
+			{5, &j.DataType},
+			{6, &j.PrintProcessor},
+			{7, &j.Parameters},
+			{8, &j.DriverName},
+			{10, &j.Status},
+		} {
+			s, err := sb.string(ptr(f.n))
+			if err != nil {
+				return nil, err
+			}
+			*f.s = s
+		}
+		dm, err := sb.devMode(ptr(9))
+		if err != nil {
+			return nil, err
+		}
+		j.DevMode = dm
+		if strings.TrimSpace(j.Status) == "" {
+			j.Status = j.StatusCode.String()
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+// JobsDetailed returns the JOB_INFO_2 and JOB_INFO_4 information about all
+// print jobs on this printer. Spoolers that only report JobInfo fill in
+// that part of each JobDetails.
+func (p *Printer) JobsDetailed() ([]JobDetails, error) {
+	if d, ok := p.h.(JobDetailer); ok {
+		return d.JobsDetailed()
+	}
+	jobs, err := p.h.Jobs()
+	if err != nil {
+		return nil, err
+	}
+	var details []JobDetails
+	for _, j := range jobs {
+		details = append(details, JobDetails{JobInfo: j})
+	}
+	return details, nil
+}
+
+// Job returns the details of the job with the given ID.
+func (p *Printer) Job(jobID uint32) (*JobDetails, error) {
+	if d, ok := p.h.(JobDetailer); ok {
+		j, err := d.Job(jobID)
+		return j, jobError("Job", jobID, err)
+	}
+	jobs, err := p.JobsDetailed()
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs {
+		if jobs[i].JobID == jobID {
+			return &jobs[i], nil
+		}
+	}
+	return nil, &JobError{Op: "Job", JobID: jobID, Err: ErrJobNotFound}
+}
@@ -0,0 +1,141 @@
+package winprinters
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// putJobInfo writes j as the i-th JOB_INFO_2 or JOB_INFO_4 structure of w.
+func putJobInfo(w *spoolWriter, level, i int, j *JobDetails) {
+	off := i * jobInfoStride(w.sb, level)
+	ptr := func(n int) int { return off + w.sb.ptr + n*w.sb.ptr }
+	w.uint32(off, j.JobID)
+	for n, s := range []string{j.PrinterName, j.UserMachineName, j.UserName, j.DocumentName, j.NotifyName,
+		j.DataType, j.PrintProcessor, j.Parameters, j.DriverName} {
+		if s != "" {
+			w.string(ptr(n), s)
+		}
+	}
+	if j.Status != "" {
+		w.string(ptr(10), j.Status)
+	}
+	if j.DevMode != nil {
+		w.devMode(ptr(9), j.DevMode, 512)
+	}
+	d := ptr(12)
+	for n, v := range []uint32{uint32(j.StatusCode), j.Priority, j.Position, uint32(j.StartTime / time.Minute),
+		uint32(j.UntilTime / time.Minute), j.TotalPages, uint32(j.Size)} {
+		w.uint32(d+4*n, v)
+	}
+	w.systemTime(d+28, j.Submitted)
+	w.uint32(d+44, uint32(j.Elapsed/time.Millisecond))
+	w.uint32(d+48, j.PagesPrinted)
+	if level == 4 {
+		w.uint32(d+52, uint32(j.Size>>32))
+	}
+}
+
+func TestDecodeJobInfo(t *testing.T) {
+	dm := new(DevMode)
+	dm.SetCopies(3)
+	dm.SetDuplex(DMDUP_VERTICAL)
+	dm.SetColor(DMCOLOR_MONOCHROME)
+	dm.SetFormName("A4")
+	want := []JobDetails{
+		{
+			JobInfo: JobInfo{
+				JobID: 41, UserMachineName: `\\WS-017`, UserName: "lin.wei", DocumentName: "月度报表.xlsx",
+				DataType: "NT EMF 1.008", Status: "Printing, Retained", StatusCode: JOB_STATUS_PRINTING | JOB_STATUS_RETAINED,
+				Priority: 1, Position: 1, TotalPages: 12, PagesPrinted: 5,
+				Submitted: time.Date(2024, 3, 8, 9, 30, 15, 250e6, time.UTC),
+			},
+			PrinterName: "Office", NotifyName: "lin.wei", PrintProcessor: "winprint", Parameters: "",
+			DriverName: "HP Universal Printing PCL 6", DevMode: dm,
+			StartTime: 22 * time.Hour, UntilTime: 6 * time.Hour,
+			Size: 5<<32 | 1234, Elapsed: 83 * time.Second,
+		},
+		{
+			JobInfo: JobInfo{
+				JobID: 42, UserName: "svc-label", DocumentName: "label", DataType: "RAW",
+				Status: "Paused", StatusCode: JOB_STATUS_PAUSED, Priority: 99, Position: 2, TotalPages: 1,
+				Submitted: time.Date(2024, 3, 8, 9, 31, 0, 0, time.UTC),
+			},
+			PrintProcessor: "winprint", Size: 2048,
+		},
+	}
+	for _, level := range []int{2, 4} {
+		for _, ptr := range []int{4, 8} {
+			w := newSpoolWriter(ptr, len(want)*jobInfoStride(spoolBuffer{ptr: ptr}, level))
+			for i := range want {
+				putJobInfo(w, level, i, &want[i])
+			}
+			got, err := decodeJobInfo(w.sb, level, len(want))
+			if err != nil {
+				t.Fatalf("level %d, %d-byte pointers: decodeJobInfo failed: %v", level, ptr, err)
+			}
+			w2 := want
+			if level == 2 {
+				w2 = append([]JobDetails(nil), want...)
+				w2[0].Size &= 0xFFFFFFFF
+			}
+			if !reflect.DeepEqual(got, w2) {
+				t.Errorf("level %d, %d-byte pointers: decodeJobInfo =\n%+v\nwant\n%+v", level, ptr, got, w2)
+			}
+			if got[0].Copies() != 3 || got[1].Copies() != 1 {
+				t.Errorf("Copies() = %d, %d, want 3, 1", got[0].Copies(), got[1].Copies())
+			}
+			if form, ok := got[0].DevMode.GetFormName(); !ok || form != "A4" {
+				t.Errorf("DevMode form name = %q, %v", form, ok)
+			}
+
+			if _, err = decodeJobInfo(w.sb, level, len(want)+40); err == nil {
+				t.Errorf("level %d, %d-byte pointers: decoding past the buffer succeeded", level, ptr)
+			}
+		}
+	}
+	if got := jobInfoStride(spoolBuffer{ptr: 8}, 2); got != 160 {
+		t.Errorf("64-bit JOB_INFO_2 size = %d, want 160", got)
+	}
+	if got := jobInfoStride(spoolBuffer{ptr: 4}, 4); got != 108 {
+		t.Errorf("32-bit JOB_INFO_4 size = %d, want 108", got)
+	}
+}
+
+func TestDecodeJobInfo_Status(t *testing.T) {
+	w := newSpoolWriter(8, jobInfoStride(spoolBuffer{ptr: 8}, 2))
+	putJobInfo(w, 2, 0, &JobDetails{JobInfo: JobInfo{JobID: 7, StatusCode: JOB_STATUS_PAUSED | JOB_STATUS_ERROR}})
+	jobs, err := decodeJobInfo(w.sb, 2, 1)
+	if err != nil || len(jobs) != 1 || jobs[0].Status != "Paused, Error" || jobs[0].DevMode != nil {
+		t.Errorf("decodeJobInfo = %+v, %v", jobs, err)
+	}
+
+	// Some drivers report a blank status string.
+	putJobInfo(w, 2, 0, &JobDetails{JobInfo: JobInfo{JobID: 7, Status: " ", StatusCode: JOB_STATUS_PAUSED}})
+	if jobs, err = decodeJobInfo(w.sb, 2, 1); err != nil || jobs[0].Status != "Paused" {
+		t.Errorf("decodeJobInfo of a blank status = %+v, %v", jobs, err)
+	}
+}
+
+func TestPrinter_JobsDetailed(t *testing.T) {
+	newTestSpooler(t)
+	p, err := Open("Label")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer closePrinter(p)
+
+	jobs, err := p.JobsDetailed()
+	if err != nil || len(jobs) != 1 || jobs[0].DocumentName != "queued" {
+		t.Fatalf("JobsDetailed() = %+v, %v", jobs, err)
+	}
+	j, err := p.Job(jobs[0].JobID)
+	if err != nil || !reflect.DeepEqual(*j, jobs[0]) {
+		t.Errorf("Job(%d) = %+v, %v", jobs[0].JobID, j, err)
+	}
+	var je *JobError
+	if _, err = p.Job(1000); !errors.As(err, &je) || je.JobID != 1000 || !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Job(1000) = %v, want ErrJobNotFound", err)
+	}
+}
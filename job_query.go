@@ -0,0 +1,146 @@
+package winprinters
+
+import (
+	"strings"
+)
+
+// DefaultJobPageSize is how many jobs a JobIterator asks the spooler for at
+// a time when JobQuery.PageSize is zero.
+const DefaultJobPageSize = 256
+
+// JobPager is implemented by PrinterHandles that can list a part of the
+// queue without reading all of it.
+type JobPager interface {
+	// JobsPage returns at most n jobs from the zero-based queue index
+	// first, and fewer only at the end of the queue.
+	JobsPage(first, n uint32) ([]JobInfo, error)
+}
+
+// JobQuery selects the jobs a JobIterator returns. The zero JobQuery
+// returns the whole queue.
+type JobQuery struct {
+	First    uint32 // zero-based index in the queue of the first job
+	PageSize uint32 // jobs read from the spooler at a time, DefaultJobPageSize when zero
+
+	UserName     string    // only jobs of this user, ignoring case
+	DocumentName string    // only jobs whose document name contains this, ignoring case
+	Status       JobStatus // only jobs with any of these status bits
+}
+
+func (q *JobQuery) match(j *JobInfo) bool {
+	if q.UserName != "" && !strings.EqualFold(j.UserName, q.UserName) {
+		return false
+	}
+	if q.DocumentName != "" && !strings.Contains(strings.ToLower(j.DocumentName), strings.ToLower(q.DocumentName)) {
+		return false
+	}
+	return q.Status == 0 || j.StatusCode&q.Status != 0
+}
+
+// JobsPage returns at most n jobs from the zero-based queue index first.
+// Spoolers that cannot read a part of the queue read all of it.
+func (p *Printer) JobsPage(first, n uint32) ([]JobInfo, error) {
+	if pg, ok := p.h.(JobPager); ok {
+		return pg.JobsPage(first, n)
+	}
+	jobs, err := p.h.Jobs()
+	if err != nil {
+		return nil, err
+	}
+	if first >= uint32(len(jobs)) {
+		return nil, nil
+	}
+	jobs = jobs[first:]
+	if n < uint32(len(jobs)) {
+		jobs = jobs[:n]
+	}
+	return jobs, nil
+}
+
+// QueryJobs returns an iterator over the jobs q selects, reading the queue
+// a page at a time:
+//
+//	it := p.QueryJobs(winprinters.JobQuery{UserName: "lin.wei"})
+//	for it.Next() {
+//		job := it.Job()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// The queue changes while it is read: no job is returned twice, but jobs
+// added meanwhile may be missed, and so may jobs moving up the queue as
+// earlier jobs leave it.
+func (p *Printer) QueryJobs(q JobQuery) *JobIterator {
+	if q.PageSize == 0 {
+		q.PageSize = DefaultJobPageSize
+	}
+	return &JobIterator{p: p, q: q, next: q.First, seen: make(map[uint32]bool)}
+}
+
+// FindJobs returns all the jobs q selects.
+func (p *Printer) FindJobs(q JobQuery) ([]JobInfo, error) {
+	var jobs []JobInfo
+	it := p.QueryJobs(q)
+	for it.Next() {
+		jobs = append(jobs, it.Job())
+	}
+	return jobs, it.Err()
+}
+
+// JobIterator walks the jobs selected by a JobQuery, see Printer.QueryJobs.
+type JobIterator struct {
+	p    *Printer
+	q    JobQuery
+	next uint32 // queue index of the next page
+	page []JobInfo
+	i    int
+	seen map[uint32]bool
+	job  JobInfo
+	done bool
+	err  error
+}
+
+// Next advances to the next job, reading the next page of the queue when
+// needed. It returns false at the end of the queue or on an error.
+func (it *JobIterator) Next() bool {
+	for {
+		for it.i < len(it.page) {
+			j := &it.page[it.i]
+			it.i++
+			if it.seen[j.JobID] {
+				continue
+			}
+			it.seen[j.JobID] = true
+			if it.q.match(j) {
+				it.job = *j
+				return true
+			}
+		}
+		if it.done || it.err != nil {
+			return false
+		}
+		it.page, it.i = nil, 0
+		page, err := it.p.JobsPage(it.next, it.q.PageSize)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if uint32(len(page)) < it.q.PageSize {
+			it.done = true
+		}
+		it.page = page
+		it.next += uint32(len(page))
+	}
+}
+
+// Job returns the current job.
+func (it *JobIterator) Job() JobInfo {
+	return it.job
+}
+
+// Err returns the error that ended the iteration, if any.
+func (it *JobIterator) Err() error {
+	return it.err
+}
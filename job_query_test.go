@@ -0,0 +1,256 @@
+package winprinters
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// putJobInfo1 writes j as the i-th JOB_INFO_1 structure of w.
+func putJobInfo1(w *spoolWriter, i int, j *JobInfo) {
+	off := i * jobInfo1Stride(w.sb)
+	ptr := func(n int) int { return off + w.sb.ptr + n*w.sb.ptr }
+	w.uint32(off, j.JobID)
+	w.string(ptr(0), "Office")
+	for n, s := range []string{j.UserMachineName, j.UserName, j.DocumentName, j.DataType, j.Status} {
+		if s != "" {
+			w.string(ptr(n+1), s)
+		}
+	}
+	d := ptr(6)
+	for n, v := range []uint32{uint32(j.StatusCode), j.Priority, j.Position, j.TotalPages, j.PagesPrinted} {
+		w.uint32(d+4*n, v)
+	}
+	w.systemTime(d+20, j.Submitted)
+}
+
+// jobInfo1Buffer returns jobs as a synthetic EnumJobs level 1 buffer.
+func jobInfo1Buffer(ptr int, jobs []JobInfo) spoolBuffer {
+	w := newSpoolWriter(ptr, len(jobs)*jobInfo1Stride(spoolBuffer{ptr: ptr}))
+	for i := range jobs {
+		putJobInfo1(w, i, &jobs[i])
+	}
+	return w.sb
+}
+
+// syntheticJobs returns n jobs of three users, every tenth one paused and
+// every hundredth one in error.
+func syntheticJobs(n int) []JobInfo {
+	users := []string{"lin.wei", "svc-label", "Ana"}
+	submitted := time.Date(2024, 3, 8, 9, 0, 0, 0, time.UTC)
+	jobs := make([]JobInfo, n)
+	for i := range jobs {
+		j := JobInfo{
+			JobID:           uint32(1000 + i),
+			UserMachineName: `\\WS-017`,
+			UserName:        users[i%len(users)],
+			DocumentName:    fmt.Sprintf("invoice-%05d.pdf", i),
+			DataType:        "RAW",
+			Priority:        1,
+			Position:        uint32(i + 1),
+			TotalPages:      uint32(i%7 + 1),
+			Submitted:       submitted.Add(time.Duration(i) * time.Second),
+		}
+		switch {
+		case i%100 == 0:
+			j.StatusCode = JOB_STATUS_ERROR | JOB_STATUS_PRINTING
+		case i%10 == 0:
+			j.StatusCode = JOB_STATUS_PAUSED
+		}
+		j.Status = j.StatusCode.String()
+		jobs[i] = j
+	}
+	return jobs
+}
+
+func TestDecodeJobInfo1(t *testing.T) {
+	want := syntheticJobs(3000)
+	for _, ptr := range []int{4, 8} {
+		got, err := decodeJobInfo1(jobInfo1Buffer(ptr, want), len(want))
+		if err != nil {
+			t.Fatalf("%d-byte pointers: decodeJobInfo1 failed: %v", ptr, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			for i := range want {
+				if !reflect.DeepEqual(got[i], want[i]) {
+					t.Fatalf("%d-byte pointers: job %d = %+v, want %+v", ptr, i, got[i], want[i])
+				}
+			}
+			t.Fatalf("%d-byte pointers: decoded %d jobs, want %d", ptr, len(got), len(want))
+		}
+
+		sb := jobInfo1Buffer(ptr, want[:2])
+		sb.b = sb.b[:jobInfo1Stride(sb)+8]
+		if _, err = decodeJobInfo1(sb, 2); err == nil {
+			t.Errorf("%d-byte pointers: decoding a truncated buffer succeeded", ptr)
+		}
+	}
+
+	// An empty status string is filled in from the status bits.
+	sb := jobInfo1Buffer(8, []JobInfo{{JobID: 7, StatusCode: JOB_STATUS_PAUSED}})
+	if got, err := decodeJobInfo1(sb, 1); err != nil || got[0].Status != "Paused" {
+		t.Errorf("decodeJobInfo1 status = %+v, %v", got, err)
+	}
+}
+
+// bufferPager serves a queue the way EnumJobs does, through synthetic
+// JOB_INFO_1 buffers.
+type bufferPager struct {
+	PrinterHandle
+	ptr   int
+	jobs  []JobInfo
+	pages []uint32 // first of every JobsPage call
+}
+
+func (h *bufferPager) JobsPage(first, n uint32) ([]JobInfo, error) {
+	h.pages = append(h.pages, first)
+	if first >= uint32(len(h.jobs)) {
+		return nil, nil
+	}
+	jobs := h.jobs[first:]
+	if n < uint32(len(jobs)) {
+		jobs = jobs[:n]
+	}
+	return decodeJobInfo1(jobInfo1Buffer(h.ptr, jobs), len(jobs))
+}
+
+func TestPrinter_QueryJobs(t *testing.T) {
+	all := syntheticJobs(5000)
+	ids := func(jobs []JobInfo) []uint32 {
+		var ids []uint32
+		for _, j := range jobs {
+			ids = append(ids, j.JobID)
+		}
+		return ids
+	}
+	filter := func(keep func(i int, j JobInfo) bool) []uint32 {
+		var ids []uint32
+		for i, j := range all {
+			if keep(i, j) {
+				ids = append(ids, j.JobID)
+			}
+		}
+		return ids
+	}
+	tests := []struct {
+		name  string
+		q     JobQuery
+		want  []uint32
+		pages []uint32
+	}{
+		{
+			name:  "all",
+			want:  ids(all),
+			pages: []uint32{0, 256, 512, 768, 1024, 1280, 1536, 1792, 2048, 2304, 2560, 2816, 3072, 3328, 3584, 3840, 4096, 4352, 4608, 4864},
+		},
+		{
+			name:  "exact pages",
+			q:     JobQuery{PageSize: 1000},
+			want:  ids(all),
+			pages: []uint32{0, 1000, 2000, 3000, 4000, 5000},
+		},
+		{
+			name:  "first",
+			q:     JobQuery{First: 4990, PageSize: 4},
+			want:  ids(all[4990:]),
+			pages: []uint32{4990, 4994, 4998},
+		},
+		{
+			name:  "past the end",
+			q:     JobQuery{First: 6000},
+			pages: []uint32{6000},
+		},
+		{
+			name: "user",
+			q:    JobQuery{UserName: "ANA", PageSize: 2500},
+			want: filter(func(i int, j JobInfo) bool { return i%3 == 2 }),
+		},
+		{
+			name: "document",
+			q:    JobQuery{DocumentName: "Invoice-049", PageSize: 2500},
+			want: filter(func(i int, j JobInfo) bool { return i >= 4900 && i < 5000 }),
+		},
+		{
+			name: "status",
+			q:    JobQuery{Status: JOB_STATUS_ERROR | JOB_STATUS_USER_INTERVENTION, PageSize: 2500},
+			want: filter(func(i int, j JobInfo) bool { return i%100 == 0 }),
+		},
+		{
+			name: "combined",
+			q:    JobQuery{UserName: "lin.wei", Status: JOB_STATUS_PAUSED, PageSize: 2500},
+			want: filter(func(i int, j JobInfo) bool { return i%3 == 0 && i%10 == 0 && i%100 != 0 }),
+		},
+	}
+	for _, tt := range tests {
+		for _, ptr := range []int{4, 8} {
+			t.Run(fmt.Sprintf("%s/%d-byte pointers", tt.name, ptr), func(t *testing.T) {
+				h := &bufferPager{ptr: ptr, jobs: all}
+				got, err := NewPrinter(h).FindJobs(tt.q)
+				if err != nil {
+					t.Fatalf("FindJobs failed: %v", err)
+				}
+				if !reflect.DeepEqual(ids(got), tt.want) {
+					t.Errorf("FindJobs returned %d jobs, want %d", len(got), len(tt.want))
+				}
+				if tt.pages != nil && !reflect.DeepEqual(h.pages, tt.pages) {
+					t.Errorf("JobsPage calls at %v, want %v", h.pages, tt.pages)
+				}
+			})
+		}
+	}
+}
+
+func TestPrinter_QueryJobsChangingQueue(t *testing.T) {
+	h := &bufferPager{ptr: 8, jobs: syntheticJobs(10)}
+	it := NewPrinter(h).QueryJobs(JobQuery{PageSize: 4})
+	var got []uint32
+	for it.Next() {
+		got = append(got, it.Job().JobID)
+		if len(got) == 4 {
+			// A job added at the front moves the queue back by one.
+			h.jobs = append([]JobInfo{{JobID: 999}}, h.jobs...)
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []uint32{1000, 1001, 1002, 1003, 1004, 1005, 1006, 1007, 1008, 1009}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("jobs = %v, want %v", got, want)
+	}
+}
+
+func TestPrinter_QueryJobsFake(t *testing.T) {
+	fake := newTestSpooler(t)
+	for i := 0; i < 600; i++ {
+		user := "lin.wei"
+		if i%2 == 1 {
+			user = "svc-label"
+		}
+		if _, err := fake.AddJob("Label", JobInfo{UserName: user, DocumentName: fmt.Sprint("label ", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := Open("Label")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closePrinter(p)
+
+	jobs, err := p.FindJobs(JobQuery{UserName: "svc-label"})
+	if err != nil || len(jobs) != 300 || jobs[0].DocumentName != "label 1" || jobs[299].DocumentName != "label 599" {
+		t.Errorf("FindJobs() = %d jobs, %v", len(jobs), err)
+	}
+	page, err := p.JobsPage(599, 10)
+	if err != nil || len(page) != 2 || page[0].Position != 600 {
+		t.Errorf("JobsPage(599, 10) = %+v, %v", page, err)
+	}
+
+	fake.InjectError("Label", "Jobs", errors.New("access denied"))
+	it := p.QueryJobs(JobQuery{})
+	if it.Next() || it.Err() == nil {
+		t.Errorf("QueryJobs on a failing printer: Err() = %v", it.Err())
+	}
+}
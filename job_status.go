@@ -0,0 +1,245 @@
+package winprinters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JobStatus is a JOB_STATUS_* bitmask. The zero JobStatus is a job waiting
+// in the queue.
+type JobStatus uint32
+
+// jobStatusNames holds the JSON names of the JOB_STATUS_* flags, in bit
+// order.
+var jobStatusNames = []string{
+	"paused",
+	"error",
+	"deleting",
+	"spooling",
+	"printing",
+	"offline",
+	"paper-out",
+	"printed",
+	"deleted",
+	"blocked-devq",
+	"user-intervention",
+	"restart",
+	"complete",
+	"retained",
+	"rendering-locally",
+}
+
+// Paused reports whether the job is paused, JOB_STATUS_PAUSED.
+func (s JobStatus) Paused() bool { return s&JOB_STATUS_PAUSED != 0 }
+
+// InError reports whether the job failed to print, JOB_STATUS_ERROR.
+func (s JobStatus) InError() bool { return s&JOB_STATUS_ERROR != 0 }
+
+// Deleting reports whether the job is being deleted, JOB_STATUS_DELETING.
+func (s JobStatus) Deleting() bool { return s&JOB_STATUS_DELETING != 0 }
+
+// Spooling reports whether the job is still being written to the spooler,
+// JOB_STATUS_SPOOLING.
+func (s JobStatus) Spooling() bool { return s&JOB_STATUS_SPOOLING != 0 }
+
+// Printing reports whether the job is being sent to the printer,
+// JOB_STATUS_PRINTING.
+func (s JobStatus) Printing() bool { return s&JOB_STATUS_PRINTING != 0 }
+
+// Offline reports whether the printer of the job is offline,
+// JOB_STATUS_OFFLINE.
+func (s JobStatus) Offline() bool { return s&JOB_STATUS_OFFLINE != 0 }
+
+// PaperOut reports whether the printer of the job is out of paper,
+// JOB_STATUS_PAPEROUT.
+func (s JobStatus) PaperOut() bool { return s&JOB_STATUS_PAPEROUT != 0 }
+
+// Printed reports whether the job has printed, JOB_STATUS_PRINTED.
+func (s JobStatus) Printed() bool { return s&JOB_STATUS_PRINTED != 0 }
+
+// Deleted reports whether the job has been deleted, JOB_STATUS_DELETED.
+func (s JobStatus) Deleted() bool { return s&JOB_STATUS_DELETED != 0 }
+
+// BlockedDevQ reports whether the driver cannot print the job,
+// JOB_STATUS_BLOCKED_DEVQ.
+func (s JobStatus) BlockedDevQ() bool { return s&JOB_STATUS_BLOCKED_DEVQ != 0 }
+
+// UserIntervention reports whether the printer waits for someone to clear
+// an error, JOB_STATUS_USER_INTERVENTION.
+func (s JobStatus) UserIntervention() bool { return s&JOB_STATUS_USER_INTERVENTION != 0 }
+
+// Restarted reports whether the job has been restarted, JOB_STATUS_RESTART.
+func (s JobStatus) Restarted() bool { return s&JOB_STATUS_RESTART != 0 }
+
+// Complete reports whether the job has been sent to the printer in full,
+// JOB_STATUS_COMPLETE. The printer may not have printed it yet.
+func (s JobStatus) Complete() bool { return s&JOB_STATUS_COMPLETE != 0 }
+
+// Retained reports whether the job is kept in the queue after printing,
+// JOB_STATUS_RETAINED.
+func (s JobStatus) Retained() bool { return s&JOB_STATUS_RETAINED != 0 }
+
+// RenderingLocally reports whether the job is rendered on the client
+// rather than the print server, JOB_STATUS_RENDERING_LOCALLY.
+func (s JobStatus) RenderingLocally() bool { return s&JOB_STATUS_RENDERING_LOCALLY != 0 }
+
+// Terminal reports whether the spooler is done with the job: it has
+// printed, been sent to the printer or been deleted. Jobs in any other
+// status, including errors the user can clear, are still in progress.
+func (s JobStatus) Terminal() bool {
+	return s&(JOB_STATUS_PRINTED|JOB_STATUS_COMPLETE|JOB_STATUS_DELETED) != 0
+}
+
+// Flags splits s into its single bit flags, lowest first.
+func (s JobStatus) Flags() []JobStatus {
+	var flags []JobStatus
+	for bit := JobStatus(1); bit != 0 && bit <= s; bit <<= 1 {
+		if s&bit != 0 {
+			flags = append(flags, bit)
+		}
+	}
+	return flags
+}
+
+// Names returns the machine-readable names of the flags in s, such as
+// "paused" or "paper-out". Flags without a name are written in hex.
+func (s JobStatus) Names() []string {
+	names := []string{}
+	for _, f := range s.Flags() {
+		names = append(names, f.name())
+	}
+	return names
+}
+
+// name returns the name of the single flag s.
+func (s JobStatus) name() string {
+	for i, n := range jobStatusNames {
+		if s == 1<<i {
+			return n
+		}
+	}
+	return fmt.Sprintf("%#x", uint32(s))
+}
+
+// String describes s in English.
+func (s JobStatus) String() string {
+	return s.Text(JobStatusEnglish)
+}
+
+// Text describes s in the language of t. Flags t has no description for
+// are given by name.
+func (s JobStatus) Text(t *JobStatusText) string {
+	if s == 0 {
+		return t.Queued
+	}
+	var parts []string
+	for _, f := range s.Flags() {
+		d, ok := t.Flags[f]
+		if !ok {
+			d = f.name()
+		}
+		parts = append(parts, d)
+	}
+	return strings.Join(parts, t.Separator)
+}
+
+// MarshalJSON encodes s as an array of flag names, so it stays readable and
+// stable when flags are added.
+func (s JobStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Names())
+}
+
+// UnmarshalJSON decodes an array of flag names as written by MarshalJSON.
+// A plain number is accepted as well.
+func (s *JobStatus) UnmarshalJSON(b []byte) error {
+	var n uint32
+	if err := json.Unmarshal(b, &n); err == nil {
+		*s = JobStatus(n)
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return fmt.Errorf("winprinters: job status %s is neither a list of flags nor a number", b)
+	}
+	var status JobStatus
+	for _, name := range names {
+		f, err := parseJobStatusFlag(name)
+		if err != nil {
+			return err
+		}
+		status |= f
+	}
+	*s = status
+	return nil
+}
+
+func parseJobStatusFlag(name string) (JobStatus, error) {
+	for i, n := range jobStatusNames {
+		if n == name {
+			return 1 << i, nil
+		}
+	}
+	if n, err := strconv.ParseUint(name, 0, 32); err == nil {
+		return JobStatus(n), nil
+	}
+	return 0, fmt.Errorf("winprinters: unknown job status flag %q", name)
+}
+
+// JobStatusText holds the descriptions of job statuses in one language.
+// Applications can fill one in for further languages.
+type JobStatusText struct {
+	Queued    string               // description of the zero JobStatus
+	Separator string               // between the descriptions of flags
+	Flags     map[JobStatus]string // description of each flag
+}
+
+var (
+	// JobStatusEnglish describes job statuses in English, the way the
+	// Windows print queue window does.
+	JobStatusEnglish = &JobStatusText{
+		Queued:    "Queued",
+		Separator: ", ",
+		Flags: map[JobStatus]string{
+			JOB_STATUS_PAUSED:            "Paused",
+			JOB_STATUS_ERROR:             "Error",
+			JOB_STATUS_DELETING:          "Deleting",
+			JOB_STATUS_SPOOLING:          "Spooling",
+			JOB_STATUS_PRINTING:          "Printing",
+			JOB_STATUS_OFFLINE:           "Printer Offline",
+			JOB_STATUS_PAPEROUT:          "Out of Paper",
+			JOB_STATUS_PRINTED:           "Printed",
+			JOB_STATUS_DELETED:           "Deleted",
+			JOB_STATUS_BLOCKED_DEVQ:      "Driver Error",
+			JOB_STATUS_USER_INTERVENTION: "User Action Required",
+			JOB_STATUS_RESTART:           "Restarted",
+			JOB_STATUS_COMPLETE:          "Sent to Printer",
+			JOB_STATUS_RETAINED:          "Retained",
+			JOB_STATUS_RENDERING_LOCALLY: "Rendering on Client",
+		},
+	}
+
+	// JobStatusChinese describes job statuses in Simplified Chinese.
+	JobStatusChinese = &JobStatusText{
+		Queued:    "已排队",
+		Separator: "、",
+		Flags: map[JobStatus]string{
+			JOB_STATUS_PAUSED:            "已暂停",
+			JOB_STATUS_ERROR:             "错误",
+			JOB_STATUS_DELETING:          "正在删除",
+			JOB_STATUS_SPOOLING:          "正在后台处理",
+			JOB_STATUS_PRINTING:          "正在打印",
+			JOB_STATUS_OFFLINE:           "打印机脱机",
+			JOB_STATUS_PAPEROUT:          "缺纸",
+			JOB_STATUS_PRINTED:           "已打印",
+			JOB_STATUS_DELETED:           "已删除",
+			JOB_STATUS_BLOCKED_DEVQ:      "驱动程序错误",
+			JOB_STATUS_USER_INTERVENTION: "需要用户干预",
+			JOB_STATUS_RESTART:           "已重新启动",
+			JOB_STATUS_COMPLETE:          "已发送到打印机",
+			JOB_STATUS_RETAINED:          "已保留",
+			JOB_STATUS_RENDERING_LOCALLY: "正在客户端呈现",
+		},
+	}
+)
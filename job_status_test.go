@@ -0,0 +1,77 @@
+package winprinters
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJobStatus(t *testing.T) {
+	for _, tt := range []struct {
+		status        JobStatus
+		names         []string
+		english, zh   string
+		terminal      bool
+		paused, error bool
+	}{
+		{0, []string{}, "Queued", "已排队", false, false, false},
+		{JOB_STATUS_PAUSED | JOB_STATUS_ERROR, []string{"paused", "error"}, "Paused, Error", "已暂停、错误", false, true, true},
+		{JOB_STATUS_PRINTING | JOB_STATUS_PAPEROUT, []string{"printing", "paper-out"}, "Printing, Out of Paper", "正在打印、缺纸", false, false, false},
+		{JOB_STATUS_PRINTED | JOB_STATUS_COMPLETE, []string{"printed", "complete"}, "Printed, Sent to Printer", "已打印、已发送到打印机", true, false, false},
+		{JOB_STATUS_DELETED, []string{"deleted"}, "Deleted", "已删除", true, false, false},
+		{JOB_STATUS_DELETING, []string{"deleting"}, "Deleting", "正在删除", false, false, false},
+		{JOB_STATUS_RETAINED | 0x10000, []string{"retained", "0x10000"}, "Retained, 0x10000", "已保留、0x10000", false, false, false},
+	} {
+		if got := tt.status.Names(); !reflect.DeepEqual(got, tt.names) {
+			t.Errorf("%#x Names() = %q, want %q", uint32(tt.status), got, tt.names)
+		}
+		if got := tt.status.String(); got != tt.english {
+			t.Errorf("%#x String() = %q, want %q", uint32(tt.status), got, tt.english)
+		}
+		if got := tt.status.Text(JobStatusChinese); got != tt.zh {
+			t.Errorf("%#x Text(JobStatusChinese) = %q, want %q", uint32(tt.status), got, tt.zh)
+		}
+		if tt.status.Terminal() != tt.terminal || tt.status.Paused() != tt.paused || tt.status.InError() != tt.error {
+			t.Errorf("%#x Terminal, Paused, InError = %v, %v, %v", uint32(tt.status),
+				tt.status.Terminal(), tt.status.Paused(), tt.status.InError())
+		}
+
+		b, err := json.Marshal(tt.status)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var s JobStatus
+		if err = json.Unmarshal(b, &s); err != nil || s != tt.status {
+			t.Errorf("Unmarshal(%s) = %#x, %v", b, uint32(s), err)
+		}
+	}
+
+	// Every flag has a name and both descriptions.
+	for bit := JobStatus(JOB_STATUS_PAUSED); bit <= JOB_STATUS_RENDERING_LOCALLY; bit <<= 1 {
+		if bit.name()[0] == '0' || JobStatusEnglish.Flags[bit] == "" || JobStatusChinese.Flags[bit] == "" {
+			t.Errorf("flag %#x lacks a name or description", uint32(bit))
+		}
+	}
+}
+
+func TestJobStatus_JSON(t *testing.T) {
+	b, err := json.Marshal(JobInfo{JobID: 3, StatusCode: JOB_STATUS_SPOOLING | JOB_STATUS_USER_INTERVENTION})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var m map[string]interface{}
+	_ = json.Unmarshal(b, &m)
+	if want := []interface{}{"spooling", "user-intervention"}; !reflect.DeepEqual(m["StatusCode"], want) {
+		t.Errorf("StatusCode JSON = %v, want %v", m["StatusCode"], want)
+	}
+
+	var s JobStatus
+	if err = json.Unmarshal([]byte("4112"), &s); err != nil || s != JOB_STATUS_PRINTING|JOB_STATUS_COMPLETE {
+		t.Errorf("Unmarshal(4112) = %#x, %v", uint32(s), err)
+	}
+	for _, bad := range []string{`["paused","jammed"]`, `"paused"`, `{}`} {
+		if err = json.Unmarshal([]byte(bad), &s); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded", bad)
+		}
+	}
+}
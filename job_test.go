@@ -0,0 +1,214 @@
+package winprinters
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// timelineStep is the state of a job in one Jobs call of a timelineHandle.
+type timelineStep struct {
+	status  JobStatus
+	printed uint32
+	gone    bool // the job has left the queue
+}
+
+// timelineHandle plays back a job through a scripted list of states, one
+// per Jobs call, repeating the last one.
+type timelineHandle struct {
+	PrinterHandle
+	jobID uint32
+	steps []timelineStep
+	polls int
+}
+
+func (h *timelineHandle) Jobs() ([]JobInfo, error) {
+	s := h.steps[len(h.steps)-1]
+	if h.polls < len(h.steps) {
+		s = h.steps[h.polls]
+	}
+	h.polls++
+	if s.gone {
+		return nil, nil
+	}
+	return []JobInfo{{JobID: h.jobID, DocumentName: "report", StatusCode: s.status, TotalPages: 3, PagesPrinted: s.printed}}, nil
+}
+
+func TestJob_Wait(t *testing.T) {
+	const (
+		spooling = JOB_STATUS_SPOOLING
+		printing = JOB_STATUS_PRINTING
+	)
+	tests := []struct {
+		name    string
+		steps   []timelineStep
+		result  JobResult
+		history []JobStatus
+		printed uint32
+	}{
+		{
+			name: "printed",
+			steps: []timelineStep{
+				{status: spooling}, {status: spooling}, {status: 0},
+				{status: printing, printed: 1}, {status: printing, printed: 2},
+				{status: JOB_STATUS_PRINTED, printed: 3},
+			},
+			result:  JobPrinted,
+			history: []JobStatus{spooling, 0, printing, JOB_STATUS_PRINTED},
+			printed: 3,
+		},
+		{
+			name:    "sent to printer",
+			steps:   []timelineStep{{status: printing}, {status: printing | JOB_STATUS_COMPLETE, printed: 3}},
+			result:  JobPrinted,
+			history: []JobStatus{printing, printing | JOB_STATUS_COMPLETE},
+			printed: 3,
+		},
+		{
+			name:    "removed after printing",
+			steps:   []timelineStep{{status: spooling}, {status: printing, printed: 2}, {gone: true}},
+			result:  JobPrinted,
+			history: []JobStatus{spooling, printing},
+			printed: 2,
+		},
+		{
+			name:    "deleted",
+			steps:   []timelineStep{{status: printing, printed: 1}, {status: printing | JOB_STATUS_DELETING, printed: 1}, {gone: true}},
+			result:  JobDeleted,
+			history: []JobStatus{printing, printing | JOB_STATUS_DELETING},
+			printed: 1,
+		},
+		{
+			name:    "deleted flag",
+			steps:   []timelineStep{{status: 0}, {status: JOB_STATUS_DELETED}},
+			result:  JobDeleted,
+			history: []JobStatus{0, JOB_STATUS_DELETED},
+		},
+		{
+			name: "error",
+			steps: []timelineStep{
+				{status: printing, printed: 1},
+				{status: printing | JOB_STATUS_PAPEROUT, printed: 1},
+				{status: printing | JOB_STATUS_ERROR | JOB_STATUS_PAPEROUT, printed: 1},
+			},
+			result:  JobFailed,
+			history: []JobStatus{printing, printing | JOB_STATUS_PAPEROUT, printing | JOB_STATUS_ERROR | JOB_STATUS_PAPEROUT},
+			printed: 1,
+		},
+		{
+			name:    "driver error",
+			steps:   []timelineStep{{status: spooling}, {status: JOB_STATUS_BLOCKED_DEVQ}},
+			result:  JobFailed,
+			history: []JobStatus{spooling, JOB_STATUS_BLOCKED_DEVQ},
+		},
+		{
+			name:    "error while deleting",
+			steps:   []timelineStep{{status: JOB_STATUS_ERROR | JOB_STATUS_DELETING}, {gone: true}},
+			result:  JobDeleted,
+			history: []JobStatus{JOB_STATUS_ERROR | JOB_STATUS_DELETING},
+		},
+		{
+			name:   "never seen",
+			steps:  []timelineStep{{gone: true}},
+			result: JobGone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestSpooler(t)
+			h, err := CurrentSpooler().Open("Office")
+			if err != nil {
+				t.Fatal(err)
+			}
+			th := &timelineHandle{PrinterHandle: h, steps: tt.steps}
+			p := NewPrinter(th)
+			defer closePrinter(p)
+
+			job, err := p.StartRawDocument("report")
+			if err != nil {
+				t.Fatalf("StartRawDocument failed: %v", err)
+			}
+			if job.ID == 0 || job.Name != "report" || job.DataType != "XPS_PASS" {
+				t.Errorf("StartRawDocument = %+v", job)
+			}
+			if err = p.EndDocument(); err != nil {
+				t.Fatal(err)
+			}
+			th.jobID = job.ID
+			job.PollInterval = time.Microsecond
+
+			out, err := job.Wait(context.Background())
+			if err != nil {
+				t.Fatalf("Wait failed: %v", err)
+			}
+			if out.JobID != job.ID || out.Result != tt.result || out.PagesPrinted != tt.printed ||
+				!reflect.DeepEqual(out.History, tt.history) {
+				t.Errorf("Wait() = %+v, want %v after %v with %d pages printed", out, tt.result, tt.history, tt.printed)
+			}
+			if want := len(tt.steps); th.polls != want {
+				t.Errorf("Wait polled %d times, want %d", th.polls, want)
+			}
+		})
+	}
+}
+
+func TestJob_WaitCanceled(t *testing.T) {
+	newTestSpooler(t)
+	h, err := CurrentSpooler().Open("Office")
+	if err != nil {
+		t.Fatal(err)
+	}
+	th := &timelineHandle{PrinterHandle: h, steps: []timelineStep{{status: JOB_STATUS_PAUSED}}}
+	p := NewPrinter(th)
+	defer closePrinter(p)
+	job, err := p.StartDocument("report", "RAW")
+	if err != nil {
+		t.Fatal(err)
+	}
+	th.jobID = job.ID
+	job.PollInterval = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	out, err := job.Wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if out.Result != JobPending || out.Status != JOB_STATUS_PAUSED || len(out.History) != 1 || th.polls < 2 {
+		t.Errorf("Wait() = %+v after %d polls", out, th.polls)
+	}
+
+	if _, err = (&Job{p: p}).Wait(ctx); err == nil {
+		t.Errorf("Wait without a job ID succeeded")
+	}
+}
+
+func TestJob_WaitFake(t *testing.T) {
+	newTestSpooler(t)
+	p, err := Open("Label")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closePrinter(p)
+	job, err := p.StartDocument("label", "RAW")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.EndDocument(); err != nil {
+		t.Fatal(err)
+	}
+	job.PollInterval = time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if out, err := job.Wait(ctx); err == nil || out.Result != JobPending || out.Status != 0 {
+		t.Errorf("Wait() on a queued job = %+v, %v", out, err)
+	}
+	if err = p.DeleteJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := job.Wait(context.Background()); err != nil || out.Result != JobGone {
+		t.Errorf("Wait() on a deleted job = %+v, %v", out, err)
+	}
+}
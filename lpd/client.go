@@ -0,0 +1,211 @@
+package lpd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Client talks to an LPD server.
+type Client struct {
+	// Addr is the server address, host[:port]; the port defaults to 515.
+	Addr string
+
+	// Timeout bounds connecting and every exchange with the server; 30
+	// seconds when zero.
+	Timeout time.Duration
+
+	// ReservedPort connects from a source port in the range 721-731, as
+	// RFC 1179 requires. Binding these ports needs privileges, and most
+	// servers do not check them.
+	ReservedPort bool
+}
+
+// NewClient returns a client for the LPD server at addr.
+func NewClient(addr string) *Client {
+	return &Client{Addr: addr}
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return 30 * time.Second
+}
+
+func (c *Client) addr() string {
+	if _, _, err := net.SplitHostPort(c.Addr); err == nil {
+		return c.Addr
+	}
+	return net.JoinHostPort(strings.Trim(c.Addr, "[]"), DefaultPort)
+}
+
+// conn is a connection whose deadline is extended before every read and
+// write.
+type conn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	if err := c.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *conn) Write(b []byte) (int, error) {
+	if err := c.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(b)
+}
+
+// dial connects to the server and sends a daemon command.
+func (c *Client) dial(cmd byte, operands ...string) (*conn, error) {
+	var (
+		nc  net.Conn
+		err error
+	)
+	d := net.Dialer{Timeout: c.timeout()}
+	if c.ReservedPort {
+		for port := 721; port <= 731; port++ {
+			d.LocalAddr = &net.TCPAddr{Port: port}
+			if nc, err = d.Dial("tcp", c.addr()); err == nil {
+				break
+			}
+		}
+	} else {
+		nc, err = d.Dial("tcp", c.addr())
+	}
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{Conn: nc, timeout: c.timeout()}
+	if _, err = fmt.Fprintf(cn, "%c%s\n", cmd, strings.Join(operands, " ")); err != nil {
+		_ = nc.Close()
+		return nil, err
+	}
+	return cn, nil
+}
+
+// ErrRejected is returned when the server answers a command with a negative
+// acknowledgement.
+var ErrRejected = errors.New("lpd: request rejected by the server")
+
+// ack reads the one byte acknowledgement of a receive job command or
+// subcommand.
+func ack(r io.Reader, what string) error {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return fmt.Errorf("lpd: reading acknowledgement of %s: %w", what, err)
+	}
+	if b[0] != 0 {
+		return fmt.Errorf("%w: %s (code %d)", ErrRejected, what, b[0])
+	}
+	return nil
+}
+
+// Print sends job to queue with size bytes of data read from data. The data
+// file is sent before the control file, as BSD lpr does, so the job is only
+// queued once all data arrived.
+func (c *Client) Print(queue string, job *Job, data io.Reader, size int64) error {
+	text, err := job.ControlFile().MarshalText()
+	if err != nil {
+		return err
+	}
+	cn, err := c.dial(CmdReceiveJob, queue)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cn.Close() }()
+	if err = ack(cn, "receive job "+queue); err != nil {
+		return err
+	}
+
+	if _, err = fmt.Fprintf(cn, "%c%d %s\n", subDataFile, size, job.DataFileName()); err != nil {
+		return err
+	}
+	if err = ack(cn, "data file"); err != nil {
+		return err
+	}
+	n, err := io.Copy(cn, io.LimitReader(data, size))
+	if err != nil {
+		return err
+	}
+	if n != size {
+		// The server takes whatever follows as data, so only closing the
+		// connection before the file is complete drops the job.
+		return fmt.Errorf("lpd: data ended after %d of %d bytes", n, size)
+	}
+	if _, err = cn.Write([]byte{0}); err != nil {
+		return err
+	}
+	if err = ack(cn, "data"); err != nil {
+		return err
+	}
+
+	if _, err = fmt.Fprintf(cn, "%c%d %s\n", subControlFile, len(text), job.ControlFileName()); err != nil {
+		return err
+	}
+	if err = ack(cn, "control file"); err != nil {
+		return err
+	}
+	if _, err = cn.Write(append(text, 0)); err != nil {
+		return err
+	}
+	return ack(cn, "control data")
+}
+
+// PrintWaiting asks the server to start printing the jobs waiting in queue.
+func (c *Client) PrintWaiting(queue string) error {
+	cn, err := c.dial(CmdPrintWaiting, queue)
+	if err != nil {
+		return err
+	}
+	return cn.Close()
+}
+
+// response reads what the server sends until it closes the connection.
+func response(cn *conn) (string, error) {
+	defer func() { _ = cn.Close() }()
+	b, err := io.ReadAll(cn)
+	return string(b), err
+}
+
+// QueueState returns the state of queue in the short or long form, limited
+// to the users or job numbers in list when it is not empty.
+func (c *Client) QueueState(queue string, long bool, list ...string) (string, error) {
+	cmd := byte(CmdQueueShort)
+	if long {
+		cmd = CmdQueueLong
+	}
+	cn, err := c.dial(cmd, append([]string{queue}, list...)...)
+	if err != nil {
+		return "", err
+	}
+	return response(cn)
+}
+
+// Queue returns the jobs in queue, read from the short form queue state.
+func (c *Client) Queue(queue string, list ...string) ([]QueueEntry, error) {
+	text, err := c.QueueState(queue, false, list...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseShortQueue(text)
+}
+
+// Remove asks the server to remove the jobs in list, user names or job
+// numbers, on behalf of agent. The server's answer is returned as it is:
+// LPD does not report whether anything was removed.
+func (c *Client) Remove(queue, agent string, list ...string) (string, error) {
+	cn, err := c.dial(CmdRemoveJobs, append([]string{queue, agent}, list...)...)
+	if err != nil {
+		return "", err
+	}
+	return response(cn)
+}
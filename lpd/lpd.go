@@ -0,0 +1,232 @@
+// Package lpd implements the Line Printer Daemon protocol defined by
+// RFC 1179: control files, queue state listings, a client and a server.
+package lpd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultPort is the TCP port of LPD servers.
+const DefaultPort = "515"
+
+// Daemon commands, the first byte of every connection.
+const (
+	CmdPrintWaiting = 0x01 // start printing the waiting jobs
+	CmdReceiveJob   = 0x02 // receive a job
+	CmdQueueShort   = 0x03 // send the queue state, short form
+	CmdQueueLong    = 0x04 // send the queue state, long form
+	CmdRemoveJobs   = 0x05 // remove jobs
+)
+
+// Subcommands of CmdReceiveJob.
+const (
+	subAbort       = 0x01
+	subControlFile = 0x02
+	subDataFile    = 0x03
+)
+
+// Print file formats, the command letters of control file lines that print
+// a data file.
+const (
+	FormatRaw        = 'l' // print the file as is, control characters included
+	FormatText       = 'f' // print a plain text file
+	FormatPostScript = 'o' // print a PostScript file
+	FormatPR         = 'p' // print with a pr(1) header
+	FormatDVI        = 'd'
+	FormatTroff      = 't'
+	FormatDitroff    = 'n'
+	FormatRaster     = 'r' // print a FORTRAN carriage control file
+	FormatCIF        = 'c'
+	FormatGraph      = 'g'
+	FormatSunRaster  = 'v'
+)
+
+// isFormat reports whether c is a print file command letter.
+func isFormat(c byte) bool {
+	return strings.IndexByte("cdfglnoprtv", c) >= 0
+}
+
+// PrintFile is a data file printed by a job.
+type PrintFile struct {
+	Format byte   // print file format, such as FormatRaw
+	Name   string // data file name, such as "dfA123host"
+	Source string // name of the file the data came from
+	Copies int    // number of copies; the format line is repeated
+}
+
+// ControlFile is an LPD control file.
+type ControlFile struct {
+	Host   string // H: host the job comes from
+	User   string // P: user who submitted the job
+	Job    string // J: job name, printed on the banner page
+	Class  string // C: class name, printed on the banner page
+	Banner string // L: print a banner page for this user
+	Title  string // T: title for FormatPR
+	Mail   string // M: mail this user when the job is done
+	Files  []PrintFile
+	Unlink []string // U: data files to remove after printing
+	Other  []string // lines with other commands, kept as they are
+}
+
+// MarshalText encodes cf.
+func (cf *ControlFile) MarshalText() ([]byte, error) {
+	var b bytes.Buffer
+	line := func(cmd byte, operand string) error {
+		if strings.ContainsAny(operand, "\n") {
+			return fmt.Errorf("lpd: control file operand %q contains a newline", operand)
+		}
+		b.WriteByte(cmd)
+		b.WriteString(operand)
+		b.WriteByte('\n')
+		return nil
+	}
+	for _, l := range []struct {
+		cmd     byte
+		operand string
+	}{
+		{'H', cf.Host}, {'P', cf.User}, {'J', cf.Job}, {'C', cf.Class},
+		{'L', cf.Banner}, {'T', cf.Title}, {'M', cf.Mail},
+	} {
+		if l.operand == "" {
+			continue
+		}
+		if err := line(l.cmd, l.operand); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range cf.Files {
+		if !isFormat(f.Format) {
+			return nil, fmt.Errorf("lpd: %q is not a print file format", f.Format)
+		}
+		for i := 0; i < f.Copies || i == 0; i++ {
+			if err := line(f.Format, f.Name); err != nil {
+				return nil, err
+			}
+		}
+		if f.Source != "" {
+			if err := line('N', f.Source); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, name := range cf.Unlink {
+		if err := line('U', name); err != nil {
+			return nil, err
+		}
+	}
+	for _, l := range cf.Other {
+		b.WriteString(l)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// UnmarshalText decodes a control file. Repeated format lines for the same
+// data file count as copies and an N line names the source of the file
+// printed before it.
+func (cf *ControlFile) UnmarshalText(text []byte) error {
+	*cf = ControlFile{}
+	for _, l := range strings.Split(string(text), "\n") {
+		l = strings.TrimSuffix(l, "\r")
+		if l == "" {
+			continue
+		}
+		cmd, operand := l[0], l[1:]
+		switch {
+		case cmd == 'H':
+			cf.Host = operand
+		case cmd == 'P':
+			cf.User = operand
+		case cmd == 'J':
+			cf.Job = operand
+		case cmd == 'C':
+			cf.Class = operand
+		case cmd == 'L':
+			cf.Banner = operand
+		case cmd == 'T':
+			cf.Title = operand
+		case cmd == 'M':
+			cf.Mail = operand
+		case cmd == 'U':
+			cf.Unlink = append(cf.Unlink, operand)
+		case cmd == 'N':
+			if n := len(cf.Files); n > 0 && cf.Files[n-1].Source == "" {
+				cf.Files[n-1].Source = operand
+			}
+		case isFormat(cmd):
+			if n := len(cf.Files); n > 0 && cf.Files[n-1].Format == cmd && cf.Files[n-1].Name == operand {
+				cf.Files[n-1].Copies++
+				continue
+			}
+			cf.Files = append(cf.Files, PrintFile{Format: cmd, Name: operand, Copies: 1})
+		default:
+			cf.Other = append(cf.Other, l)
+		}
+	}
+	if cf.Host == "" || cf.User == "" {
+		return errors.New("lpd: control file without H or P line")
+	}
+	return nil
+}
+
+// Job is a print job of one data file, sent by Client.Print.
+type Job struct {
+	Number int    // job number, 0-999, part of the file names
+	Host   string // host the job comes from
+	User   string // user who submitted the job
+	Name   string // job name
+	Source string // name of the printed file; "" for standard input
+	Class  string
+	Banner bool // print a banner page
+	Format byte // print file format; FormatRaw when zero
+	Copies int
+}
+
+// fileHost returns the host part of file names, which must not contain
+// spaces or slashes.
+func (j *Job) fileHost() string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '/' || r > '~' {
+			return -1
+		}
+		return r
+	}, j.Host)
+}
+
+// DataFileName returns the name of the job's data file, e.g. "dfA042host".
+func (j *Job) DataFileName() string {
+	return fmt.Sprintf("dfA%03d%s", j.Number%1000, j.fileHost())
+}
+
+// ControlFileName returns the name of the job's control file, e.g.
+// "cfA042host".
+func (j *Job) ControlFileName() string {
+	return fmt.Sprintf("cfA%03d%s", j.Number%1000, j.fileHost())
+}
+
+// ControlFile returns the control file of the job.
+func (j *Job) ControlFile() *ControlFile {
+	format := j.Format
+	if format == 0 {
+		format = FormatRaw
+	}
+	source := j.Source
+	if source == "" {
+		source = "(standard input)"
+	}
+	cf := &ControlFile{
+		Host:   j.Host,
+		User:   j.User,
+		Job:    j.Name,
+		Class:  j.Class,
+		Files:  []PrintFile{{Format: format, Name: j.DataFileName(), Source: source, Copies: j.Copies}},
+		Unlink: []string{j.DataFileName()},
+	}
+	if j.Banner {
+		cf.Banner = j.User
+	}
+	return cf
+}
@@ -0,0 +1,115 @@
+package lpd
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestControlFile(t *testing.T) {
+	job := &Job{Number: 42, Host: "build box", User: "alice", Name: "report", Source: "report.txt",
+		Format: FormatText, Copies: 2, Banner: true}
+	if got := job.DataFileName(); got != "dfA042buildbox" {
+		t.Errorf("DataFileName = %q", got)
+	}
+	if got := job.ControlFileName(); got != "cfA042buildbox" {
+		t.Errorf("ControlFileName = %q", got)
+	}
+	text, err := job.ControlFile().MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText failed: %v", err)
+	}
+	want := "Hbuild box\nPalice\nJreport\nLalice\nfdfA042buildbox\nfdfA042buildbox\nNreport.txt\nUdfA042buildbox\n"
+	if string(text) != want {
+		t.Errorf("MarshalText =\n%s\nwant\n%s", text, want)
+	}
+
+	var cf ControlFile
+	if err = cf.UnmarshalText(text); err != nil {
+		t.Fatalf("UnmarshalText failed: %v", err)
+	}
+	if !reflect.DeepEqual(&cf, job.ControlFile()) {
+		t.Errorf("UnmarshalText = %+v, want %+v", cf, job.ControlFile())
+	}
+
+	// BSD lpr writes U before N and may use commands this package does not
+	// model.
+	bsd := "Hhost\nProot\nJ(stdin)\nldfA001host\nUdfA001host\nN(standard input)\nS123 456\n"
+	if err = cf.UnmarshalText([]byte(bsd)); err != nil {
+		t.Fatalf("UnmarshalText failed: %v", err)
+	}
+	wantFiles := []PrintFile{{Format: FormatRaw, Name: "dfA001host", Source: "(standard input)", Copies: 1}}
+	if !reflect.DeepEqual(cf.Files, wantFiles) || !reflect.DeepEqual(cf.Other, []string{"S123 456"}) {
+		t.Errorf("UnmarshalText = %+v", cf)
+	}
+
+	if err = cf.UnmarshalText([]byte("Jjob\nldfA001host\n")); err == nil {
+		t.Error("UnmarshalText accepted a control file without H and P")
+	}
+	bad := &ControlFile{Host: "h", User: "u\nHevil"}
+	if _, err = bad.MarshalText(); err == nil {
+		t.Error("MarshalText accepted a newline in an operand")
+	}
+}
+
+func TestQueue(t *testing.T) {
+	entries := []QueueEntry{
+		{Rank: Rank(0), Owner: "alice", Job: 7, Host: "ws1", Files: []string{"report.txt"}, Size: 1234},
+		{Rank: Rank(1), Owner: "bob", Job: 12, Host: "ws2", Files: []string{"a b.ps", "c.ps"}, Size: 99},
+	}
+	var short, long bytes.Buffer
+	if err := WriteQueue(&short, entries, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteQueue(&long, entries, true); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ParseShortQueue("printer is ready and printing\n" + short.String())
+	if err != nil {
+		t.Fatalf("ParseShortQueue failed: %v", err)
+	}
+	wantShort := []QueueEntry{
+		{Rank: "active", Owner: "alice", Job: 7, Files: []string{"report.txt"}, Size: 1234},
+		{Rank: "1st", Owner: "bob", Job: 12, Files: []string{"a b.ps", "c.ps"}, Size: 99},
+	}
+	if !reflect.DeepEqual(got, wantShort) {
+		t.Errorf("ParseShortQueue =\n%+v\nwant\n%+v", got, wantShort)
+	}
+
+	if got, err = ParseLongQueue(long.String()); err != nil {
+		t.Fatalf("ParseLongQueue failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("ParseLongQueue =\n%+v\nwant\n%+v", got, entries)
+	}
+
+	// Output of a BSD lpd.
+	bsd := "lp is ready and printing\n" +
+		"root: active                             [job 023localhost]\n" +
+		"        /etc/motd                        1082 bytes\n" +
+		"        (standard input)                 16 bytes\n\n"
+	got, err = ParseLongQueue(bsd)
+	want := []QueueEntry{{Rank: "active", Owner: "root", Job: 23, Host: "localhost",
+		Files: []string{"/etc/motd", "(standard input)"}, Size: 1098}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLongQueue(bsd) = %+v, %v", got, err)
+	}
+
+	for _, text := range []string{"no entries\n", ""} {
+		if got, err = ParseShortQueue(text); err != nil || len(got) != 0 {
+			t.Errorf("ParseShortQueue(%q) = %+v, %v", text, got, err)
+		}
+	}
+	if _, err = ParseShortQueue(shortHeader + "\nactive alice x report 1 bytes\n"); err == nil {
+		t.Error("ParseShortQueue accepted a bad job number")
+	}
+}
+
+func TestRank(t *testing.T) {
+	for i, want := range map[int]string{0: "active", 1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th", 22: "22nd", 113: "113th"} {
+		if got := Rank(i); got != want {
+			t.Errorf("Rank(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
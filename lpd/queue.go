@@ -0,0 +1,146 @@
+package lpd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// QueueEntry is a job listed by a queue state command.
+type QueueEntry struct {
+	Rank  string // "active" for the printing job, else "1st", "2nd", ...
+	Owner string
+	Job   int
+	Host  string // only listed by the long form
+	Files []string
+	Size  int64 // total size in bytes
+}
+
+// Rank returns the rank of the job at position i in the queue, starting
+// with 0 for the active job.
+func Rank(i int) string {
+	if i == 0 {
+		return "active"
+	}
+	suffix := "th"
+	if i%100 < 11 || i%100 > 13 {
+		switch i % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(i) + suffix
+}
+
+const shortHeader = "Rank   Owner      Job  Files                                 Total Size"
+
+// WriteQueue writes entries in the short or long form used by BSD lpd. An
+// empty queue is listed as "no entries".
+func WriteQueue(w io.Writer, entries []QueueEntry, long bool) error {
+	bw := bufio.NewWriter(w)
+	switch {
+	case len(entries) == 0:
+		fmt.Fprintln(bw, "no entries")
+	case long:
+		for _, e := range entries {
+			fmt.Fprintf(bw, "%s: %-33s [job %03d%s]\n", e.Owner, e.Rank, e.Job, e.Host)
+			if len(e.Files) == 0 {
+				fmt.Fprintf(bw, "\t%-32s %d bytes\n", "(standard input)", e.Size)
+			}
+			for i, f := range e.Files {
+				size := int64(0)
+				if i == 0 {
+					size = e.Size
+				}
+				fmt.Fprintf(bw, "\t%-32s %d bytes\n", f, size)
+			}
+			fmt.Fprintln(bw)
+		}
+	default:
+		fmt.Fprintln(bw, shortHeader)
+		for _, e := range entries {
+			fmt.Fprintf(bw, "%-7s%-11s%-5d%-38s%d bytes\n", e.Rank, e.Owner, e.Job, strings.Join(e.Files, ", "), e.Size)
+		}
+	}
+	return bw.Flush()
+}
+
+// ParseShortQueue parses a short form queue state. Lines before the column
+// header, such as printer status, are skipped.
+func ParseShortQueue(text string) ([]QueueEntry, error) {
+	var entries []QueueEntry
+	header := false
+	for _, l := range strings.Split(text, "\n") {
+		f := strings.Fields(l)
+		if !header {
+			header = len(f) > 0 && f[0] == "Rank"
+			continue
+		}
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) < 5 || f[len(f)-1] != "bytes" {
+			return nil, fmt.Errorf("lpd: bad queue entry %q", l)
+		}
+		job, err := strconv.Atoi(f[2])
+		if err != nil {
+			return nil, fmt.Errorf("lpd: bad job number in %q", l)
+		}
+		size, err := strconv.ParseInt(f[len(f)-2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("lpd: bad size in %q", l)
+		}
+		e := QueueEntry{Rank: f[0], Owner: f[1], Job: job, Size: size}
+		if files := strings.Join(f[3:len(f)-2], " "); files != "" {
+			e.Files = strings.Split(files, ", ")
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// ParseLongQueue parses a long form queue state: for every job an
+// "owner: rank [job 123host]" line followed by indented "file size bytes"
+// lines.
+func ParseLongQueue(text string) ([]QueueEntry, error) {
+	var entries []QueueEntry
+	for _, l := range strings.Split(text, "\n") {
+		l = strings.TrimRight(l, "\r")
+		if i, j := strings.Index(l, ": "), strings.LastIndex(l, "[job "); i > 0 && j > i && strings.HasSuffix(l, "]") {
+			spec := l[j+len("[job ") : len(l)-1]
+			n := 0
+			for n < len(spec) && spec[n] >= '0' && spec[n] <= '9' {
+				n++
+			}
+			job, err := strconv.Atoi(spec[:n])
+			if err != nil {
+				return nil, fmt.Errorf("lpd: bad job number in %q", l)
+			}
+			entries = append(entries, QueueEntry{
+				Owner: l[:i],
+				Rank:  strings.TrimSpace(l[i+2 : j]),
+				Job:   job,
+				Host:  spec[n:],
+			})
+			continue
+		}
+		f := strings.Fields(l)
+		if len(entries) == 0 || len(f) < 3 || f[len(f)-1] != "bytes" || l[0] != ' ' && l[0] != '\t' {
+			continue
+		}
+		size, err := strconv.ParseInt(f[len(f)-2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("lpd: bad size in %q", l)
+		}
+		e := &entries[len(entries)-1]
+		e.Files = append(e.Files, strings.Join(f[:len(f)-2], " "))
+		e.Size += size
+	}
+	return entries, nil
+}
@@ -0,0 +1,287 @@
+package lpd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request is a daemon command received by a Server.
+type Request struct {
+	Command byte     // one of the Cmd constants
+	Queue   string   // queue name
+	Agent   string   // user asking CmdRemoveJobs
+	List    []string // user names or job numbers of queue state and remove commands
+	Remote  net.Addr // client address
+}
+
+// ReceivedJob is a job received by a Server.
+type ReceivedJob struct {
+	ControlName string
+	Control     *ControlFile
+	Data        map[string][]byte // data files by name
+}
+
+// Handler serves the commands received by a Server.
+type Handler interface {
+	// Accept is called before any other method; an error refuses the
+	// request.
+	Accept(r *Request) error
+	// PrintWaiting starts printing the jobs waiting in r.Queue.
+	PrintWaiting(r *Request) error
+	// ReceiveJob queues a job. The client is told whether it failed.
+	ReceiveJob(r *Request, job *ReceivedJob) error
+	// QueueState writes the state of r.Queue to w, in the long form when
+	// r.Command is CmdQueueLong.
+	QueueState(w io.Writer, r *Request) error
+	// RemoveJobs removes the jobs of r.Queue in r.List, or the active job
+	// of r.Agent when r.List is empty, writing what it did to w.
+	RemoveJobs(w io.Writer, r *Request) error
+}
+
+// Server is an LPD server.
+type Server struct {
+	Handler Handler
+
+	// MaxFileSize limits the size of received files; 1 GiB when zero.
+	MaxFileSize int64
+
+	// MaxJobSize limits the total size of the files a connection holds
+	// until their jobs are complete; MaxFileSize when zero.
+	MaxJobSize int64
+
+	// Timeout bounds every read and write; 5 minutes when zero.
+	Timeout time.Duration
+
+	// ErrorLog receives the errors of failed connections; nil discards
+	// them.
+	ErrorLog func(format string, args ...interface{})
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog(format, args...)
+	}
+}
+
+// Serve serves the connections accepted by l until it fails.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(c)
+	}
+}
+
+// ServeConn serves one connection and closes it.
+func (s *Server) ServeConn(c net.Conn) {
+	defer func() { _ = c.Close() }()
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	cn := &conn{Conn: c, timeout: timeout}
+	if err := s.serve(cn, bufio.NewReader(cn)); err != nil {
+		s.logf("lpd: %s: %v", c.RemoteAddr(), err)
+	}
+}
+
+// readLine reads a line no longer than the buffer of r.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return "", errors.New("line too long")
+	}
+	return string(line), err
+}
+
+func (s *Server) serve(c *conn, r *bufio.Reader) error {
+	line, err := readLine(r)
+	if err != nil {
+		return err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return errors.New("empty command")
+	}
+	f := strings.Fields(line[1:])
+	if len(f) == 0 {
+		return fmt.Errorf("command %d without a queue", line[0])
+	}
+	req := &Request{Command: line[0], Queue: f[0], Remote: c.RemoteAddr()}
+	switch req.Command {
+	case CmdPrintWaiting, CmdReceiveJob:
+	case CmdQueueShort, CmdQueueLong:
+		req.List = f[1:]
+	case CmdRemoveJobs:
+		if len(f) < 2 {
+			return errors.New("remove jobs without an agent")
+		}
+		req.Agent, req.List = f[1], f[2:]
+	default:
+		return fmt.Errorf("unknown command %d", req.Command)
+	}
+
+	if err = s.Handler.Accept(req); err != nil {
+		if req.Command == CmdReceiveJob {
+			_, _ = c.Write([]byte{1})
+		} else if req.Command != CmdPrintWaiting {
+			_, _ = fmt.Fprintf(c, "%s\n", err)
+		}
+		return nil
+	}
+	switch req.Command {
+	case CmdPrintWaiting:
+		return s.Handler.PrintWaiting(req)
+	case CmdReceiveJob:
+		if _, err = c.Write([]byte{0}); err != nil {
+			return err
+		}
+		return s.receive(c, r, req)
+	case CmdQueueShort, CmdQueueLong:
+		w := bufio.NewWriter(c)
+		if err = s.Handler.QueueState(w, req); err != nil {
+			_, _ = fmt.Fprintf(w, "%s\n", err)
+		}
+		return w.Flush()
+	default:
+		w := bufio.NewWriter(c)
+		if err = s.Handler.RemoveJobs(w, req); err != nil {
+			_, _ = fmt.Fprintf(w, "%s\n", err)
+		}
+		return w.Flush()
+	}
+}
+
+// receive reads the subcommands of a receive job command. A job is handed
+// to the Handler as soon as its control file and all the data files it
+// prints have arrived, whatever their order, and the subcommand completing
+// it is acknowledged with the result.
+func (s *Server) receive(c *conn, r *bufio.Reader, req *Request) error {
+	max := s.MaxFileSize
+	if max <= 0 {
+		max = 1 << 30
+	}
+	maxJob := s.MaxJobSize
+	if maxJob <= 0 {
+		maxJob = max
+	}
+	data := make(map[string][]byte)
+	controls := make(map[string]*ControlFile)
+	controlSizes := make(map[string]int64)
+	held := func() int64 {
+		var n int64
+		for _, b := range data {
+			n += int64(len(b))
+		}
+		for name := range controls {
+			n += controlSizes[name]
+		}
+		return n
+	}
+	for {
+		line, err := readLine(r)
+		if err == io.EOF && line == "" {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			return errors.New("empty subcommand")
+		}
+		if line[0] == subAbort {
+			data = make(map[string][]byte)
+			controls = make(map[string]*ControlFile)
+			controlSizes = make(map[string]int64)
+			continue
+		}
+		f := strings.Fields(line[1:])
+		if (line[0] != subControlFile && line[0] != subDataFile) || len(f) != 2 {
+			return fmt.Errorf("bad subcommand %q", line)
+		}
+		size, err := strconv.ParseInt(f[0], 10, 64)
+		if err != nil || size < 0 || size > max || held()+size > maxJob {
+			_, _ = c.Write([]byte{1})
+			return fmt.Errorf("refused %s of %s bytes", f[1], f[0])
+		}
+		if _, err = c.Write([]byte{0}); err != nil {
+			return err
+		}
+		// The buffer grows as the file arrives rather than trusting the
+		// announced size.
+		var buf bytes.Buffer
+		if _, err = io.CopyN(&buf, r, size+1); err != nil {
+			return err
+		}
+		b := buf.Bytes()
+		if b[size] != 0 {
+			return fmt.Errorf("%s is not followed by a zero byte", f[1])
+		}
+		b = b[:size]
+
+		if line[0] == subControlFile {
+			cf := new(ControlFile)
+			if err = cf.UnmarshalText(b); err != nil {
+				_, _ = c.Write([]byte{1})
+				return err
+			}
+			controls[f[1]] = cf
+			controlSizes[f[1]] = size
+		} else {
+			data[f[1]] = b
+		}
+		err = s.complete(req, controls, data)
+		for name := range controlSizes {
+			if controls[name] == nil {
+				delete(controlSizes, name)
+			}
+		}
+		result := byte(0)
+		if err != nil {
+			result = 1
+		}
+		if _, werr := c.Write([]byte{result}); werr != nil {
+			return werr
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// complete hands the jobs whose files all arrived to the Handler.
+func (s *Server) complete(req *Request, controls map[string]*ControlFile, data map[string][]byte) error {
+	for name, cf := range controls {
+		job := &ReceivedJob{ControlName: name, Control: cf, Data: make(map[string][]byte)}
+		ready := true
+		for _, f := range cf.Files {
+			b, ok := data[f.Name]
+			if !ok {
+				ready = false
+				break
+			}
+			job.Data[f.Name] = b
+		}
+		if !ready {
+			continue
+		}
+		delete(controls, name)
+		for n := range job.Data {
+			delete(data, n)
+		}
+		if err := s.Handler.ReceiveJob(req, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package lpd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memHandler keeps the jobs of queue "lp" in memory.
+type memHandler struct {
+	mu      sync.Mutex
+	jobs    []*ReceivedJob
+	removed []string
+	waiting int
+	fail    error
+}
+
+// received returns the jobs received so far.
+func (h *memHandler) received() []*ReceivedJob {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]*ReceivedJob(nil), h.jobs...)
+}
+
+func (h *memHandler) Accept(r *Request) error {
+	if r.Queue != "lp" {
+		return fmt.Errorf("unknown printer %s", r.Queue)
+	}
+	return nil
+}
+
+func (h *memHandler) PrintWaiting(*Request) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.waiting++
+	return nil
+}
+
+func (h *memHandler) ReceiveJob(_ *Request, job *ReceivedJob) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.fail != nil {
+		return h.fail
+	}
+	h.jobs = append(h.jobs, job)
+	return nil
+}
+
+func (h *memHandler) QueueState(w io.Writer, r *Request) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var entries []QueueEntry
+	for i, j := range h.jobs {
+		n, _ := strconv.Atoi(j.ControlName[3:6])
+		var size int64
+		for _, b := range j.Data {
+			size += int64(len(b))
+		}
+		entries = append(entries, QueueEntry{Rank: Rank(i), Owner: j.Control.User, Job: n,
+			Host: j.Control.Host, Files: []string{j.Control.Files[0].Source}, Size: size})
+	}
+	return WriteQueue(w, entries, r.Command == CmdQueueLong)
+}
+
+func (h *memHandler) RemoveJobs(w io.Writer, r *Request) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removed = append(h.removed, r.Agent+":"+strings.Join(r.List, ","))
+	_, err := fmt.Fprintf(w, "%s dequeued\n", strings.Join(r.List, " "))
+	return err
+}
+
+func newTestServer(t *testing.T) (*memHandler, *Client) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	h := &memHandler{}
+	s := &Server{Handler: h, MaxFileSize: 1 << 20}
+	go func() { _ = s.Serve(ln) }()
+	return h, &Client{Addr: ln.Addr().String(), Timeout: 5 * time.Second}
+}
+
+func TestClient_Print(t *testing.T) {
+	h, c := newTestServer(t)
+	job := &Job{Number: 7, Host: "ws1", User: "alice", Name: "report", Source: "report.txt", Copies: 3}
+	data := "\x1bE report \x1bE"
+	if err := c.Print("lp", job, strings.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("Print failed: %v", err)
+	}
+	jobs := h.received()
+	if len(jobs) != 1 {
+		t.Fatalf("server received %d jobs", len(jobs))
+	}
+	got := jobs[0]
+	f := got.Control.Files[0]
+	if got.ControlName != "cfA007ws1" || got.Control.User != "alice" || got.Control.Job != "report" ||
+		f.Format != FormatRaw || f.Copies != 3 || string(got.Data[f.Name]) != data {
+		t.Errorf("server received %+v %+v", got, got.Control)
+	}
+
+	entries, err := c.Queue("lp")
+	if err != nil {
+		t.Fatalf("Queue failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Job != 7 || entries[0].Owner != "alice" || entries[0].Size != int64(len(data)) {
+		t.Errorf("Queue = %+v", entries)
+	}
+	long, err := c.QueueState("lp", true)
+	if err != nil {
+		t.Fatalf("QueueState failed: %v", err)
+	}
+	if entries, err = ParseLongQueue(long); err != nil || len(entries) != 1 || entries[0].Host != "ws1" {
+		t.Errorf("long QueueState = %q", long)
+	}
+
+	out, err := c.Remove("lp", "alice", "7")
+	h.mu.Lock()
+	removed := h.removed
+	h.mu.Unlock()
+	if err != nil || out != "7 dequeued\n" || len(removed) != 1 || removed[0] != "alice:7" {
+		t.Errorf("Remove = %q, %v (%q)", out, err, removed)
+	}
+	if err = c.PrintWaiting("lp"); err != nil {
+		t.Errorf("PrintWaiting failed: %v", err)
+	}
+}
+
+func TestClient_Rejected(t *testing.T) {
+	h, c := newTestServer(t)
+	job := &Job{Number: 1, Host: "ws1", User: "bob"}
+
+	err := c.Print("missing", job, strings.NewReader("x"), 1)
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("Print to a missing queue = %v, want ErrRejected", err)
+	}
+	if out, err := c.QueueState("missing", false); err != nil || !strings.Contains(out, "unknown printer") {
+		t.Errorf("QueueState of a missing queue = %q, %v", out, err)
+	}
+
+	h.mu.Lock()
+	h.fail = errors.New("spooler down")
+	h.mu.Unlock()
+	if err = c.Print("lp", job, strings.NewReader("x"), 1); !errors.Is(err, ErrRejected) {
+		t.Errorf("Print with a failing handler = %v, want ErrRejected", err)
+	}
+	h.mu.Lock()
+	h.fail = nil
+	h.mu.Unlock()
+
+	if err = c.Print("lp", job, strings.NewReader(strings.Repeat("x", 2<<20)), 2<<20); !errors.Is(err, ErrRejected) {
+		t.Errorf("Print of a too large file = %v, want ErrRejected", err)
+	}
+	if err = c.Print("lp", job, strings.NewReader("short"), 10); err == nil {
+		t.Error("Print of truncated data succeeded")
+	}
+	if jobs := h.received(); len(jobs) != 0 {
+		t.Errorf("server received %+v", jobs)
+	}
+}
+
+// TestServer_ControlFirst sends the control file before the data file, as
+// Windows LPR does.
+func TestServer_ControlFirst(t *testing.T) {
+	h, c := newTestServer(t)
+	nc, err := net.Dial("tcp", c.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = nc.Close() }()
+	r := bufio.NewReader(nc)
+	expect := func(want byte) {
+		t.Helper()
+		b, err := r.ReadByte()
+		if err != nil || b != want {
+			t.Fatalf("acknowledgement = %d, %v, want %d", b, err, want)
+		}
+	}
+	cf := "Hwin\nPcarol\nJletter\nldfA003win\n"
+	fmt.Fprintf(nc, "\x02lp\n")
+	expect(0)
+	fmt.Fprintf(nc, "\x02%d cfA003win\n", len(cf))
+	expect(0)
+	fmt.Fprintf(nc, "%s\x00", cf)
+	expect(0)
+	if len(h.received()) != 0 {
+		t.Fatal("job handled before its data file arrived")
+	}
+	fmt.Fprintf(nc, "\x035 dfA003win\n")
+	expect(0)
+	fmt.Fprintf(nc, "hello\x00")
+	expect(0)
+	jobs := h.received()
+	if len(jobs) != 1 || string(jobs[0].Data["dfA003win"]) != "hello" || jobs[0].Control.User != "carol" {
+		t.Errorf("server received %+v", jobs)
+	}
+}
+
+func TestServer_Limits(t *testing.T) {
+	h, c := newTestServer(t)
+	dial := func() (net.Conn, *bufio.Reader) {
+		t.Helper()
+		nc, err := net.Dial("tcp", c.Addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = nc.Close() })
+		fmt.Fprintf(nc, "\x02lp\n")
+		r := bufio.NewReader(nc)
+		if b, err := r.ReadByte(); err != nil || b != 0 {
+			t.Fatalf("receive job acknowledgement = %d, %v", b, err)
+		}
+		return nc, r
+	}
+
+	// Data files of 768 KiB each fit MaxFileSize but not together.
+	nc, r := dial()
+	data := strings.Repeat("x", 768<<10)
+	fmt.Fprintf(nc, "\x03%d dfA001ws1\n", len(data))
+	if b, err := r.ReadByte(); err != nil || b != 0 {
+		t.Fatalf("first data file acknowledgement = %d, %v", b, err)
+	}
+	fmt.Fprintf(nc, "%s\x00", data)
+	if b, err := r.ReadByte(); err != nil || b != 0 {
+		t.Fatalf("first data file result = %d, %v", b, err)
+	}
+	fmt.Fprintf(nc, "\x03%d dfB001ws1\n", len(data))
+	if b, err := r.ReadByte(); err != nil || b != 1 {
+		t.Errorf("second data file acknowledgement = %d, %v, want 1", b, err)
+	}
+
+	// A subcommand line is bounded.
+	nc, r = dial()
+	fmt.Fprintf(nc, "\x03%s\n", strings.Repeat("9", 8192))
+	if b, err := r.ReadByte(); err == nil {
+		t.Errorf("long subcommand answered %d, want the connection closed", b)
+	}
+	if jobs := h.received(); len(jobs) != 0 {
+		t.Errorf("server received %+v", jobs)
+	}
+}
@@ -0,0 +1,273 @@
+package winprinters
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chenxi2015/winprinters/lpd"
+)
+
+// LPRSpooler is a Spooler for LPD print servers (RFC 1179), such as legacy
+// Unix print servers and embedded print boxes. Documents are buffered until
+// EndDocument, because LPD needs their size before the data.
+//
+// Printer names are queue names on the server at Addr, or lpd:// URIs such
+// as "lpd://printbox.local/lp" for queues on other servers.
+type LPRSpooler struct {
+	// Addr is the server address, host[:port]; the port defaults to 515.
+	Addr string
+
+	// Timeout bounds connecting and every exchange with the server; 30
+	// seconds when zero.
+	Timeout time.Duration
+
+	// ReservedPort connects from a privileged source port, for servers
+	// that check it as RFC 1179 requires.
+	ReservedPort bool
+
+	// UserName is the job owner; the current user when empty.
+	UserName string
+
+	// HostName is the host jobs come from; the local host name when empty.
+	HostName string
+
+	// Copies is the number of copies printed of every document.
+	Copies int
+
+	// Banner asks the server to print a banner page before every job.
+	Banner bool
+
+	mu      sync.Mutex
+	queues  []string
+	def     string
+	nextJob int
+}
+
+// NewLPRSpooler returns an LPRSpooler for the server at addr, listing
+// queues. The first queue, if any, is the default printer.
+func NewLPRSpooler(addr string, queues ...string) *LPRSpooler {
+	s := &LPRSpooler{Addr: addr, queues: append([]string(nil), queues...), nextJob: os.Getpid()}
+	if len(queues) > 0 {
+		s.def = queues[0]
+	}
+	return s
+}
+
+func (s *LPRSpooler) GetDefault() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.def == "" {
+		return "", ErrPrinterNotFound
+	}
+	return s.def, nil
+}
+
+// SetDefault makes one of the listed queues the default printer.
+func (s *LPRSpooler) SetDefault(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, q := range s.queues {
+		if q == name {
+			s.def = name
+			return nil
+		}
+	}
+	return ErrPrinterNotFound
+}
+
+// ReadNames returns the queues passed to NewLPRSpooler: LPD cannot list
+// the queues of a server.
+func (s *LPRSpooler) ReadNames() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queues...), nil
+}
+
+// Open checks that the server answers by asking for the queue state. LPD
+// servers do not report unknown queues in a way that could be told apart.
+func (s *LPRSpooler) Open(name string) (PrinterHandle, error) {
+	addr, queue := s.Addr, name
+	if strings.HasPrefix(name, "lpd://") {
+		u, err := url.Parse(name)
+		if err != nil {
+			return nil, err
+		}
+		addr, queue = u.Host, strings.TrimPrefix(u.Path, "/")
+	}
+	if addr == "" || queue == "" || strings.ContainsAny(queue, " \n") {
+		return nil, fmt.Errorf("winprinters: bad LPD printer name %q", name)
+	}
+	p := &lprPrinter{
+		s:     s,
+		c:     &lpd.Client{Addr: addr, Timeout: s.Timeout, ReservedPort: s.ReservedPort},
+		queue: queue,
+	}
+	if _, err := p.c.QueueState(queue, false); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *LPRSpooler) userName() string {
+	if s.UserName != "" {
+		return s.UserName
+	}
+	if u, err := user.Current(); err == nil {
+		// Windows user names come as DOMAIN\user.
+		return u.Username[strings.LastIndexByte(u.Username, '\\')+1:]
+	}
+	return "nobody"
+}
+
+func (s *LPRSpooler) hostName() string {
+	if s.HostName != "" {
+		return s.HostName
+	}
+	if h, err := os.Hostname(); err == nil {
+		return h
+	}
+	return "localhost"
+}
+
+// jobNumber returns the next job number, 0-999.
+func (s *LPRSpooler) jobNumber() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextJob++
+	return s.nextJob % 1000
+}
+
+// lprPrinter is the PrinterHandle returned by LPRSpooler.Open.
+type lprPrinter struct {
+	s     *LPRSpooler
+	c     *lpd.Client
+	queue string
+	doc   *lprDocument
+}
+
+type lprDocument struct {
+	job  lpd.Job
+	data bytes.Buffer
+}
+
+var errLPRNoDocument = errors.New("winprinters: no document started")
+
+// Jobs reads the short form queue state. The job ID is the LPD job number.
+func (p *lprPrinter) Jobs() ([]JobInfo, error) {
+	entries, err := p.c.Queue(p.queue)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []JobInfo
+	for i, e := range entries {
+		job := JobInfo{
+			JobID:        uint32(e.Job),
+			UserName:     e.Owner,
+			DocumentName: strings.Join(e.Files, ", "),
+			Position:     uint32(i + 1),
+		}
+		if e.Rank == "active" {
+			job.StatusCode = JOB_STATUS_PRINTING
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (p *lprPrinter) Forms() ([]FormInfo, error) {
+	return nil, &UnsupportedError{Op: "Forms"}
+}
+
+func (p *lprPrinter) DriverInfo() (*DriverInfo, error) {
+	return &DriverInfo{Name: "LPD", Environment: "lpd", DriverPath: "lpd://" + p.c.Addr + "/" + p.queue}, nil
+}
+
+// CancelJob asks the server to remove the job on behalf of the job owner.
+func (p *lprPrinter) CancelJob(jobID uint32) error {
+	jobs, err := p.Jobs()
+	if err != nil {
+		return err
+	}
+	for _, j := range jobs {
+		if j.JobID == jobID {
+			_, err = p.c.Remove(p.queue, p.s.userName(), strconv.FormatUint(uint64(jobID), 10))
+			return err
+		}
+	}
+	return ErrJobNotFound
+}
+
+// lprFormat maps a winspool data type onto an LPD print file format.
+func lprFormat(datatype string) byte {
+	switch strings.ToLower(datatype) {
+	case "text", "text/plain":
+		return lpd.FormatText
+	case "application/postscript":
+		return lpd.FormatPostScript
+	}
+	return lpd.FormatRaw
+}
+
+// StartDocument returns the LPD job number, which Jobs reports as the job
+// ID once the server has queued the job.
+func (p *lprPrinter) StartDocument(name, datatype string) (uint32, error) {
+	if p.doc != nil {
+		return 0, errors.New("winprinters: document already started")
+	}
+	p.doc = &lprDocument{job: lpd.Job{
+		Number: p.s.jobNumber(),
+		Host:   p.s.hostName(),
+		User:   p.s.userName(),
+		Name:   name,
+		Source: name,
+		Format: lprFormat(datatype),
+		Copies: p.s.Copies,
+		Banner: p.s.Banner,
+	}}
+	return uint32(p.doc.job.Number), nil
+}
+
+func (p *lprPrinter) Write(b []byte) (int, error) {
+	if p.doc == nil {
+		return 0, errLPRNoDocument
+	}
+	return p.doc.data.Write(b)
+}
+
+// EndDocument sends the buffered document as an LPD job.
+func (p *lprPrinter) EndDocument() error {
+	if p.doc == nil {
+		return errLPRNoDocument
+	}
+	doc := p.doc
+	p.doc = nil
+	return p.c.Print(p.queue, &doc.job, &doc.data, int64(doc.data.Len()))
+}
+
+// StartPage does nothing: LPD documents are not split into pages by the
+// client.
+func (p *lprPrinter) StartPage() error {
+	if p.doc == nil {
+		return errLPRNoDocument
+	}
+	return nil
+}
+
+// EndPage does nothing, see StartPage.
+func (p *lprPrinter) EndPage() error {
+	return p.StartPage()
+}
+
+// Close drops a document that was started but not ended.
+func (p *lprPrinter) Close() error {
+	p.doc = nil
+	return nil
+}
@@ -0,0 +1,203 @@
+package winprinters
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chenxi2015/winprinters/lpd"
+)
+
+// lpdStandIn is an LPD server queueing jobs on "lp" in memory.
+type lpdStandIn struct {
+	mu   sync.Mutex
+	jobs []*lpd.ReceivedJob
+}
+
+func (s *lpdStandIn) Accept(r *lpd.Request) error {
+	if r.Queue != "lp" {
+		return fmt.Errorf("%s: unknown printer", r.Queue)
+	}
+	return nil
+}
+
+func (s *lpdStandIn) PrintWaiting(*lpd.Request) error {
+	return nil
+}
+
+func (s *lpdStandIn) ReceiveJob(_ *lpd.Request, job *lpd.ReceivedJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+func standInJobNumber(j *lpd.ReceivedJob) int {
+	n, _ := strconv.Atoi(j.ControlName[3:6])
+	return n
+}
+
+func (s *lpdStandIn) QueueState(w io.Writer, r *lpd.Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var entries []lpd.QueueEntry
+	for i, j := range s.jobs {
+		f := j.Control.Files[0]
+		entries = append(entries, lpd.QueueEntry{Rank: lpd.Rank(i), Owner: j.Control.User,
+			Job: standInJobNumber(j), Files: []string{f.Source}, Size: int64(len(j.Data[f.Name]))})
+	}
+	return lpd.WriteQueue(w, entries, r.Command == lpd.CmdQueueLong)
+}
+
+func (s *lpdStandIn) RemoveJobs(w io.Writer, r *lpd.Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range r.List {
+		for i, j := range s.jobs {
+			if strconv.Itoa(standInJobNumber(j)) == id && j.Control.User == r.Agent {
+				s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+				_, _ = fmt.Fprintf(w, "%s dequeued\n", j.ControlName)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func newLPDStandIn(t *testing.T) (*lpdStandIn, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	s := &lpdStandIn{}
+	go func() { _ = (&lpd.Server{Handler: s}).Serve(ln) }()
+	return s, ln.Addr().String()
+}
+
+func TestLPRSpooler(t *testing.T) {
+	standIn, addr := newLPDStandIn(t)
+	s := NewLPRSpooler(addr, "lp")
+	s.UserName, s.HostName, s.Copies, s.Timeout = "alice", "ws1", 2, 5*time.Second
+	defer SetSpooler(SetSpooler(s))
+
+	if names, err := ReadNames(); err != nil || len(names) != 1 || names[0] != "lp" {
+		t.Errorf("ReadNames = %q, %v", names, err)
+	}
+	p, err := Open("lp")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer closePrinter(p)
+
+	if _, err = p.StartDocument("report.txt", "TEXT"); err != nil {
+		t.Fatalf("StartDocument failed: %v", err)
+	}
+	if _, err = p.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if jobs, _ := p.Jobs(); len(jobs) != 0 {
+		t.Errorf("job queued before EndDocument: %+v", jobs)
+	}
+	if err = p.EndDocument(); err != nil {
+		t.Fatalf("EndDocument failed: %v", err)
+	}
+
+	standIn.mu.Lock()
+	job := standIn.jobs[0]
+	standIn.mu.Unlock()
+	f := job.Control.Files[0]
+	if job.Control.User != "alice" || job.Control.Host != "ws1" || job.Control.Job != "report.txt" ||
+		f.Format != lpd.FormatText || f.Copies != 2 || string(job.Data[f.Name]) != "hello\n" {
+		t.Errorf("server received %+v", job.Control)
+	}
+
+	jobs, err := p.Jobs()
+	if err != nil {
+		t.Fatalf("Jobs failed: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].JobID != uint32(standInJobNumber(job)) || jobs[0].UserName != "alice" ||
+		jobs[0].DocumentName != "report.txt" || jobs[0].StatusCode != JOB_STATUS_PRINTING {
+		t.Fatalf("Jobs = %+v", jobs)
+	}
+	if err = p.CancelJob(jobs[0].JobID); err != nil {
+		t.Fatalf("CancelJob failed: %v", err)
+	}
+	if jobs, _ = p.Jobs(); len(jobs) != 0 {
+		t.Errorf("Jobs after CancelJob = %+v", jobs)
+	}
+	if err = p.CancelJob(1000); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("CancelJob of a missing job = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestLPRSpooler_URI(t *testing.T) {
+	_, addr := newLPDStandIn(t)
+	s := NewLPRSpooler("")
+	h, err := s.Open("lpd://" + addr + "/lp")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	p := NewPrinter(h)
+	defer closePrinter(p)
+	if _, err = p.StartRawDocument("label"); err != nil {
+		t.Fatalf("StartRawDocument failed: %v", err)
+	}
+	if _, err = p.Write([]byte("^XA^XZ")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err = p.EndDocument(); err != nil {
+		t.Fatalf("EndDocument failed: %v", err)
+	}
+	if jobs, err := p.Jobs(); err != nil || len(jobs) != 1 || jobs[0].DocumentName != "label" {
+		t.Errorf("Jobs = %+v, %v", jobs, err)
+	}
+
+	for _, name := range []string{"lp", "lpd://" + addr + "/", "lpd://" + addr + "/a b"} {
+		if _, err = s.Open(name); err == nil {
+			t.Errorf("Open(%q) succeeded", name)
+		}
+	}
+	if _, err = p.StartDocument("rejected", "RAW"); err != nil {
+		t.Fatal(err)
+	}
+	h, _ = NewLPRSpooler(addr).Open("missing")
+	q := NewPrinter(h)
+	_, _ = q.StartDocument("x", "RAW")
+	if err = q.EndDocument(); !errors.Is(err, lpd.ErrRejected) {
+		t.Errorf("EndDocument on a missing queue = %v, want lpd.ErrRejected", err)
+	}
+}
+
+func TestLPRFormat(t *testing.T) {
+	for datatype, want := range map[string]byte{
+		"RAW": lpd.FormatRaw, "XPS_PASS": lpd.FormatRaw, "TEXT": lpd.FormatText,
+		"text/plain": lpd.FormatText, "application/postscript": lpd.FormatPostScript,
+	} {
+		if got := lprFormat(datatype); got != want {
+			t.Errorf("lprFormat(%q) = %c, want %c", datatype, got, want)
+		}
+	}
+}
+
+func TestOpenURI(t *testing.T) {
+	_, addr := newLPDStandIn(t)
+	for _, uri := range []string{"lpd://" + addr + "/lp", "socket://127.0.0.1:9100"} {
+		p, err := OpenURI(uri)
+		if err != nil {
+			t.Errorf("OpenURI(%q) failed: %v", uri, err)
+			continue
+		}
+		closePrinter(p)
+	}
+	for _, uri := range []string{"usb://Zebra/ZT410", "Office"} {
+		if _, err := OpenURI(uri); err == nil {
+			t.Errorf("OpenURI(%q) succeeded", uri)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package winprinters
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// pjlUEL is the PJL Universal Exit Language command, which ends the current
+// printer language and returns control to PJL.
+const pjlUEL = "\x1b%-12345X"
+
+// PJLStatus is the device status reported by "@PJL INFO STATUS".
+//
+// Codes from 10000 to 19999 are informational, such as 10001 (ready);
+// 30000 to 39999 are warnings the printer recovers from; 40000 and above
+// need an operator, such as 41xxx (paper out) or 42xxx (paper jam).
+type PJLStatus struct {
+	Code    int
+	Display string
+	Online  bool
+}
+
+// Ready reports whether the printer is online and has no error.
+func (s *PJLStatus) Ready() bool {
+	return s.Online && s.Code < 40000
+}
+
+// PJLStatusReader is implemented by PrinterHandles that can read the status
+// of PJL devices.
+type PJLStatusReader interface {
+	PJLStatus() (*PJLStatus, error)
+}
+
+// PJLStatus reads the device status of a PJL printer, such as one opened by
+// a SocketSpooler with PJL enabled.
+func (p *Printer) PJLStatus() (*PJLStatus, error) {
+	r, ok := p.h.(PJLStatusReader)
+	if !ok {
+		return nil, &UnsupportedError{Op: "PJLStatus"}
+	}
+	return r.PJLStatus()
+}
+
+// pjlName quotes a job name for @PJL JOB, dropping the characters PJL does
+// not allow in strings.
+func pjlName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '"' || r < ' ' {
+			return -1
+		}
+		return r
+	}, name)
+	return `"` + truncate(name, 80) + `"`
+}
+
+// pjlJobStart and pjlJobEnd wrap a document in a PJL job.
+func pjlJobStart(name string) []byte {
+	return []byte(pjlUEL + "@PJL JOB NAME=" + pjlName(name) + "\r\n")
+}
+
+func pjlJobEnd(name string) []byte {
+	return []byte(pjlUEL + "@PJL EOJ NAME=" + pjlName(name) + "\r\n" + pjlUEL)
+}
+
+// pjlStatusQuery asks the printer for its status.
+const pjlStatusQuery = pjlUEL + "@PJL INFO STATUS\r\n" + pjlUEL
+
+// readPJLStatus reads an "@PJL INFO STATUS" response, which ends with a
+// form feed.
+func readPJLStatus(r io.Reader) (*PJLStatus, error) {
+	resp, err := bufio.NewReader(r).ReadBytes('\f')
+	if err != nil {
+		return nil, fmt.Errorf("winprinters: reading PJL status: %w", err)
+	}
+	return parsePJLStatus(resp)
+}
+
+func parsePJLStatus(resp []byte) (*PJLStatus, error) {
+	i := bytes.Index(resp, []byte("@PJL INFO STATUS"))
+	if i < 0 {
+		return nil, errors.New("winprinters: no PJL INFO STATUS response")
+	}
+	st := &PJLStatus{}
+	seen := false
+	for _, line := range strings.Split(string(resp[i:]), "\n") {
+		key, value := line, ""
+		if j := strings.IndexByte(line, '='); j >= 0 {
+			key, value = line[:j], line[j+1:]
+		}
+		value = strings.TrimSpace(strings.TrimRight(value, "\f"))
+		switch strings.TrimSpace(key) {
+		case "CODE":
+			code, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("winprinters: bad PJL status code %q", value)
+			}
+			st.Code, seen = code, true
+		case "DISPLAY":
+			st.Display = strings.Trim(value, `"`)
+		case "ONLINE":
+			st.Online = strings.EqualFold(value, "TRUE")
+		}
+	}
+	if !seen {
+		return nil, errors.New("winprinters: PJL status without CODE")
+	}
+	return st, nil
+}
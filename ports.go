@@ -0,0 +1,320 @@
+package winprinters
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	PORT_TYPE_WRITE        = 0x0001
+	PORT_TYPE_READ         = 0x0002
+	PORT_TYPE_REDIRECTED   = 0x0004
+	PORT_TYPE_NET_ATTACHED = 0x0008
+
+	// Monitors of the ports Windows creates.
+	StandardTCPPortMonitor = "Standard TCP/IP Port"
+	LocalPortMonitor       = "Local Port"
+)
+
+// PortInfo describes a printer port, from PORT_INFO_1 or PORT_INFO_2.
+type PortInfo struct {
+	Name        string
+	MonitorName string // empty from level 1
+	Description string // empty from level 1
+	Type        uint32 // PORT_TYPE_* flags, zero from level 1
+}
+
+// PortProtocol is how a Standard TCP/IP port sends jobs.
+type PortProtocol uint32
+
+const (
+	// PortRaw sends jobs to a raw TCP port, usually 9100.
+	PortRaw PortProtocol = 1
+	// PortLPR sends jobs to an LPD queue on port 515.
+	PortLPR PortProtocol = 2
+)
+
+func (p PortProtocol) String() string {
+	switch p {
+	case PortRaw:
+		return "raw"
+	case PortLPR:
+		return "lpr"
+	}
+	return fmt.Sprintf("PortProtocol(%d)", uint32(p))
+}
+
+// PortConfig is the configuration of a Standard TCP/IP port, the
+// PORT_DATA_1 of its XcvData calls.
+type PortConfig struct {
+	Name       string // such as "IP_10.0.0.17"
+	Host       string // host name or IP address of the printer
+	Protocol   PortProtocol
+	PortNumber uint32 // 9100 for PortRaw and 515 for PortLPR when zero
+
+	Queue        string // LPD queue name, for PortLPR
+	ByteCounting bool   // LPR byte counting, for PortLPR
+
+	SNMPEnabled   bool
+	SNMPCommunity string // "public" when empty
+	SNMPDevIndex  uint32 // 1 when zero
+}
+
+// Sizes of the PORT_DATA_1 strings in characters, with the terminating NUL.
+const (
+	maxPortNameLen      = 64
+	maxNetworkNameLen   = 49
+	maxSNMPCommunityLen = 33
+	maxQueueNameLen     = 33
+	maxIPAddrLen        = 16
+)
+
+// Offsets in PORT_DATA_1: sztPortName, dwVersion, dwProtocol, cbSize,
+// dwReserved, sztHostAddress, sztSNMPCommunity, dwDoubleSpool, sztQueue,
+// sztIPAddress, Reserved[540], dwPortNumber, dwSNMPEnabled, dwSNMPDevIndex.
+const (
+	pd1Version       = 2 * maxPortNameLen
+	pd1Protocol      = pd1Version + 4
+	pd1Size          = pd1Protocol + 4
+	pd1HostAddress   = pd1Size + 8
+	pd1SNMPCommunity = pd1HostAddress + 2*maxNetworkNameLen
+	pd1DoubleSpool   = pd1SNMPCommunity + 2*maxSNMPCommunityLen
+	pd1Queue         = pd1DoubleSpool + 4
+	pd1IPAddress     = pd1Queue + 2*maxQueueNameLen
+	pd1PortNumber    = (pd1IPAddress + 2*maxIPAddrLen + 540 + 3) &^ 3
+	pd1SNMPEnabled   = pd1PortNumber + 4
+	pd1SNMPDevIndex  = pd1SNMPEnabled + 4
+	portData1Size    = pd1SNMPDevIndex + 4
+)
+
+// withDefaults checks c and fills in its default port number and SNMP
+// settings.
+func (c PortConfig) withDefaults() (PortConfig, error) {
+	switch {
+	case c.Name == "" || c.Host == "":
+		return c, fmt.Errorf("winprinters: port %q needs a name and a host", c.Name)
+	case c.Protocol != PortRaw && c.Protocol != PortLPR:
+		return c, fmt.Errorf("winprinters: port %q has unknown protocol %v", c.Name, c.Protocol)
+	case c.Protocol == PortLPR && c.Queue == "":
+		return c, fmt.Errorf("winprinters: LPR port %q needs a queue", c.Name)
+	}
+	for _, f := range []struct {
+		what string
+		s    string
+		max  int
+	}{
+		{"name", c.Name, maxPortNameLen},
+		{"host", c.Host, maxNetworkNameLen},
+		{"queue", c.Queue, maxQueueNameLen},
+		{"SNMP community", c.SNMPCommunity, maxSNMPCommunityLen},
+	} {
+		if n := len(utf16.Encode([]rune(f.s))); n >= f.max {
+			return c, fmt.Errorf("winprinters: port %s %q is longer than %d characters", f.what, f.s, f.max-1)
+		}
+	}
+	if c.PortNumber == 0 {
+		c.PortNumber = 9100
+		if c.Protocol == PortLPR {
+			c.PortNumber = 515
+		}
+	}
+	if c.SNMPEnabled {
+		if c.SNMPCommunity == "" {
+			c.SNMPCommunity = "public"
+		}
+		if c.SNMPDevIndex == 0 {
+			c.SNMPDevIndex = 1
+		}
+	}
+	return c, nil
+}
+
+// encodePortData1 returns c, checked by withDefaults, as a PORT_DATA_1.
+func encodePortData1(c *PortConfig) []byte {
+	b := make([]byte, portData1Size)
+	le := binary.LittleEndian
+	le.PutUint32(b[pd1Version:], 1)
+	le.PutUint32(b[pd1Protocol:], uint32(c.Protocol))
+	le.PutUint32(b[pd1Size:], portData1Size)
+	putFixedString(b, c.Name)
+	putFixedString(b[pd1HostAddress:], c.Host)
+	putFixedString(b[pd1SNMPCommunity:], c.SNMPCommunity)
+	if c.ByteCounting {
+		le.PutUint32(b[pd1DoubleSpool:], 1)
+	}
+	putFixedString(b[pd1Queue:], c.Queue)
+	le.PutUint32(b[pd1PortNumber:], c.PortNumber)
+	if c.SNMPEnabled {
+		le.PutUint32(b[pd1SNMPEnabled:], 1)
+	}
+	le.PutUint32(b[pd1SNMPDevIndex:], c.SNMPDevIndex)
+	return b
+}
+
+// decodePortData1 decodes the PORT_DATA_1 GetConfigInfo returns.
+func decodePortData1(b []byte) (*PortConfig, error) {
+	if len(b) < portData1Size {
+		return nil, fmt.Errorf("winprinters: port configuration of %d bytes is shorter than PORT_DATA_1", len(b))
+	}
+	le := binary.LittleEndian
+	c := &PortConfig{
+		Name:          fixedString(b, maxPortNameLen),
+		Host:          fixedString(b[pd1HostAddress:], maxNetworkNameLen),
+		Protocol:      PortProtocol(le.Uint32(b[pd1Protocol:])),
+		PortNumber:    le.Uint32(b[pd1PortNumber:]),
+		Queue:         fixedString(b[pd1Queue:], maxQueueNameLen),
+		ByteCounting:  le.Uint32(b[pd1DoubleSpool:]) != 0,
+		SNMPEnabled:   le.Uint32(b[pd1SNMPEnabled:]) != 0,
+		SNMPCommunity: fixedString(b[pd1SNMPCommunity:], maxSNMPCommunityLen),
+		SNMPDevIndex:  le.Uint32(b[pd1SNMPDevIndex:]),
+	}
+	if c.Host == "" {
+		c.Host = fixedString(b[pd1IPAddress:], maxIPAddrLen)
+	}
+	return c, nil
+}
+
+// encodeDeletePortData1 returns the DELETE_PORT_DATA_1 that deletes the
+// Standard TCP/IP port name: psztPortName[64], Reserved[98], then dwVersion
+// and dwReserved aligned to a DWORD.
+func encodeDeletePortData1(name string) []byte {
+	const version = (2*maxPortNameLen + 98 + 3) &^ 3
+	b := make([]byte, version+8)
+	putFixedString(b, name)
+	binary.LittleEndian.PutUint32(b[version:], 1)
+	return b
+}
+
+// putFixedString writes s as a NUL terminated UTF-16 string at the start of
+// b. The caller checked that it fits.
+func putFixedString(b []byte, s string) {
+	for i, c := range utf16.Encode([]rune(s)) {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+}
+
+// portInfoStride returns the size of a PORT_INFO_1 or PORT_INFO_2 structure
+// in a buffer of sb: the pPortName pointer, then for level 2 the
+// pMonitorName and pDescription pointers, fPortType and Reserved.
+func portInfoStride(sb spoolBuffer, level int) int {
+	if level == 1 {
+		return sb.ptr
+	}
+	return sb.align(3*sb.ptr + 8)
+}
+
+// decodePortInfo decodes count PORT_INFO_1 or PORT_INFO_2 structures from sb.
+func decodePortInfo(sb spoolBuffer, level, count int) ([]PortInfo, error) {
+	stride := portInfoStride(sb, level)
+	if err := sb.check(0, count*stride); err != nil {
+		return nil, err
+	}
+	ports := make([]PortInfo, 0, count)
+	for i := 0; i < count; i++ {
+		off := i * stride
+		var p PortInfo
+		strs := []*string{&p.Name}
+		if level == 2 {
+			strs = append(strs, &p.MonitorName, &p.Description)
+			p.Type = sb.uint32(off + 3*sb.ptr)
+		}
+		for n, s := range strs {
+			v, err := sb.string(off + n*sb.ptr)
+			if err != nil {
+				return nil, err
+			}
+			*s = v
+		}
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+// PortAdmin is implemented by Spoolers that list and create printer ports.
+type PortAdmin interface {
+	// Ports returns the ports of the print server.
+	Ports() ([]PortInfo, error)
+	// AddTCPPort creates a Standard TCP/IP port.
+	AddTCPPort(c PortConfig) error
+	// ConfigureTCPPort changes the Standard TCP/IP port named c.Name.
+	ConfigureTCPPort(c PortConfig) error
+	// TCPPortConfig returns the configuration of a Standard TCP/IP port.
+	TCPPortConfig(name string) (*PortConfig, error)
+	// AddLocalPort creates a Local Port, such as a file path.
+	AddLocalPort(name string) error
+	// DeletePort removes a port of the monitor.
+	DeletePort(monitor, name string) error
+}
+
+func portAdmin(op string) (PortAdmin, error) {
+	if a, ok := CurrentSpooler().(PortAdmin); ok {
+		return a, nil
+	}
+	return nil, &UnsupportedError{Op: op}
+}
+
+// Ports returns the printer ports of the current Spooler.
+func Ports() ([]PortInfo, error) {
+	a, err := portAdmin("Ports")
+	if err != nil {
+		return nil, err
+	}
+	return a.Ports()
+}
+
+// AddTCPPort creates a Standard TCP/IP port, printing raw to port 9100 or
+// to an LPD queue, to install printers on with AddPrinter.
+func AddTCPPort(c PortConfig) error {
+	a, err := portAdmin("AddTCPPort")
+	if err != nil {
+		return err
+	}
+	if c, err = c.withDefaults(); err != nil {
+		return err
+	}
+	return a.AddTCPPort(c)
+}
+
+// ConfigureTCPPort changes the configuration of the Standard TCP/IP port
+// named c.Name.
+func ConfigureTCPPort(c PortConfig) error {
+	a, err := portAdmin("ConfigureTCPPort")
+	if err != nil {
+		return err
+	}
+	if c, err = c.withDefaults(); err != nil {
+		return err
+	}
+	return a.ConfigureTCPPort(c)
+}
+
+// TCPPortConfig returns the configuration of a Standard TCP/IP port.
+func TCPPortConfig(name string) (*PortConfig, error) {
+	a, err := portAdmin("TCPPortConfig")
+	if err != nil {
+		return nil, err
+	}
+	return a.TCPPortConfig(name)
+}
+
+// AddLocalPort creates a Local Port, such as a file path or a shared
+// printer path like \\server\printer.
+func AddLocalPort(name string) error {
+	a, err := portAdmin("AddLocalPort")
+	if err != nil {
+		return err
+	}
+	return a.AddLocalPort(name)
+}
+
+// DeletePort removes a port of the given monitor, StandardTCPPortMonitor
+// or LocalPortMonitor.
+func DeletePort(monitor, name string) error {
+	a, err := portAdmin("DeletePort")
+	if err != nil {
+		return err
+	}
+	return a.DeletePort(monitor, name)
+}
@@ -0,0 +1,185 @@
+package winprinters
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodePortInfo(t *testing.T) {
+	want := []PortInfo{
+		{Name: "LPT1:", MonitorName: "Local Port", Description: "Local Port", Type: PORT_TYPE_WRITE | PORT_TYPE_READ},
+		{Name: "IP_10.0.0.17", MonitorName: "Standard TCP/IP Port", Description: "Standard TCP/IP Port", Type: PORT_TYPE_WRITE},
+		{Name: "PORTPROMPT:"},
+	}
+	for _, ptr := range []int{4, 8} {
+		for _, level := range []int{1, 2} {
+			stride := portInfoStride(spoolBuffer{ptr: ptr}, level)
+			w := newSpoolWriter(ptr, len(want)*stride)
+			for i, p := range want {
+				off := i * stride
+				w.string(off, p.Name)
+				if level == 2 {
+					if p.MonitorName != "" {
+						w.string(off+ptr, p.MonitorName)
+						w.string(off+2*ptr, p.Description)
+					}
+					w.uint32(off+3*ptr, p.Type)
+				}
+			}
+			got, err := decodePortInfo(w.sb, level, len(want))
+			if err != nil {
+				t.Fatalf("%d-byte pointers, level %d: decodePortInfo failed: %v", ptr, level, err)
+			}
+			for i := range got {
+				w := want[i]
+				if level == 1 {
+					w = PortInfo{Name: w.Name}
+				}
+				if got[i] != w {
+					t.Errorf("%d-byte pointers, level %d: port %d = %+v, want %+v", ptr, level, i, got[i], w)
+				}
+			}
+			if _, err = decodePortInfo(w.sb, level, len(want)+1); err == nil {
+				t.Errorf("%d-byte pointers, level %d: decoding past the buffer succeeded", ptr, level)
+			}
+		}
+	}
+}
+
+func TestPortData1(t *testing.T) {
+	if portData1Size != 964 {
+		t.Fatalf("PORT_DATA_1 is %d bytes, want 964", portData1Size)
+	}
+	c := PortConfig{
+		Name:          "LPR_printsrv_labels",
+		Host:          "printsrv.store12.example",
+		Protocol:      PortLPR,
+		Queue:         "labels",
+		ByteCounting:  true,
+		SNMPEnabled:   true,
+		SNMPCommunity: "store12",
+	}
+	c, err := c.withDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := encodePortData1(&c)
+	le := binary.LittleEndian
+	if le.Uint32(b[128:]) != 1 || le.Uint32(b[132:]) != 2 || le.Uint32(b[136:]) != 964 || le.Uint32(b[952:]) != 515 || le.Uint32(b[960:]) != 1 {
+		t.Errorf("PORT_DATA_1 version, protocol, size, port or SNMP index at the wrong offset")
+	}
+	got, err := decodePortData1(b)
+	if err != nil || !reflect.DeepEqual(*got, c) {
+		t.Errorf("decodePortData1() = %+v, %v, want %+v", got, err, c)
+	}
+
+	// Old ports keep the address in sztIPAddress.
+	b = encodePortData1(&PortConfig{Name: "IP_10.0.0.17", Protocol: PortRaw, PortNumber: 9100})
+	putFixedString(b[pd1IPAddress:], "10.0.0.17")
+	if got, err = decodePortData1(b); err != nil || got.Host != "10.0.0.17" {
+		t.Errorf("decodePortData1() of an IP address port = %+v, %v", got, err)
+	}
+	if _, err = decodePortData1(b[:900]); err == nil {
+		t.Errorf("decoding a short PORT_DATA_1 succeeded")
+	}
+
+	d := encodeDeletePortData1("IP_10.0.0.17")
+	if len(d) != 236 || le.Uint32(d[228:]) != 1 || fixedString(d, maxPortNameLen) != "IP_10.0.0.17" {
+		t.Errorf("DELETE_PORT_DATA_1 = %d bytes", len(d))
+	}
+}
+
+func TestPortConfig_withDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		in   PortConfig
+		want PortConfig
+		err  string
+	}{
+		{
+			name: "raw",
+			in:   PortConfig{Name: "IP_10.0.0.17", Host: "10.0.0.17", Protocol: PortRaw},
+			want: PortConfig{Name: "IP_10.0.0.17", Host: "10.0.0.17", Protocol: PortRaw, PortNumber: 9100},
+		},
+		{
+			name: "snmp",
+			in:   PortConfig{Name: "IP_10.0.0.18", Host: "10.0.0.18", Protocol: PortRaw, PortNumber: 9101, SNMPEnabled: true},
+			want: PortConfig{Name: "IP_10.0.0.18", Host: "10.0.0.18", Protocol: PortRaw, PortNumber: 9101, SNMPEnabled: true, SNMPCommunity: "public", SNMPDevIndex: 1},
+		},
+		{name: "no host", in: PortConfig{Name: "IP_", Protocol: PortRaw}, err: "needs a name and a host"},
+		{name: "no protocol", in: PortConfig{Name: "IP_1", Host: "h"}, err: "unknown protocol"},
+		{name: "lpr without queue", in: PortConfig{Name: "LPR_1", Host: "h", Protocol: PortLPR}, err: "needs a queue"},
+		{name: "long queue", in: PortConfig{Name: "LPR_1", Host: "h", Protocol: PortLPR, Queue: strings.Repeat("q", 33)}, err: "longer than 32"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.in.withDefaults()
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("withDefaults() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("withDefaults() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortsFake(t *testing.T) {
+	newTestSpooler(t)
+	if err := AddTCPPort(PortConfig{Name: "IP_10.0.12.41", Host: "10.0.12.41", Protocol: PortRaw}); err != nil {
+		t.Fatalf("AddTCPPort failed: %v", err)
+	}
+	if err := AddTCPPort(PortConfig{Name: "IP_10.0.12.41", Host: "10.0.12.41", Protocol: PortRaw}); err == nil {
+		t.Errorf("adding a port twice succeeded")
+	}
+	if err := AddLocalPort(`C:\spool\labels.prn`); err != nil {
+		t.Fatalf("AddLocalPort failed: %v", err)
+	}
+	ports, err := Ports()
+	if err != nil || len(ports) != 2 || ports[0].MonitorName != StandardTCPPortMonitor || ports[1].Name != `C:\spool\labels.prn` {
+		t.Errorf("Ports() = %+v, %v", ports, err)
+	}
+
+	c, err := TCPPortConfig("IP_10.0.12.41")
+	if err != nil || c.PortNumber != 9100 {
+		t.Fatalf("TCPPortConfig() = %+v, %v", c, err)
+	}
+	c.Protocol, c.Queue = PortLPR, "labels"
+	c.PortNumber = 0
+	if err = ConfigureTCPPort(*c); err != nil {
+		t.Fatalf("ConfigureTCPPort failed: %v", err)
+	}
+	if c, err = TCPPortConfig("IP_10.0.12.41"); err != nil || c.Protocol != PortLPR || c.PortNumber != 515 {
+		t.Errorf("TCPPortConfig() after ConfigureTCPPort = %+v, %v", c, err)
+	}
+	if _, err = TCPPortConfig(`C:\spool\labels.prn`); err == nil {
+		t.Errorf("TCPPortConfig() of a local port succeeded")
+	}
+
+	if err = DeletePort(StandardTCPPortMonitor, `C:\spool\labels.prn`); err == nil {
+		t.Errorf("deleting a port of another monitor succeeded")
+	}
+	for _, p := range ports {
+		if err = DeletePort(p.MonitorName, p.Name); err != nil {
+			t.Errorf("DeletePort(%q) failed: %v", p.Name, err)
+		}
+	}
+	if ports, err = Ports(); err != nil || len(ports) != 0 {
+		t.Errorf("Ports() after DeletePort = %+v, %v", ports, err)
+	}
+
+	files, err := NewFileSpooler(t.TempDir(), "Label")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetSpooler(files)
+	if _, err = Ports(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Ports() of a spooler without ports: %v", err)
+	}
+}
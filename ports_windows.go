@@ -0,0 +1,102 @@
+package winprinters
+
+import (
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Ports implements PortAdmin with EnumPorts level 2, falling back to level
+// 1 where the spooler does not know it.
+func (winspool) Ports() ([]PortInfo, error) {
+	return enumPorts("")
+}
+
+// enumPorts lists the ports of the print server, the local one when server
+// is empty.
+func enumPorts(server string) ([]PortInfo, error) {
+	name, err := optionalUTF16(server)
+	if err != nil {
+		return nil, err
+	}
+	level := uint32(2)
+	enum := func(buf *byte, bufN uint32, needed, returned *uint32) error {
+		return EnumPorts(name, level, buf, bufN, needed, returned)
+	}
+	sb, count, err := enumSpooler(enum)
+	if err == windows.ERROR_INVALID_LEVEL {
+		level = 1
+		sb, count, err = enumSpooler(enum)
+	}
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return decodePortInfo(sb, int(level), count)
+}
+
+func (winspool) AddTCPPort(c PortConfig) error {
+	_, err := xcvData(",XcvMonitor "+StandardTCPPortMonitor, "AddPort", encodePortData1(&c), 0)
+	return err
+}
+
+func (winspool) ConfigureTCPPort(c PortConfig) error {
+	_, err := xcvData(",XcvMonitor "+StandardTCPPortMonitor, "ConfigPort", encodePortData1(&c), 0)
+	return err
+}
+
+func (winspool) TCPPortConfig(name string) (*PortConfig, error) {
+	// CONFIG_INFO_DATA_1: Reserved[128], then dwVersion.
+	in := make([]byte, 132)
+	in[128] = 1
+	out, err := xcvData(",XcvPort "+name, "GetConfigInfo", in, portData1Size)
+	if err != nil {
+		return nil, err
+	}
+	return decodePortData1(out)
+}
+
+func (winspool) AddLocalPort(name string) error {
+	_, err := xcvData(",XcvMonitor "+LocalPortMonitor, "AddPort", utf16Bytes(name), 0)
+	return err
+}
+
+func (winspool) DeletePort(monitor, name string) error {
+	in := utf16Bytes(name)
+	if monitor == StandardTCPPortMonitor {
+		in = encodeDeletePortData1(name)
+	}
+	_, err := xcvData(",XcvMonitor "+monitor, "DeletePort", in, 0)
+	return err
+}
+
+// xcvData opens the port monitor object xcv, such as ",XcvMonitor Local
+// Port", and sends it the command with input, returning outputN bytes of
+// output. The status the monitor reports is the error.
+func xcvData(xcv, command string, input []byte, outputN int) ([]byte, error) {
+	p, err := openWinspool(xcv, &PrinterDefaults{DesiredAccess: SERVER_ACCESS_ADMINISTER})
+	if err != nil {
+		return nil, err
+	}
+	defer p.Close()
+	name, err := windows.UTF16PtrFromString(command)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, outputN+1) // never empty, for &out[0]
+	var needed, status uint32
+	err = XcvData(p.h, name, &input[0], uint32(len(input)), &out[0], uint32(outputN), &needed, &status)
+	if err != nil {
+		return nil, err
+	}
+	if status != 0 {
+		return nil, syscall.Errno(status)
+	}
+	return out[:outputN], nil
+}
+
+// utf16Bytes returns s as NUL terminated UTF-16 bytes.
+func utf16Bytes(s string) []byte {
+	u, _ := windows.UTF16FromString(s)
+	return append([]byte(nil), unsafe.Slice((*byte)(unsafe.Pointer(&u[0])), 2*len(u))...)
+}
@@ -0,0 +1,413 @@
+package winprinters
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PrinterSpec describes a print queue to install, or the desired state of
+// one for Reconcile.
+type PrinterSpec struct {
+	Name       string
+	PortName   string // such as "USB001" or a Standard TCP/IP port
+	DriverName string // an installed driver
+	ShareName  string // share name, shared only with PRINTER_ATTRIBUTE_SHARED
+	Location   string
+	Comment    string
+
+	// PrintProcessor and DataType default to "winprint" and "RAW" when
+	// the queue is added, and are left alone by Reconcile when empty.
+	PrintProcessor string
+	DataType       string
+
+	// Attributes holds the PRINTER_ATTRIBUTE_* flags of the queue.
+	// Reconcile only compares the flags an administrator sets, such as
+	// shared, queued, direct, keep printed jobs or work offline.
+	Attributes PrinterAttributes
+
+	// DevMode holds the default document settings. Reconcile only
+	// compares the fields it sets, such as the paper size or copies.
+	DevMode *DevMode
+}
+
+// configurableAttributes holds the PRINTER_ATTRIBUTE_* flags a PrinterSpec
+// controls; the spooler sets the others.
+const configurableAttributes = PRINTER_ATTRIBUTE_QUEUED | PRINTER_ATTRIBUTE_DIRECT | PRINTER_ATTRIBUTE_SHARED |
+	PRINTER_ATTRIBUTE_HIDDEN | PRINTER_ATTRIBUTE_ENABLE_DEVQ | PRINTER_ATTRIBUTE_KEEPPRINTEDJOBS |
+	PRINTER_ATTRIBUTE_DO_COMPLETE_FIRST | PRINTER_ATTRIBUTE_WORK_OFFLINE | PRINTER_ATTRIBUTE_ENABLE_BIDI |
+	PRINTER_ATTRIBUTE_RAW_ONLY
+
+// info returns spec as the PrinterInfo of a new queue.
+func (spec *PrinterSpec) info() *PrinterInfo {
+	info := &PrinterInfo{
+		Name:           spec.Name,
+		PortName:       spec.PortName,
+		DriverName:     spec.DriverName,
+		ShareName:      spec.ShareName,
+		Location:       spec.Location,
+		Comment:        spec.Comment,
+		PrintProcessor: spec.PrintProcessor,
+		DataType:       spec.DataType,
+		Attributes:     spec.Attributes,
+	}
+	if info.PrintProcessor == "" {
+		info.PrintProcessor = "winprint"
+	}
+	if info.DataType == "" {
+		info.DataType = "RAW"
+	}
+	if spec.DevMode != nil {
+		dm := *spec.DevMode
+		info.DevMode = &dm
+	}
+	return info
+}
+
+// diff returns the names of the PrinterInfo fields that differ from spec.
+func (spec *PrinterSpec) diff(info *PrinterInfo) []string {
+	var fields []string
+	strs := []struct {
+		name       string
+		want, have string
+		optional   bool
+	}{
+		{"PortName", spec.PortName, info.PortName, false},
+		{"DriverName", spec.DriverName, info.DriverName, false},
+		{"ShareName", spec.ShareName, info.ShareName, false},
+		{"Location", spec.Location, info.Location, false},
+		{"Comment", spec.Comment, info.Comment, false},
+		{"PrintProcessor", spec.PrintProcessor, info.PrintProcessor, true},
+		{"DataType", spec.DataType, info.DataType, true},
+	}
+	for _, s := range strs {
+		if s.want != s.have && !(s.optional && s.want == "") {
+			fields = append(fields, s.name)
+		}
+	}
+	if (spec.Attributes^info.Attributes)&configurableAttributes != 0 {
+		fields = append(fields, "Attributes")
+	}
+	if spec.DevMode != nil && devModeDiffers(spec.DevMode, info.DevMode) {
+		fields = append(fields, "DevMode")
+	}
+	return fields
+}
+
+// apply changes info to match spec.
+func (spec *PrinterSpec) apply(info *PrinterInfo) {
+	info.PortName = spec.PortName
+	info.DriverName = spec.DriverName
+	info.ShareName = spec.ShareName
+	info.Location = spec.Location
+	info.Comment = spec.Comment
+	if spec.PrintProcessor != "" {
+		info.PrintProcessor = spec.PrintProcessor
+	}
+	if spec.DataType != "" {
+		info.DataType = spec.DataType
+	}
+	info.Attributes = info.Attributes&^configurableAttributes | spec.Attributes&configurableAttributes
+	if spec.DevMode != nil {
+		if info.DevMode == nil {
+			dm := *spec.DevMode
+			info.DevMode = &dm
+		} else {
+			mergeDevMode(info.DevMode, spec.DevMode)
+		}
+	}
+}
+
+// devModeFields gets and sets the DevMode fields a PrinterSpec compares.
+var devModeFields = []struct {
+	get func(dm *DevMode) (interface{}, bool)
+	set func(dm, from *DevMode)
+}{
+	{
+		func(dm *DevMode) (interface{}, bool) { return dm.GetOrientation() },
+		func(dm, from *DevMode) { v, _ := from.GetOrientation(); dm.SetOrientation(v) },
+	},
+	{
+		func(dm *DevMode) (interface{}, bool) { return dm.GetPaperSize() },
+		func(dm, from *DevMode) { v, _ := from.GetPaperSize(); dm.SetPaperSize(v) },
+	},
+	{
+		func(dm *DevMode) (interface{}, bool) { return dm.GetPaperLength() },
+		func(dm, from *DevMode) { v, _ := from.GetPaperLength(); dm.SetPaperLength(v) },
+	},
+	{
+		func(dm *DevMode) (interface{}, bool) { return dm.GetPaperWidth() },
+		func(dm, from *DevMode) { v, _ := from.GetPaperWidth(); dm.SetPaperWidth(v) },
+	},
+	{
+		func(dm *DevMode) (interface{}, bool) { return dm.GetCopies() },
+		func(dm, from *DevMode) { v, _ := from.GetCopies(); dm.SetCopies(v) },
+	},
+	{
+		func(dm *DevMode) (interface{}, bool) { return dm.GetColor() },
+		func(dm, from *DevMode) { v, _ := from.GetColor(); dm.SetColor(v) },
+	},
+	{
+		func(dm *DevMode) (interface{}, bool) { return dm.GetDuplex() },
+		func(dm, from *DevMode) { v, _ := from.GetDuplex(); dm.SetDuplex(v) },
+	},
+	{
+		func(dm *DevMode) (interface{}, bool) { return dm.GetCollate() },
+		func(dm, from *DevMode) { v, _ := from.GetCollate(); dm.SetCollate(v) },
+	},
+	{
+		func(dm *DevMode) (interface{}, bool) { return dm.GetFormName() },
+		func(dm, from *DevMode) { v, _ := from.GetFormName(); dm.SetFormName(v) },
+	},
+}
+
+// devModeDiffers reports whether have lacks a field want sets, or holds
+// another value for it.
+func devModeDiffers(want, have *DevMode) bool {
+	for _, f := range devModeFields {
+		w, ok := f.get(want)
+		if !ok {
+			continue
+		}
+		if have == nil {
+			return true
+		}
+		if h, ok := f.get(have); !ok || h != w {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeDevMode copies the fields from sets into dm.
+func mergeDevMode(dm, from *DevMode) {
+	for _, f := range devModeFields {
+		if _, ok := f.get(from); ok {
+			f.set(dm, from)
+		}
+	}
+}
+
+// PrinterAdmin is implemented by Spoolers that can install and remove
+// print queues.
+type PrinterAdmin interface {
+	// CreatePrinter installs a queue. Its port and driver must exist.
+	CreatePrinter(spec PrinterSpec) error
+	// DeletePrinter removes the named queue, or returns
+	// ErrPrinterNotFound.
+	DeletePrinter(name string) error
+}
+
+// AddPrinter installs a print queue with the current Spooler. The name, port
+// and driver are required.
+func AddPrinter(spec PrinterSpec) error {
+	if spec.Name == "" || spec.PortName == "" || spec.DriverName == "" {
+		return fmt.Errorf("winprinters: printer %q needs a name, a port and a driver", spec.Name)
+	}
+	a, ok := CurrentSpooler().(PrinterAdmin)
+	if !ok {
+		return &UnsupportedError{Op: "AddPrinter"}
+	}
+	return a.CreatePrinter(spec)
+}
+
+// DeletePrinter removes the named print queue with the current Spooler.
+func DeletePrinter(name string) error {
+	a, ok := CurrentSpooler().(PrinterAdmin)
+	if !ok {
+		return &UnsupportedError{Op: "DeletePrinter"}
+	}
+	return a.DeletePrinter(name)
+}
+
+// ChangeAction is what a PrinterChange does to a queue.
+type ChangeAction int
+
+const (
+	ChangeCreate ChangeAction = iota + 1
+	ChangeUpdate
+	ChangeDelete
+)
+
+func (a ChangeAction) String() string {
+	switch a {
+	case ChangeCreate:
+		return "create"
+	case ChangeUpdate:
+		return "update"
+	case ChangeDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes a as its String, for JSON.
+func (a ChangeAction) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// PrinterChange is one step of a ReconcilePlan.
+type PrinterChange struct {
+	Action ChangeAction
+	Name   string
+	Spec   *PrinterSpec `json:",omitempty"` // desired state, nil for ChangeDelete
+	Fields []string     `json:",omitempty"` // PrinterInfo fields ChangeUpdate changes
+}
+
+func (c PrinterChange) String() string {
+	if c.Action == ChangeUpdate {
+		return fmt.Sprintf("update %q: %s", c.Name, strings.Join(c.Fields, ", "))
+	}
+	return fmt.Sprintf("%s %q", c.Action, c.Name)
+}
+
+// ReconcileOptions tunes PlanReconcile.
+type ReconcileOptions struct {
+	// Prune selects the installed queues missing from the specs to
+	// delete, such as those named with a store prefix. When nil, no
+	// queue is deleted. Printer connections are never candidates.
+	Prune func(name string) bool
+}
+
+// ReconcilePlan lists the changes that make the installed queues match a
+// desired state: creates and updates in the order of the specs, then
+// deletes by name.
+type ReconcilePlan struct {
+	Changes []PrinterChange
+}
+
+// Empty reports whether the queues already match the desired state.
+func (p *ReconcilePlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+func (p *ReconcilePlan) String() string {
+	if p.Empty() {
+		return "no changes"
+	}
+	lines := make([]string, len(p.Changes))
+	for i, c := range p.Changes {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PlanReconcile compares specs with the local queues of the current Spooler
+// and returns the changes that make them match, without applying them.
+// Printer connections like \\printsrv01\Labels are left alone: deleting one
+// would delete the shared queue of the print server.
+func PlanReconcile(specs []PrinterSpec, opts ReconcileOptions) (*ReconcilePlan, error) {
+	all, err := ReadNames()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	installed := make(map[string]bool, len(all))
+	for _, name := range all {
+		if !strings.HasPrefix(name, `\\`) {
+			names = append(names, name)
+			installed[name] = true
+		}
+	}
+	plan := new(ReconcilePlan)
+	wanted := make(map[string]bool, len(specs))
+	for i := range specs {
+		spec := &specs[i]
+		if spec.Name == "" || wanted[spec.Name] {
+			return nil, fmt.Errorf("winprinters: printer spec %d has an empty or duplicate name %q", i, spec.Name)
+		}
+		if strings.HasPrefix(spec.Name, `\\`) {
+			return nil, fmt.Errorf("winprinters: printer spec %q names a printer connection, not a local queue", spec.Name)
+		}
+		wanted[spec.Name] = true
+		if !installed[spec.Name] {
+			plan.Changes = append(plan.Changes, PrinterChange{Action: ChangeCreate, Name: spec.Name, Spec: spec})
+			continue
+		}
+		info, err := printerInfo(spec.Name)
+		if err != nil {
+			return nil, err
+		}
+		if fields := spec.diff(info); len(fields) > 0 {
+			plan.Changes = append(plan.Changes, PrinterChange{Action: ChangeUpdate, Name: spec.Name, Spec: spec, Fields: fields})
+		}
+	}
+	if opts.Prune != nil {
+		sort.Strings(names)
+		for _, name := range names {
+			if !wanted[name] && opts.Prune(name) {
+				plan.Changes = append(plan.Changes, PrinterChange{Action: ChangeDelete, Name: name})
+			}
+		}
+	}
+	return plan, nil
+}
+
+func printerInfo(name string) (*PrinterInfo, error) {
+	p, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Close()
+	return p.Info()
+}
+
+// ReconcileError is returned by ReconcilePlan.Apply for the change that
+// failed. The changes before it were applied.
+type ReconcileError struct {
+	Change PrinterChange
+	Err    error
+}
+
+func (e *ReconcileError) Error() string {
+	return "winprinters: " + e.Change.String() + ": " + e.Err.Error()
+}
+
+func (e *ReconcileError) Unwrap() error {
+	return e.Err
+}
+
+// Apply makes the changes of the plan with the current Spooler, stopping
+// at the first one that fails with a *ReconcileError.
+func (p *ReconcilePlan) Apply() error {
+	for _, c := range p.Changes {
+		if err := c.apply(); err != nil {
+			return &ReconcileError{Change: c, Err: err}
+		}
+	}
+	return nil
+}
+
+func (c *PrinterChange) apply() error {
+	switch c.Action {
+	case ChangeCreate:
+		return AddPrinter(*c.Spec)
+	case ChangeDelete:
+		return DeletePrinter(c.Name)
+	case ChangeUpdate:
+		p, err := Open(c.Name)
+		if err != nil {
+			return err
+		}
+		defer p.Close()
+		info, err := p.Info()
+		if err != nil {
+			return err
+		}
+		c.Spec.apply(info)
+		return p.Update(*info)
+	}
+	return errors.New("winprinters: unknown printer change")
+}
+
+// Reconcile creates, updates and deletes queues of the current Spooler to
+// match specs, and returns the plan it applied. Use PlanReconcile to
+// review the changes first.
+func Reconcile(specs []PrinterSpec, opts ReconcileOptions) (*ReconcilePlan, error) {
+	plan, err := PlanReconcile(specs, opts)
+	if err != nil {
+		return nil, err
+	}
+	return plan, plan.Apply()
+}
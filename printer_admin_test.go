@@ -0,0 +1,180 @@
+package winprinters
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddDeletePrinter(t *testing.T) {
+	newTestSpooler(t)
+	spec := PrinterSpec{Name: "Store 12 Receipts", PortName: "USB001", DriverName: "EPSON TM-T88V Receipt", Location: "Till 1"}
+	if err := AddPrinter(spec); err != nil {
+		t.Fatalf("AddPrinter failed: %v", err)
+	}
+	p, err := Open(spec.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := p.Info()
+	closePrinter(p)
+	if err != nil || info.PortName != "USB001" || info.DriverName != spec.DriverName || info.Location != "Till 1" ||
+		info.PrintProcessor != "winprint" || info.DataType != "RAW" {
+		t.Errorf("Info() of the added printer = %+v, %v", info, err)
+	}
+	if err = AddPrinter(spec); err == nil {
+		t.Errorf("adding a printer twice succeeded")
+	}
+	if err = AddPrinter(PrinterSpec{Name: "No driver", PortName: "USB002"}); err == nil {
+		t.Errorf("adding a printer without a driver succeeded")
+	}
+
+	if err = DeletePrinter(spec.Name); err != nil {
+		t.Fatalf("DeletePrinter failed: %v", err)
+	}
+	if err = DeletePrinter(spec.Name); !errors.Is(err, ErrPrinterNotFound) {
+		t.Errorf("deleting a deleted printer: %v", err)
+	}
+	if names, _ := ReadNames(); !reflect.DeepEqual(names, []string{"Label", "Office"}) {
+		t.Errorf("ReadNames() = %v", names)
+	}
+}
+
+func TestSpecDiff(t *testing.T) {
+	a4 := new(DevMode)
+	a4.SetPaperSize(9)
+	a4.SetCopies(1)
+	a4Duplex := *a4
+	a4Duplex.SetDuplex(DMDUP_VERTICAL)
+
+	spec := PrinterSpec{
+		Name:       "Office",
+		PortName:   "IP_10.0.0.20",
+		DriverName: "Microsoft PS Class Driver",
+		Attributes: PRINTER_ATTRIBUTE_SHARED,
+		ShareName:  "office",
+		DevMode:    a4,
+	}
+	same := PrinterInfo{
+		Name:           "Office",
+		PortName:       "IP_10.0.0.20",
+		DriverName:     "Microsoft PS Class Driver",
+		ShareName:      "office",
+		PrintProcessor: "winprint",
+		DataType:       "RAW",
+		Attributes:     PRINTER_ATTRIBUTE_SHARED | PRINTER_ATTRIBUTE_LOCAL | PRINTER_ATTRIBUTE_PUBLISHED,
+		DevMode:        &a4Duplex,
+	}
+	tests := []struct {
+		name   string
+		change func(info *PrinterInfo)
+		want   []string
+	}{
+		{name: "same", change: func(*PrinterInfo) {}},
+		{name: "port", change: func(i *PrinterInfo) { i.PortName = "USB001" }, want: []string{"PortName"}},
+		{name: "comment", change: func(i *PrinterInfo) { i.Comment = "old" }, want: []string{"Comment"}},
+		{name: "processor left alone", change: func(i *PrinterInfo) { i.PrintProcessor = "hpcpp" }},
+		{name: "not shared", change: func(i *PrinterInfo) { i.Attributes &^= PRINTER_ATTRIBUTE_SHARED }, want: []string{"Attributes"}},
+		{name: "spooler attributes", change: func(i *PrinterInfo) { i.Attributes |= PRINTER_ATTRIBUTE_DEFAULT }},
+		{name: "paper", change: func(i *PrinterInfo) { dm := *i.DevMode; dm.SetPaperSize(1); i.DevMode = &dm }, want: []string{"DevMode"}},
+		{name: "no DevMode", change: func(i *PrinterInfo) { i.DevMode = nil }, want: []string{"DevMode"}},
+		{
+			name:   "several",
+			change: func(i *PrinterInfo) { i.DriverName = "HP Universal"; i.Location = "2F" },
+			want:   []string{"DriverName", "Location"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := same
+			tt.change(&info)
+			got := spec.diff(&info)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("diff() = %v, want %v", got, tt.want)
+			}
+			spec.apply(&info)
+			if got = spec.diff(&info); got != nil {
+				t.Errorf("diff() after apply = %v", got)
+			}
+			if !info.Attributes.Local() {
+				t.Errorf("apply dropped the spooler attributes: %v", info.Attributes)
+			}
+		})
+	}
+	if d, _ := same.DevMode.GetDuplex(); d != DMDUP_VERTICAL {
+		t.Errorf("apply changed a DevMode field the spec does not set")
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	fake := newTestSpooler(t)
+	for _, spec := range []PrinterSpec{
+		{Name: "S12 Kitchen", PortName: "IP_10.0.12.31", DriverName: "EPSON TM-T20", Comment: "old"},
+		{Name: "S12 Old Labels", PortName: "IP_10.0.12.40", DriverName: "ZDesigner"},
+		{Name: "S12 Receipts", PortName: "USB001", DriverName: "EPSON TM-T88V"},
+	} {
+		if err := AddPrinter(spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	specs := []PrinterSpec{
+		{Name: "S12 Receipts", PortName: "USB001", DriverName: "EPSON TM-T88V"},
+		{Name: "S12 Kitchen", PortName: "IP_10.0.12.32", DriverName: "EPSON TM-T20", Comment: "Hot line"},
+		{Name: "S12 Labels", PortName: "IP_10.0.12.41", DriverName: "ZDesigner", Attributes: PRINTER_ATTRIBUTE_SHARED, ShareName: "labels"},
+	}
+	opts := ReconcileOptions{Prune: func(name string) bool { return strings.HasPrefix(name, "S12 ") }}
+
+	plan, err := PlanReconcile(specs, opts)
+	if err != nil {
+		t.Fatalf("PlanReconcile failed: %v", err)
+	}
+	want := `update "S12 Kitchen": PortName, Comment
+create "S12 Labels"
+delete "S12 Old Labels"`
+	if plan.String() != want {
+		t.Errorf("plan:\n%s\nwant:\n%s", plan, want)
+	}
+	if names, _ := ReadNames(); len(names) != 5 {
+		t.Errorf("PlanReconcile changed the printers: %v", names)
+	}
+
+	fake.InjectError("S12 Labels", "CreatePrinter", errors.New("driver not installed"))
+	var re *ReconcileError
+	if err = plan.Apply(); !errors.As(err, &re) || re.Change.Name != "S12 Labels" {
+		t.Fatalf("Apply with a failing create: %v", err)
+	}
+	fake.InjectError("S12 Labels", "CreatePrinter", nil)
+
+	if plan, err = Reconcile(specs, opts); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(plan.Changes) != 2 || plan.Changes[0].Action != ChangeCreate || plan.Changes[1].Action != ChangeDelete {
+		t.Errorf("Reconcile after a partial apply:\n%s", plan)
+	}
+	if plan, err = PlanReconcile(specs, opts); err != nil || !plan.Empty() {
+		t.Errorf("plan after Reconcile:\n%s, %v", plan, err)
+	}
+	if names, _ := ReadNames(); !reflect.DeepEqual(names, []string{"Label", "Office", "S12 Kitchen", "S12 Receipts", "S12 Labels"}) {
+		t.Errorf("ReadNames() = %v", names)
+	}
+
+	if _, err = PlanReconcile([]PrinterSpec{specs[0], specs[0]}, opts); err == nil {
+		t.Errorf("PlanReconcile with a duplicate spec succeeded")
+	}
+
+	// Printer connections are not local queues: deleting one would delete
+	// the shared queue of its server.
+	fake.AddServer(FakeServer{Name: `\\printsrv01`})
+	fake.AddPrinter(FakePrinter{Name: `\\printsrv01\S12 Shared`})
+	if err = AddPrinterConnection(`\\printsrv01\S12 Shared`); err != nil {
+		t.Fatal(err)
+	}
+	opts.Prune = func(name string) bool { return strings.Contains(name, "S12 ") }
+	if plan, err = PlanReconcile(specs, opts); err != nil || !plan.Empty() {
+		t.Errorf("plan with a printer connection:\n%s, %v", plan, err)
+	}
+	if _, err = PlanReconcile([]PrinterSpec{{Name: `\\printsrv01\S12 Shared`}}, opts); err == nil {
+		t.Errorf("PlanReconcile of a printer connection succeeded")
+	}
+}
@@ -0,0 +1,41 @@
+package winprinters
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	PRINTER_ACCESS_ADMINISTER = 0x00000004
+	PRINTER_ACCESS_USE        = 0x00000008
+	PRINTER_ALL_ACCESS        = windows.STANDARD_RIGHTS_REQUIRED | PRINTER_ACCESS_ADMINISTER | PRINTER_ACCESS_USE
+)
+
+// CreatePrinter implements PrinterAdmin with AddPrinter level 2.
+func (winspool) CreatePrinter(spec PrinterSpec) error {
+	pi, err := newPrinterInfo2(spec.info())
+	if err != nil {
+		return err
+	}
+	h, err := addPrinter(nil, 2, (*byte)(unsafe.Pointer(pi)))
+	if err != nil {
+		return err
+	}
+	return ClosePrinter(h)
+}
+
+// DeletePrinter implements PrinterAdmin. The queue goes once its jobs are
+// done or deleted.
+func (winspool) DeletePrinter(name string) error {
+	p, err := openWinspool(name, &PrinterDefaults{DesiredAccess: PRINTER_ALL_ACCESS})
+	if err == windows.ERROR_INVALID_PRINTER_NAME {
+		return ErrPrinterNotFound
+	}
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	return deletePrinter(p.h)
+}
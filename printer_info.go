@@ -0,0 +1,241 @@
+package winprinters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// PrinterAttributes is a PRINTER_ATTRIBUTE_* bitmask.
+type PrinterAttributes uint32
+
+// printerAttributeNames holds the JSON names of the PRINTER_ATTRIBUTE_*
+// flags, in bit order. Bit 16 is unused.
+var printerAttributeNames = []string{
+	"queued",
+	"direct",
+	"default",
+	"shared",
+	"network",
+	"hidden",
+	"local",
+	"enable-devq",
+	"keep-printed-jobs",
+	"do-complete-first",
+	"work-offline",
+	"enable-bidi",
+	"raw-only",
+	"published",
+	"fax",
+	"ts",
+	"",
+	"pushed-user",
+	"pushed-machine",
+	"machine",
+	"friendly-name",
+	"ts-generic-driver",
+	"per-user",
+	"enterprise-cloud",
+}
+
+func (a PrinterAttributes) Queued() bool      { return a&PRINTER_ATTRIBUTE_QUEUED != 0 }
+func (a PrinterAttributes) Direct() bool      { return a&PRINTER_ATTRIBUTE_DIRECT != 0 }
+func (a PrinterAttributes) Default() bool     { return a&PRINTER_ATTRIBUTE_DEFAULT != 0 }
+func (a PrinterAttributes) Shared() bool      { return a&PRINTER_ATTRIBUTE_SHARED != 0 }
+func (a PrinterAttributes) Network() bool     { return a&PRINTER_ATTRIBUTE_NETWORK != 0 }
+func (a PrinterAttributes) Hidden() bool      { return a&PRINTER_ATTRIBUTE_HIDDEN != 0 }
+func (a PrinterAttributes) Local() bool       { return a&PRINTER_ATTRIBUTE_LOCAL != 0 }
+func (a PrinterAttributes) WorkOffline() bool { return a&PRINTER_ATTRIBUTE_WORK_OFFLINE != 0 }
+func (a PrinterAttributes) EnableBidi() bool  { return a&PRINTER_ATTRIBUTE_ENABLE_BIDI != 0 }
+func (a PrinterAttributes) RawOnly() bool     { return a&PRINTER_ATTRIBUTE_RAW_ONLY != 0 }
+func (a PrinterAttributes) Published() bool   { return a&PRINTER_ATTRIBUTE_PUBLISHED != 0 }
+
+func (a PrinterAttributes) KeepPrintedJobs() bool {
+	return a&PRINTER_ATTRIBUTE_KEEPPRINTEDJOBS != 0
+}
+
+// Names returns the machine-readable names of the flags in a, such as
+// "shared" or "work-offline". Flags without a name are written in hex.
+func (a PrinterAttributes) Names() []string {
+	return flagNames(uint32(a), printerAttributeNames)
+}
+
+func (a PrinterAttributes) String() string {
+	if a == 0 {
+		return "none"
+	}
+	return strings.Join(a.Names(), ", ")
+}
+
+// MarshalJSON encodes a as an array of flag names.
+func (a PrinterAttributes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.Names())
+}
+
+// UnmarshalJSON decodes an array of flag names or a plain number.
+func (a *PrinterAttributes) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalFlags(b, printerAttributeNames, "printer attribute")
+	*a = PrinterAttributes(v)
+	return err
+}
+
+// PrinterInfo stores the PRINTER_INFO_2 information about a printer. It
+// holds no references to spooler memory, so it may be kept, copied and
+// changed freely, then passed to Printer.Update.
+type PrinterInfo struct {
+	ServerName string // empty for a local printer
+	Name       string
+	ShareName  string
+	PortName   string
+	DriverName string
+	Comment    string
+	Location   string
+
+	// DevMode holds the default document settings of the printer, or is
+	// nil when the spooler reports none.
+	DevMode *DevMode
+
+	SepFile        string // separator page file
+	PrintProcessor string
+	DataType       string // default data type, such as "RAW"
+	Parameters     string // print processor parameters
+
+	Attributes      PrinterAttributes
+	Priority        uint32
+	DefaultPriority uint32 // priority of new jobs
+
+	// StartTime and UntilTime bound the time of day, in UTC, when the
+	// printer prints. Both are zero when it prints at any time.
+	StartTime time.Duration
+	UntilTime time.Duration
+
+	Status     PrinterStatus
+	Jobs       uint32 // number of queued jobs
+	AveragePPM uint32 // average pages per minute
+
+	// devModeExtra is the private driver data that followed DevMode,
+	// written back by Update while DevMode still declares its size.
+	devModeExtra []byte
+}
+
+// PrinterInfoReader is implemented by PrinterHandles that report the
+// PRINTER_INFO_2 information of the printer.
+type PrinterInfoReader interface {
+	PrinterInfo() (*PrinterInfo, error)
+}
+
+// PrinterUpdater is implemented by PrinterHandles that can change the
+// PRINTER_INFO_2 information of the printer.
+type PrinterUpdater interface {
+	UpdatePrinter(info *PrinterInfo) error
+}
+
+// Info returns the PRINTER_INFO_2 information about the printer: its
+// ports, driver, attributes, status and default DevMode.
+func (p *Printer) Info() (*PrinterInfo, error) {
+	r, ok := p.h.(PrinterInfoReader)
+	if !ok {
+		return nil, &UnsupportedError{Op: "Info"}
+	}
+	return r.PrinterInfo()
+}
+
+// Update changes the printer to match info, usually as returned by Info
+// and then modified. Status, Jobs and AveragePPM are read-only and
+// ignored. StartTime and UntilTime are rounded down to the minute.
+func (p *Printer) Update(info PrinterInfo) error {
+	u, ok := p.h.(PrinterUpdater)
+	if !ok {
+		return &UnsupportedError{Op: "Update"}
+	}
+	for _, t := range []time.Duration{info.StartTime, info.UntilTime} {
+		if t < 0 || t >= 24*time.Hour {
+			return fmt.Errorf("winprinters: printer time of day %v is not within a day", t)
+		}
+	}
+	return u.UpdatePrinter(&info)
+}
+
+// GetDataType returns the default data type of the printer.
+func (p *Printer) GetDataType() (string, error) {
+	info, err := p.Info()
+	if err != nil {
+		return "", err
+	}
+	return info.DataType, nil
+}
+
+// printerInfo2Stride returns the size of a PRINTER_INFO_2 structure in a
+// buffer of sb: thirteen pointers, from pServerName to
+// pSecurityDescriptor, then eight DWORDs from Attributes to AveragePPM.
+func printerInfo2Stride(sb spoolBuffer) int {
+	return sb.align(13*sb.ptr + 8*4)
+}
+
+// decodePrinterInfo2 decodes count PRINTER_INFO_2 structures from sb.
+func decodePrinterInfo2(sb spoolBuffer, count int) ([]PrinterInfo, error) {
+	stride := printerInfo2Stride(sb)
+	if err := sb.check(0, count*stride); err != nil {
+		return nil, err
+	}
+	infos := make([]PrinterInfo, 0, count)
+	for i := 0; i < count; i++ {
+		off := i * stride
+		ptr := func(n int) int { return off + n*sb.ptr }
+		d := ptr(13)
+		info := PrinterInfo{
+			Attributes:      PrinterAttributes(sb.uint32(d)),
+			Priority:        sb.uint32(d + 4),
+			DefaultPriority: sb.uint32(d + 8),
+			StartTime:       time.Duration(sb.uint32(d+12)) * time.Minute,
+			UntilTime:       time.Duration(sb.uint32(d+16)) * time.Minute,
+			Status:          PrinterStatus(sb.uint32(d + 20)),
+			Jobs:            sb.uint32(d + 24),
+			AveragePPM:      sb.uint32(d + 28),
+		}
+		strs := []*string{
+			&info.ServerName, &info.Name, &info.ShareName, &info.PortName, &info.DriverName, &info.Comment, &info.Location,
+			nil, // pDevMode
+			&info.SepFile, &info.PrintProcessor, &info.DataType, &info.Parameters,
+		}
+		for n, s := range strs {
+			if s == nil {
+				continue
+			}
+			v, err := sb.string(ptr(n))
+			if err != nil {
+				return nil, err
+			}
+			*s = v
+		}
+		var err error
+		if info.DevMode, err = sb.devMode(ptr(7)); err != nil {
+			return nil, err
+		}
+		if info.devModeExtra, err = sb.devModeExtra(ptr(7)); err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// encodeDevMode returns dm as a DEVMODE for the spooler, followed by the
+// private driver data extra. The driver data is dropped, and dmDriverExtra
+// cleared, unless dm still declares it.
+func encodeDevMode(dm *DevMode, extra []byte) []byte {
+	c := *dm
+	max := uint16(unsafe.Sizeof(c))
+	if c.dmSize == 0 || c.dmSize > max {
+		c.dmSize = max
+	}
+	if int(c.dmDriverExtra) != len(extra) {
+		c.dmDriverExtra = 0
+		extra = nil
+	}
+	b := make([]byte, 0, int(max)+len(extra))
+	b = append(b, unsafe.Slice((*byte)(unsafe.Pointer(&c)), c.dmSize)...)
+	return append(b, extra...)
+}
@@ -0,0 +1,263 @@
+package winprinters
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// printerInfo2Buffer returns info as a synthetic GetPrinter level 2 buffer,
+// its DevMode followed by extra bytes of private driver data.
+func printerInfo2Buffer(ptr int, info *PrinterInfo, extra []byte) spoolBuffer {
+	w := newSpoolWriter(ptr, printerInfo2Stride(spoolBuffer{ptr: ptr}))
+	strs := []string{
+		info.ServerName, info.Name, info.ShareName, info.PortName, info.DriverName, info.Comment, info.Location,
+		"", // pDevMode
+		info.SepFile, info.PrintProcessor, info.DataType, info.Parameters,
+	}
+	for n, s := range strs {
+		if s != "" {
+			w.string(n*ptr, s)
+		}
+	}
+	if info.DevMode != nil {
+		dm := *info.DevMode
+		w.devMode(7*ptr, &dm, len(extra))
+		copy(w.sb.b[len(w.sb.b)-len(extra):], extra)
+	}
+	d := 13 * ptr
+	for n, v := range []uint32{
+		uint32(info.Attributes), info.Priority, info.DefaultPriority,
+		uint32(info.StartTime / time.Minute), uint32(info.UntilTime / time.Minute),
+		uint32(info.Status), info.Jobs, info.AveragePPM,
+	} {
+		w.uint32(d+4*n, v)
+	}
+	return w.sb
+}
+
+func TestDecodePrinterInfo2(t *testing.T) {
+	dm := new(DevMode)
+	dm.SetCopies(2)
+	dm.SetDuplex(DMDUP_VERTICAL)
+	dm.SetFormName("4x6")
+	dm.dmSize = uint16(unsafe.Sizeof(*dm))
+	extra := []byte{0x5a, 0x44, 0x01, 0x02, 0x03}
+	want := PrinterInfo{
+		Name:            "Label",
+		ShareName:       "label-01",
+		PortName:        "IP_10.0.0.17",
+		DriverName:      "ZDesigner ZD420-203dpi ZPL",
+		Comment:         "Warehouse 3",
+		Location:        "Dock door 2",
+		DevMode:         dm,
+		PrintProcessor:  "winprint",
+		DataType:        "RAW",
+		Attributes:      PRINTER_ATTRIBUTE_SHARED | PRINTER_ATTRIBUTE_LOCAL | PRINTER_ATTRIBUTE_ENABLE_BIDI,
+		Priority:        1,
+		DefaultPriority: 5,
+		StartTime:       22 * time.Hour,
+		UntilTime:       6*time.Hour + 30*time.Minute,
+		Status:          PRINTER_STATUS_PAPER_OUT | PRINTER_STATUS_USER_INTERVENTION,
+		Jobs:            3,
+		AveragePPM:      12,
+	}
+	for _, ptr := range []int{4, 8} {
+		got, err := decodePrinterInfo2(printerInfo2Buffer(ptr, &want, extra), 1)
+		if err != nil {
+			t.Fatalf("%d-byte pointers: decodePrinterInfo2 failed: %v", ptr, err)
+		}
+		w := want
+		w.devModeExtra = extra
+		w.DevMode.dmDriverExtra = uint16(len(extra))
+		if !reflect.DeepEqual(got[0], w) {
+			t.Errorf("%d-byte pointers: decoded %+v, want %+v", ptr, got[0], w)
+		}
+		w.DevMode.dmDriverExtra = 0
+
+		sb := printerInfo2Buffer(ptr, &PrinterInfo{Name: "Office"}, nil)
+		if got, err = decodePrinterInfo2(sb, 1); err != nil || got[0].DevMode != nil || got[0].devModeExtra != nil {
+			t.Errorf("%d-byte pointers: decoded printer without DevMode %+v, %v", ptr, got, err)
+		}
+		sb.b = sb.b[:printerInfo2Stride(sb)-4]
+		if _, err = decodePrinterInfo2(sb, 1); err == nil {
+			t.Errorf("%d-byte pointers: decoding a truncated buffer succeeded", ptr)
+		}
+	}
+}
+
+func TestEncodeDevMode(t *testing.T) {
+	size := int(unsafe.Sizeof(DevMode{}))
+	dm := new(DevMode)
+	dm.SetCopies(3)
+	dm.dmDriverExtra = 3
+	extra := []byte{1, 2, 3}
+
+	b := encodeDevMode(dm, extra)
+	if len(b) != size+3 || !reflect.DeepEqual(b[size:], extra) {
+		t.Fatalf("encodeDevMode with driver data = %d bytes ending %v", len(b), b[size:])
+	}
+	sb := spoolBuffer{b: b, ptr: 8}
+	sb.b = append(make([]byte, 8), b...)
+	sb.base = 0x1000
+	sb.b[0], sb.b[1] = 0x08, 0x10 // pointer to offset 8
+	got, err := sb.devMode(0)
+	if err != nil || got.dmSize != uint16(size) || got.dmDriverExtra != 3 {
+		t.Fatalf("encoded DevMode decodes to %+v, %v", got, err)
+	}
+	if n, _ := got.GetCopies(); n != 3 {
+		t.Errorf("encoded DevMode copies = %d, want 3", n)
+	}
+	if e, err := sb.devModeExtra(0); err != nil || !reflect.DeepEqual(e, extra) {
+		t.Errorf("encoded driver data = %v, %v", e, err)
+	}
+	if dm.dmSize != 0 {
+		t.Errorf("encodeDevMode changed its argument")
+	}
+
+	// Driver data the DevMode no longer declares is dropped.
+	dm.dmDriverExtra = 0
+	if b = encodeDevMode(dm, extra); len(b) != size {
+		t.Errorf("encodeDevMode with stale driver data = %d bytes, want %d", len(b), size)
+	}
+	dm.dmDriverExtra = 7
+	b = encodeDevMode(dm, extra)
+	if sb = (spoolBuffer{b: b}); len(b) != size || sb.uint16(CCHDEVICENAME*2+6) != 0 {
+		t.Errorf("encodeDevMode with mismatched driver data = %d bytes", len(b))
+	}
+}
+
+func TestPrinterFlags(t *testing.T) {
+	tests := []struct {
+		flags interface {
+			Names() []string
+			String() string
+		}
+		names []string
+		str   string
+	}{
+		{PrinterStatus(0), []string{}, "Ready"},
+		{PrinterStatus(PRINTER_STATUS_PAPER_JAM | PRINTER_STATUS_TONER_LOW), []string{"paper-jam", "toner-low"}, "Paper Jam, Toner Low"},
+		{PrinterStatus(PRINTER_STATUS_OFFLINE | 0x80000000), []string{"offline", "0x80000000"}, "Offline, 0x80000000"},
+		{PrinterAttributes(0), []string{}, "none"},
+		{PrinterAttributes(PRINTER_ATTRIBUTE_SHARED | PRINTER_ATTRIBUTE_KEEPPRINTEDJOBS), []string{"shared", "keep-printed-jobs"}, "shared, keep-printed-jobs"},
+		{PrinterAttributes(0x10000 | PRINTER_ATTRIBUTE_PUSHED_USER), []string{"0x10000", "pushed-user"}, "0x10000, pushed-user"},
+	}
+	for _, tt := range tests {
+		if got := tt.flags.Names(); !reflect.DeepEqual(got, tt.names) {
+			t.Errorf("%#x Names() = %q, want %q", tt.flags, got, tt.names)
+		}
+		if got := tt.flags.String(); got != tt.str {
+			t.Errorf("%#x String() = %q, want %q", tt.flags, got, tt.str)
+		}
+	}
+
+	a := PrinterAttributes(PRINTER_ATTRIBUTE_NETWORK | PRINTER_ATTRIBUTE_WORK_OFFLINE)
+	if !a.Network() || !a.WorkOffline() || a.Shared() || a.Local() {
+		t.Errorf("%v: wrong flag accessors", a)
+	}
+}
+
+func TestPrinterInfo_JSON(t *testing.T) {
+	info := PrinterInfo{
+		Name:       "Label",
+		Attributes: PRINTER_ATTRIBUTE_LOCAL | PRINTER_ATTRIBUTE_RAW_ONLY,
+		Status:     PRINTER_STATUS_DOOR_OPEN,
+	}
+	b, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var m map[string]interface{}
+	_ = json.Unmarshal(b, &m)
+	if want := []interface{}{"local", "raw-only"}; !reflect.DeepEqual(m["Attributes"], want) {
+		t.Errorf("Attributes JSON = %v, want %v", m["Attributes"], want)
+	}
+	if want := []interface{}{"door-open"}; !reflect.DeepEqual(m["Status"], want) {
+		t.Errorf("Status JSON = %v, want %v", m["Status"], want)
+	}
+	var got PrinterInfo
+	if err = json.Unmarshal(b, &got); err != nil || !reflect.DeepEqual(got, info) {
+		t.Errorf("Unmarshal = %+v, %v", got, err)
+	}
+
+	var s PrinterStatus
+	if err = json.Unmarshal([]byte(`["offline","0x100"]`), &s); err != nil || s != PRINTER_STATUS_OFFLINE|PRINTER_STATUS_IO_ACTIVE {
+		t.Errorf("Unmarshal hex flag = %#x, %v", uint32(s), err)
+	}
+	if err = json.Unmarshal([]byte("128"), &s); err != nil || s != PRINTER_STATUS_OFFLINE {
+		t.Errorf("Unmarshal(128) = %#x, %v", uint32(s), err)
+	}
+	for _, bad := range []string{`["offline","jammed"]`, `"offline"`, `{}`} {
+		if err = json.Unmarshal([]byte(bad), &s); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestPrinter_InfoUpdate(t *testing.T) {
+	fake := newTestSpooler(t)
+	if err := fake.UpdatePrinter("Label", func(p *FakePrinter) {
+		p.Status = PRINTER_STATUS_PAPER_OUT
+		p.Info = PrinterInfo{PortName: "USB001", DataType: "RAW", Attributes: PRINTER_ATTRIBUTE_LOCAL}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Open("Label")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closePrinter(p)
+
+	info, err := p.Info()
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if info.Name != "Label" || info.PortName != "USB001" || info.Status != PRINTER_STATUS_PAPER_OUT || info.Jobs != 1 || info.DriverName == "" {
+		t.Errorf("Info() = %+v", info)
+	}
+	if dt, err := p.GetDataType(); err != nil || dt != "RAW" {
+		t.Errorf("GetDataType() = %q, %v", dt, err)
+	}
+
+	info.Name = "Label 2"
+	info.Comment = "Dock door 2"
+	info.Attributes |= PRINTER_ATTRIBUTE_SHARED
+	info.DevMode = new(DevMode)
+	info.DevMode.SetCopies(2)
+	info.UntilTime = 6 * time.Hour
+	if err = p.Update(*info); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	info.DevMode.SetCopies(9) // the caller's copy is not kept
+	got, err := p.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Label 2" || got.Comment != "Dock door 2" || !got.Attributes.Shared() || got.UntilTime != 6*time.Hour {
+		t.Errorf("Info() after Update = %+v", got)
+	}
+	if n, _ := got.DevMode.GetCopies(); n != 2 {
+		t.Errorf("DevMode copies after Update = %d, want 2", n)
+	}
+	if names, _ := ReadNames(); !reflect.DeepEqual(names, []string{"Label 2", "Office"}) {
+		t.Errorf("ReadNames() after rename = %v", names)
+	}
+
+	got.StartTime = 25 * time.Hour
+	if err = p.Update(*got); err == nil {
+		t.Errorf("Update with a start time past midnight succeeded")
+	}
+	got.StartTime = 0
+	got.Name = "Office"
+	if err = p.Update(*got); err == nil {
+		t.Errorf("renaming over another printer succeeded")
+	}
+	fake.InjectError("Label 2", "UpdatePrinter", errors.New("access denied"))
+	if err = p.Update(*info); err == nil {
+		t.Errorf("Update with an injected error succeeded")
+	}
+}
@@ -0,0 +1,182 @@
+package winprinters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PrinterStatus is a PRINTER_STATUS_* bitmask. The zero PrinterStatus is a
+// ready printer.
+type PrinterStatus uint32
+
+// printerStatusNames holds the JSON names of the PRINTER_STATUS_* flags, in
+// bit order.
+var printerStatusNames = []string{
+	"paused",
+	"error",
+	"pending-deletion",
+	"paper-jam",
+	"paper-out",
+	"manual-feed",
+	"paper-problem",
+	"offline",
+	"io-active",
+	"busy",
+	"printing",
+	"output-bin-full",
+	"not-available",
+	"waiting",
+	"processing",
+	"initializing",
+	"warming-up",
+	"toner-low",
+	"no-toner",
+	"page-punt",
+	"user-intervention",
+	"out-of-memory",
+	"door-open",
+	"server-unknown",
+	"power-save",
+	"server-offline",
+	"driver-update-needed",
+}
+
+// printerStatusText describes the PRINTER_STATUS_* flags in English, the
+// way the Windows printer settings do.
+var printerStatusText = map[PrinterStatus]string{
+	PRINTER_STATUS_PAUSED:               "Paused",
+	PRINTER_STATUS_ERROR:                "Error",
+	PRINTER_STATUS_PENDING_DELETION:     "Deleting",
+	PRINTER_STATUS_PAPER_JAM:            "Paper Jam",
+	PRINTER_STATUS_PAPER_OUT:            "Out of Paper",
+	PRINTER_STATUS_MANUAL_FEED:          "Manual Feed",
+	PRINTER_STATUS_PAPER_PROBLEM:        "Paper Problem",
+	PRINTER_STATUS_OFFLINE:              "Offline",
+	PRINTER_STATUS_IO_ACTIVE:            "I/O Active",
+	PRINTER_STATUS_BUSY:                 "Busy",
+	PRINTER_STATUS_PRINTING:             "Printing",
+	PRINTER_STATUS_OUTPUT_BIN_FULL:      "Output Bin Full",
+	PRINTER_STATUS_NOT_AVAILABLE:        "Not Available",
+	PRINTER_STATUS_WAITING:              "Waiting",
+	PRINTER_STATUS_PROCESSING:           "Processing",
+	PRINTER_STATUS_INITIALIZING:         "Initializing",
+	PRINTER_STATUS_WARMING_UP:           "Warming Up",
+	PRINTER_STATUS_TONER_LOW:            "Toner Low",
+	PRINTER_STATUS_NO_TONER:             "No Toner",
+	PRINTER_STATUS_PAGE_PUNT:            "Page Punt",
+	PRINTER_STATUS_USER_INTERVENTION:    "User Intervention Required",
+	PRINTER_STATUS_OUT_OF_MEMORY:        "Out of Memory",
+	PRINTER_STATUS_DOOR_OPEN:            "Door Open",
+	PRINTER_STATUS_SERVER_UNKNOWN:       "Server Unknown",
+	PRINTER_STATUS_POWER_SAVE:           "Power Save",
+	PRINTER_STATUS_SERVER_OFFLINE:       "Server Offline",
+	PRINTER_STATUS_DRIVER_UPDATE_NEEDED: "Driver Update Needed",
+}
+
+// Flags splits s into its single bit flags, lowest first.
+func (s PrinterStatus) Flags() []PrinterStatus {
+	var flags []PrinterStatus
+	for _, f := range splitFlags(uint32(s)) {
+		flags = append(flags, PrinterStatus(f))
+	}
+	return flags
+}
+
+// Names returns the machine-readable names of the flags in s, such as
+// "paper-jam" or "toner-low". Flags without a name are written in hex.
+func (s PrinterStatus) Names() []string {
+	return flagNames(uint32(s), printerStatusNames)
+}
+
+// String describes s in English.
+func (s PrinterStatus) String() string {
+	if s == 0 {
+		return "Ready"
+	}
+	var parts []string
+	for _, f := range s.Flags() {
+		d, ok := printerStatusText[f]
+		if !ok {
+			d = flagName(uint32(f), printerStatusNames)
+		}
+		parts = append(parts, d)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// MarshalJSON encodes s as an array of flag names.
+func (s PrinterStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Names())
+}
+
+// UnmarshalJSON decodes an array of flag names or a plain number.
+func (s *PrinterStatus) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalFlags(b, printerStatusNames, "printer status")
+	*s = PrinterStatus(v)
+	return err
+}
+
+// splitFlags splits v into its single bit flags, lowest first.
+func splitFlags(v uint32) []uint32 {
+	var flags []uint32
+	for bit := uint32(1); bit != 0 && bit <= v; bit <<= 1 {
+		if v&bit != 0 {
+			flags = append(flags, bit)
+		}
+	}
+	return flags
+}
+
+// flagNames returns the names of the flags in v, names[i] naming bit i.
+func flagNames(v uint32, names []string) []string {
+	s := []string{}
+	for _, f := range splitFlags(v) {
+		s = append(s, flagName(f, names))
+	}
+	return s
+}
+
+// flagName returns the name of the single flag f, or f in hex.
+func flagName(f uint32, names []string) string {
+	for i, n := range names {
+		if f == 1<<i && n != "" {
+			return n
+		}
+	}
+	return fmt.Sprintf("%#x", f)
+}
+
+// unmarshalFlags decodes an array of the flag names in names, or a number.
+func unmarshalFlags(b []byte, names []string, what string) (uint32, error) {
+	var n uint32
+	if err := json.Unmarshal(b, &n); err == nil {
+		return n, nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return 0, fmt.Errorf("winprinters: %s %s is neither a list of flags nor a number", what, b)
+	}
+	var v uint32
+	for _, name := range list {
+		f, err := parseFlag(name, names, what)
+		if err != nil {
+			return 0, err
+		}
+		v |= f
+	}
+	return v, nil
+}
+
+func parseFlag(name string, names []string, what string) (uint32, error) {
+	for i, n := range names {
+		if n == name && n != "" {
+			return 1 << i, nil
+		}
+	}
+	if n, err := strconv.ParseUint(name, 0, 32); err == nil {
+		return uint32(n), nil
+	}
+	return 0, fmt.Errorf("winprinters: unknown %s flag %q", what, name)
+}
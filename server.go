@@ -0,0 +1,332 @@
+package winprinters
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Values of the print server GetPrinterData reads.
+//
+//goland:noinspection GoSnakeCaseUsage
+const (
+	SPLREG_DEFAULT_SPOOL_DIRECTORY = "DefaultSpoolDirectory"
+	SPLREG_EVENT_LOG               = "EventLog"
+	SPLREG_BEEP_ENABLED            = "BeepEnabled"
+	SPLREG_NET_POPUP               = "NetPopup"
+	SPLREG_RETRY_POPUP             = "RetryPopup"
+	SPLREG_MAJOR_VERSION           = "MajorVersion"
+	SPLREG_MINOR_VERSION           = "MinorVersion"
+	SPLREG_ARCHITECTURE            = "Architecture"
+	SPLREG_DNS_MACHINE_NAME        = "DNSMachineName"
+
+	REG_SZ    = 1
+	REG_DWORD = 4
+
+	EVENTLOG_ERROR_TYPE       = 0x0001
+	EVENTLOG_WARNING_TYPE     = 0x0002
+	EVENTLOG_INFORMATION_TYPE = 0x0004
+)
+
+// PrinterEntry is a printer, print server or domain listed by
+// EnumPrinters level 1, the only level that browses the network.
+type PrinterEntry struct {
+	Flags       uint32 // PRINTER_ENUM_CONTAINER, PRINTER_ENUM_EXPAND and PRINTER_ENUM_ICON* flags
+	Description string // for printers, the name, driver and location separated by commas
+	Name        string
+	Comment     string
+}
+
+// Container reports whether e holds printers, such as a print server or a
+// domain, rather than being a printer.
+func (e PrinterEntry) Container() bool {
+	return e.Flags&PRINTER_ENUM_CONTAINER != 0
+}
+
+// printerInfo1Stride returns the size of a PRINTER_INFO_1 in a buffer of sb:
+// Flags, then the pDescription, pName and pComment pointers.
+func printerInfo1Stride(sb spoolBuffer) int {
+	return sb.align(4) + 3*sb.ptr
+}
+
+// decodePrinterInfo1 decodes count PRINTER_INFO_1 structures from sb.
+func decodePrinterInfo1(sb spoolBuffer, count int) ([]PrinterEntry, error) {
+	stride := printerInfo1Stride(sb)
+	if err := sb.check(0, count*stride); err != nil {
+		return nil, err
+	}
+	entries := make([]PrinterEntry, 0, count)
+	for i := 0; i < count; i++ {
+		off := i * stride
+		e := PrinterEntry{Flags: sb.uint32(off)}
+		for n, s := range []*string{&e.Description, &e.Name, &e.Comment} {
+			v, err := sb.string(off + sb.align(4) + n*sb.ptr)
+			if err != nil {
+				return nil, err
+			}
+			*s = v
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// ServerProperties are the settings of a print server.
+type ServerProperties struct {
+	SpoolDirectory string // where jobs are spooled
+	EventLog       uint32 // EVENTLOG_*_TYPE flags of the spooler events logged
+	BeepEnabled    bool   // beep on remote job errors
+	NetPopup       bool   // notify users when their remote jobs print
+	RetryPopup     bool   // notify users again while jobs fail
+	MajorVersion   uint32 // of the spooler
+	MinorVersion   uint32
+	Architecture   string // environment of the server, such as "Windows x64"
+	DNSMachineName string
+}
+
+// serverPropertyFields returns the pointers to the fields of p with the
+// values they are read from.
+func serverPropertyFields(p *ServerProperties) []struct {
+	value string
+	field interface{}
+} {
+	return []struct {
+		value string
+		field interface{}
+	}{
+		{SPLREG_DEFAULT_SPOOL_DIRECTORY, &p.SpoolDirectory},
+		{SPLREG_EVENT_LOG, &p.EventLog},
+		{SPLREG_BEEP_ENABLED, &p.BeepEnabled},
+		{SPLREG_NET_POPUP, &p.NetPopup},
+		{SPLREG_RETRY_POPUP, &p.RetryPopup},
+		{SPLREG_MAJOR_VERSION, &p.MajorVersion},
+		{SPLREG_MINOR_VERSION, &p.MinorVersion},
+		{SPLREG_ARCHITECTURE, &p.Architecture},
+		{SPLREG_DNS_MACHINE_NAME, &p.DNSMachineName},
+	}
+}
+
+// readServerProperties reads the server properties with get, which returns
+// the type and data of a value like GetPrinterData.
+func readServerProperties(get func(value string) (uint32, []byte, error)) (*ServerProperties, error) {
+	p := new(ServerProperties)
+	for _, f := range serverPropertyFields(p) {
+		typ, data, err := get(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("winprinters: server property %s: %w", f.value, err)
+		}
+		want := uint32(REG_DWORD)
+		if _, ok := f.field.(*string); ok {
+			want = REG_SZ
+		}
+		if typ != want || want == REG_DWORD && len(data) < 4 {
+			return nil, fmt.Errorf("winprinters: server property %s has type %d and %d bytes", f.value, typ, len(data))
+		}
+		switch v := f.field.(type) {
+		case *string:
+			*v = decodeRegString(data)
+		case *uint32:
+			*v = binary.LittleEndian.Uint32(data)
+		case *bool:
+			*v = binary.LittleEndian.Uint32(data) != 0
+		}
+	}
+	return p, nil
+}
+
+// serverPropertyData returns the type and data GetPrinterData returns for
+// value of p, the inverse of readServerProperties, and false for values p
+// does not hold.
+func serverPropertyData(p *ServerProperties, value string) (uint32, []byte, bool) {
+	for _, f := range serverPropertyFields(p) {
+		if !strings.EqualFold(f.value, value) {
+			continue
+		}
+		data := make([]byte, 4)
+		switch v := f.field.(type) {
+		case *string:
+			return REG_SZ, encodeRegString(*v), true
+		case *uint32:
+			binary.LittleEndian.PutUint32(data, *v)
+		case *bool:
+			if *v {
+				data[0] = 1
+			}
+		}
+		return REG_DWORD, data, true
+	}
+	return 0, nil, false
+}
+
+// decodeRegString decodes a REG_SZ, dropping its NUL terminator.
+func decodeRegString(b []byte) string {
+	s := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		c := binary.LittleEndian.Uint16(b[i:])
+		if c == 0 {
+			break
+		}
+		s = append(s, c)
+	}
+	return string(utf16.Decode(s))
+}
+
+// encodeRegString returns s as a NUL terminated REG_SZ.
+func encodeRegString(s string) []byte {
+	u := append(utf16.Encode([]rune(s)), 0)
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+	return b
+}
+
+// ServerHandle is a print server opened by a ServerOpener. Server wraps it.
+type ServerHandle interface {
+	DriverInventory
+	// EnumPrinters lists the entries EnumPrinters level 1 returns for the
+	// PRINTER_ENUM_* flags, passing the server name as the name.
+	EnumPrinters(flags uint32) ([]PrinterEntry, error)
+	// Forms returns the paper size forms of the server.
+	Forms() ([]FormInfo, error)
+	// Ports returns the ports of the server.
+	Ports() ([]PortInfo, error)
+	// PrinterData returns the type and data of a server value, such as
+	// SPLREG_DEFAULT_SPOOL_DIRECTORY.
+	PrinterData(value string) (uint32, []byte, error)
+	// Close releases the handle.
+	Close() error
+}
+
+// ServerOpener is implemented by Spoolers that open print servers.
+type ServerOpener interface {
+	// OpenServer opens the print server named like \\printsrv01, or the
+	// local one when name is empty.
+	OpenServer(name string) (ServerHandle, error)
+}
+
+// Server is a print server, the local one or a remote one inventoried by
+// name from an admin workstation.
+type Server struct {
+	name string
+	h    ServerHandle
+}
+
+// serverName returns name as \\host, or empty for the local server.
+func serverName(name string) (string, error) {
+	host := strings.TrimLeft(name, `\`)
+	if host == "" {
+		return "", nil
+	}
+	if strings.ContainsAny(host, `\,`) {
+		return "", fmt.Errorf("winprinters: %q is not a print server name", name)
+	}
+	return `\\` + host, nil
+}
+
+// OpenServer opens the print server name, such as \\printsrv01 or
+// printsrv01, with the current Spooler. The empty name is the local server.
+func OpenServer(name string) (*Server, error) {
+	o, ok := CurrentSpooler().(ServerOpener)
+	if !ok {
+		return nil, &UnsupportedError{Op: "OpenServer"}
+	}
+	name, err := serverName(name)
+	if err != nil {
+		return nil, err
+	}
+	h, err := o.OpenServer(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{name: name, h: h}, nil
+}
+
+// Name returns the name of s like \\printsrv01, or empty for the local
+// server.
+func (s *Server) Name() string {
+	return s.name
+}
+
+// Close releases the server handle.
+func (s *Server) Close() error {
+	return s.h.Close()
+}
+
+// ReadNames returns the names of the printers of s. Those of a remote server
+// are full names like \\printsrv01\Labels, which Open accepts.
+func (s *Server) ReadNames() ([]string, error) {
+	flags := uint32(PRINTER_ENUM_NAME)
+	if s.name == "" {
+		flags = PRINTER_ENUM_LOCAL | PRINTER_ENUM_CONNECTIONS
+	}
+	entries, err := s.h.EnumPrinters(flags)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.Container() {
+			names = append(names, e.Name)
+		}
+	}
+	return names, nil
+}
+
+// Printers enumerates printers with the PRINTER_ENUM_* flags: the printers
+// of s with PRINTER_ENUM_NAME, only the shared ones when
+// PRINTER_ENUM_SHARED is added, and the printers and print servers of the
+// domain with PRINTER_ENUM_REMOTE or PRINTER_ENUM_NETWORK.
+func (s *Server) Printers(flags uint32) ([]PrinterEntry, error) {
+	return s.h.EnumPrinters(flags)
+}
+
+// Open opens the named printer of s. A name without a server is taken to
+// be a printer of s.
+func (s *Server) Open(printer string) (*Printer, error) {
+	if s.name != "" && !strings.HasPrefix(printer, `\\`) {
+		printer = s.name + `\` + printer
+	}
+	return Open(printer)
+}
+
+// Forms returns the paper size forms of s.
+func (s *Server) Forms() ([]FormInfo, error) {
+	return s.h.Forms()
+}
+
+// Ports returns the ports of s.
+func (s *Server) Ports() ([]PortInfo, error) {
+	return s.h.Ports()
+}
+
+// Drivers returns the printer drivers installed on s for environment, as
+// the package level Drivers does.
+func (s *Server) Drivers(environment string) ([]DriverInfo, error) {
+	return s.h.Drivers(environment)
+}
+
+// PrintProcessors returns the print processors installed on s for
+// environment.
+func (s *Server) PrintProcessors(environment string) ([]string, error) {
+	return s.h.PrintProcessors(environment)
+}
+
+// PrintProcessorDataTypes returns the data types the print processor of s
+// accepts.
+func (s *Server) PrintProcessorDataTypes(processor string) ([]string, error) {
+	return s.h.PrintProcessorDataTypes(processor)
+}
+
+// PrinterData returns the type, REG_SZ or REG_DWORD among others, and the
+// data of a value of s, such as SPLREG_DEFAULT_SPOOL_DIRECTORY.
+func (s *Server) PrinterData(value string) (uint32, []byte, error) {
+	return s.h.PrinterData(value)
+}
+
+// Properties reads the spool directory, event logging, beep and popup
+// settings, version and architecture of s.
+func (s *Server) Properties() (*ServerProperties, error) {
+	return readServerProperties(s.h.PrinterData)
+}
@@ -0,0 +1,184 @@
+package winprinters
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodePrinterInfo1(t *testing.T) {
+	want := []PrinterEntry{
+		{Flags: PRINTER_ENUM_CONTAINER | PRINTER_ENUM_EXPAND, Description: `\\PRINTSRV01,Store 12`, Name: `\\PRINTSRV01`, Comment: "Store 12"},
+		{Flags: PRINTER_ENUM_ICON8, Description: `\\PRINTSRV01\Labels,ZDesigner GK420d,Dock`, Name: `\\PRINTSRV01\Labels`},
+	}
+	for _, ptr := range []int{4, 8} {
+		stride := printerInfo1Stride(spoolBuffer{ptr: ptr})
+		if stride != 4*ptr {
+			t.Errorf("%d-byte pointers: PRINTER_INFO_1 is %d bytes", ptr, stride)
+		}
+		w := newSpoolWriter(ptr, len(want)*stride)
+		for i, e := range want {
+			off := i * stride
+			w.uint32(off, e.Flags)
+			w.string(off+ptr, e.Description)
+			w.string(off+2*ptr, e.Name)
+			if e.Comment != "" {
+				w.string(off+3*ptr, e.Comment)
+			}
+		}
+		got, err := decodePrinterInfo1(w.sb, len(want))
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%d-byte pointers: decodePrinterInfo1() = %+v, %v", ptr, got, err)
+		}
+		if _, err = decodePrinterInfo1(w.sb, len(want)+1); err == nil {
+			t.Errorf("%d-byte pointers: decoding past the buffer succeeded", ptr)
+		}
+	}
+}
+
+func TestServerProperties(t *testing.T) {
+	want := ServerProperties{
+		SpoolDirectory: `C:\Windows\system32\spool\PRINTERS`,
+		EventLog:       EVENTLOG_ERROR_TYPE | EVENTLOG_WARNING_TYPE,
+		BeepEnabled:    true,
+		MajorVersion:   3,
+		Architecture:   "Windows x64",
+		DNSMachineName: "printsrv01.store12.example",
+	}
+	get := func(value string) (uint32, []byte, error) {
+		typ, data, ok := serverPropertyData(&want, value)
+		if !ok {
+			return 0, nil, errors.New("not found")
+		}
+		return typ, data, nil
+	}
+	got, err := readServerProperties(get)
+	if err != nil || *got != want {
+		t.Errorf("readServerProperties() = %+v, %v", got, err)
+	}
+
+	_, err = readServerProperties(func(value string) (uint32, []byte, error) {
+		if value == SPLREG_EVENT_LOG {
+			return REG_SZ, encodeRegString("7"), nil
+		}
+		return get(value)
+	})
+	if err == nil || !strings.Contains(err.Error(), "EventLog has type 1") {
+		t.Errorf("readServerProperties() with a mistyped value: %v", err)
+	}
+	_, err = readServerProperties(func(value string) (uint32, []byte, error) {
+		if value == SPLREG_DNS_MACHINE_NAME {
+			return 0, nil, errors.New("access denied")
+		}
+		return get(value)
+	})
+	if err == nil || !strings.Contains(err.Error(), "DNSMachineName: access denied") {
+		t.Errorf("readServerProperties() with a failing value: %v", err)
+	}
+}
+
+func TestServerName(t *testing.T) {
+	tests := []struct {
+		in, want string
+		err      bool
+	}{
+		{in: "", want: ""},
+		{in: `\\`, want: ""},
+		{in: "printsrv01", want: `\\printsrv01`},
+		{in: `\\printsrv01`, want: `\\printsrv01`},
+		{in: `\\printsrv01\Labels`, err: true},
+	}
+	for _, tt := range tests {
+		got, err := serverName(tt.in)
+		if got != tt.want || (err != nil) != tt.err {
+			t.Errorf("serverName(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestServerFake(t *testing.T) {
+	fake := newTestSpooler(t)
+	fake.AddServer(FakeServer{
+		Name:       "printsrv01",
+		Comment:    "Store 12",
+		Forms:      []FormInfo{{Name: "A4", Size: SIZE{Width: 210000, Height: 297000}}},
+		Ports:      []PortInfo{{Name: "IP_10.0.12.41", MonitorName: StandardTCPPortMonitor}},
+		Properties: ServerProperties{SpoolDirectory: `D:\spool`, BeepEnabled: true, Architecture: "Windows x64"},
+	})
+	fake.AddPrinter(FakePrinter{
+		Name:   `\\printsrv01\Labels`,
+		Driver: DriverInfo{Name: "ZDesigner GK420d", Version: 3},
+		Info:   PrinterInfo{Attributes: PRINTER_ATTRIBUTE_SHARED, Location: "Dock"},
+	})
+	fake.AddPrinter(FakePrinter{Name: `\\printsrv01\Back Office`, Driver: DriverInfo{Name: "HP Universal", Version: 4}})
+
+	if names, _ := ReadNames(); !reflect.DeepEqual(names, []string{"Label", "Office"}) {
+		t.Errorf("ReadNames() lists the printers of the remote server: %q", names)
+	}
+
+	srv, err := OpenServer("printsrv01")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	defer srv.Close()
+	if srv.Name() != `\\printsrv01` {
+		t.Errorf("Name() = %q", srv.Name())
+	}
+	names, err := srv.ReadNames()
+	if err != nil || !reflect.DeepEqual(names, []string{`\\printsrv01\Labels`, `\\printsrv01\Back Office`}) {
+		t.Errorf("ReadNames() of the server = %q, %v", names, err)
+	}
+	shared, err := srv.Printers(PRINTER_ENUM_NAME | PRINTER_ENUM_SHARED)
+	if err != nil || len(shared) != 1 || shared[0].Description != `\\printsrv01\Labels,ZDesigner GK420d,Dock` {
+		t.Errorf("Printers(NAME|SHARED) = %+v, %v", shared, err)
+	}
+	if forms, err := srv.Forms(); err != nil || len(forms) != 1 || forms[0].Name != "A4" {
+		t.Errorf("Forms() = %+v, %v", forms, err)
+	}
+	if ports, err := srv.Ports(); err != nil || len(ports) != 1 {
+		t.Errorf("Ports() = %+v, %v", ports, err)
+	}
+	if drivers, err := srv.Drivers("all"); err != nil || len(drivers) != 2 || drivers[0].Name != "ZDesigner GK420d" {
+		t.Errorf("Drivers() = %+v, %v", drivers, err)
+	}
+	props, err := srv.Properties()
+	if err != nil || props.SpoolDirectory != `D:\spool` || !props.BeepEnabled || props.Architecture != "Windows x64" {
+		t.Errorf("Properties() = %+v, %v", props, err)
+	}
+	if _, _, err = srv.PrinterData("NoSuchValue"); err == nil {
+		t.Errorf("PrinterData() of an unknown value succeeded")
+	}
+	p, err := srv.Open("Labels")
+	if err != nil {
+		t.Fatalf("Open(Labels) on the server failed: %v", err)
+	}
+	closePrinter(p)
+
+	local, err := OpenServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer local.Close()
+	if names, err = local.ReadNames(); err != nil || !reflect.DeepEqual(names, []string{"Label", "Office"}) {
+		t.Errorf("ReadNames() of the local server = %q, %v", names, err)
+	}
+	remote, err := local.Printers(PRINTER_ENUM_REMOTE)
+	if err != nil || len(remote) != 2 || !remote[0].Container() || remote[0].Comment != "Store 12" || remote[1].Name != `\\printsrv01\Labels` {
+		t.Errorf("Printers(REMOTE) = %+v, %v", remote, err)
+	}
+
+	if _, err = OpenServer("printsrv02"); err == nil {
+		t.Errorf("opening an unknown server succeeded")
+	}
+	fake.InjectError(`\\printsrv01`, "Forms", errors.New("access denied"))
+	if _, err = srv.Forms(); err == nil {
+		t.Errorf("injected Forms failure ignored")
+	}
+	if err = srv.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if _, err = srv.Ports(); err == nil {
+		t.Errorf("Ports() on a closed server succeeded")
+	}
+}
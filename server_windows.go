@@ -0,0 +1,93 @@
+package winprinters
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	SERVER_ACCESS_ADMINISTER = 0x00000001
+	SERVER_ACCESS_ENUMERATE  = 0x00000002
+)
+
+// OpenServer implements ServerOpener with the server handle OpenPrinter
+// returns for a server name, or for a nil name on the local server.
+func (winspool) OpenServer(name string) (ServerHandle, error) {
+	n, err := optionalUTF16(name)
+	if err != nil {
+		return nil, err
+	}
+	s := &winspoolServer{name: name}
+	if err = OpenPrinter(n, &s.p.h, &PrinterDefaults{DesiredAccess: SERVER_ACCESS_ENUMERATE}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// winspoolServer is a ServerHandle holding a server handle of OpenPrinter.
+// The enumerations take the server name rather than the handle.
+type winspoolServer struct {
+	name string
+	p    winspoolPrinter
+}
+
+func (s *winspoolServer) EnumPrinters(flags uint32) ([]PrinterEntry, error) {
+	name, err := optionalUTF16(s.name)
+	if err != nil {
+		return nil, err
+	}
+	sb, count, err := enumSpooler(func(buf *byte, bufN uint32, needed, returned *uint32) error {
+		return EnumPrinters(flags, name, 1, buf, bufN, needed, returned)
+	})
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return decodePrinterInfo1(sb, count)
+}
+
+func (s *winspoolServer) Forms() ([]FormInfo, error) {
+	return s.p.Forms()
+}
+
+func (s *winspoolServer) Ports() ([]PortInfo, error) {
+	return enumPorts(s.name)
+}
+
+func (s *winspoolServer) Drivers(environment string) ([]DriverInfo, error) {
+	return enumDrivers(s.name, environment)
+}
+
+func (s *winspoolServer) PrintProcessors(environment string) ([]string, error) {
+	return enumPrintProcessors(s.name, environment)
+}
+
+func (s *winspoolServer) PrintProcessorDataTypes(processor string) ([]string, error) {
+	return enumDataTypes(s.name, processor)
+}
+
+// PrinterData reads a server value with GetPrinterData, which returns its
+// error code rather than setting the last error.
+func (s *winspoolServer) PrinterData(value string) (uint32, []byte, error) {
+	v, err := windows.UTF16PtrFromString(value)
+	if err != nil {
+		return 0, nil, err
+	}
+	var typ, needed uint32
+	buf := make([]byte, 256)
+	for {
+		status := syscall.Errno(getPrinterData(s.p.h, v, &typ, &buf[0], uint32(len(buf)), &needed))
+		if status == 0 {
+			return typ, buf[:needed], nil
+		}
+		if status != windows.ERROR_MORE_DATA || needed <= uint32(len(buf)) {
+			return 0, nil, status
+		}
+		buf = make([]byte, needed)
+	}
+}
+
+func (s *winspoolServer) Close() error {
+	return s.p.Close()
+}
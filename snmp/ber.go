@@ -0,0 +1,244 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// BER tags of the SNMP message structure.
+const (
+	tagInteger  = 0x02
+	tagSequence = 0x30
+)
+
+var errTruncated = errors.New("snmp: truncated BER data")
+
+func appendLength(b []byte, n int) []byte {
+	switch {
+	case n < 0x80:
+		return append(b, byte(n))
+	case n <= 0xff:
+		return append(b, 0x81, byte(n))
+	case n <= 0xffff:
+		return append(b, 0x82, byte(n>>8), byte(n))
+	default:
+		return append(b, 0x84, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+}
+
+func appendTLV(b []byte, tag byte, data []byte) []byte {
+	b = append(b, tag)
+	b = appendLength(b, len(data))
+	return append(b, data...)
+}
+
+// appendInt appends v in the fewest two's complement octets.
+func appendInt(b []byte, tag byte, v int64) []byte {
+	n := 1
+	for w := v; w > 127 || w < -128; w >>= 8 {
+		n++
+	}
+	data := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		data[i] = byte(v)
+		v >>= 8
+	}
+	return appendTLV(b, tag, data)
+}
+
+// appendUint appends v as an unsigned integer, with a leading zero octet
+// when its top bit is set.
+func appendUint(b []byte, tag byte, v uint64) []byte {
+	var data []byte
+	for {
+		data = append([]byte{byte(v)}, data...)
+		v >>= 8
+		if v == 0 {
+			break
+		}
+	}
+	if data[0]&0x80 != 0 {
+		data = append([]byte{0}, data...)
+	}
+	return appendTLV(b, tag, data)
+}
+
+func appendOID(b []byte, oid OID) ([]byte, error) {
+	if len(oid) < 2 || oid[0] > 2 || (oid[0] < 2 && oid[1] > 39) {
+		return nil, fmt.Errorf("snmp: bad object identifier %v", oid)
+	}
+	data := appendBase128(nil, oid[0]*40+oid[1])
+	for _, n := range oid[2:] {
+		data = appendBase128(data, n)
+	}
+	return appendTLV(b, byte(TypeObjectIdentifier), data), nil
+}
+
+func appendBase128(b []byte, n uint32) []byte {
+	var tmp [5]byte
+	i := len(tmp) - 1
+	tmp[i] = byte(n & 0x7f)
+	for n >>= 7; n > 0; n >>= 7 {
+		i--
+		tmp[i] = byte(n&0x7f) | 0x80
+	}
+	return append(b, tmp[i:]...)
+}
+
+// readTLV splits the first BER element off b.
+func readTLV(b []byte) (tag byte, data, rest []byte, err error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errTruncated
+	}
+	tag, n, off := b[0], int(b[1]), 2
+	if n&0x80 != 0 {
+		octets := n & 0x7f
+		if octets == 0 || octets > 4 || len(b) < 2+octets {
+			return 0, nil, nil, fmt.Errorf("snmp: bad BER length of tag 0x%02x", tag)
+		}
+		n = 0
+		for _, c := range b[2 : 2+octets] {
+			n = n<<8 | int(c)
+		}
+		off += octets
+	}
+	if n < 0 || len(b)-off < n {
+		return 0, nil, nil, errTruncated
+	}
+	return tag, b[off : off+n], b[off+n:], nil
+}
+
+// expect reads an element with the given tag.
+func expect(b []byte, tag byte) (data, rest []byte, err error) {
+	t, data, rest, err := readTLV(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t != tag {
+		return nil, nil, fmt.Errorf("snmp: got BER tag 0x%02x, want 0x%02x", t, tag)
+	}
+	return data, rest, nil
+}
+
+func parseInt(data []byte) (int64, error) {
+	if len(data) == 0 || len(data) > 8 {
+		return 0, fmt.Errorf("snmp: integer of %d octets", len(data))
+	}
+	v := int64(int8(data[0]))
+	for _, c := range data[1:] {
+		v = v<<8 | int64(c)
+	}
+	return v, nil
+}
+
+func parseUint(data []byte) (uint64, error) {
+	if len(data) > 0 && data[0] == 0 {
+		data = data[1:]
+	}
+	if len(data) > 8 {
+		return 0, fmt.Errorf("snmp: unsigned integer of %d octets", len(data))
+	}
+	var v uint64
+	for _, c := range data {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func parseOID(data []byte) (OID, error) {
+	var (
+		oid OID
+		n   uint32
+	)
+	for i, c := range data {
+		if n > 1<<25 {
+			return nil, errors.New("snmp: object identifier component overflows")
+		}
+		n = n<<7 | uint32(c&0x7f)
+		if c&0x80 != 0 {
+			if i == len(data)-1 {
+				return nil, errTruncated
+			}
+			continue
+		}
+		if oid == nil {
+			switch {
+			case n < 40:
+				oid = OID{0, n}
+			case n < 80:
+				oid = OID{1, n - 40}
+			default:
+				oid = OID{2, n - 80}
+			}
+		} else {
+			oid = append(oid, n)
+		}
+		n = 0
+	}
+	if oid == nil {
+		return nil, errors.New("snmp: empty object identifier")
+	}
+	return oid, nil
+}
+
+// appendValue appends the value of v, which must match its type.
+func appendValue(b []byte, v Variable) ([]byte, error) {
+	tag := byte(v.Type)
+	switch v.Type {
+	case TypeInteger:
+		n, ok := v.Value.(int64)
+		if !ok {
+			return nil, fmt.Errorf("snmp: %v: Integer value of type %T", v.Name, v.Value)
+		}
+		return appendInt(b, tag, n), nil
+	case TypeCounter32, TypeGauge32, TypeTimeTicks, TypeCounter64:
+		n, ok := v.Value.(uint64)
+		if !ok {
+			return nil, fmt.Errorf("snmp: %v: %v value of type %T", v.Name, v.Type, v.Value)
+		}
+		return appendUint(b, tag, n), nil
+	case TypeOctetString, TypeOpaque:
+		s, ok := v.Value.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("snmp: %v: %v value of type %T", v.Name, v.Type, v.Value)
+		}
+		return appendTLV(b, tag, s), nil
+	case TypeIPAddress:
+		ip, ok := v.Value.(net.IP)
+		if !ok || ip.To4() == nil {
+			return nil, fmt.Errorf("snmp: %v: bad IpAddress %v", v.Name, v.Value)
+		}
+		return appendTLV(b, tag, ip.To4()), nil
+	case TypeObjectIdentifier:
+		oid, ok := v.Value.(OID)
+		if !ok {
+			return nil, fmt.Errorf("snmp: %v: ObjectIdentifier value of type %T", v.Name, v.Value)
+		}
+		return appendOID(b, oid)
+	case TypeNull, TypeNoSuchObject, TypeNoSuchInstance, TypeEndOfMibView:
+		return appendTLV(b, tag, nil), nil
+	}
+	return nil, fmt.Errorf("snmp: %v: unsupported type 0x%02x", v.Name, tag)
+}
+
+// parseValue decodes the value of type t.
+func parseValue(t Type, data []byte) (interface{}, error) {
+	switch t {
+	case TypeInteger:
+		return parseInt(data)
+	case TypeCounter32, TypeGauge32, TypeTimeTicks, TypeCounter64:
+		return parseUint(data)
+	case TypeIPAddress:
+		if len(data) != net.IPv4len {
+			return nil, fmt.Errorf("snmp: IpAddress of %d octets", len(data))
+		}
+		return net.IP(append([]byte(nil), data...)), nil
+	case TypeObjectIdentifier:
+		return parseOID(data)
+	case TypeNull, TypeNoSuchObject, TypeNoSuchInstance, TypeEndOfMibView:
+		return nil, nil
+	}
+	// Octet strings, Opaque and unknown application types.
+	return append([]byte(nil), data...), nil
+}
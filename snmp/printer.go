@@ -0,0 +1,483 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Objects read by DeviceStatus.
+var (
+	oidSysDescr  = MustParseOID("1.3.6.1.2.1.1.1.0")
+	oidSysUpTime = MustParseOID("1.3.6.1.2.1.1.3.0")
+	oidSysName   = MustParseOID("1.3.6.1.2.1.1.5.0")
+
+	oidHrDeviceEntry  = MustParseOID("1.3.6.1.2.1.25.3.2.1")
+	oidHrPrinterEntry = MustParseOID("1.3.6.1.2.1.25.3.5.1")
+
+	oidPrtGeneralEntry         = MustParseOID("1.3.6.1.2.1.43.5.1.1")
+	oidPrtCoverEntry           = MustParseOID("1.3.6.1.2.1.43.6.1.1")
+	oidPrtInputEntry           = MustParseOID("1.3.6.1.2.1.43.8.2.1")
+	oidPrtMarkerSuppliesEntry  = MustParseOID("1.3.6.1.2.1.43.11.1.1")
+	oidPrtMarkerColorantEntry  = MustParseOID("1.3.6.1.2.1.43.12.1.1")
+	oidPrtConsoleDisplayBuffer = MustParseOID("1.3.6.1.2.1.43.16.5.1")
+	oidPrtAlertEntry           = MustParseOID("1.3.6.1.2.1.43.18.1.1")
+)
+
+// PrinterStatus is hrPrinterStatus.
+type PrinterStatus int
+
+const (
+	PrinterOther    PrinterStatus = 1
+	PrinterUnknown  PrinterStatus = 2
+	PrinterIdle     PrinterStatus = 3
+	PrinterPrinting PrinterStatus = 4
+	PrinterWarmup   PrinterStatus = 5
+)
+
+func (s PrinterStatus) String() string {
+	return enumName([]string{"", "other", "unknown", "idle", "printing", "warmup"}, int(s))
+}
+
+// DeviceState is hrDeviceStatus.
+type DeviceState int
+
+const (
+	DeviceUnknown DeviceState = 1
+	DeviceRunning DeviceState = 2
+	DeviceWarning DeviceState = 3
+	DeviceTesting DeviceState = 4
+	DeviceDown    DeviceState = 5
+)
+
+func (s DeviceState) String() string {
+	return enumName([]string{"", "unknown", "running", "warning", "testing", "down"}, int(s))
+}
+
+func enumName(names []string, v int) string {
+	if v > 0 && v < len(names) && names[v] != "" {
+		return names[v]
+	}
+	return fmt.Sprint(v)
+}
+
+// ErrorState is the hrPrinterDetectedErrorState bitmask. Bit 0 is the
+// most significant bit of the first octet on the wire.
+type ErrorState uint16
+
+const (
+	LowPaper ErrorState = 1 << iota
+	NoPaper
+	LowToner
+	NoToner
+	DoorOpen
+	Jammed
+	Offline
+	ServiceRequested
+	InputTrayMissing
+	OutputTrayMissing
+	MarkerSupplyMissing
+	OutputNearFull
+	OutputFull
+	InputTrayEmpty
+	OverduePreventMaint
+)
+
+var errorStateNames = []string{
+	"lowPaper", "noPaper", "lowToner", "noToner", "doorOpen", "jammed", "offline",
+	"serviceRequested", "inputTrayMissing", "outputTrayMissing", "markerSupplyMissing",
+	"outputNearFull", "outputFull", "inputTrayEmpty", "overduePreventMaint",
+}
+
+// parseErrorState decodes the octets of hrPrinterDetectedErrorState.
+func parseErrorState(b []byte) ErrorState {
+	var s ErrorState
+	for i := 0; i < 16 && i/8 < len(b); i++ {
+		if b[i/8]&(0x80>>(i%8)) != 0 {
+			s |= 1 << i
+		}
+	}
+	return s
+}
+
+// Names returns the MIB names of the bits set in s.
+func (s ErrorState) Names() []string {
+	var names []string
+	for i, n := range errorStateNames {
+		if s&(1<<i) != 0 {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+func (s ErrorState) String() string {
+	if s == 0 {
+		return "none"
+	}
+	return strings.Join(s.Names(), ",")
+}
+
+// SubUnitStatus is PrtSubUnitStatusTC, the status of input trays.
+type SubUnitStatus int
+
+// Available reports whether the sub-unit can be used.
+func (s SubUnitStatus) Available() bool {
+	return s&7 == 0 || s&7 == 2 || s&7 == 4 || s&7 == 6
+}
+
+// Broken reports whether the sub-unit is unavailable because it is broken.
+func (s SubUnitStatus) Broken() bool { return s&7 == 3 }
+
+// NonCriticalAlert reports whether a non-critical alert is active.
+func (s SubUnitStatus) NonCriticalAlert() bool { return s&8 != 0 }
+
+// CriticalAlert reports whether a critical alert is active.
+func (s SubUnitStatus) CriticalAlert() bool { return s&16 != 0 }
+
+// Offline reports whether the sub-unit is off-line.
+func (s SubUnitStatus) Offline() bool { return s&32 != 0 }
+
+// Level values of supplies and trays below zero.
+const (
+	LevelOther         = -1 // other, e.g. unlimited
+	LevelUnknown       = -2 // unknown
+	LevelSomeRemaining = -3 // some remaining, or not full for receptacles
+)
+
+// SupplyType is prtMarkerSuppliesType.
+type SupplyType int
+
+var supplyTypeNames = []string{"", "other", "unknown", "toner", "wasteToner", "ink",
+	"inkCartridge", "inkRibbon", "wasteInk", "opc", "developer", "fuserOil", "solidWax",
+	"ribbonWax", "wasteWax", "fuser", "coronaWire", "fuserOilWick", "cleanerUnit",
+	"fuserCleaningPad", "transferUnit", "tonerCartridge", "fuserOiler", "water",
+	"wasteWater", "glueWaterAdditive", "wastePaper", "bindingSupply", "bandingSupply",
+	"stitchingWire", "shrinkWrap", "paperWrap", "staples", "inserts", "covers"}
+
+func (t SupplyType) String() string {
+	return enumName(supplyTypeNames, int(t))
+}
+
+// Supply is a row of prtMarkerSuppliesTable.
+type Supply struct {
+	Index       int
+	Description string
+	Type        SupplyType
+	Color       string // prtMarkerColorantValue of the colorant, e.g. "cyan"
+	Receptacle  bool   // filled rather than consumed, e.g. waste toner
+	Unit        int    // prtMarkerSuppliesSupplyUnit
+	MaxCapacity int    // in Unit, or one of the Level constants
+	Level       int    // in Unit, or one of the Level constants
+}
+
+// Percent returns the level in percent of the capacity, when both are
+// known.
+func (s Supply) Percent() (int, bool) {
+	if s.MaxCapacity <= 0 || s.Level < 0 {
+		return 0, false
+	}
+	return s.Level * 100 / s.MaxCapacity, true
+}
+
+// InputTray is a row of prtInputTable.
+type InputTray struct {
+	Index       int
+	Name        string
+	Description string
+	MediaName   string
+	MaxCapacity int // in sheets or CapacityUnit, or one of the Level constants
+	Level       int // in sheets or CapacityUnit, or one of the Level constants
+	Status      SubUnitStatus
+}
+
+// Empty reports whether the tray is known to be empty.
+func (t InputTray) Empty() bool {
+	return t.Level == 0
+}
+
+// CoverStatus is prtCoverStatus.
+type CoverStatus int
+
+const (
+	CoverOther           CoverStatus = 1
+	CoverOpen            CoverStatus = 3
+	CoverClosed          CoverStatus = 4
+	CoverInterlockOpen   CoverStatus = 5
+	CoverInterlockClosed CoverStatus = 6
+)
+
+func (s CoverStatus) String() string {
+	return enumName([]string{"", "other", "", "coverOpen", "coverClosed", "interlockOpen", "interlockClosed"}, int(s))
+}
+
+// Cover is a row of prtCoverTable.
+type Cover struct {
+	Index       int
+	Description string
+	Status      CoverStatus
+}
+
+// Open reports whether the cover or its interlock is open.
+func (c Cover) Open() bool {
+	return c.Status == CoverOpen || c.Status == CoverInterlockOpen
+}
+
+// AlertSeverity is prtAlertSeverityLevel.
+type AlertSeverity int
+
+const (
+	AlertOther    AlertSeverity = 1
+	AlertCritical AlertSeverity = 3
+	AlertWarning  AlertSeverity = 4
+	// AlertWarningBinaryChange is a warning that is only reported once,
+	// such as a configuration change.
+	AlertWarningBinaryChange AlertSeverity = 5
+)
+
+func (s AlertSeverity) String() string {
+	return enumName([]string{"", "other", "", "critical", "warning", "warningBinaryChangeEvent"}, int(s))
+}
+
+// AlertCode is prtAlertCode.
+type AlertCode int
+
+var alertCodeNames = map[AlertCode]string{
+	1: "other", 2: "unknown", 3: "coverOpen", 4: "coverClosed", 5: "interlockOpen",
+	6: "interlockClosed", 7: "configurationChange", 8: "jam", 9: "subunitMissing",
+	10: "subunitLifeAlmostOver", 11: "subunitLifeOver", 12: "subunitAlmostEmpty",
+	13: "subunitEmpty", 14: "subunitAlmostFull", 15: "subunitFull", 16: "subunitNearLimit",
+	17: "subunitAtLimit", 18: "subunitOpened", 19: "subunitClosed", 20: "subunitTurnedOn",
+	21: "subunitTurnedOff", 22: "subunitOffline", 23: "subunitPowerSaver",
+	24: "subunitWarmingUp", 25: "subunitAdded", 26: "subunitRemoved",
+	27: "subunitResourceAdded", 28: "subunitResourceRemoved",
+	29: "subunitRecoverableFailure", 30: "subunitUnrecoverableFailure",
+	501: "doorOpen", 502: "doorClosed", 503: "powerUp", 504: "powerDown",
+	801: "inputMediaTrayMissing", 802: "inputMediaSizeChange", 807: "inputMediaSupplyLow",
+	808: "inputMediaSupplyEmpty", 809: "inputManualInputRequest",
+	901: "outputMediaTrayMissing", 902: "outputMediaTrayAlmostFull", 903: "outputMediaTrayFull",
+	1001: "markerFuserUnderTemperature", 1002: "markerFuserOverTemperature",
+	1101: "markerTonerEmpty", 1102: "markerInkEmpty", 1103: "markerPrintRibbonEmpty",
+	1104: "markerTonerAlmostEmpty", 1105: "markerInkAlmostEmpty",
+	1106: "markerPrintRibbonAlmostEmpty", 1107: "markerWasteTonerReceptacleAlmostFull",
+	1108: "markerWasteInkReceptacleAlmostFull", 1109: "markerWasteTonerReceptacleFull",
+	1110: "markerWasteInkReceptacleFull", 1111: "markerOpcLifeAlmostOver",
+	1112: "markerOpcLifeOver", 1113: "markerDeveloperAlmostEmpty", 1114: "markerDeveloperEmpty",
+	1301: "mediaPathMediaTrayMissing", 1302: "mediaPathMediaTrayAlmostFull",
+	1303: "mediaPathMediaTrayFull",
+}
+
+func (c AlertCode) String() string {
+	if n, ok := alertCodeNames[c]; ok {
+		return n
+	}
+	return fmt.Sprint(int(c))
+}
+
+// Alert is a row of prtAlertTable.
+type Alert struct {
+	Index       int
+	Severity    AlertSeverity
+	Group       int // prtAlertGroup: the table of the sub-unit, e.g. 8 for input
+	GroupIndex  int // row of the sub-unit in that table
+	Location    int
+	Code        AlertCode
+	Description string
+	Time        time.Duration // sysUpTime when the alert was added
+}
+
+// DeviceStatus is the state of a printer read from its Printer-MIB and Host
+// Resources MIB.
+type DeviceStatus struct {
+	Description  string        // sysDescr
+	Name         string        // sysName
+	Uptime       time.Duration // sysUpTime
+	SerialNumber string        // prtGeneralSerialNumber
+	Status       PrinterStatus // hrPrinterStatus
+	DeviceState  DeviceState   // hrDeviceStatus
+	Errors       ErrorState    // hrPrinterDetectedErrorState
+	Display      []string      // prtConsoleDisplayBufferText lines
+	Supplies     []Supply
+	Trays        []InputTray
+	Covers       []Cover
+	Alerts       []Alert
+}
+
+// row is a table row: its columns by number.
+type row struct {
+	index OID // the row index, after the column number
+	cols  map[uint32]Variable
+}
+
+func (r *row) int(col uint32) int {
+	return int(r.cols[col].Int())
+}
+
+func (r *row) text(col uint32) string {
+	return r.cols[col].Text()
+}
+
+// last returns the last component of the row index, the row number within
+// the printer device.
+func (r *row) last() int {
+	if len(r.index) == 0 {
+		return 0
+	}
+	return int(r.index[len(r.index)-1])
+}
+
+// table walks the table whose entry object is entry and returns its rows
+// in index order.
+func (c *Client) table(entry OID) ([]*row, error) {
+	rows := make(map[string]*row)
+	err := c.Walk(entry, func(v Variable) error {
+		if len(v.Name) <= len(entry)+1 {
+			return nil
+		}
+		index := v.Name[len(entry)+1:]
+		key := index.String()
+		r := rows[key]
+		if r == nil {
+			r = &row{index: index, cols: make(map[uint32]Variable)}
+			rows[key] = r
+		}
+		r.cols[v.Name[len(entry)]] = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*row, 0, len(rows))
+	for _, r := range rows {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].index.Compare(list[j].index) < 0 })
+	return list, nil
+}
+
+func timeTicks(v Variable) time.Duration {
+	return time.Duration(v.Int()) * 10 * time.Millisecond
+}
+
+// DeviceStatus reads the state of the printer. Tables the printer does not
+// implement are left empty.
+func (c *Client) DeviceStatus() (*DeviceStatus, error) {
+	ds := new(DeviceStatus)
+	for _, oid := range []OID{oidSysDescr, oidSysName, oidSysUpTime} {
+		vars, err := c.Get(oid)
+		var e *Error
+		if errors.As(err, &e) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(vars) != 1 || !vars[0].Exists() {
+			continue
+		}
+		switch {
+		case vars[0].Name.Compare(oidSysDescr) == 0:
+			ds.Description = vars[0].Text()
+		case vars[0].Name.Compare(oidSysName) == 0:
+			ds.Name = vars[0].Text()
+		case vars[0].Name.Compare(oidSysUpTime) == 0:
+			ds.Uptime = timeTicks(vars[0])
+		}
+	}
+
+	tables := make(map[string][]*row)
+	for _, entry := range []OID{oidHrDeviceEntry, oidHrPrinterEntry, oidPrtGeneralEntry, oidPrtCoverEntry,
+		oidPrtInputEntry, oidPrtMarkerSuppliesEntry, oidPrtMarkerColorantEntry,
+		oidPrtConsoleDisplayBuffer, oidPrtAlertEntry} {
+		rows, err := c.table(entry)
+		if err != nil {
+			return nil, err
+		}
+		tables[entry.String()] = rows
+	}
+
+	// The printer is the first device of the printer table.
+	printers := tables[oidHrPrinterEntry.String()]
+	var device uint32
+	if len(printers) > 0 {
+		p := printers[0]
+		device = p.index[0]
+		ds.Status = PrinterStatus(p.int(1))
+		ds.Errors = parseErrorState(p.cols[2].Bytes())
+	}
+	// of returns the rows of the printer device; Printer-MIB tables are
+	// indexed by hrDeviceIndex first.
+	of := func(entry OID) []*row {
+		var rows []*row
+		for _, r := range tables[entry.String()] {
+			if device == 0 || r.index[0] == device {
+				rows = append(rows, r)
+			}
+		}
+		return rows
+	}
+	for _, r := range tables[oidHrDeviceEntry.String()] {
+		if len(r.index) == 1 && (device == 0 || r.index[0] == device) {
+			ds.DeviceState = DeviceState(r.int(5))
+			break
+		}
+	}
+	if rows := of(oidPrtGeneralEntry); len(rows) > 0 {
+		ds.SerialNumber = rows[0].text(17)
+	}
+	for _, r := range of(oidPrtConsoleDisplayBuffer) {
+		if line := strings.TrimSpace(r.text(2)); line != "" {
+			ds.Display = append(ds.Display, line)
+		}
+	}
+
+	colorants := make(map[int]string)
+	for _, r := range of(oidPrtMarkerColorantEntry) {
+		colorants[r.last()] = r.text(4)
+	}
+	for _, r := range of(oidPrtMarkerSuppliesEntry) {
+		ds.Supplies = append(ds.Supplies, Supply{
+			Index:       r.last(),
+			Description: r.text(6),
+			Type:        SupplyType(r.int(5)),
+			Color:       colorants[r.int(3)],
+			Receptacle:  r.int(4) == 4,
+			Unit:        r.int(7),
+			MaxCapacity: r.int(8),
+			Level:       r.int(9),
+		})
+	}
+	for _, r := range of(oidPrtInputEntry) {
+		ds.Trays = append(ds.Trays, InputTray{
+			Index:       r.last(),
+			Name:        r.text(13),
+			Description: r.text(18),
+			MediaName:   r.text(12),
+			MaxCapacity: r.int(9),
+			Level:       r.int(10),
+			Status:      SubUnitStatus(r.int(11)),
+		})
+	}
+	for _, r := range of(oidPrtCoverEntry) {
+		ds.Covers = append(ds.Covers, Cover{
+			Index:       r.last(),
+			Description: r.text(2),
+			Status:      CoverStatus(r.int(3)),
+		})
+	}
+	for _, r := range of(oidPrtAlertEntry) {
+		ds.Alerts = append(ds.Alerts, Alert{
+			Index:       r.last(),
+			Severity:    AlertSeverity(r.int(2)),
+			Group:       r.int(4),
+			GroupIndex:  r.int(5),
+			Location:    r.int(6),
+			Code:        AlertCode(r.int(7)),
+			Description: r.text(8),
+			Time:        timeTicks(r.cols[9]),
+		})
+	}
+	return ds, nil
+}
@@ -0,0 +1,146 @@
+package snmp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// printerMIB is the MIB view of a color laser printer with a paper jam, an
+// empty tray 2 and an open front cover.
+var printerMIB = []Variable{
+	str("1.3.6.1.2.1.1.1.0", "HP ETHERNET MULTI-ENVIRONMENT,ROM none,JETDIRECT,JD153"),
+	{Name: MustParseOID("1.3.6.1.2.1.1.3.0"), Type: TypeTimeTicks, Value: uint64(360000)},
+	str("1.3.6.1.2.1.1.5.0", "NPI1A2B3C"),
+
+	str("1.3.6.1.2.1.25.3.2.1.3.1", "HP Color LaserJet M553"),
+	integer("1.3.6.1.2.1.25.3.2.1.5.1", 3),
+	str("1.3.6.1.2.1.25.3.2.1.3.2", "Ethernet"),
+	integer("1.3.6.1.2.1.25.3.2.1.5.2", 2),
+	integer("1.3.6.1.2.1.25.3.5.1.1.1", 3),
+	{Name: MustParseOID("1.3.6.1.2.1.25.3.5.1.2.1"), Type: TypeOctetString, Value: []byte{0x04, 0x04}},
+
+	str("1.3.6.1.2.1.43.5.1.1.17.1", "JPBCK12345"),
+
+	str("1.3.6.1.2.1.43.6.1.1.2.1.1", "Front Door"),
+	integer("1.3.6.1.2.1.43.6.1.1.3.1.1", 3),
+	str("1.3.6.1.2.1.43.6.1.1.2.1.2", "Right Door"),
+	integer("1.3.6.1.2.1.43.6.1.1.3.1.2", 4),
+
+	integer("1.3.6.1.2.1.43.8.2.1.9.1.1", 100),
+	integer("1.3.6.1.2.1.43.8.2.1.9.1.2", 550),
+	integer("1.3.6.1.2.1.43.8.2.1.10.1.1", -3),
+	integer("1.3.6.1.2.1.43.8.2.1.10.1.2", 0),
+	integer("1.3.6.1.2.1.43.8.2.1.11.1.1", 0),
+	integer("1.3.6.1.2.1.43.8.2.1.11.1.2", 8),
+	str("1.3.6.1.2.1.43.8.2.1.12.1.1", "Plain"),
+	str("1.3.6.1.2.1.43.8.2.1.12.1.2", "Letter"),
+	str("1.3.6.1.2.1.43.8.2.1.13.1.1", "Tray 1"),
+	str("1.3.6.1.2.1.43.8.2.1.13.1.2", "Tray 2"),
+	str("1.3.6.1.2.1.43.8.2.1.18.1.1", "Multipurpose tray"),
+	str("1.3.6.1.2.1.43.8.2.1.18.1.2", "Cassette\x00\x00"),
+
+	integer("1.3.6.1.2.1.43.11.1.1.3.1.1", 1),
+	integer("1.3.6.1.2.1.43.11.1.1.3.1.2", 0),
+	integer("1.3.6.1.2.1.43.11.1.1.4.1.1", 3),
+	integer("1.3.6.1.2.1.43.11.1.1.4.1.2", 4),
+	integer("1.3.6.1.2.1.43.11.1.1.5.1.1", 21),
+	integer("1.3.6.1.2.1.43.11.1.1.5.1.2", 4),
+	str("1.3.6.1.2.1.43.11.1.1.6.1.1", "Black Cartridge HP CF360A"),
+	str("1.3.6.1.2.1.43.11.1.1.6.1.2", "Toner Collection Unit"),
+	integer("1.3.6.1.2.1.43.11.1.1.7.1.1", 19),
+	integer("1.3.6.1.2.1.43.11.1.1.7.1.2", 19),
+	integer("1.3.6.1.2.1.43.11.1.1.8.1.1", 100),
+	integer("1.3.6.1.2.1.43.11.1.1.8.1.2", -2),
+	integer("1.3.6.1.2.1.43.11.1.1.9.1.1", 7),
+	integer("1.3.6.1.2.1.43.11.1.1.9.1.2", -3),
+	str("1.3.6.1.2.1.43.12.1.1.4.1.1", "black"),
+
+	str("1.3.6.1.2.1.43.16.5.1.2.1.1", "Jam in tray 2"),
+	str("1.3.6.1.2.1.43.16.5.1.2.1.2", "  "),
+
+	integer("1.3.6.1.2.1.43.18.1.1.2.1.17", 3),
+	integer("1.3.6.1.2.1.43.18.1.1.4.1.17", 13),
+	integer("1.3.6.1.2.1.43.18.1.1.5.1.17", 2),
+	integer("1.3.6.1.2.1.43.18.1.1.6.1.17", 13200),
+	integer("1.3.6.1.2.1.43.18.1.1.7.1.17", 8),
+	str("1.3.6.1.2.1.43.18.1.1.8.1.17", "13.B2.D1 jam in tray 2"),
+	{Name: MustParseOID("1.3.6.1.2.1.43.18.1.1.9.1.17"), Type: TypeTimeTicks, Value: uint64(350000)},
+}
+
+func TestClient_DeviceStatus(t *testing.T) {
+	a := newAgent(t, "public", printerMIB)
+	for _, version := range []Version{Version1, Version2c} {
+		c := &Client{Addr: a.addr(), Version: version, Timeout: time.Second}
+		ds, err := c.DeviceStatus()
+		if err != nil {
+			t.Fatalf("version %d: DeviceStatus failed: %v", version, err)
+		}
+		want := &DeviceStatus{
+			Description:  "HP ETHERNET MULTI-ENVIRONMENT,ROM none,JETDIRECT,JD153",
+			Name:         "NPI1A2B3C",
+			Uptime:       time.Hour,
+			SerialNumber: "JPBCK12345",
+			Status:       PrinterIdle,
+			DeviceState:  DeviceWarning,
+			Errors:       Jammed | InputTrayEmpty,
+			Display:      []string{"Jam in tray 2"},
+			Supplies: []Supply{
+				{Index: 1, Description: "Black Cartridge HP CF360A", Type: 21, Color: "black", Unit: 19, MaxCapacity: 100, Level: 7},
+				{Index: 2, Description: "Toner Collection Unit", Type: 4, Receptacle: true, Unit: 19, MaxCapacity: LevelUnknown, Level: LevelSomeRemaining},
+			},
+			Trays: []InputTray{
+				{Index: 1, Name: "Tray 1", Description: "Multipurpose tray", MediaName: "Plain", MaxCapacity: 100, Level: LevelSomeRemaining},
+				{Index: 2, Name: "Tray 2", Description: "Cassette", MediaName: "Letter", MaxCapacity: 550, Level: 0, Status: 8},
+			},
+			Covers: []Cover{
+				{Index: 1, Description: "Front Door", Status: CoverOpen},
+				{Index: 2, Description: "Right Door", Status: CoverClosed},
+			},
+			Alerts: []Alert{
+				{Index: 17, Severity: AlertCritical, Group: 13, GroupIndex: 2, Location: 13200, Code: 8,
+					Description: "13.B2.D1 jam in tray 2", Time: 3500 * time.Second},
+			},
+		}
+		if !reflect.DeepEqual(ds, want) {
+			t.Errorf("version %d: DeviceStatus =\n%+v\nwant\n%+v", version, ds, want)
+		}
+	}
+}
+
+func TestDeviceStatus_Types(t *testing.T) {
+	s := parseErrorState([]byte{0x34, 0x04})
+	if got := s.String(); got != "lowToner,noToner,jammed,inputTrayEmpty" {
+		t.Errorf("ErrorState = %q", got)
+	}
+	if s = parseErrorState([]byte{0x80}); s != LowPaper || ErrorState(0).String() != "none" {
+		t.Errorf("parseErrorState(0x80) = %v", s)
+	}
+
+	if p, ok := (Supply{MaxCapacity: 8000, Level: 2000}).Percent(); !ok || p != 25 {
+		t.Errorf("Percent = %d, %v", p, ok)
+	}
+	if _, ok := (Supply{MaxCapacity: 100, Level: LevelSomeRemaining}).Percent(); ok {
+		t.Error("Percent of an unknown level is known")
+	}
+	if SupplyType(21).String() != "tonerCartridge" || SupplyType(99).String() != "99" {
+		t.Errorf("SupplyType names: %v %v", SupplyType(21), SupplyType(99))
+	}
+	if AlertCode(1104).String() != "markerTonerAlmostEmpty" || AlertSeverity(3).String() != "critical" {
+		t.Errorf("alert names: %v %v", AlertCode(1104), AlertSeverity(3))
+	}
+	for status, want := range map[SubUnitStatus][5]bool{
+		0:  {true, false, false, false, false},
+		3:  {false, true, false, false, false},
+		12: {true, false, true, false, false},
+		48: {true, false, false, true, true},
+	} {
+		got := [5]bool{status.Available(), status.Broken(), status.NonCriticalAlert(), status.CriticalAlert(), status.Offline()}
+		if got != want {
+			t.Errorf("SubUnitStatus(%d) = %v, want %v", status, got, want)
+		}
+	}
+	if !(Cover{Status: CoverInterlockOpen}).Open() || (Cover{Status: CoverClosed}).Open() {
+		t.Error("Cover.Open is wrong")
+	}
+}
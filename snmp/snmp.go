@@ -0,0 +1,514 @@
+// Package snmp is a small SNMPv1 and SNMPv2c client, enough to read the
+// Printer-MIB (RFC 3805) and Host Resources MIB (RFC 2790) of network
+// printers.
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is an SNMP protocol version, as encoded in messages.
+type Version int
+
+const (
+	Version1  Version = 0
+	Version2c Version = 1
+)
+
+// Type is the BER tag of a variable value.
+type Type byte
+
+const (
+	TypeInteger          Type = 0x02
+	TypeOctetString      Type = 0x04
+	TypeNull             Type = 0x05
+	TypeObjectIdentifier Type = 0x06
+	TypeIPAddress        Type = 0x40
+	TypeCounter32        Type = 0x41
+	TypeGauge32          Type = 0x42
+	TypeTimeTicks        Type = 0x43
+	TypeOpaque           Type = 0x44
+	TypeCounter64        Type = 0x46
+	TypeNoSuchObject     Type = 0x80
+	TypeNoSuchInstance   Type = 0x81
+	TypeEndOfMibView     Type = 0x82
+)
+
+var typeNames = map[Type]string{
+	TypeInteger:          "Integer",
+	TypeOctetString:      "OctetString",
+	TypeNull:             "Null",
+	TypeObjectIdentifier: "ObjectIdentifier",
+	TypeIPAddress:        "IpAddress",
+	TypeCounter32:        "Counter32",
+	TypeGauge32:          "Gauge32",
+	TypeTimeTicks:        "TimeTicks",
+	TypeOpaque:           "Opaque",
+	TypeCounter64:        "Counter64",
+	TypeNoSuchObject:     "noSuchObject",
+	TypeNoSuchInstance:   "noSuchInstance",
+	TypeEndOfMibView:     "endOfMibView",
+}
+
+func (t Type) String() string {
+	if s, ok := typeNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("type-0x%02x", byte(t))
+}
+
+// PDU types.
+const (
+	pduGet      = 0xa0
+	pduGetNext  = 0xa1
+	pduResponse = 0xa2
+	pduGetBulk  = 0xa5
+)
+
+// OID is an object identifier.
+type OID []uint32
+
+// ParseOID parses a dotted object identifier such as "1.3.6.1.2.1.1.1.0".
+// A leading dot is allowed.
+func ParseOID(s string) (OID, error) {
+	var oid OID
+	for _, f := range strings.Split(strings.TrimPrefix(s, "."), ".") {
+		n, err := strconv.ParseUint(f, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("snmp: bad object identifier %q", s)
+		}
+		oid = append(oid, uint32(n))
+	}
+	return oid, nil
+}
+
+// MustParseOID is ParseOID panicking on errors, for constants.
+func MustParseOID(s string) OID {
+	oid, err := ParseOID(s)
+	if err != nil {
+		panic(err)
+	}
+	return oid
+}
+
+func (o OID) String() string {
+	var b strings.Builder
+	for i, n := range o {
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(strconv.FormatUint(uint64(n), 10))
+	}
+	return b.String()
+}
+
+// HasPrefix reports whether o is prefix or below it.
+func (o OID) HasPrefix(prefix OID) bool {
+	if len(o) < len(prefix) {
+		return false
+	}
+	for i, n := range prefix {
+		if o[i] != n {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare compares o and p in lexicographic order, returning -1, 0 or 1.
+func (o OID) Compare(p OID) int {
+	for i := 0; i < len(o) && i < len(p); i++ {
+		switch {
+		case o[i] < p[i]:
+			return -1
+		case o[i] > p[i]:
+			return 1
+		}
+	}
+	switch {
+	case len(o) < len(p):
+		return -1
+	case len(o) > len(p):
+		return 1
+	}
+	return 0
+}
+
+// Variable is a variable binding. Value is an int64 for Integer, a uint64
+// for Counter32, Gauge32, TimeTicks and Counter64, a []byte for
+// OctetString and Opaque, a net.IP for IpAddress, an OID for
+// ObjectIdentifier and nil otherwise.
+type Variable struct {
+	Name  OID
+	Type  Type
+	Value interface{}
+}
+
+// Int returns the value of an integer variable, or 0.
+func (v Variable) Int() int64 {
+	switch n := v.Value.(type) {
+	case int64:
+		return n
+	case uint64:
+		return int64(n)
+	}
+	return 0
+}
+
+// Bytes returns the value of an octet string variable, or nil.
+func (v Variable) Bytes() []byte {
+	b, _ := v.Value.([]byte)
+	return b
+}
+
+// Text returns the value of an octet string variable as text, without the
+// trailing NUL characters some printers send.
+func (v Variable) Text() string {
+	return strings.TrimRight(string(v.Bytes()), "\x00")
+}
+
+// Exists reports whether the agent had a value for the variable.
+func (v Variable) Exists() bool {
+	return v.Type != TypeNoSuchObject && v.Type != TypeNoSuchInstance && v.Type != TypeEndOfMibView
+}
+
+// pdu is a protocol data unit. ErrorStatus and ErrorIndex are the
+// non-repeaters and max-repetitions of GetBulk requests.
+type pdu struct {
+	Type        byte
+	RequestID   int32
+	ErrorStatus int
+	ErrorIndex  int
+	Vars        []Variable
+}
+
+// message is an SNMPv1 or SNMPv2c message.
+type message struct {
+	Version   Version
+	Community string
+	PDU       pdu
+}
+
+func (m *message) MarshalBinary() ([]byte, error) {
+	var vars []byte
+	for _, v := range m.PDU.Vars {
+		vb, err := appendOID(nil, v.Name)
+		if err != nil {
+			return nil, err
+		}
+		if vb, err = appendValue(vb, v); err != nil {
+			return nil, err
+		}
+		vars = appendTLV(vars, tagSequence, vb)
+	}
+	p := appendInt(nil, tagInteger, int64(m.PDU.RequestID))
+	p = appendInt(p, tagInteger, int64(m.PDU.ErrorStatus))
+	p = appendInt(p, tagInteger, int64(m.PDU.ErrorIndex))
+	p = appendTLV(p, tagSequence, vars)
+
+	b := appendInt(nil, tagInteger, int64(m.Version))
+	b = appendTLV(b, byte(TypeOctetString), []byte(m.Community))
+	b = appendTLV(b, m.PDU.Type, p)
+	return appendTLV(nil, tagSequence, b), nil
+}
+
+func (m *message) UnmarshalBinary(b []byte) error {
+	body, _, err := expect(b, tagSequence)
+	if err != nil {
+		return err
+	}
+	ints := func(n int) ([]int64, error) {
+		vs := make([]int64, n)
+		for i := range vs {
+			var data []byte
+			if data, body, err = expect(body, tagInteger); err != nil {
+				return nil, err
+			}
+			if vs[i], err = parseInt(data); err != nil {
+				return nil, err
+			}
+		}
+		return vs, nil
+	}
+	v, err := ints(1)
+	if err != nil {
+		return err
+	}
+	m.Version = Version(v[0])
+	community, body, err := expect(body, byte(TypeOctetString))
+	if err != nil {
+		return err
+	}
+	m.Community = string(community)
+	if m.PDU.Type, body, _, err = readTLV(body); err != nil {
+		return err
+	}
+	if v, err = ints(3); err != nil {
+		return err
+	}
+	m.PDU.RequestID, m.PDU.ErrorStatus, m.PDU.ErrorIndex = int32(v[0]), int(v[1]), int(v[2])
+	list, _, err := expect(body, tagSequence)
+	if err != nil {
+		return err
+	}
+	m.PDU.Vars = nil
+	for len(list) > 0 {
+		var vb []byte
+		if vb, list, err = expect(list, tagSequence); err != nil {
+			return err
+		}
+		name, rest, err := expect(vb, byte(TypeObjectIdentifier))
+		if err != nil {
+			return err
+		}
+		var v Variable
+		if v.Name, err = parseOID(name); err != nil {
+			return err
+		}
+		tag, data, _, err := readTLV(rest)
+		if err != nil {
+			return err
+		}
+		v.Type = Type(tag)
+		if v.Value, err = parseValue(v.Type, data); err != nil {
+			return err
+		}
+		m.PDU.Vars = append(m.PDU.Vars, v)
+	}
+	return nil
+}
+
+// ErrorStatus is the error-status of a response.
+type ErrorStatus int
+
+const (
+	NoError    ErrorStatus = 0
+	TooBig     ErrorStatus = 1
+	NoSuchName ErrorStatus = 2 // SNMPv1 only
+	BadValue   ErrorStatus = 3
+	ReadOnly   ErrorStatus = 4
+	GenErr     ErrorStatus = 5
+	NoAccess   ErrorStatus = 6
+)
+
+var errorStatusNames = []string{
+	"noError", "tooBig", "noSuchName", "badValue", "readOnly", "genErr", "noAccess",
+	"wrongType", "wrongLength", "wrongEncoding", "wrongValue", "noCreation",
+	"inconsistentValue", "resourceUnavailable", "commitFailed", "undoFailed",
+	"authorizationError", "notWritable", "inconsistentName",
+}
+
+func (s ErrorStatus) String() string {
+	if s >= 0 && int(s) < len(errorStatusNames) {
+		return errorStatusNames[s]
+	}
+	return "error-" + strconv.Itoa(int(s))
+}
+
+// Error is a response with a non-zero error-status.
+type Error struct {
+	Status ErrorStatus
+	Index  int // 1-based index of the variable in error, 0 if none
+	Name   OID // the variable in error, if any
+}
+
+func (e *Error) Error() string {
+	if e.Name != nil {
+		return fmt.Sprintf("snmp: %v: %v", e.Status, e.Name)
+	}
+	return fmt.Sprintf("snmp: %v", e.Status)
+}
+
+// ErrTimeout is returned when the agent does not answer, which is also what
+// happens with a wrong community.
+var ErrTimeout = errors.New("snmp: request timed out")
+
+// Client is an SNMP client.
+type Client struct {
+	// Addr is the agent address, host[:port]; the port defaults to 161.
+	Addr string
+
+	// Community is the community string; "public" when empty.
+	Community string
+
+	// Version is Version1 or Version2c. Walks use GetBulk with Version2c.
+	Version Version
+
+	// Timeout bounds every attempt of a request; 2 seconds when zero.
+	Timeout time.Duration
+
+	// Retries is how many more times a request is sent when the agent does
+	// not answer.
+	Retries int
+
+	// MaxRepetitions is the number of variables asked per GetBulk request;
+	// 16 when zero.
+	MaxRepetitions int
+
+	mu        sync.Mutex
+	requestID int32
+}
+
+// NewClient returns an SNMPv2c client for the agent at addr reading with
+// the community public.
+func NewClient(addr string) *Client {
+	return &Client{Addr: addr, Version: Version2c}
+}
+
+func (c *Client) addr() string {
+	if _, _, err := net.SplitHostPort(c.Addr); err == nil {
+		return c.Addr
+	}
+	return net.JoinHostPort(strings.Trim(c.Addr, "[]"), "161")
+}
+
+// request sends a request PDU and returns the response PDU.
+func (c *Client) request(typ byte, a, b int, vars []Variable) (*pdu, error) {
+	c.mu.Lock()
+	c.requestID = (c.requestID + 1) & 0x7fffffff
+	if c.requestID == 0 {
+		c.requestID = int32(time.Now().UnixNano() & 0x7fffffff)
+	}
+	id := c.requestID
+	c.mu.Unlock()
+
+	community := c.Community
+	if community == "" {
+		community = "public"
+	}
+	req := &message{Version: c.Version, Community: community,
+		PDU: pdu{Type: typ, RequestID: id, ErrorStatus: a, ErrorIndex: b, Vars: vars}}
+	out, err := req.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.Dial("udp", c.addr())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	buf := make([]byte, 65535)
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if _, err = conn.Write(out); err != nil {
+			return nil, err
+		}
+		deadline := time.Now().Add(timeout)
+		if err = conn.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+		for {
+			n, err := conn.Read(buf)
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			var resp message
+			if resp.UnmarshalBinary(buf[:n]) != nil || resp.PDU.Type != pduResponse || resp.PDU.RequestID != id {
+				// A late answer to an earlier attempt or garbage.
+				continue
+			}
+			if resp.PDU.ErrorStatus != 0 {
+				e := &Error{Status: ErrorStatus(resp.PDU.ErrorStatus), Index: resp.PDU.ErrorIndex}
+				if e.Index > 0 && e.Index <= len(vars) {
+					e.Name = vars[e.Index-1].Name
+				}
+				return nil, e
+			}
+			return &resp.PDU, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTimeout, c.addr())
+}
+
+func nullVars(oids []OID) []Variable {
+	vars := make([]Variable, len(oids))
+	for i, oid := range oids {
+		vars[i] = Variable{Name: oid, Type: TypeNull}
+	}
+	return vars
+}
+
+// Get reads the variables named oids.
+func (c *Client) Get(oids ...OID) ([]Variable, error) {
+	p, err := c.request(pduGet, 0, 0, nullVars(oids))
+	if err != nil {
+		return nil, err
+	}
+	return p.Vars, nil
+}
+
+// GetNext reads the variables following oids.
+func (c *Client) GetNext(oids ...OID) ([]Variable, error) {
+	p, err := c.request(pduGetNext, 0, 0, nullVars(oids))
+	if err != nil {
+		return nil, err
+	}
+	return p.Vars, nil
+}
+
+// GetBulk reads up to maxRepetitions variables following oid. It needs
+// Version2c.
+func (c *Client) GetBulk(maxRepetitions int, oid OID) ([]Variable, error) {
+	p, err := c.request(pduGetBulk, 0, maxRepetitions, nullVars([]OID{oid}))
+	if err != nil {
+		return nil, err
+	}
+	return p.Vars, nil
+}
+
+// Walk calls fn for every variable below root, in order, using GetBulk
+// with Version2c and GetNext with Version1.
+func (c *Client) Walk(root OID, fn func(Variable) error) error {
+	last := root
+	for {
+		var (
+			vars []Variable
+			err  error
+		)
+		if c.Version == Version2c {
+			n := c.MaxRepetitions
+			if n <= 0 {
+				n = 16
+			}
+			vars, err = c.GetBulk(n, last)
+		} else {
+			vars, err = c.GetNext(last)
+		}
+		var e *Error
+		if errors.As(err, &e) && e.Status == NoSuchName {
+			// The end of the MIB view in SNMPv1.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(vars) == 0 {
+			return nil
+		}
+		for _, v := range vars {
+			if !v.Exists() || !v.Name.HasPrefix(root) {
+				return nil
+			}
+			if v.Name.Compare(last) <= 0 {
+				return fmt.Errorf("snmp: agent returned %v after %v", v.Name, last)
+			}
+			if err = fn(v); err != nil {
+				return err
+			}
+			last = v.Name
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package snmp
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// agent is a UDP SNMP stub agent serving a fixed MIB view.
+type agent struct {
+	conn      *net.UDPConn
+	community string
+	vars      []Variable // sorted by name
+
+	mu       sync.Mutex
+	requests int
+	drop     int // requests to leave unanswered
+}
+
+func newAgent(t *testing.T, community string, vars []Variable) *agent {
+	c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	a := &agent{conn: c, community: community, vars: append([]Variable(nil), vars...)}
+	sort.Slice(a.vars, func(i, j int) bool { return a.vars[i].Name.Compare(a.vars[j].Name) < 0 })
+	go a.serve()
+	return a
+}
+
+func (a *agent) addr() string {
+	return a.conn.LocalAddr().String()
+}
+
+func (a *agent) count() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.requests
+}
+
+// next returns the index of the first variable after oid.
+func (a *agent) next(oid OID) int {
+	return sort.Search(len(a.vars), func(i int) bool { return a.vars[i].Name.Compare(oid) > 0 })
+}
+
+func (a *agent) answer(req *message) *message {
+	resp := &message{Version: req.Version, Community: req.Community,
+		PDU: pdu{Type: pduResponse, RequestID: req.PDU.RequestID}}
+	fail := func(status ErrorStatus, i int) *message {
+		resp.PDU.ErrorStatus, resp.PDU.ErrorIndex, resp.PDU.Vars = int(status), i+1, req.PDU.Vars
+		return resp
+	}
+	for i, v := range req.PDU.Vars {
+		switch req.PDU.Type {
+		case pduGet:
+			j := sort.Search(len(a.vars), func(i int) bool { return a.vars[i].Name.Compare(v.Name) >= 0 })
+			if j < len(a.vars) && a.vars[j].Name.Compare(v.Name) == 0 {
+				resp.PDU.Vars = append(resp.PDU.Vars, a.vars[j])
+			} else if req.Version == Version1 {
+				return fail(NoSuchName, i)
+			} else {
+				resp.PDU.Vars = append(resp.PDU.Vars, Variable{Name: v.Name, Type: TypeNoSuchObject})
+			}
+		case pduGetNext:
+			if j := a.next(v.Name); j < len(a.vars) {
+				resp.PDU.Vars = append(resp.PDU.Vars, a.vars[j])
+			} else if req.Version == Version1 {
+				return fail(NoSuchName, i)
+			} else {
+				resp.PDU.Vars = append(resp.PDU.Vars, Variable{Name: v.Name, Type: TypeEndOfMibView})
+			}
+		case pduGetBulk:
+			if req.Version == Version1 {
+				return nil
+			}
+			j := a.next(v.Name)
+			for n := 0; n < req.PDU.ErrorIndex; n++ {
+				if j+n >= len(a.vars) {
+					resp.PDU.Vars = append(resp.PDU.Vars, Variable{Name: v.Name, Type: TypeEndOfMibView})
+					break
+				}
+				resp.PDU.Vars = append(resp.PDU.Vars, a.vars[j+n])
+			}
+		}
+	}
+	return resp
+}
+
+func (a *agent) serve() {
+	buf := make([]byte, 65535)
+	for {
+		n, from, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		var req message
+		if req.UnmarshalBinary(buf[:n]) != nil || req.Community != a.community {
+			continue
+		}
+		a.mu.Lock()
+		a.requests++
+		drop := a.drop > 0
+		if drop {
+			a.drop--
+		}
+		a.mu.Unlock()
+		if drop {
+			continue
+		}
+		resp := a.answer(&req)
+		if resp == nil {
+			continue
+		}
+		b, err := resp.MarshalBinary()
+		if err != nil {
+			panic(err)
+		}
+		_, _ = a.conn.WriteToUDP(b, from)
+	}
+}
+
+func str(oid, s string) Variable {
+	return Variable{Name: MustParseOID(oid), Type: TypeOctetString, Value: []byte(s)}
+}
+
+func integer(oid string, n int64) Variable {
+	return Variable{Name: MustParseOID(oid), Type: TypeInteger, Value: n}
+}
+
+func TestMessage(t *testing.T) {
+	m := &message{Version: Version2c, Community: "public", PDU: pdu{
+		Type: pduResponse, RequestID: 0x12345678,
+		Vars: []Variable{
+			integer("1.3.6.1.2.1.43.11.1.1.9.1.1", -3),
+			integer("1.3.6.1.2.1.43.11.1.1.8.1.1", 4000000000),
+			str("1.3.6.1.2.1.1.1.0", "HP ETHERNET MULTI-ENVIRONMENT"),
+			{Name: MustParseOID("1.3.6.1.2.1.1.3.0"), Type: TypeTimeTicks, Value: uint64(4294967295)},
+			{Name: MustParseOID("1.3.6.1.2.1.1.2.0"), Type: TypeObjectIdentifier, Value: MustParseOID("1.3.6.1.4.1.11.2.3.9.1")},
+			{Name: MustParseOID("1.3.6.1.2.1.4.20.1.1.10.0.0.1"), Type: TypeIPAddress, Value: net.IP{10, 0, 0, 1}},
+			{Name: MustParseOID("1.3.6.1.2.1.31.1.1.1.6.1"), Type: TypeCounter64, Value: uint64(1) << 63},
+			{Name: MustParseOID("1.3.6.1.2.1.25.3.5.1.2.1"), Type: TypeOctetString, Value: []byte{0x80, 0x01}},
+			{Name: MustParseOID("2.999.1"), Type: TypeNoSuchInstance},
+		},
+	}}
+	b, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	var got message
+	if err = got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	if !reflect.DeepEqual(&got, m) {
+		t.Errorf("UnmarshalBinary =\n%+v\nwant\n%+v", got, *m)
+	}
+
+	// A GetRequest for sysDescr.0 with community public, as net-snmp
+	// sends it.
+	wire := []byte{
+		0x30, 0x26, 0x02, 0x01, 0x01, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa0, 0x19, 0x02, 0x01, 0x2a, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00,
+		0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, 0x05, 0x00,
+	}
+	req := &message{Version: Version2c, Community: "public", PDU: pdu{Type: pduGet, RequestID: 42,
+		Vars: []Variable{{Name: oidSysDescr, Type: TypeNull}}}}
+	if b, err = req.MarshalBinary(); err != nil || !reflect.DeepEqual(b, wire) {
+		t.Errorf("MarshalBinary =\n% x\nwant\n% x", b, wire)
+	}
+	for _, bad := range [][]byte{wire[:10], wire[:len(wire)-1], {0x30, 0x84, 0xff, 0xff, 0xff, 0xff}} {
+		if err = got.UnmarshalBinary(bad); err == nil {
+			t.Errorf("UnmarshalBinary(% x) succeeded", bad)
+		}
+	}
+}
+
+func TestOID(t *testing.T) {
+	oid, err := ParseOID(".1.3.6.1.2.1.43")
+	if err != nil || oid.String() != "1.3.6.1.2.1.43" {
+		t.Errorf("ParseOID = %v, %v", oid, err)
+	}
+	if _, err = ParseOID("1.3.x"); err == nil {
+		t.Error("ParseOID accepted 1.3.x")
+	}
+	if !MustParseOID("1.3.6.1.2.1.43.5").HasPrefix(oid) || oid.HasPrefix(MustParseOID("1.3.6.1.2.1.43.5")) {
+		t.Error("HasPrefix is wrong")
+	}
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.3.6", "1.3.6", 0},
+		{"1.3.6", "1.3.6.1", -1},
+		{"1.3.10", "1.3.9.1", 1},
+	} {
+		if got := MustParseOID(tt.a).Compare(MustParseOID(tt.b)); got != tt.want {
+			t.Errorf("%s Compare %s = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+var walkVars = []Variable{
+	str("1.3.6.1.2.1.1.1.0", "printer"),
+	integer("1.3.6.1.2.1.43.8.2.1.9.1.1", 250),
+	integer("1.3.6.1.2.1.43.8.2.1.9.1.2", 500),
+	integer("1.3.6.1.2.1.43.8.2.1.10.1.1", 100),
+	integer("1.3.6.1.2.1.43.8.2.1.10.1.2", 0),
+	integer("1.3.6.1.2.1.43.9.2.1.9.1.1", 150),
+}
+
+func TestClient_Walk(t *testing.T) {
+	a := newAgent(t, "secret", walkVars)
+	root := MustParseOID("1.3.6.1.2.1.43.8")
+	for _, version := range []Version{Version1, Version2c} {
+		c := &Client{Addr: a.addr(), Community: "secret", Version: version, MaxRepetitions: 3, Timeout: time.Second}
+		var got []string
+		err := c.Walk(root, func(v Variable) error {
+			got = append(got, v.Name.String())
+			return nil
+		})
+		want := []string{"1.3.6.1.2.1.43.8.2.1.9.1.1", "1.3.6.1.2.1.43.8.2.1.9.1.2",
+			"1.3.6.1.2.1.43.8.2.1.10.1.1", "1.3.6.1.2.1.43.8.2.1.10.1.2"}
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("version %d: Walk = %q, %v", version, got, err)
+		}
+
+		// Walking past the end of the MIB view.
+		n := 0
+		if err = c.Walk(MustParseOID("1.3.6.1.2.1.43.9"), func(Variable) error { n++; return nil }); err != nil || n != 1 {
+			t.Errorf("version %d: Walk to the end = %d, %v", version, n, err)
+		}
+	}
+
+	c := &Client{Addr: a.addr(), Community: "secret"}
+	vars, err := c.Get(oidSysDescr)
+	if err != nil || len(vars) != 1 || vars[0].Text() != "printer" {
+		t.Errorf("Get = %+v, %v", vars, err)
+	}
+	_, err = c.Get(oidSysName)
+	var e *Error
+	if !errors.As(err, &e) || e.Status != NoSuchName || e.Name.Compare(oidSysName) != 0 {
+		t.Errorf("Get of a missing object = %v, want noSuchName", err)
+	}
+	c.Version = Version2c
+	if vars, err = c.Get(oidSysName); err != nil || vars[0].Exists() {
+		t.Errorf("SNMPv2c Get of a missing object = %+v, %v", vars, err)
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	a := newAgent(t, "public", walkVars)
+	c := &Client{Addr: a.addr(), Community: "wrong", Timeout: 50 * time.Millisecond, Retries: 1}
+	if _, err := c.Get(oidSysDescr); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Get with a wrong community = %v, want ErrTimeout", err)
+	}
+
+	a.mu.Lock()
+	a.drop = 1
+	a.mu.Unlock()
+	c.Community = ""
+	if vars, err := c.Get(oidSysDescr); err != nil || vars[0].Text() != "printer" {
+		t.Errorf("Get with a retry = %+v, %v", vars, err)
+	}
+	if n := a.count(); n != 2 {
+		t.Errorf("agent received %d requests, want 2", n)
+	}
+}
@@ -0,0 +1,306 @@
+package winprinters
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SocketSpooler is a Spooler for printers reached over raw TCP, also known
+// as AppSocket or JetDirect. Every document is sent over a connection of its
+// own, which the printer treats as one job.
+//
+// Printer names are socket:// URIs such as "socket://192.168.1.30:9100", or
+// plain host[:port] addresses. The port defaults to 9100.
+type SocketSpooler struct {
+	// ConnectTimeout bounds each connection attempt; 10 seconds when zero.
+	ConnectTimeout time.Duration
+
+	// WriteTimeout bounds each Write; 30 seconds when zero.
+	WriteTimeout time.Duration
+
+	// StatusTimeout bounds reading PJL status; 5 seconds when zero.
+	StatusTimeout time.Duration
+
+	// Retries is how many more times a failed connection attempt is
+	// retried, e.g. while the printer is busy with another host. A document
+	// is never resent once part of it was written.
+	Retries int
+
+	// RetryDelay is the pause between connection attempts; 1 second when
+	// zero.
+	RetryDelay time.Duration
+
+	// PJL wraps every document in a PJL job and enables reading the device
+	// status with Printer.PJLStatus.
+	PJL bool
+
+	mu       sync.Mutex
+	printers []string
+	def      string
+}
+
+// NewSocketSpooler returns a SocketSpooler listing the printers at uris. The
+// first one, if any, is the default printer.
+func NewSocketSpooler(uris ...string) *SocketSpooler {
+	s := &SocketSpooler{printers: append([]string(nil), uris...)}
+	if len(uris) > 0 {
+		s.def = uris[0]
+	}
+	return s
+}
+
+// OpenSocket opens the raw TCP printer at uri with the default timeouts.
+func OpenSocket(uri string) (*Printer, error) {
+	h, err := NewSocketSpooler().Open(uri)
+	if err != nil {
+		return nil, err
+	}
+	return &Printer{h: h}, nil
+}
+
+// socketAddr returns the host:port dialed for a printer name.
+func socketAddr(name string) (string, error) {
+	hostport := name
+	if strings.Contains(name, "://") {
+		u, err := url.Parse(name)
+		if err != nil {
+			return "", err
+		}
+		if u.Scheme != "socket" {
+			return "", fmt.Errorf("winprinters: %q is not a socket:// URI", name)
+		}
+		hostport = u.Host
+	}
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = strings.Trim(hostport, "[]"), "9100"
+	}
+	if host == "" {
+		return "", fmt.Errorf("winprinters: %q has no host", name)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+func (s *SocketSpooler) GetDefault() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.def == "" {
+		return "", ErrPrinterNotFound
+	}
+	return s.def, nil
+}
+
+// SetDefault makes one of the listed printers the default one.
+func (s *SocketSpooler) SetDefault(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.printers {
+		if p == name {
+			s.def = name
+			return nil
+		}
+	}
+	return ErrPrinterNotFound
+}
+
+// ReadNames returns the printers passed to NewSocketSpooler.
+func (s *SocketSpooler) ReadNames() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.printers...), nil
+}
+
+// Open returns a handle for the printer at name, listed or not. It does not
+// connect: StartDocument and PJLStatus do.
+func (s *SocketSpooler) Open(name string) (PrinterHandle, error) {
+	addr, err := socketAddr(name)
+	if err != nil {
+		return nil, err
+	}
+	return &socketPrinter{s: s, name: name, addr: addr}, nil
+}
+
+func (s *SocketSpooler) timeout(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
+// dial connects to addr, retrying failed attempts.
+func (s *SocketSpooler) dial(addr string) (conn net.Conn, err error) {
+	for i := 0; ; i++ {
+		if conn, err = net.DialTimeout("tcp", addr, s.timeout(s.ConnectTimeout, 10*time.Second)); err == nil {
+			return
+		}
+		if i >= s.Retries {
+			return nil, fmt.Errorf("winprinters: connecting to %s: %w", addr, err)
+		}
+		time.Sleep(s.timeout(s.RetryDelay, time.Second))
+	}
+}
+
+// socketPrinter is the PrinterHandle returned by SocketSpooler.Open.
+type socketPrinter struct {
+	s      *SocketSpooler
+	name   string
+	addr   string
+	conn   net.Conn
+	doc    *JobInfo
+	jobs   uint32 // documents started, used as job IDs
+	closed bool
+}
+
+var (
+	errSocketClosed     = errors.New("winprinters: socket printer is closed")
+	errSocketNoDocument = errors.New("winprinters: no document started")
+)
+
+// Jobs returns the document being sent, if any: the printer keeps no queue
+// that could be read over a raw socket.
+func (p *socketPrinter) Jobs() ([]JobInfo, error) {
+	if p.doc == nil {
+		return nil, nil
+	}
+	return []JobInfo{*p.doc}, nil
+}
+
+func (p *socketPrinter) Forms() ([]FormInfo, error) {
+	return nil, &UnsupportedError{Op: "Forms"}
+}
+
+// DriverInfo describes the raw TCP connection; documents are always sent
+// as RAW data.
+func (p *socketPrinter) DriverInfo() (*DriverInfo, error) {
+	return &DriverInfo{Name: "Raw TCP/IP", Environment: "socket", DriverPath: "socket://" + p.addr}, nil
+}
+
+// StartDocument connects to the printer. Data types are not translated: the
+// document must already be in a language the printer understands.
+func (p *socketPrinter) StartDocument(name, datatype string) (uint32, error) {
+	if p.closed {
+		return 0, errSocketClosed
+	}
+	if p.doc != nil {
+		return 0, errors.New("winprinters: document already started")
+	}
+	conn, err := p.s.dial(p.addr)
+	if err != nil {
+		return 0, err
+	}
+	p.conn = conn
+	p.jobs++
+	p.doc = &JobInfo{
+		JobID:        p.jobs,
+		DocumentName: name,
+		DataType:     datatype,
+		StatusCode:   JOB_STATUS_PRINTING,
+		Position:     1,
+		Submitted:    time.Now().UTC(),
+	}
+	if p.s.PJL {
+		if _, err = p.write(pjlJobStart(name)); err != nil {
+			return 0, err
+		}
+	}
+	return p.jobs, nil
+}
+
+// write sends b within the write timeout, dropping the connection and the
+// document on failure.
+func (p *socketPrinter) write(b []byte) (int, error) {
+	if err := p.conn.SetWriteDeadline(time.Now().Add(p.s.timeout(p.s.WriteTimeout, 30*time.Second))); err != nil {
+		return 0, err
+	}
+	n, err := p.conn.Write(b)
+	if err != nil {
+		p.abort()
+		return n, fmt.Errorf("winprinters: writing to %s: %w", p.addr, err)
+	}
+	return n, nil
+}
+
+func (p *socketPrinter) abort() {
+	_ = p.conn.Close()
+	p.conn, p.doc = nil, nil
+}
+
+func (p *socketPrinter) Write(b []byte) (int, error) {
+	if p.doc == nil {
+		return 0, errSocketNoDocument
+	}
+	return p.write(b)
+}
+
+// EndDocument ends the PJL job, if any, and closes the connection.
+func (p *socketPrinter) EndDocument() error {
+	if p.doc == nil {
+		return errSocketNoDocument
+	}
+	if p.s.PJL {
+		if _, err := p.write(pjlJobEnd(p.doc.DocumentName)); err != nil {
+			return err
+		}
+	}
+	err := p.conn.Close()
+	p.conn, p.doc = nil, nil
+	return err
+}
+
+// StartPage does nothing: raw documents carry their own page breaks.
+func (p *socketPrinter) StartPage() error {
+	if p.doc == nil {
+		return errSocketNoDocument
+	}
+	return nil
+}
+
+// EndPage does nothing, see StartPage.
+func (p *socketPrinter) EndPage() error {
+	return p.StartPage()
+}
+
+// PJLStatus asks the printer for its status over a new connection. It fails
+// while a document is being sent: the query would land in the middle of the
+// document, and most printers take one connection at a time.
+func (p *socketPrinter) PJLStatus() (*PJLStatus, error) {
+	if p.closed {
+		return nil, errSocketClosed
+	}
+	if !p.s.PJL {
+		return nil, &UnsupportedError{Op: "PJLStatus"}
+	}
+	if p.conn != nil {
+		return nil, errors.New("winprinters: PJL status cannot be read while a document is being sent")
+	}
+	conn, err := p.s.dial(p.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+	deadline := time.Now().Add(p.s.timeout(p.s.StatusTimeout, 5*time.Second))
+	if err = conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	if _, err = conn.Write([]byte(pjlStatusQuery)); err != nil {
+		return nil, fmt.Errorf("winprinters: writing to %s: %w", p.addr, err)
+	}
+	return readPJLStatus(conn)
+}
+
+// Close drops a document that was started but not ended.
+func (p *socketPrinter) Close() error {
+	if p.closed {
+		return errSocketClosed
+	}
+	if p.conn != nil {
+		p.abort()
+	}
+	p.closed = true
+	return nil
+}
@@ -0,0 +1,337 @@
+package winprinters
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// socketStandIn is a raw TCP printer that records what every connection
+// sent and answers PJL status queries.
+type socketStandIn struct {
+	ln     net.Listener
+	status string
+	mu     sync.Mutex
+	jobs   [][]byte
+	done   chan struct{} // receives after every connection closed by the client
+}
+
+func newSocketStandIn(t *testing.T) *socketStandIn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &socketStandIn{
+		ln:     ln,
+		status: "@PJL INFO STATUS\r\nCODE=10001\r\nDISPLAY=\"00 READY\"\r\nONLINE=TRUE\r\n\f",
+		done:   make(chan struct{}, 16),
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *socketStandIn) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *socketStandIn) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	var data []byte
+	answered := 0
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		data = append(data, buf[:n]...)
+		for ; answered < bytes.Count(data, []byte("@PJL INFO STATUS\r\n")); answered++ {
+			_, _ = io.WriteString(conn, s.status)
+		}
+		if err != nil {
+			break
+		}
+	}
+	s.mu.Lock()
+	s.jobs = append(s.jobs, data)
+	s.mu.Unlock()
+	s.done <- struct{}{}
+}
+
+func (s *socketStandIn) wait(t *testing.T) []byte {
+	t.Helper()
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the connection to close")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[len(s.jobs)-1]
+}
+
+func TestSocketSpooler_Print(t *testing.T) {
+	standIn := newSocketStandIn(t)
+	uri := "socket://" + standIn.ln.Addr().String()
+	p, err := OpenSocket(uri)
+	if err != nil {
+		t.Fatalf("OpenSocket failed: %v", err)
+	}
+	defer closePrinter(p)
+
+	// The handle is reused: every document gets a connection of its own.
+	for _, doc := range []string{"^XA^FDone^FS^XZ", "^XA^FDtwo^FS^XZ"} {
+		if _, err = p.StartRawDocument("label"); err != nil {
+			t.Fatalf("StartRawDocument failed: %v", err)
+		}
+		jobs, err := p.Jobs()
+		if err != nil || len(jobs) != 1 || jobs[0].DocumentName != "label" || jobs[0].DataType != "RAW" {
+			t.Errorf("Jobs during document = %+v, %v", jobs, err)
+		}
+		if _, err = p.Write([]byte(doc)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if err = p.EndDocument(); err != nil {
+			t.Fatalf("EndDocument failed: %v", err)
+		}
+		if got := string(standIn.wait(t)); got != doc {
+			t.Errorf("printer received %q, want %q", got, doc)
+		}
+	}
+	if jobs, _ := p.Jobs(); len(jobs) != 0 {
+		t.Errorf("Jobs after EndDocument = %+v", jobs)
+	}
+	if _, err = p.Forms(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Forms = %v, want ErrUnsupported", err)
+	}
+	if _, err = p.PJLStatus(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("PJLStatus without PJL = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestSocketSpooler_PJL(t *testing.T) {
+	standIn := newSocketStandIn(t)
+	s := NewSocketSpooler(standIn.ln.Addr().String())
+	s.PJL = true
+	defer SetSpooler(SetSpooler(s))
+
+	name, err := GetDefault()
+	if err != nil {
+		t.Fatalf("GetDefault failed: %v", err)
+	}
+	p, err := Open(name)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer closePrinter(p)
+
+	st, err := p.PJLStatus()
+	if err != nil {
+		t.Fatalf("PJLStatus failed: %v", err)
+	}
+	if want := (&PJLStatus{Code: 10001, Display: "00 READY", Online: true}); !reflect.DeepEqual(st, want) || !st.Ready() {
+		t.Errorf("PJLStatus = %+v, want %+v", st, want)
+	}
+	standIn.wait(t)
+
+	if _, err = p.StartDocument(`Q3 "report"`, "RAW"); err != nil {
+		t.Fatalf("StartDocument failed: %v", err)
+	}
+	if _, err = p.Write([]byte("\x1bE")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	// The status query would corrupt the document being sent.
+	if st, err = p.PJLStatus(); err == nil {
+		t.Errorf("PJLStatus during document = %+v", st)
+	}
+	if err = p.EndDocument(); err != nil {
+		t.Fatalf("EndDocument failed: %v", err)
+	}
+	want := pjlUEL + "@PJL JOB NAME=\"Q3 report\"\r\n\x1bE" +
+		pjlUEL + "@PJL EOJ NAME=\"Q3 report\"\r\n" + pjlUEL
+	if got := string(standIn.wait(t)); got != want {
+		t.Errorf("printer received %q, want %q", got, want)
+	}
+}
+
+func TestSocketSpooler_Reconnect(t *testing.T) {
+	// Find a free port, then start listening on it only after the first
+	// connection attempts were refused.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	s := NewSocketSpooler()
+	s.Retries = 50
+	s.RetryDelay = 20 * time.Millisecond
+	h, err := s.Open(addr)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	p := NewPrinter(h)
+	defer closePrinter(p)
+
+	received := make(chan []byte, 1)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer func() { _ = ln.Close() }()
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		b, _ := io.ReadAll(conn)
+		_ = conn.Close()
+		received <- b
+	}()
+	if _, err = p.StartDocument("retry", "RAW"); err != nil {
+		t.Fatalf("StartDocument failed: %v", err)
+	}
+	if _, err = p.Write([]byte("data")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err = p.EndDocument(); err != nil {
+		t.Fatalf("EndDocument failed: %v", err)
+	}
+	if b := <-received; string(b) != "data" {
+		t.Errorf("printer received %q", b)
+	}
+
+	s.Retries = 0
+	if _, err = p.StartDocument("refused", "RAW"); err == nil {
+		t.Error("StartDocument succeeded with nothing listening")
+	}
+}
+
+func TestSocketSpooler_WriteTimeout(t *testing.T) {
+	// A printer that accepts the connection but never reads.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	s := NewSocketSpooler()
+	s.WriteTimeout = 50 * time.Millisecond
+	h, _ := s.Open("socket://" + ln.Addr().String())
+	p := NewPrinter(h)
+	defer closePrinter(p)
+	if _, err = p.StartDocument("stuck", "RAW"); err != nil {
+		t.Fatalf("StartDocument failed: %v", err)
+	}
+	defer func() { _ = (<-accepted).Close() }()
+
+	chunk := make([]byte, 1<<20)
+	for i := 0; i < 256 && err == nil; i++ {
+		_, err = p.Write(chunk)
+	}
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Write = %v, want a deadline error", err)
+	}
+	if _, err = p.Write(chunk); err == nil {
+		t.Error("Write after a failed write succeeded")
+	}
+	if err = p.EndDocument(); err == nil {
+		t.Error("EndDocument after a failed write succeeded")
+	}
+}
+
+func TestSocketAddr(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"socket://10.0.0.5:9101", "10.0.0.5:9101"},
+		{"socket://printer.local", "printer.local:9100"},
+		{"10.0.0.5", "10.0.0.5:9100"},
+		{"10.0.0.5:9102", "10.0.0.5:9102"},
+		{"[fe80::1]:9100", "[fe80::1]:9100"},
+		{"fe80::1", "[fe80::1]:9100"},
+		{"ipp://10.0.0.5", ""},
+		{"socket://", ""},
+	}
+	for _, tt := range tests {
+		got, err := socketAddr(tt.name)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("socketAddr(%q) = %q, want error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("socketAddr(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestParsePJLStatus(t *testing.T) {
+	tests := []struct {
+		resp string
+		want *PJLStatus
+	}{
+		{"@PJL INFO STATUS\r\nCODE=10001\r\nDISPLAY=\"Ready\"\r\nONLINE=TRUE\r\n\f",
+			&PJLStatus{Code: 10001, Display: "Ready", Online: true}},
+		{"\x00garbage@PJL INFO STATUS\nCODE=41213\nDISPLAY=\"LOAD TRAY 2\"\nONLINE=FALSE\n\f",
+			&PJLStatus{Code: 41213, Display: "LOAD TRAY 2"}},
+		{"@PJL INFO STATUS\r\nDISPLAY=\"Ready\"\r\n\f", nil},
+		{"@PJL INFO STATUS\r\nCODE=abc\r\n\f", nil},
+		{"@PJL INFO ID\r\n\"LaserJet\"\r\n\f", nil},
+	}
+	for _, tt := range tests {
+		got, err := readPJLStatus(strings.NewReader(tt.resp))
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("readPJLStatus(%q) = %+v, want error", tt.resp, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readPJLStatus(%q) = %+v, %v, want %+v", tt.resp, got, err, tt.want)
+		}
+	}
+	if (&PJLStatus{Code: 41213, Online: true}).Ready() {
+		t.Error("paper out status is ready")
+	}
+}
+
+func TestPJLName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{`Q3 "report"`, `"Q3 report"`},
+		{"tab\tand\nnewline", `"tabandnewline"`},
+		{strings.Repeat("x", 100), `"` + strings.Repeat("x", 80) + `"`},
+		// 79 bytes, then a 3 byte rune that does not fit.
+		{strings.Repeat("x", 79) + "报告", `"` + strings.Repeat("x", 79) + `"`},
+	}
+	for _, tt := range tests {
+		if got := pjlName(tt.name); got != tt.want {
+			t.Errorf("pjlName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
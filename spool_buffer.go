@@ -0,0 +1,183 @@
+package winprinters
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// spoolBuffer decodes the structures winspool returns in a caller supplied
+// buffer, whose string and DEVMODE pointers point back into the buffer. It
+// reads fields by offset rather than through Go struct types, so the
+// layouts are checked on every platform with synthetic buffers.
+type spoolBuffer struct {
+	b    []byte
+	base uint64 // address of b[0]
+	ptr  int    // pointer size, 4 or 8
+}
+
+// newSpoolBuffer wraps a buffer filled by winspool in this process.
+func newSpoolBuffer(b []byte) spoolBuffer {
+	return spoolBuffer{b: b, base: uint64(uintptr(unsafe.Pointer(&b[0]))), ptr: int(unsafe.Sizeof(uintptr(0)))}
+}
+
+// align rounds n up to a multiple of the pointer size.
+func (sb spoolBuffer) align(n int) int {
+	return (n + sb.ptr - 1) / sb.ptr * sb.ptr
+}
+
+func (sb spoolBuffer) check(off, n int) error {
+	if off < 0 || n < 0 || off+n > len(sb.b) {
+		return fmt.Errorf("winprinters: spooler buffer of %d bytes has no %d bytes at offset %d", len(sb.b), n, off)
+	}
+	return nil
+}
+
+func (sb spoolBuffer) uint16(off int) uint16 {
+	return binary.LittleEndian.Uint16(sb.b[off:])
+}
+
+func (sb spoolBuffer) uint32(off int) uint32 {
+	return binary.LittleEndian.Uint32(sb.b[off:])
+}
+
+func (sb spoolBuffer) uint64(off int) uint64 {
+	return binary.LittleEndian.Uint64(sb.b[off:])
+}
+
+// pointer returns the buffer offset of the pointer at off, or -1 for a
+// null pointer.
+func (sb spoolBuffer) pointer(off int) (int, error) {
+	var p uint64
+	if sb.ptr == 4 {
+		p = uint64(binary.LittleEndian.Uint32(sb.b[off:]))
+	} else {
+		p = binary.LittleEndian.Uint64(sb.b[off:])
+	}
+	if p == 0 {
+		return -1, nil
+	}
+	if p < sb.base || p-sb.base >= uint64(len(sb.b)) {
+		return 0, fmt.Errorf("winprinters: spooler buffer pointer %#x at offset %d points outside the buffer", p, off)
+	}
+	return int(p - sb.base), nil
+}
+
+// string reads the NUL terminated UTF-16 string the pointer at off points
+// to. A null pointer is the empty string.
+func (sb spoolBuffer) string(off int) (string, error) {
+	p, err := sb.pointer(off)
+	if err != nil || p < 0 {
+		return "", err
+	}
+	s, _, err := sb.stringAt(p)
+	return s, err
+}
+
+// multiString reads the list of NUL terminated UTF-16 strings, ended by an
+// empty string, the pointer at off points to. A null pointer is an empty
+// list.
+func (sb spoolBuffer) multiString(off int) ([]string, error) {
+	p, err := sb.pointer(off)
+	if err != nil || p < 0 {
+		return nil, err
+	}
+	var list []string
+	for {
+		s, next, err := sb.stringAt(p)
+		if err != nil {
+			return nil, err
+		}
+		if s == "" {
+			return list, nil
+		}
+		list = append(list, s)
+		p = next
+	}
+}
+
+// stringAt reads the NUL terminated UTF-16 string at buffer offset p, and
+// returns the offset after its NUL.
+func (sb spoolBuffer) stringAt(p int) (string, int, error) {
+	var s []uint16
+	for ; ; p += 2 {
+		if err := sb.check(p, 2); err != nil {
+			return "", 0, fmt.Errorf("winprinters: unterminated string in spooler buffer")
+		}
+		c := sb.uint16(p)
+		if c == 0 {
+			break
+		}
+		s = append(s, c)
+	}
+	return string(utf16.Decode(s)), p + 2, nil
+}
+
+// systemTime reads the SYSTEMTIME at off, which winspool fills in UTC.
+func (sb spoolBuffer) systemTime(off int) time.Time {
+	w := func(i int) int { return int(sb.uint16(off + 2*i)) }
+	if w(0) == 0 {
+		return time.Time{}
+	}
+	// wYear, wMonth, wDayOfWeek, wDay, wHour, wMinute, wSecond, wMilliseconds
+	return time.Date(w(0), time.Month(w(1)), w(3), w(4), w(5), w(6), w(7)*int(time.Millisecond), time.UTC)
+}
+
+// fileTime reads the FILETIME at off, a count of 100ns intervals since
+// 1601 in UTC. A zero FILETIME is the zero time.
+func (sb spoolBuffer) fileTime(off int) time.Time {
+	ft := int64(sb.uint64(off))
+	if ft == 0 {
+		return time.Time{}
+	}
+	const unixEpoch = 116444736000000000 // 1970-01-01 in 100ns intervals since 1601
+	ft -= unixEpoch
+	return time.Unix(ft/1e7, ft%1e7*100).UTC()
+}
+
+// devMode copies the DEVMODE the pointer at off points to, or returns nil
+// for a null pointer. Private driver data after the public fields is not
+// copied.
+func (sb spoolBuffer) devMode(off int) (*DevMode, error) {
+	p, err := sb.pointer(off)
+	if err != nil || p < 0 {
+		return nil, err
+	}
+	const sizeOffset = CCHDEVICENAME*2 + 4 // dmSize follows dmSpecVersion and dmDriverVersion
+	if err = sb.check(p, sizeOffset+2); err != nil {
+		return nil, err
+	}
+	n := int(sb.uint16(p + sizeOffset))
+	if err = sb.check(p, n); err != nil {
+		return nil, err
+	}
+	dm := new(DevMode)
+	if max := int(unsafe.Sizeof(*dm)); n > max {
+		n = max
+	}
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(dm)), n), sb.b[p:p+n])
+	return dm, nil
+}
+
+// devModeExtra copies the private driver data after the DEVMODE the pointer
+// at off points to, or returns nil when there is none.
+func (sb spoolBuffer) devModeExtra(off int) ([]byte, error) {
+	p, err := sb.pointer(off)
+	if err != nil || p < 0 {
+		return nil, err
+	}
+	const sizeOffset = CCHDEVICENAME*2 + 4
+	if err = sb.check(p, sizeOffset+4); err != nil {
+		return nil, err
+	}
+	size, extra := int(sb.uint16(p+sizeOffset)), int(sb.uint16(p+sizeOffset+2))
+	if extra == 0 {
+		return nil, nil
+	}
+	if err = sb.check(p+size, extra); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), sb.b[p+size:p+size+extra]...), nil
+}
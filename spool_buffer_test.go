@@ -0,0 +1,127 @@
+package winprinters
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// spoolWriter builds synthetic winspool buffers: fixed size structures
+// first, then the strings and DEVMODEs they point to, as the spooler lays
+// them out.
+type spoolWriter struct {
+	sb spoolBuffer
+}
+
+// newSpoolWriter returns a writer of a buffer with fixed bytes of
+// structures, pointers of ptr bytes and a fake base address.
+func newSpoolWriter(ptr, fixed int) *spoolWriter {
+	base := uint64(0x7ff0_1234_0000)
+	if ptr == 4 {
+		base = 0x0123_0000
+	}
+	return &spoolWriter{sb: spoolBuffer{b: make([]byte, fixed), base: base, ptr: ptr}}
+}
+
+func (w *spoolWriter) uint16(off int, v uint16) {
+	binary.LittleEndian.PutUint16(w.sb.b[off:], v)
+}
+
+func (w *spoolWriter) uint32(off int, v uint32) {
+	binary.LittleEndian.PutUint32(w.sb.b[off:], v)
+}
+
+// pointer stores at off a pointer to the buffer offset target.
+func (w *spoolWriter) pointer(off, target int) {
+	p := w.sb.base + uint64(target)
+	if w.sb.ptr == 4 {
+		binary.LittleEndian.PutUint32(w.sb.b[off:], uint32(p))
+	} else {
+		binary.LittleEndian.PutUint64(w.sb.b[off:], p)
+	}
+}
+
+// append adds data to the end of the buffer and returns its offset.
+func (w *spoolWriter) append(data []byte) int {
+	off := w.sb.align(len(w.sb.b))
+	w.sb.b = append(w.sb.b, make([]byte, off-len(w.sb.b))...)
+	w.sb.b = append(w.sb.b, data...)
+	return off
+}
+
+// string appends s and stores a pointer to it at off.
+func (w *spoolWriter) string(off int, s string) {
+	var data []byte
+	for _, c := range append(utf16.Encode([]rune(s)), 0) {
+		data = append(data, byte(c), byte(c>>8))
+	}
+	w.pointer(off, w.append(data))
+}
+
+// multiString appends list as NUL terminated strings ended by an empty
+// string and stores a pointer to it at off.
+func (w *spoolWriter) multiString(off int, list ...string) {
+	var data []byte
+	for _, s := range list {
+		for _, c := range append(utf16.Encode([]rune(s)), 0) {
+			data = append(data, byte(c), byte(c>>8))
+		}
+	}
+	w.pointer(off, w.append(append(data, 0, 0)))
+}
+
+// fileTime stores t as a FILETIME at off.
+func (w *spoolWriter) fileTime(off int, t time.Time) {
+	ft := t.Unix()*1e7 + int64(t.Nanosecond()/100) + 116444736000000000
+	binary.LittleEndian.PutUint64(w.sb.b[off:], uint64(ft))
+}
+
+// systemTime stores t as a SYSTEMTIME at off.
+func (w *spoolWriter) systemTime(off int, t time.Time) {
+	for i, v := range []int{t.Year(), int(t.Month()), int(t.Weekday()), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond() / 1e6} {
+		w.uint16(off+2*i, uint16(v))
+	}
+}
+
+// devMode appends dm followed by extra bytes of private driver data and
+// stores a pointer to it at off.
+func (w *spoolWriter) devMode(off int, dm *DevMode, extra int) {
+	dm.dmSize = uint16(unsafe.Sizeof(*dm))
+	dm.dmDriverExtra = uint16(extra)
+	data := append([]byte(nil), unsafe.Slice((*byte)(unsafe.Pointer(dm)), dm.dmSize)...)
+	w.pointer(off, w.append(append(data, make([]byte, extra)...)))
+}
+
+func TestSpoolBuffer(t *testing.T) {
+	for _, ptr := range []int{4, 8} {
+		w := newSpoolWriter(ptr, 4*ptr)
+		w.string(0, "Microsoft Print to PDF")
+		w.string(ptr, "")
+		w.pointer(3*ptr, len(w.sb.b)+16)
+		sb := w.sb
+
+		if s, err := sb.string(0); err != nil || s != "Microsoft Print to PDF" {
+			t.Errorf("%d-byte pointers: string = %q, %v", ptr, s, err)
+		}
+		if s, err := sb.string(ptr); err != nil || s != "" {
+			t.Errorf("%d-byte pointers: empty string = %q, %v", ptr, s, err)
+		}
+		if s, err := sb.string(2 * ptr); err != nil || s != "" {
+			t.Errorf("%d-byte pointers: null string = %q, %v", ptr, s, err)
+		}
+		if _, err := sb.string(3 * ptr); err == nil || !strings.Contains(err.Error(), "outside the buffer") {
+			t.Errorf("%d-byte pointers: string outside the buffer error = %v", ptr, err)
+		}
+
+		// Cut the terminating NUL off the last string.
+		w.string(2*ptr, "tail")
+		sb = w.sb
+		sb.b = sb.b[:len(sb.b)-2]
+		if _, err := sb.string(2 * ptr); err == nil {
+			t.Errorf("%d-byte pointers: unterminated string succeeded", ptr)
+		}
+	}
+}
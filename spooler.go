@@ -0,0 +1,160 @@
+package winprinters
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrUnsupported is matched by errors.Is for every *UnsupportedError.
+	ErrUnsupported = errors.New("winprinters: operation not supported")
+
+	// ErrPrinterNotFound is returned by a Spooler when no printer has the
+	// requested name.
+	ErrPrinterNotFound = errors.New("winprinters: printer not found")
+
+	// ErrJobNotFound is returned by a Spooler when no job has the requested
+	// ID.
+	ErrJobNotFound = errors.New("winprinters: job not found")
+)
+
+// UnsupportedError is returned for operations the platform or the Spooler
+// behind a Printer cannot perform, such as winspool calls on Linux.
+type UnsupportedError struct {
+	Op string
+}
+
+func (e *UnsupportedError) Error() string {
+	return "winprinters: " + e.Op + " is not supported"
+}
+
+// Is reports whether target is ErrUnsupported.
+func (e *UnsupportedError) Is(target error) bool {
+	return target == ErrUnsupported
+}
+
+// Spooler is a print spooler backend. GetDefault, SetDefault, ReadNames and
+// Open all go through the current Spooler. It is winspool on Windows; on
+// other platforms every operation fails with an *UnsupportedError until a
+// backend is installed with SetSpooler.
+type Spooler interface {
+	// GetDefault returns the name of the default printer.
+	GetDefault() (string, error)
+	// SetDefault makes the named printer the default one.
+	SetDefault(name string) error
+	// ReadNames returns the names of all printers.
+	ReadNames() ([]string, error)
+	// Open opens the named printer.
+	Open(name string) (PrinterHandle, error)
+}
+
+// PrinterHandle is a printer opened by a Spooler. Printer wraps it.
+type PrinterHandle interface {
+	// Jobs returns the print jobs queued on the printer.
+	Jobs() ([]JobInfo, error)
+	// Forms returns the paper size forms known to the printer.
+	Forms() ([]FormInfo, error)
+	// DriverInfo returns information about the printer driver.
+	DriverInfo() (*DriverInfo, error)
+	// StartDocument starts a new document of the given data type and
+	// returns the ID of its job, or 0 when the spooler assigns the ID only
+	// once the document ends, see JobIDReporter.
+	StartDocument(name, datatype string) (uint32, error)
+	// Write sends document data to the printer.
+	Write(b []byte) (int, error)
+	// EndDocument ends the document started by StartDocument.
+	EndDocument() error
+	// StartPage starts a new page.
+	StartPage() error
+	// EndPage ends the current page.
+	EndPage() error
+	// Close releases the handle.
+	Close() error
+}
+
+// JobCanceler is implemented by PrinterHandles that can cancel queued jobs.
+type JobCanceler interface {
+	CancelJob(jobID uint32) error
+}
+
+// JobController is implemented by PrinterHandles that can control queued
+// jobs. Printer checks job IDs and argument ranges before calling it.
+type JobController interface {
+	// ControlJob runs a JOB_CONTROL_* command on the job.
+	ControlJob(jobID, command uint32) error
+	// SetJobPriority sets the job priority.
+	SetJobPriority(jobID, priority uint32) error
+	// SetJobPosition moves the job in the queue, counted from 1.
+	SetJobPosition(jobID, position uint32) error
+}
+
+// JobDetailer is implemented by PrinterHandles that report more about jobs
+// than JobInfo holds.
+type JobDetailer interface {
+	// JobsDetailed returns the details of all jobs queued on the printer.
+	JobsDetailed() ([]JobDetails, error)
+	// Job returns the details of one job, or ErrJobNotFound.
+	Job(jobID uint32) (*JobDetails, error)
+}
+
+// FileDocumentStarter is implemented by PrinterHandles that can print a
+// document to a file instead of the device.
+type FileDocumentStarter interface {
+	// StartFileDocument is StartDocument writing the output to the file
+	// at path, an absolute path checked by Printer.StartDocumentToFile.
+	StartFileDocument(name, datatype, path string) (uint32, error)
+}
+
+// JobIDReporter is implemented by PrinterHandles whose spooler assigns job
+// IDs when the document ends, such as IPP printers answering Print-Job.
+type JobIDReporter interface {
+	// LastJobID returns the ID of the job of the document ended last.
+	LastJobID() uint32
+}
+
+var (
+	spoolerMu sync.RWMutex
+	spooler   Spooler = newPlatformSpooler()
+)
+
+// CurrentSpooler returns the Spooler used by the package level functions.
+func CurrentSpooler() Spooler {
+	spoolerMu.RLock()
+	defer spoolerMu.RUnlock()
+	return spooler
+}
+
+// SetSpooler replaces the Spooler used by the package level functions and
+// returns the previous one, so tests can restore it when they are done.
+func SetSpooler(s Spooler) (previous Spooler) {
+	spoolerMu.Lock()
+	defer spoolerMu.Unlock()
+	previous, spooler = spooler, s
+	return
+}
+
+// OpenURI opens a network printer by URI with the backend for its scheme:
+// ipp://, ipps://, http:// and https:// with an IPPSpooler, socket:// with
+// a SocketSpooler and lpd:// with an LPRSpooler. Printers found by the
+// discovery package are opened with their URI. It ignores the Spooler
+// installed by SetSpooler.
+func OpenURI(uri string) (*Printer, error) {
+	var s Spooler
+	switch scheme := strings.ToLower(uri[:strings.Index(uri+":", ":")]); scheme {
+	case "ipp", "ipps", "http", "https":
+		s = NewIPPSpooler(uri)
+	case "socket":
+		s = NewSocketSpooler()
+	case "lpd":
+		s = NewLPRSpooler("")
+	default:
+		return nil, fmt.Errorf("winprinters: unsupported printer URI scheme %q", scheme)
+	}
+	h, err := s.Open(uri)
+	if err != nil {
+		return nil, err
+	}
+	return NewPrinter(h), nil
+}
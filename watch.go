@@ -0,0 +1,368 @@
+package winprinters
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+)
+
+// DefaultWatchInterval is how often a PollWatcher takes a snapshot of the
+// printer when its Interval is zero.
+const DefaultWatchInterval = time.Second
+
+// PrinterEventType tells what changed on a watched printer.
+type PrinterEventType int
+
+const (
+	// EventJobAdded reports a job added to the queue.
+	EventJobAdded PrinterEventType = iota + 1
+	// EventJobChanged reports a change of the status, progress, position
+	// or priority of a queued job.
+	EventJobChanged
+	// EventJobDeleted reports a job that left the queue.
+	EventJobDeleted
+	// EventPrinterStatusChanged reports a change of the PRINTER_STATUS_*
+	// bits of the printer.
+	EventPrinterStatusChanged
+	// EventFormAdded reports a paper size form added to the printer.
+	EventFormAdded
+	// EventFormChanged reports a form whose size or imageable area changed.
+	EventFormChanged
+	// EventFormDeleted reports a form removed from the printer.
+	EventFormDeleted
+	// EventDriverChanged reports a new or updated printer driver.
+	EventDriverChanged
+	// EventError reports a failure to read the printer state. Watching
+	// goes on after it unless the channel is closed.
+	EventError
+)
+
+func (t PrinterEventType) String() string {
+	switch t {
+	case EventJobAdded:
+		return "job added"
+	case EventJobChanged:
+		return "job changed"
+	case EventJobDeleted:
+		return "job deleted"
+	case EventPrinterStatusChanged:
+		return "printer status changed"
+	case EventFormAdded:
+		return "form added"
+	case EventFormChanged:
+		return "form changed"
+	case EventFormDeleted:
+		return "form deleted"
+	case EventDriverChanged:
+		return "driver changed"
+	case EventError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// PrinterEvent is a change of a watched printer. Only the fields for its
+// Type are set.
+type PrinterEvent struct {
+	Type PrinterEventType
+
+	// Job is the job for job events; for EventJobDeleted it is the job
+	// as last seen. OldJobStatus is its status before EventJobChanged.
+	Job          JobInfo
+	OldJobStatus JobStatus
+
+	// Status and OldStatus are the PRINTER_STATUS_* bits of the printer
+	// after and before EventPrinterStatusChanged.
+	Status    PrinterStatus
+	OldStatus PrinterStatus
+
+	Form   FormInfo   // the form for form events, as last seen when deleted
+	Driver DriverInfo // the new driver for EventDriverChanged
+	Err    error      // the failure for EventError
+}
+
+// Watcher watches a printer for changes. PrinterHandles whose spooler
+// notifies changes implement it; PollWatcher implements it for all others.
+type Watcher interface {
+	// Watch sends the changes to the printer on the returned channel
+	// until ctx is done, then closes it.
+	Watch(ctx context.Context) (<-chan PrinterEvent, error)
+}
+
+// PrinterStatusReader is implemented by PrinterHandles that report the
+// PRINTER_STATUS_* bits of the printer.
+type PrinterStatusReader interface {
+	PrinterStatus() (PrinterStatus, error)
+}
+
+// Watch sends the changes to the printer on the returned channel until ctx
+// is done, then closes it. It uses the change notifications of the
+// spooler when the PrinterHandle implements Watcher, which also report
+// jobs that come and go between two looks at the queue, and falls back to
+// a PollWatcher.
+//
+// Events must be received promptly: the watch waits for the receiver.
+func (p *Printer) Watch(ctx context.Context) (<-chan PrinterEvent, error) {
+	if w, ok := p.h.(Watcher); ok {
+		events, err := w.Watch(ctx)
+		if !errors.Is(err, ErrUnsupported) {
+			return events, err
+		}
+	}
+	return (&PollWatcher{Printer: p}).Watch(ctx)
+}
+
+// PollWatcher watches a printer by comparing snapshots of its jobs, status,
+// forms and driver. It works with every Spooler, but misses jobs added and
+// deleted between two snapshots.
+type PollWatcher struct {
+	Printer  *Printer
+	Interval time.Duration // time between snapshots, DefaultWatchInterval when zero
+}
+
+// Watch implements Watcher.
+func (pw *PollWatcher) Watch(ctx context.Context) (<-chan PrinterEvent, error) {
+	w, err := newWatcher(pw.Printer.h)
+	if err != nil {
+		return nil, err
+	}
+	interval := pw.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	t := time.NewTicker(interval)
+	go func() {
+		defer t.Stop()
+		w.run(ctx, func(ctx context.Context) (watchChange, error) {
+			select {
+			case <-ctx.Done():
+				return watchChange{}, ctx.Err()
+			case <-t.C:
+				return watchChange{kinds: watchAll}, nil
+			}
+		})
+	}()
+	return w.events, nil
+}
+
+// watchKinds selects the parts of the printer state a watcher refreshes.
+type watchKinds uint8
+
+const (
+	watchJobs watchKinds = 1 << iota
+	watchStatus
+	watchForms
+	watchDriver
+
+	watchAll = watchJobs | watchStatus | watchForms | watchDriver
+)
+
+// watchChange is what a notification tells a watcher: the parts of the
+// printer state to refresh, and the jobs it saw, so that jobs gone by the
+// time of the refresh are still reported.
+type watchChange struct {
+	kinds watchKinds
+	jobs  []JobInfo
+}
+
+// watcher turns snapshots of a printer into PrinterEvents.
+type watcher struct {
+	h      PrinterHandle
+	kinds  watchKinds // parts of the state h reports
+	jobs   []JobInfo
+	status PrinterStatus
+	forms  []FormInfo
+	driver DriverInfo
+	events chan PrinterEvent
+}
+
+// newWatcher takes the first snapshot of h. Parts of the printer state h
+// does not support are left out of the watch.
+func newWatcher(h PrinterHandle) (*watcher, error) {
+	w := &watcher{h: h, kinds: watchJobs, events: make(chan PrinterEvent, 16)}
+	var err error
+	if w.jobs, err = h.Jobs(); err != nil {
+		return nil, err
+	}
+	if r, ok := h.(PrinterStatusReader); ok {
+		if w.status, err = r.PrinterStatus(); err == nil {
+			w.kinds |= watchStatus
+		} else if !errors.Is(err, ErrUnsupported) {
+			return nil, err
+		}
+	}
+	if w.forms, err = h.Forms(); err == nil {
+		w.kinds |= watchForms
+	} else if !errors.Is(err, ErrUnsupported) {
+		return nil, err
+	}
+	if d, err := h.DriverInfo(); err == nil {
+		w.driver = *d
+		w.kinds |= watchDriver
+	} else if !errors.Is(err, ErrUnsupported) {
+		return nil, err
+	}
+	return w, nil
+}
+
+// run refreshes the snapshot after every change next waits for, until ctx
+// is done or next fails, and then closes w.events.
+func (w *watcher) run(ctx context.Context, next func(context.Context) (watchChange, error)) {
+	defer close(w.events)
+	for {
+		c, err := next(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			w.send(ctx, PrinterEvent{Type: EventError, Err: err})
+			return
+		}
+		if !w.refresh(ctx, c) {
+			return
+		}
+	}
+}
+
+// send sends e unless ctx is done first.
+func (w *watcher) send(ctx context.Context, e PrinterEvent) bool {
+	select {
+	case w.events <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// refresh reads the parts of the printer state c names and sends the
+// differences to the previous snapshot.
+func (w *watcher) refresh(ctx context.Context, c watchChange) bool {
+	kinds := c.kinds & w.kinds
+	if c.jobs != nil {
+		kinds |= watchJobs
+	}
+	var events []PrinterEvent
+	fail := func(err error) {
+		events = append(events, PrinterEvent{Type: EventError, Err: err})
+	}
+	if kinds&watchJobs != 0 {
+		if jobs, err := w.h.Jobs(); err != nil {
+			fail(err)
+		} else {
+			events = append(events, diffJobs(w.jobs, jobs, c.jobs)...)
+			w.jobs = jobs
+		}
+	}
+	if kinds&watchStatus != 0 {
+		if status, err := w.h.(PrinterStatusReader).PrinterStatus(); err != nil {
+			fail(err)
+		} else if status != w.status {
+			events = append(events, PrinterEvent{Type: EventPrinterStatusChanged, Status: status, OldStatus: w.status})
+			w.status = status
+		}
+	}
+	if kinds&watchForms != 0 {
+		if forms, err := w.h.Forms(); err != nil {
+			fail(err)
+		} else {
+			events = append(events, diffForms(w.forms, forms)...)
+			w.forms = forms
+		}
+	}
+	if kinds&watchDriver != 0 {
+		if d, err := w.h.DriverInfo(); err != nil {
+			fail(err)
+		} else if !reflect.DeepEqual(*d, w.driver) {
+			events = append(events, PrinterEvent{Type: EventDriverChanged, Driver: *d})
+			w.driver = *d
+		}
+	}
+	for _, e := range events {
+		if !w.send(ctx, e) {
+			return false
+		}
+	}
+	return true
+}
+
+// diffJobs returns the events turning the queue old into cur. Jobs in seen
+// that are in neither, having come and gone in between, are reported as
+// added and deleted.
+func diffJobs(old, cur, seen []JobInfo) []PrinterEvent {
+	var events []PrinterEvent
+	known := make(map[uint32]JobInfo, len(old)+len(cur))
+	for _, j := range old {
+		known[j.JobID] = j
+	}
+	for _, j := range cur {
+		o, ok := known[j.JobID]
+		switch {
+		case !ok:
+			events = append(events, PrinterEvent{Type: EventJobAdded, Job: j})
+		case jobChanged(o, j):
+			events = append(events, PrinterEvent{Type: EventJobChanged, Job: j, OldJobStatus: o.StatusCode})
+		}
+	}
+	current := make(map[uint32]bool, len(cur))
+	for _, j := range cur {
+		current[j.JobID] = true
+		known[j.JobID] = j
+	}
+	var gone []JobInfo
+	for i, j := range seen {
+		if _, ok := known[j.JobID]; ok {
+			continue
+		}
+		// Report the last state seen of a job noted more than once.
+		for _, k := range seen[i+1:] {
+			if k.JobID == j.JobID {
+				j = k
+			}
+		}
+		known[j.JobID] = j
+		events = append(events, PrinterEvent{Type: EventJobAdded, Job: j})
+		gone = append(gone, j)
+	}
+	for _, j := range old {
+		if !current[j.JobID] {
+			gone = append(gone, j)
+		}
+	}
+	for _, j := range gone {
+		events = append(events, PrinterEvent{Type: EventJobDeleted, Job: j})
+	}
+	return events
+}
+
+func jobChanged(a, b JobInfo) bool {
+	return a.StatusCode != b.StatusCode || a.Status != b.Status ||
+		a.PagesPrinted != b.PagesPrinted || a.TotalPages != b.TotalPages ||
+		a.Position != b.Position || a.Priority != b.Priority
+}
+
+// diffForms returns the events turning the forms old into cur.
+func diffForms(old, cur []FormInfo) []PrinterEvent {
+	var events []PrinterEvent
+	known := make(map[string]FormInfo, len(old))
+	for _, f := range old {
+		known[f.Name] = f
+	}
+	current := make(map[string]bool, len(cur))
+	for _, f := range cur {
+		current[f.Name] = true
+		o, ok := known[f.Name]
+		switch {
+		case !ok:
+			events = append(events, PrinterEvent{Type: EventFormAdded, Form: f})
+		case o != f:
+			events = append(events, PrinterEvent{Type: EventFormChanged, Form: f})
+		}
+	}
+	for _, f := range old {
+		if !current[f.Name] {
+			events = append(events, PrinterEvent{Type: EventFormDeleted, Form: f})
+		}
+	}
+	return events
+}
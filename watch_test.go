@@ -0,0 +1,236 @@
+package winprinters
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// nextEvents receives n events, failing the test when they do not come.
+func nextEvents(t *testing.T, events <-chan PrinterEvent, n int) []PrinterEvent {
+	t.Helper()
+	var got []PrinterEvent
+	timeout := time.After(5 * time.Second)
+	for len(got) < n {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatalf("events closed after %+v", got)
+			}
+			got = append(got, e)
+		case <-timeout:
+			t.Fatalf("got events %+v, want %d", got, n)
+		}
+	}
+	return got
+}
+
+func eventTypes(events []PrinterEvent) []PrinterEventType {
+	var types []PrinterEventType
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestDiffJobs(t *testing.T) {
+	a := JobInfo{JobID: 1, DocumentName: "a"}
+	b := JobInfo{JobID: 2, DocumentName: "b", StatusCode: JOB_STATUS_SPOOLING}
+	bPrinting := JobInfo{JobID: 2, DocumentName: "b", StatusCode: JOB_STATUS_PRINTING, PagesPrinted: 1}
+	c := JobInfo{JobID: 3, DocumentName: "c"}
+	tests := []struct {
+		name           string
+		old, cur, seen []JobInfo
+		want           []PrinterEvent
+	}{
+		{name: "unchanged", old: []JobInfo{a, b}, cur: []JobInfo{a, b}, seen: []JobInfo{b}},
+		{
+			name: "added",
+			old:  []JobInfo{a},
+			cur:  []JobInfo{a, b},
+			want: []PrinterEvent{{Type: EventJobAdded, Job: b}},
+		},
+		{
+			name: "changed",
+			old:  []JobInfo{a, b},
+			cur:  []JobInfo{a, bPrinting},
+			want: []PrinterEvent{{Type: EventJobChanged, Job: bPrinting, OldJobStatus: JOB_STATUS_SPOOLING}},
+		},
+		{
+			name: "deleted",
+			old:  []JobInfo{a, b},
+			cur:  []JobInfo{b},
+			want: []PrinterEvent{{Type: EventJobDeleted, Job: a}},
+		},
+		{
+			name: "short-lived",
+			old:  []JobInfo{a},
+			cur:  nil,
+			seen: []JobInfo{b, c, bPrinting},
+			want: []PrinterEvent{
+				{Type: EventJobAdded, Job: bPrinting},
+				{Type: EventJobAdded, Job: c},
+				{Type: EventJobDeleted, Job: bPrinting},
+				{Type: EventJobDeleted, Job: c},
+				{Type: EventJobDeleted, Job: a},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffJobs(tt.old, tt.cur, tt.seen); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffJobs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffForms(t *testing.T) {
+	a4 := FormInfo{Name: "A4", Size: SIZE{Width: 210000, Height: 297000}}
+	label := FormInfo{Name: "4x6", Size: SIZE{Width: 101600, Height: 152400}}
+	bigLabel := FormInfo{Name: "4x6", Size: SIZE{Width: 101600, Height: 203200}}
+	got := diffForms([]FormInfo{a4, label}, []FormInfo{bigLabel, {Name: "Letter"}})
+	want := []PrinterEvent{
+		{Type: EventFormChanged, Form: bigLabel},
+		{Type: EventFormAdded, Form: FormInfo{Name: "Letter"}},
+		{Type: EventFormDeleted, Form: a4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffForms() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPrinter_Watch(t *testing.T) {
+	fake := newTestSpooler(t)
+	p, err := Open("Label")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closePrinter(p)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := p.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	// A job deleted right after it is added is reported either way.
+	id, _ := fake.AddJob("Label", JobInfo{DocumentName: "blink"})
+	fake.RemoveJob("Label", id)
+	got := nextEvents(t, events, 2)
+	if got[0].Type != EventJobAdded || got[1].Type != EventJobDeleted || got[0].Job.JobID != id || got[1].Job.DocumentName != "blink" {
+		t.Errorf("short-lived job events = %+v", got)
+	}
+
+	jobs, _ := p.Jobs()
+	if err = p.ResumeJob(jobs[0].JobID); err != nil {
+		t.Fatal(err)
+	}
+	got = nextEvents(t, events, 1)
+	if got[0].Type != EventJobChanged || got[0].Job.StatusCode != 0 || got[0].OldJobStatus != JOB_STATUS_PAUSED {
+		t.Errorf("resume events = %+v", got)
+	}
+
+	err = fake.UpdatePrinter("Label", func(p *FakePrinter) {
+		p.Status = PRINTER_STATUS_PAPER_OUT
+		p.Forms = append(p.Forms, FormInfo{Name: "2x1"})
+		p.Driver.Name = "ZDesigner ZD421"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = nextEvents(t, events, 3)
+	want := []PrinterEventType{EventPrinterStatusChanged, EventFormAdded, EventDriverChanged}
+	if !reflect.DeepEqual(eventTypes(got), want) || got[0].Status != PRINTER_STATUS_PAPER_OUT || got[0].OldStatus != 0 ||
+		got[1].Form.Name != "2x1" || got[2].Driver.Name != "ZDesigner ZD421" {
+		t.Errorf("printer events = %+v", got)
+	}
+
+	cancel()
+	for e := range events {
+		t.Errorf("event after cancel: %+v", e)
+	}
+}
+
+func TestPollWatcher(t *testing.T) {
+	fake := newTestSpooler(t)
+	p, err := Open("Office")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closePrinter(p)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := (&PollWatcher{Printer: p, Interval: time.Millisecond}).Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	job, err := p.StartDocument("report", "RAW")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := nextEvents(t, events, 1)
+	if got[0].Type != EventJobAdded || got[0].Job.JobID != job.ID || !got[0].Job.StatusCode.Spooling() {
+		t.Errorf("StartDocument events = %+v", got)
+	}
+	if err = p.EndDocument(); err != nil {
+		t.Fatal(err)
+	}
+	got = nextEvents(t, events, 1)
+	if got[0].Type != EventJobChanged || got[0].OldJobStatus != JOB_STATUS_SPOOLING {
+		t.Errorf("EndDocument events = %+v", got)
+	}
+
+	fake.InjectError("Office", "Jobs", errors.New("spooler stopped"))
+	got = nextEvents(t, events, 1)
+	if got[0].Type != EventError || got[0].Err == nil {
+		t.Errorf("events while failing = %+v", got)
+	}
+	fake.InjectError("Office", "Jobs", nil)
+	if err = p.DeleteJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		got = nextEvents(t, events, 1)
+		if got[0].Type != EventError {
+			break
+		}
+	}
+	if got[0].Type != EventJobDeleted || got[0].Job.JobID != job.ID {
+		t.Errorf("DeleteJob events = %+v", got)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestPrinter_WatchFallback(t *testing.T) {
+	fake := newTestSpooler(t)
+	fake.InjectError("Label", "Watch", &UnsupportedError{Op: "Watch"})
+	p, err := Open("Label")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closePrinter(p)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := p.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	if _, err = fake.AddJob("Label", JobInfo{DocumentName: "polled"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := nextEvents(t, events, 1); got[0].Type != EventJobAdded || got[0].Job.DocumentName != "polled" {
+		t.Errorf("events = %+v", got)
+	}
+
+	fake.InjectError("Label", "Forms", errors.New("access denied"))
+	if _, err = p.Watch(ctx); err == nil {
+		t.Errorf("Watch succeeded without the first snapshot")
+	}
+}
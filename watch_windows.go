@@ -0,0 +1,292 @@
+package winprinters
+
+import (
+	"context"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
+const (
+	PRINTER_CHANGE_SET_PRINTER    = 0x00000002
+	PRINTER_CHANGE_DELETE_PRINTER = 0x00000004
+	PRINTER_CHANGE_PRINTER        = 0x000000FF
+	PRINTER_CHANGE_ADD_JOB        = 0x00000100
+	PRINTER_CHANGE_SET_JOB        = 0x00000200
+	PRINTER_CHANGE_DELETE_JOB     = 0x00000400
+	PRINTER_CHANGE_WRITE_JOB      = 0x00000800
+	PRINTER_CHANGE_JOB            = 0x0000FF00
+	PRINTER_CHANGE_FORM           = 0x00070000
+	PRINTER_CHANGE_PRINTER_DRIVER = 0x70000000
+	PRINTER_CHANGE_ALL            = 0x7777FFFF
+
+	PRINTER_NOTIFY_TYPE = 0x00
+	JOB_NOTIFY_TYPE     = 0x01
+
+	PRINTER_NOTIFY_FIELD_STATUS = 0x12
+
+	JOB_NOTIFY_FIELD_USER_NAME     = 0x03
+	JOB_NOTIFY_FIELD_DATATYPE      = 0x05
+	JOB_NOTIFY_FIELD_STATUS        = 0x0A
+	JOB_NOTIFY_FIELD_DOCUMENT      = 0x0D
+	JOB_NOTIFY_FIELD_PRIORITY      = 0x0E
+	JOB_NOTIFY_FIELD_POSITION      = 0x0F
+	JOB_NOTIFY_FIELD_TOTAL_PAGES   = 0x14
+	JOB_NOTIFY_FIELD_PAGES_PRINTED = 0x15
+
+	PRINTER_NOTIFY_OPTIONS_REFRESH = 0x01
+	PRINTER_NOTIFY_INFO_DISCARDED  = 0x01
+)
+
+//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
+type PRINTER_NOTIFY_OPTIONS_TYPE struct {
+	/*
+	  WORD  Type;
+	  WORD  Reserved0;
+	  DWORD Reserved1;
+	  DWORD Reserved2;
+	  DWORD Count;
+	  PWORD pFields;
+	*/
+	Type      uint16
+	Reserved0 uint16
+	Reserved1 uint32
+	Reserved2 uint32
+	Count     uint32
+	Fields    *uint16
+}
+
+//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
+type PRINTER_NOTIFY_OPTIONS struct {
+	/*
+	  DWORD                        Version;
+	  DWORD                        Flags;
+	  DWORD                        Count;
+	  PPRINTER_NOTIFY_OPTIONS_TYPE pTypes;
+	*/
+	Version uint32
+	Flags   uint32
+	Count   uint32
+	Types   *PRINTER_NOTIFY_OPTIONS_TYPE
+}
+
+//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
+type PRINTER_NOTIFY_INFO_DATA struct {
+	/*
+	  WORD  Type;
+	  WORD  Field;
+	  DWORD Reserved;
+	  DWORD Id;
+	  union {
+	    DWORD adwData[2];
+	    struct {
+	      DWORD  cbBuf;
+	      LPVOID pBuf;
+	    } Data;
+	  } NotifyData;
+	*/
+	Type     uint16
+	Field    uint16
+	Reserved uint32
+	Id       uint32
+	// NotifyData holds adwData in the low 32 bits of each word on 64-bit
+	// Windows, and cbBuf and pBuf in its two words everywhere.
+	NotifyData [2]uintptr
+}
+
+//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
+type PRINTER_NOTIFY_INFO struct {
+	/*
+	  DWORD                    Version;
+	  DWORD                    Flags;
+	  DWORD                    Count;
+	  PRINTER_NOTIFY_INFO_DATA aData[1];
+	*/
+	Version uint32
+	Flags   uint32
+	Count   uint32
+	Data    [1]PRINTER_NOTIFY_INFO_DATA
+}
+
+// dword returns adwData[0].
+func (d *PRINTER_NOTIFY_INFO_DATA) dword() uint32 {
+	return uint32(d.NotifyData[0])
+}
+
+// string returns the string pBuf points to.
+func (d *PRINTER_NOTIFY_INFO_DATA) string() string {
+	return windows.UTF16PtrToString(*(**uint16)(unsafe.Pointer(&d.NotifyData[1])))
+}
+
+var (
+	watchJobFields = []uint16{
+		JOB_NOTIFY_FIELD_USER_NAME, JOB_NOTIFY_FIELD_DATATYPE, JOB_NOTIFY_FIELD_STATUS,
+		JOB_NOTIFY_FIELD_DOCUMENT, JOB_NOTIFY_FIELD_PRIORITY, JOB_NOTIFY_FIELD_POSITION,
+		JOB_NOTIFY_FIELD_TOTAL_PAGES, JOB_NOTIFY_FIELD_PAGES_PRINTED,
+	}
+	watchPrinterFields = []uint16{PRINTER_NOTIFY_FIELD_STATUS}
+)
+
+// watchNotifyOptions asks for the job fields a watch reports, so that jobs
+// deleted before the watch reads the queue are reported too.
+func watchNotifyOptions(flags uint32) *PRINTER_NOTIFY_OPTIONS {
+	types := []PRINTER_NOTIFY_OPTIONS_TYPE{
+		{Type: JOB_NOTIFY_TYPE, Count: uint32(len(watchJobFields)), Fields: &watchJobFields[0]},
+		{Type: PRINTER_NOTIFY_TYPE, Count: uint32(len(watchPrinterFields)), Fields: &watchPrinterFields[0]},
+	}
+	return &PRINTER_NOTIFY_OPTIONS{Version: 2, Flags: flags, Count: uint32(len(types)), Types: &types[0]}
+}
+
+// winspoolWatchKinds returns the parts of the printer state that the
+// PRINTER_CHANGE_* bits in changed concern.
+func winspoolWatchKinds(changed uint32) watchKinds {
+	var kinds watchKinds
+	if changed&PRINTER_CHANGE_JOB != 0 {
+		kinds |= watchJobs
+	}
+	if changed&PRINTER_CHANGE_PRINTER != 0 {
+		kinds |= watchStatus
+	}
+	if changed&PRINTER_CHANGE_FORM != 0 {
+		kinds |= watchForms
+	}
+	if changed&PRINTER_CHANGE_PRINTER_DRIVER != 0 {
+		kinds |= watchDriver
+	}
+	return kinds
+}
+
+// notifyJobs collects the job fields in info into one JobInfo per job, in
+// the order the jobs first appear.
+func notifyJobs(info *PRINTER_NOTIFY_INFO) (watchKinds, []JobInfo) {
+	var kinds watchKinds
+	var jobs []JobInfo
+	index := make(map[uint32]int)
+	data := unsafe.Slice(&info.Data[0], info.Count)
+	for i := range data {
+		d := &data[i]
+		if d.Type == PRINTER_NOTIFY_TYPE {
+			kinds |= watchStatus
+			continue
+		}
+		if d.Type != JOB_NOTIFY_TYPE {
+			continue
+		}
+		kinds |= watchJobs
+		k, ok := index[d.Id]
+		if !ok {
+			k = len(jobs)
+			index[d.Id] = k
+			jobs = append(jobs, JobInfo{JobID: d.Id})
+		}
+		j := &jobs[k]
+		switch d.Field {
+		case JOB_NOTIFY_FIELD_USER_NAME:
+			j.UserName = d.string()
+		case JOB_NOTIFY_FIELD_DATATYPE:
+			j.DataType = d.string()
+		case JOB_NOTIFY_FIELD_STATUS:
+			j.StatusCode = JobStatus(d.dword())
+			j.Status = j.StatusCode.String()
+		case JOB_NOTIFY_FIELD_DOCUMENT:
+			j.DocumentName = d.string()
+		case JOB_NOTIFY_FIELD_PRIORITY:
+			j.Priority = d.dword()
+		case JOB_NOTIFY_FIELD_POSITION:
+			j.Position = d.dword()
+		case JOB_NOTIFY_FIELD_TOTAL_PAGES:
+			j.TotalPages = d.dword()
+		case JOB_NOTIFY_FIELD_PAGES_PRINTED:
+			j.PagesPrinted = d.dword()
+		}
+	}
+	return kinds, jobs
+}
+
+// PrinterStatus returns the PRINTER_STATUS_* bits of PRINTER_INFO_6.
+func (p *winspoolPrinter) PrinterStatus() (PrinterStatus, error) {
+	var status, needed uint32
+	err := GetPrinter(p.h, 6, (*byte)(unsafe.Pointer(&status)), uint32(unsafe.Sizeof(status)), &needed)
+	if err != nil {
+		return 0, err
+	}
+	return PrinterStatus(status), nil
+}
+
+// Watch implements Watcher with FindFirstPrinterChangeNotification. Each
+// notification refreshes the parts of the printer state it concerns; the
+// job fields it carries report jobs already gone by then.
+func (p *winspoolPrinter) Watch(ctx context.Context) (<-chan PrinterEvent, error) {
+	w, err := newWatcher(p)
+	if err != nil {
+		return nil, err
+	}
+	change, err := FindFirstPrinterChangeNotification(p.h, PRINTER_CHANGE_ALL, 0, watchNotifyOptions(0))
+	if err != nil {
+		return nil, err
+	}
+	cancel, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		_ = FindClosePrinterChangeNotification(change)
+		return nil, err
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-ctx.Done():
+			_ = windows.SetEvent(cancel)
+		case <-stop:
+		}
+	}()
+
+	refresh := false
+	next := func(ctx context.Context) (watchChange, error) {
+		ev, err := windows.WaitForMultipleObjects([]windows.Handle{windows.Handle(change), cancel}, false, windows.INFINITE)
+		if err != nil {
+			return watchChange{}, err
+		}
+		if ev != windows.WAIT_OBJECT_0 {
+			return watchChange{}, ctx.Err()
+		}
+		var changed uint32
+		var info *PRINTER_NOTIFY_INFO
+		var opts *PRINTER_NOTIFY_OPTIONS
+		if refresh {
+			opts = watchNotifyOptions(PRINTER_NOTIFY_OPTIONS_REFRESH)
+		}
+		if err = FindNextPrinterChangeNotification(change, &changed, opts, &info); err != nil {
+			return watchChange{}, err
+		}
+		c := watchChange{kinds: winspoolWatchKinds(changed)}
+		refresh = false
+		if info != nil {
+			if info.Flags&PRINTER_NOTIFY_INFO_DISCARDED != 0 {
+				// Notifications were lost: refresh everything and
+				// ask for the current values next time.
+				refresh = true
+				c.kinds = watchAll
+			} else {
+				kinds, jobs := notifyJobs(info)
+				c.kinds |= kinds
+				c.jobs = jobs
+			}
+			_ = FreePrinterNotifyInfo(info)
+		}
+		return c, nil
+	}
+	go func() {
+		defer func() {
+			close(stop)
+			wg.Wait()
+			_ = windows.CloseHandle(cancel)
+			_ = FindClosePrinterChangeNotification(change)
+		}()
+		w.run(ctx, next)
+	}()
+	return w.events, nil
+}
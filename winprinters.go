@@ -2,66 +2,20 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package windows printers Windows printing.
+// Package winprinters prints through the Windows spooler. On other platforms
+// it builds as well and works with any Spooler installed by SetSpooler.
 package winprinters
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
-	"syscall"
 	"time"
-	"unsafe"
-
-	"golang.org/x/sys/windows"
+	"unicode/utf8"
 )
 
-//go:generate go run cmd/mksyscall/mksyscall_windows.go -output zapi.go winprinters.go
-
-//sys	GetDefaultPrinter(buf *uint16, bufN *uint32) (err error) = winspool.GetDefaultPrinterW
-//sys	SetDefaultPrinter(name *uint16) (err error) = winspool.SetDefaultPrinterW
-//sys	ClosePrinter(h syscall.Handle) (err error) = winspool.ClosePrinter
-//sys	OpenPrinter(name *uint16, h *syscall.Handle, defaults *PrinterDefaults) (err error) = winspool.OpenPrinterW
-//sys	StartDocPrinter(h syscall.Handle, level uint32, docInfo *DOC_INFO_1) (err error) = winspool.StartDocPrinterW
-//sys	EndDocPrinter(h syscall.Handle) (err error) = winspool.EndDocPrinter
-//sys	WritePrinter(h syscall.Handle, buf *byte, bufN uint32, written *uint32) (err error) = winspool.WritePrinter
-//sys	StartPagePrinter(h syscall.Handle) (err error) = winspool.StartPagePrinter
-//sys	EndPagePrinter(h syscall.Handle) (err error) = winspool.EndPagePrinter
-//sys	EnumPrinters(flags uint32, name *uint16, level uint32, buf *byte, bufN uint32, needed *uint32, returned *uint32) (err error) = winspool.EnumPrintersW
-//sys	GetPrinterDriver(h syscall.Handle, env *uint16, level uint32, di *byte, n uint32, needed *uint32) (err error) = winspool.GetPrinterDriverW
-//sys	EnumJobs(h syscall.Handle, firstJob uint32, noJobs uint32, level uint32, buf *byte, bufN uint32, bytesNeeded *uint32, jobsReturned *uint32) (err error) = winspool.EnumJobsW
-//sys	DocumentProperties(hWnd uint32, h syscall.Handle, pDeviceName *uint16, devModeOut *DevMode, devModeIn *DevMode, fMode uint32) (err error) = winspool.DocumentPropertiesW
-//sys	GetPrinter(h syscall.Handle, level uint32, buf *byte, bufN uint32, needed *uint32) (err error) = winspool.GetPrinterW
-//sys	SetPrinter(h syscall.Handle, level uint32, buf *byte, command uint32) (err error) = winspool.SetPrinterW
-//sys	AddForm(h syscall.Handle, level uint32, form *FORM_INFO_1) (err error) = winspool.AddFormW
-//sys	DeleteForm(h syscall.Handle, pFormName *uint16) (err error) = winspool.DeleteFormW
-//sys	EnumForms(h syscall.Handle, level uint32, pForm *byte, cbBuf uint32, pcbNeeded *uint32, pcReturned *uint32) (err error) = winspool.EnumFormsW
-//sys	DeleteJob(h syscall.Handle, jobId uint32) (err error) = winspool.SetJobW
-
-//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
-type DOC_INFO_1 struct {
-	/*
-	  LPTSTR pDocName;
-	  LPTSTR pOutputFile;
-	  LPTSTR pDatatype;
-	*/
-	DocName    *uint16
-	OutputFile *uint16
-	Datatype   *uint16
-}
-
-//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
-type FORM_INFO_1 struct {
-	/*
-	  DWORD  Flags;
-	  LPTSTR pName;
-	  SIZEL  Size;
-	  RECTL  ImageableArea;
-	*/
-	Flags         uint32
-	pName         *uint16
-	Size          SIZE
-	ImageableArea Rect
-}
-
 // SIZE windows.Coord
 type SIZE struct {
 	Width  uint32 // 宽度，以千毫米为单位
@@ -76,176 +30,81 @@ type Rect struct {
 	Bottom uint32
 }
 
-//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
-type PRINTER_INFO_9 struct {
-	/*
-	  LPDEVMODE pDevMode;
-	*/
-	pDevMode *DevMode
-}
-
-//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
-type PRINTER_INFO_2 struct {
-	/*
-	  LPTSTR               pServerName;
-	  LPTSTR               pPrinterName;
-	  LPTSTR               pShareName;
-	  LPTSTR               pPortName;
-	  LPTSTR               pDriverName;
-	  LPTSTR               pComment;
-	  LPTSTR               pLocation;
-	  LPDEVMODE            pDevMode;
-	  LPTSTR               pSepFile;
-	  LPTSTR               pPrintProcessor;
-	  LPTSTR               pDatatype;
-	  LPTSTR               pParameters;
-	  PSECURITY_DESCRIPTOR pSecurityDescriptor;
-	  DWORD                Attributes;
-	  DWORD                Priority;
-	  DWORD                DefaultPriority;
-	  DWORD                StartTime;
-	  DWORD                UntilTime;
-	  DWORD                Status;
-	  DWORD                cJobs;
-	  DWORD                AveragePPM;
-	*/
-	pServerName         *uint16
-	pPrinterName        *uint16
-	pShareName          *uint16
-	pPortName           *uint16
-	pDriverName         *uint16
-	pComment            *uint16
-	pLocation           *uint16
-	pDevMode            *DevMode
-	pSepFile            *uint16
-	pPrintProcessor     *uint16
-	pDatatype           *uint16
-	pParameters         *uint16
-	pSecurityDescriptor uintptr
-	attributes          uint32
-	priority            uint32
-	defaultPriority     uint32
-	startTime           uint32
-	untilTime           uint32
-	status              uint32
-	cJobs               uint32
-	averagePPM          uint32
-}
-
-func (pi *PRINTER_INFO_2) GetDataType() string {
-	return utf16PtrToString(pi.pDatatype)
-}
-
-//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
-type PRINTER_INFO_5 struct {
-	/*
-	  LPTSTR pPrinterName;
-	  LPTSTR pPortName;
-	  DWORD  Attributes;
-	  DWORD  DeviceNotSelectedTimeout;
-	  DWORD  TransmissionRetryTimeout;
-	*/
-	PrinterName              *uint16
-	PortName                 *uint16
-	Attributes               uint32
-	DeviceNotSelectedTimeout uint32
-	TransmissionRetryTimeout uint32
-}
+//goland:noinspection GoSnakeCaseUsage
+const (
+	PRINTER_ENUM_LOCAL       = 0x00000002 // Printers installed on this computer
+	PRINTER_ENUM_CONNECTIONS = 0x00000004 // Printers the user connected to
+	PRINTER_ENUM_NAME        = 0x00000008 // Printers of the named server, domain or provider
+	PRINTER_ENUM_REMOTE      = 0x00000010 // Printers and print servers in the domain
+	PRINTER_ENUM_SHARED      = 0x00000020 // Only printers shared on the network
+	PRINTER_ENUM_NETWORK     = 0x00000040 // Network printers in the domain
+	PRINTER_ENUM_EXPAND      = 0x00004000 // Entry of PRINTER_INFO_1 worth enumerating first
+	PRINTER_ENUM_CONTAINER   = 0x00008000 // Entry of PRINTER_INFO_1 that holds printers
+	PRINTER_ENUM_ICON8       = 0x00800000 // Entry of PRINTER_INFO_1 that is a printer
+
+	PRINTER_DRIVER_PACKAGE_AWARE = 0x00000001
+	PRINTER_DRIVER_XPS           = 0x00000002
+	PRINTER_DRIVER_SANDBOX       = 0x00000004
+	PRINTER_DRIVER_CLASS         = 0x00000008
+	PRINTER_DRIVER_DERIVED       = 0x00000010
+)
 
 //goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
-type DRIVER_INFO_8 struct {
-	/*
-	  DWORD     cVersion;
-	  LPTSTR    pName;
-	  LPTSTR    pEnvironment;
-	  LPTSTR    pDriverPath;
-	  LPTSTR    pDataFile;
-	  LPTSTR    pConfigFile;
-	  LPTSTR    pHelpFile;
-	  LPTSTR    pDependentFiles;
-	  LPTSTR    pMonitorName;
-	  LPTSTR    pDefaultDataType;
-	  LPTSTR    pszzPreviousNames;
-	  FILETIME  ftDriverDate;
-	  DWORDLONG dwlDriverVersion;
-	  LPTSTR    pszMfgName;
-	  LPTSTR    pszOEMUrl;
-	  LPTSTR    pszHardwareID;
-	  LPTSTR    pszProvider;
-	  LPTSTR    pszPrintProcessor;
-	  LPTSTR    pszVendorSetup;
-	  LPTSTR    pszzColorProfiles;
-	  LPTSTR    pszInfPath;
-	  DWORD     dwPrinterDriverAttributes;
-	  LPTSTR    pszzCoreDriverDependencies;
-	  FILETIME  ftMinInboxDriverVerDate;
-	  DWORDLONG dwlMinInboxDriverVerVersion;
-	*/
-	Version                  uint32
-	Name                     *uint16
-	Environment              *uint16
-	DriverPath               *uint16
-	DataFile                 *uint16
-	ConfigFile               *uint16
-	HelpFile                 *uint16
-	DependentFiles           *uint16
-	MonitorName              *uint16
-	DefaultDataType          *uint16
-	PreviousNames            *uint16
-	DriverDate               windows.Filetime
-	DriverVersion            uint64
-	MfgName                  *uint16
-	OEMUrl                   *uint16
-	HardwareID               *uint16
-	Provider                 *uint16
-	PrintProcessor           *uint16
-	VendorSetup              *uint16
-	ColorProfiles            *uint16
-	InfPath                  *uint16
-	PrinterDriverAttributes  uint32
-	CoreDriverDependencies   *uint16
-	MinInboxDriverVerDate    windows.Filetime
-	MinInboxDriverVerVersion uint32
-}
+const (
+	PRINTER_ATTRIBUTE_QUEUED            = 0x00000001 // Jobs print once fully spooled
+	PRINTER_ATTRIBUTE_DIRECT            = 0x00000002 // Jobs go to the printer without spooling
+	PRINTER_ATTRIBUTE_DEFAULT           = 0x00000004 // Default printer
+	PRINTER_ATTRIBUTE_SHARED            = 0x00000008 // Shared on the network
+	PRINTER_ATTRIBUTE_NETWORK           = 0x00000010 // Network printer connection
+	PRINTER_ATTRIBUTE_HIDDEN            = 0x00000020 // Reserved
+	PRINTER_ATTRIBUTE_LOCAL             = 0x00000040 // Local printer
+	PRINTER_ATTRIBUTE_ENABLE_DEVQ       = 0x00000080 // Mismatched jobs are held
+	PRINTER_ATTRIBUTE_KEEPPRINTEDJOBS   = 0x00000100 // Jobs stay in the queue after printing
+	PRINTER_ATTRIBUTE_DO_COMPLETE_FIRST = 0x00000200 // Fully spooled jobs print first
+	PRINTER_ATTRIBUTE_WORK_OFFLINE      = 0x00000400 // Printer is used offline
+	PRINTER_ATTRIBUTE_ENABLE_BIDI       = 0x00000800 // Bidirectional communication is on
+	PRINTER_ATTRIBUTE_RAW_ONLY          = 0x00001000 // Only raw data is spooled
+	PRINTER_ATTRIBUTE_PUBLISHED         = 0x00002000 // Published in the directory
+	PRINTER_ATTRIBUTE_FAX               = 0x00004000 // Fax printer
+	PRINTER_ATTRIBUTE_TS                = 0x00008000 // Redirected Remote Desktop printer
+	PRINTER_ATTRIBUTE_PUSHED_USER       = 0x00020000 // Deployed by group policy for the user
+	PRINTER_ATTRIBUTE_PUSHED_MACHINE    = 0x00040000 // Deployed by group policy for the machine
+	PRINTER_ATTRIBUTE_MACHINE           = 0x00080000 // Per-machine connection
+	PRINTER_ATTRIBUTE_FRIENDLY_NAME     = 0x00100000 // Name is a friendly name
+	PRINTER_ATTRIBUTE_TS_GENERIC_DRIVER = 0x00200000 // Remote Desktop printer with a generic driver
+	PRINTER_ATTRIBUTE_PER_USER          = 0x00400000 // Per-user printer
+	PRINTER_ATTRIBUTE_ENTERPRISE_CLOUD  = 0x00800000 // Universal Print cloud printer
+)
 
 //goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
-type JOB_INFO_1 struct {
-	/*
-	  DWORD      JobId;
-	  LPTSTR     pPrinterName;
-	  LPTSTR     pMachineName;
-	  LPTSTR     pUserName;
-	  LPTSTR     pDocument;
-	  LPTSTR     pDatatype;
-	  LPTSTR     pStatus;
-	  DWORD      Status;
-	  DWORD      Priority;
-	  DWORD      Position;
-	  DWORD      TotalPages;
-	  DWORD      PagesPrinted;
-	  SYSTEMTIME Submitted;
-	*/
-	JobID        uint32
-	PrinterName  *uint16
-	MachineName  *uint16
-	UserName     *uint16
-	Document     *uint16
-	DataType     *uint16
-	Status       *uint16
-	StatusCode   uint32
-	Priority     uint32
-	Position     uint32
-	TotalPages   uint32
-	PagesPrinted uint32
-	Submitted    windows.Systemtime
-}
-
-//goland:noinspection GoSnakeCaseUsage
 const (
-	PRINTER_ENUM_LOCAL       = 2
-	PRINTER_ENUM_CONNECTIONS = 4
-
-	PRINTER_DRIVER_XPS = 0x00000002
+	PRINTER_STATUS_PAUSED               = 0x00000001 // Printer is paused
+	PRINTER_STATUS_ERROR                = 0x00000002 // Printer is in an error state
+	PRINTER_STATUS_PENDING_DELETION     = 0x00000004 // Printer is being deleted
+	PRINTER_STATUS_PAPER_JAM            = 0x00000008 // Paper is jammed
+	PRINTER_STATUS_PAPER_OUT            = 0x00000010 // Printer is out of paper
+	PRINTER_STATUS_MANUAL_FEED          = 0x00000020 // Printer waits for manual feed
+	PRINTER_STATUS_PAPER_PROBLEM        = 0x00000040 // Printer has a paper problem
+	PRINTER_STATUS_OFFLINE              = 0x00000080 // Printer is offline
+	PRINTER_STATUS_IO_ACTIVE            = 0x00000100 // Printer is in an active I/O state
+	PRINTER_STATUS_BUSY                 = 0x00000200 // Printer is busy
+	PRINTER_STATUS_PRINTING             = 0x00000400 // Printer is printing
+	PRINTER_STATUS_OUTPUT_BIN_FULL      = 0x00000800 // Output bin is full
+	PRINTER_STATUS_NOT_AVAILABLE        = 0x00001000 // Printer is not available
+	PRINTER_STATUS_WAITING              = 0x00002000 // Printer is waiting
+	PRINTER_STATUS_PROCESSING           = 0x00004000 // Printer is processing a job
+	PRINTER_STATUS_INITIALIZING         = 0x00008000 // Printer is initializing
+	PRINTER_STATUS_WARMING_UP           = 0x00010000 // Printer is warming up
+	PRINTER_STATUS_TONER_LOW            = 0x00020000 // Toner is low
+	PRINTER_STATUS_NO_TONER             = 0x00040000 // Printer is out of toner
+	PRINTER_STATUS_PAGE_PUNT            = 0x00080000 // Printer cannot print the current page
+	PRINTER_STATUS_USER_INTERVENTION    = 0x00100000 // User action required
+	PRINTER_STATUS_OUT_OF_MEMORY        = 0x00200000 // Printer is out of memory
+	PRINTER_STATUS_DOOR_OPEN            = 0x00400000 // Printer door is open
+	PRINTER_STATUS_SERVER_UNKNOWN       = 0x00800000 // Print server state is unknown
+	PRINTER_STATUS_POWER_SAVE           = 0x01000000 // Printer is in power save mode
+	PRINTER_STATUS_SERVER_OFFLINE       = 0x02000000 // Print server is offline
+	PRINTER_STATUS_DRIVER_UPDATE_NEEDED = 0x04000000 // Printer driver needs an update
 )
 
 //goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
@@ -267,83 +126,58 @@ const (
 	JOB_STATUS_RENDERING_LOCALLY = 0x00004000 // Job rendering locally on the client
 )
 
+//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
+const (
+	JOB_CONTROL_PAUSE             = 1 // Pause the job
+	JOB_CONTROL_RESUME            = 2 // Resume a paused job
+	JOB_CONTROL_CANCEL            = 3 // Cancel the job; winspool wants JOB_CONTROL_DELETE instead
+	JOB_CONTROL_RESTART           = 4 // Restart the job from its first page
+	JOB_CONTROL_DELETE            = 5 // Delete the job
+	JOB_CONTROL_SENT_TO_PRINTER   = 6 // Used by port monitors to end the job
+	JOB_CONTROL_LAST_PAGE_EJECTED = 7 // Used by language monitors to end the job
+	JOB_CONTROL_RETAIN            = 8 // Keep the job in the queue after it prints
+	JOB_CONTROL_RELEASE           = 9 // Release a retained job
+
+	JOB_POSITION_UNSPECIFIED = 0 // SetJob leaves the queue position alone
+
+	MIN_PRIORITY = 1  // Lowest job priority
+	MAX_PRIORITY = 99 // Highest job priority
+	DEF_PRIORITY = 1  // Priority of new jobs
+)
+
 // GetDefault 获取默认打印机名称
 func GetDefault() (printer string, err error) {
-	b := make([]uint16, 3)
-	n := uint32(len(b))
-	err = GetDefaultPrinter(&b[0], &n)
-	if err != nil {
-		if err != windows.ERROR_INSUFFICIENT_BUFFER {
-			return
-		}
-		b = make([]uint16, n)
-		err = GetDefaultPrinter(&b[0], &n)
-		if err != nil {
-			return
-		}
-	}
-	printer = windows.UTF16ToString(b)
-	return
+	return CurrentSpooler().GetDefault()
 }
 
 // SetDefault 根据打印机名称设置默认打印机
 func SetDefault(printer string) (err error) {
-	docName, _ := windows.UTF16FromString(printer)
-	err = SetDefaultPrinter(&(docName)[0])
-	return
+	return CurrentSpooler().SetDefault(printer)
 }
 
 // ReadNames return printer names on the system
 func ReadNames() ([]string, error) {
-	const flags = PRINTER_ENUM_LOCAL | PRINTER_ENUM_CONNECTIONS
-	var needed, returned uint32
-	buf := make([]byte, 1)
-	err := EnumPrinters(flags, nil, 5, &buf[0], uint32(len(buf)), &needed, &returned)
-	if err != nil {
-		if err != windows.ERROR_INSUFFICIENT_BUFFER {
-			return nil, err
-		}
-		buf = make([]byte, needed)
-		err = EnumPrinters(flags, nil, 5, &buf[0], uint32(len(buf)), &needed, &returned)
-		if err != nil {
-			return nil, err
-		}
-	}
-	ps := (*[1024]PRINTER_INFO_5)(unsafe.Pointer(&buf[0]))[:returned:returned]
-	names := make([]string, 0, returned)
-	for _, p := range ps {
-		names = append(names, windows.UTF16PtrToString(p.PrinterName))
-	}
-	return names, nil
+	return CurrentSpooler().ReadNames()
 }
 
+// Printer is an open printer of the current Spooler.
 type Printer struct {
-	h syscall.Handle
+	h   PrinterHandle
+	doc *Job // job of the document started last, until it ends
 }
 
-func Open(name string) (*Printer, error) {
-	var p Printer
-	docName, _ := windows.UTF16FromString(name)
-	err := OpenPrinter(&(docName)[0], &p.h, nil)
-	if err != nil {
-		return nil, err
-	}
-	return &p, nil
+// NewPrinter wraps a PrinterHandle opened by any Spooler into a Printer.
+func NewPrinter(h PrinterHandle) *Printer {
+	return &Printer{h: h}
 }
 
-func OpenWithDefaults(name string, defaults *PrinterDefaults) (*Printer, error) {
-	var p Printer
-	docName, _ := windows.UTF16FromString(name)
-	err := OpenPrinter(&(docName)[0], &p.h, defaults)
+// Open opens the named printer with the current Spooler.
+func Open(name string) (*Printer, error) {
+	h, err := CurrentSpooler().Open(name)
 	if err != nil {
 		return nil, err
 	}
-	return &p, nil
-}
-
-func CancelJob(jobId uint32) error {
-	var p Printer
-	return DeleteJob(p.h, jobId)
+	return &Printer{h: h}, nil
 }
 
 type PrinterDefaults struct {
@@ -352,12 +186,35 @@ type PrinterDefaults struct {
 	DesiredAccess uint32
 }
 
-// DriverInfo stores information about printer driver.
+// DriverInfo stores information about printer driver, from DRIVER_INFO_8.
+// Spoolers other than winspool fill in only the first four fields.
 type DriverInfo struct {
 	Name        string
-	Environment string
+	Environment string // such as "Windows x64"
 	DriverPath  string
-	Attributes  uint32
+	Attributes  uint32 // PRINTER_DRIVER_* flags
+
+	Version                uint32 // driver model: 3 for v3 drivers, 4 for v4 drivers
+	DataFile               string
+	ConfigFile             string
+	HelpFile               string
+	DependentFiles         []string
+	MonitorName            string
+	DefaultDataType        string
+	PreviousNames          []string // names the driver was installed under before
+	Date                   time.Time
+	DriverVersion          DriverVersion
+	Manufacturer           string
+	OEMURL                 string
+	HardwareID             string
+	Provider               string
+	PrintProcessor         string
+	VendorSetup            string
+	ColorProfiles          []string
+	InfPath                string
+	CoreDriverDependencies []string
+	MinInboxDriverDate     time.Time
+	MinInboxDriverVersion  DriverVersion
 }
 
 // JobInfo stores information about a print job.
@@ -368,7 +225,7 @@ type JobInfo struct {
 	DocumentName    string
 	DataType        string
 	Status          string
-	StatusCode      uint32
+	StatusCode      JobStatus
 	Priority        uint32
 	Position        uint32
 	TotalPages      uint32
@@ -388,293 +245,225 @@ type FormInfo struct {
 
 // Forms returns information about all paper size forms on the print server
 func (p *Printer) Forms() (forms []FormInfo, err error) {
-	var bytesNeeded, formsReturned uint32
-	buf := make([]byte, 1)
-	for {
-		err = EnumForms(p.h, 1, &buf[0], uint32(len(buf)), &bytesNeeded, &formsReturned)
-		if err == nil {
-			break
-		}
-		if err != windows.ERROR_INSUFFICIENT_BUFFER {
-			return
-		}
-		if bytesNeeded <= uint32(len(buf)) {
-			return
-		}
-		buf = make([]byte, bytesNeeded)
-	}
-	if formsReturned <= 0 {
-		return
-	}
-	forms = make([]FormInfo, 0, formsReturned)
-	formsInfo := (*[2048]FORM_INFO_1)(unsafe.Pointer(&buf[0]))[:formsReturned:formsReturned]
-	for _, form := range formsInfo {
-		formInfo := FormInfo{
-			Flags:         form.Flags,
-			Size:          form.Size,
-			ImageableArea: form.ImageableArea,
-		}
-		if form.pName != nil {
-			formInfo.Name = windows.UTF16PtrToString(form.pName)
-		}
-		forms = append(forms, formInfo)
-	}
-	return
+	return p.h.Forms()
 }
 
 // Jobs returns information about all print jobs on this printer
 func (p *Printer) Jobs() ([]JobInfo, error) {
-	var bytesNeeded, jobsReturned uint32
-	buf := make([]byte, 1)
-	for {
-		err := EnumJobs(p.h, 0, 255, 1, &buf[0], uint32(len(buf)), &bytesNeeded, &jobsReturned)
-		if err == nil {
-			break
-		}
-		if err != windows.ERROR_INSUFFICIENT_BUFFER {
-			return nil, err
-		}
-		if bytesNeeded <= uint32(len(buf)) {
-			return nil, err
-		}
-		buf = make([]byte, bytesNeeded)
-	}
-	if jobsReturned <= 0 {
-		return nil, nil
-	}
-	pjs := make([]JobInfo, 0, jobsReturned)
-	ji := (*[2048]JOB_INFO_1)(unsafe.Pointer(&buf[0]))[:jobsReturned:jobsReturned]
-	for _, j := range ji {
-		pji := JobInfo{
-			JobID:        j.JobID,
-			StatusCode:   j.StatusCode,
-			Priority:     j.Priority,
-			Position:     j.Position,
-			TotalPages:   j.TotalPages,
-			PagesPrinted: j.PagesPrinted,
-		}
-		if j.MachineName != nil {
-			pji.UserMachineName = windows.UTF16PtrToString(j.MachineName)
-		}
-		if j.UserName != nil {
-			pji.UserName = windows.UTF16PtrToString(j.UserName)
-		}
-		if j.Document != nil {
-			pji.DocumentName = windows.UTF16PtrToString(j.Document)
-		}
-		if j.DataType != nil {
-			pji.DataType = windows.UTF16PtrToString(j.DataType)
-		}
-		if j.Status != nil {
-			pji.Status = windows.UTF16PtrToString(j.Status)
-		}
-		if strings.TrimSpace(pji.Status) == "" {
-			if pji.StatusCode == 0 {
-				pji.Status += "Queue Paused, "
-			}
-			if pji.StatusCode&JOB_STATUS_PRINTING != 0 {
-				pji.Status += "Printing, "
-			}
-			if pji.StatusCode&JOB_STATUS_PAUSED != 0 {
-				pji.Status += "Paused, "
-			}
-			if pji.StatusCode&JOB_STATUS_ERROR != 0 {
-				pji.Status += "Error, "
-			}
-			if pji.StatusCode&JOB_STATUS_DELETING != 0 {
-				pji.Status += "Deleting, "
-			}
-			if pji.StatusCode&JOB_STATUS_SPOOLING != 0 {
-				pji.Status += "Spooling, "
-			}
-			if pji.StatusCode&JOB_STATUS_OFFLINE != 0 {
-				pji.Status += "Printer Offline, "
-			}
-			if pji.StatusCode&JOB_STATUS_PAPEROUT != 0 {
-				pji.Status += "Out of Paper, "
-			}
-			if pji.StatusCode&JOB_STATUS_PRINTED != 0 {
-				pji.Status += "Printed, "
-			}
-			if pji.StatusCode&JOB_STATUS_DELETED != 0 {
-				pji.Status += "Deleted, "
-			}
-			if pji.StatusCode&JOB_STATUS_BLOCKED_DEVQ != 0 {
-				pji.Status += "Driver Error, "
-			}
-			if pji.StatusCode&JOB_STATUS_USER_INTERVENTION != 0 {
-				pji.Status += "User Action Required, "
-			}
-			if pji.StatusCode&JOB_STATUS_RESTART != 0 {
-				pji.Status += "Restarted, "
-			}
-			if pji.StatusCode&JOB_STATUS_COMPLETE != 0 {
-				pji.Status += "Sent to Printer, "
-			}
-			if pji.StatusCode&JOB_STATUS_RETAINED != 0 {
-				pji.Status += "Retained, "
-			}
-			if pji.StatusCode&JOB_STATUS_RENDERING_LOCALLY != 0 {
-				pji.Status += "Rendering on Client, "
-			}
-			pji.Status = strings.TrimRight(pji.Status, ", ")
-		}
-		pji.Submitted = time.Date(
-			int(j.Submitted.Year),
-			time.Month(int(j.Submitted.Month)),
-			int(j.Submitted.Day),
-			int(j.Submitted.Hour),
-			int(j.Submitted.Minute),
-			int(j.Submitted.Second),
-			int(1000*j.Submitted.Milliseconds),
-			time.Local,
-		).UTC()
-		pjs = append(pjs, pji)
-	}
-	return pjs, nil
+	return p.h.Jobs()
 }
 
 // DriverInfo returns information about printer p driver.
 func (p *Printer) DriverInfo() (*DriverInfo, error) {
-	var needed uint32
-	b := make([]byte, 1024*10)
-	for {
-		err := GetPrinterDriver(p.h, nil, 8, &b[0], uint32(len(b)), &needed)
-		if err == nil {
-			break
-		}
-		if err != windows.ERROR_INSUFFICIENT_BUFFER {
-			return nil, err
-		}
-		if needed <= uint32(len(b)) {
-			return nil, err
-		}
-		b = make([]byte, needed)
-	}
-	di := (*DRIVER_INFO_8)(unsafe.Pointer(&b[0]))
-	return &DriverInfo{
-		Attributes:  di.PrinterDriverAttributes,
-		Name:        windows.UTF16PtrToString(di.Name),
-		DriverPath:  windows.UTF16PtrToString(di.DriverPath),
-		Environment: windows.UTF16PtrToString(di.Environment),
-	}, nil
-}
-
-// GetPrinter2 get Printer Info 2
-func (p *Printer) GetPrinter2() (printerInfo *PRINTER_INFO_2, err error) {
-	var needed uint32
-	var buf = make([]byte, 1)
-
-	var r1 uintptr
-	r1, _, err = procGetPrinterW.Call(uintptr(p.h), 2, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), uintptr(unsafe.Pointer(&needed)))
-	if r1 == 0 {
-		var newBuf = make([]byte, int(needed))
-		var newLen = uint32(len(newBuf))
-		err = GetPrinter(p.h, 2, &newBuf[0], newLen, &needed)
-		if err != nil {
-			//fmt.Println("Failed: ", err)
-			return
-		}
-		printerInfo = (*PRINTER_INFO_2)(unsafe.Pointer(&newBuf[0]))
-		//fmt.Println("Get Printer Info 2 Duplex Setting: ", printerInfo.pDevMode.dmDuplex)
-	}
-	return
+	return p.h.DriverInfo()
 }
 
-func (p *Printer) SetPrinter2(printerInfo *PRINTER_INFO_2) (err error) {
-	bs := (*[unsafe.Sizeof(printerInfo)]byte)(unsafe.Pointer(printerInfo))
+// JobError records a failed operation on a print job.
+type JobError struct {
+	Op    string
+	JobID uint32
+	Err   error
+}
 
-	//fmt.Println("Set printer to duplex with the info 2...")
-	err = SetPrinter(p.h, 2, &bs[0], 0)
-	return
+func (e *JobError) Error() string {
+	return fmt.Sprintf("winprinters: %s %d: %s", e.Op, e.JobID, strings.TrimPrefix(e.Err.Error(), "winprinters: "))
 }
 
-// GetPrinter9 get Printer Info 9
-func (p *Printer) GetPrinter9() (printerInfo *PRINTER_INFO_9, err error) {
-	var needed uint32
-	var buf = make([]byte, 1)
+func (e *JobError) Unwrap() error {
+	return e.Err
+}
 
-	err = GetPrinter(p.h, 9, &buf[0], uint32(len(buf)), &needed)
+// CancelJob cancels the job with the given ID on whichever printer of the
+// current Spooler queues it. Use Printer.CancelJob when the printer is
+// known, as this opens every printer in turn to look for the job.
+func CancelJob(jobID uint32) error {
+	names, err := ReadNames()
 	if err != nil {
-		var newBuf = make([]byte, int(needed))
-		err = GetPrinter(p.h, 9, &newBuf[0], uint32(len(newBuf)), &needed)
+		return err
+	}
+	for _, name := range names {
+		p, err := Open(name)
 		if err != nil {
-			//fmt.Println("Failed: ", err)
-			return
+			continue
+		}
+		jobs, _ := p.Jobs()
+		for _, j := range jobs {
+			if j.JobID == jobID {
+				err = p.CancelJob(jobID)
+				_ = p.Close()
+				return err
+			}
 		}
-		printerInfo = (*PRINTER_INFO_9)(unsafe.Pointer(&newBuf[0]))
-		//fmt.Println("Get Printer Info 9 Duplex Setting: ", printerInfo.pDevMode.dmDuplex)
+		_ = p.Close()
 	}
-	return
+	return &JobError{Op: "CancelJob", JobID: jobID, Err: ErrJobNotFound}
 }
 
-func (p *Printer) SetPrinter9(printerInfo *PRINTER_INFO_9) (err error) {
-	bs := (*[unsafe.Sizeof(printerInfo)]byte)(unsafe.Pointer(printerInfo))
+// CancelJob cancels the job with the given ID on this printer and removes
+// it from the queue.
+func (p *Printer) CancelJob(jobID uint32) error {
+	if c, ok := p.h.(JobCanceler); ok {
+		return jobError("CancelJob", jobID, c.CancelJob(jobID))
+	}
+	return p.controlJob("CancelJob", jobID, JOB_CONTROL_CANCEL)
+}
 
-	//fmt.Println("Set printer to duplex with the info 9...")
-	err = SetPrinter(p.h, 9, &bs[0], 0)
-	return
+// PauseJob pauses the job with the given ID.
+func (p *Printer) PauseJob(jobID uint32) error {
+	return p.controlJob("PauseJob", jobID, JOB_CONTROL_PAUSE)
 }
 
-func (p *Printer) DocumentPropertiesGet(deviceName string) (devMode *DevMode, err error) {
-	var pDeviceName *uint16
-	if pDeviceName, err = windows.UTF16PtrFromString(deviceName); err != nil {
-		return
+// ResumeJob resumes the job with the given ID after PauseJob.
+func (p *Printer) ResumeJob(jobID uint32) error {
+	return p.controlJob("ResumeJob", jobID, JOB_CONTROL_RESUME)
+}
+
+// RestartJob prints the job with the given ID again from its first page.
+func (p *Printer) RestartJob(jobID uint32) error {
+	return p.controlJob("RestartJob", jobID, JOB_CONTROL_RESTART)
+}
+
+// DeleteJob deletes the job with the given ID from the queue.
+func (p *Printer) DeleteJob(jobID uint32) error {
+	return p.controlJob("DeleteJob", jobID, JOB_CONTROL_DELETE)
+}
+
+// RetainJob keeps the job with the given ID in the queue after it prints,
+// so it can be printed again with RestartJob.
+func (p *Printer) RetainJob(jobID uint32) error {
+	return p.controlJob("RetainJob", jobID, JOB_CONTROL_RETAIN)
+}
+
+// ReleaseJob lets the job with the given ID leave the queue after RetainJob.
+func (p *Printer) ReleaseJob(jobID uint32) error {
+	return p.controlJob("ReleaseJob", jobID, JOB_CONTROL_RELEASE)
+}
+
+// SetJobPriority sets the priority of the job with the given ID, from
+// MIN_PRIORITY to MAX_PRIORITY. Jobs of higher priority print first.
+func (p *Printer) SetJobPriority(jobID, priority uint32) error {
+	c, ok := p.h.(JobController)
+	if !ok {
+		return &UnsupportedError{Op: "SetJobPriority"}
 	}
+	if priority < MIN_PRIORITY || priority > MAX_PRIORITY {
+		return &JobError{Op: "SetJobPriority", JobID: jobID,
+			Err: fmt.Errorf("priority %d out of range [%d, %d]", priority, MIN_PRIORITY, MAX_PRIORITY)}
+	}
+	return jobError("SetJobPriority", jobID, c.SetJobPriority(jobID, priority))
+}
 
-	var r1 uintptr
-	r1, _, err = procDocumentPropertiesW.Call(0, uintptr(p.h), uintptr(unsafe.Pointer(pDeviceName)), 0, 0, 0)
-	iDevModeSize := int32(r1)
-	if iDevModeSize < 0 {
-		return
+// SetJobPosition moves the job with the given ID to a position in the
+// queue, counted from 1.
+func (p *Printer) SetJobPosition(jobID, position uint32) error {
+	c, ok := p.h.(JobController)
+	if !ok {
+		return &UnsupportedError{Op: "SetJobPosition"}
 	}
+	if position == JOB_POSITION_UNSPECIFIED {
+		return &JobError{Op: "SetJobPosition", JobID: jobID, Err: errors.New("queue positions start at 1")}
+	}
+	return jobError("SetJobPosition", jobID, c.SetJobPosition(jobID, position))
+}
 
-	devMode = new(DevMode)
-	//devMode.dmSize = uint16(iDevModeSize)
-	//devMode.dmSpecVersion = DM_SPECVERSION
-	err = DocumentProperties(0, p.h, pDeviceName, devMode, new(DevMode), DM_COPY)
+func (p *Printer) controlJob(op string, jobID, command uint32) error {
+	c, ok := p.h.(JobController)
+	if !ok {
+		return &UnsupportedError{Op: op}
+	}
+	return jobError(op, jobID, c.ControlJob(jobID, command))
+}
 
-	//fmt.Println("From get:", devMode.dmDuplex)
-	return
+// jobError wraps a non-nil err from a PrinterHandle into a *JobError.
+func jobError(op string, jobID uint32, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, ErrUnsupported) {
+		return err
+	}
+	return &JobError{Op: op, JobID: jobID, Err: err}
 }
 
-func (p *Printer) DocumentPropertiesSet(deviceName string, devMode *DevMode) (err error) {
-	var pDeviceName *uint16
-	pDeviceName, err = windows.UTF16PtrFromString(deviceName)
+// StartDocument starts a new document of the given data type and returns
+// the Job it is printed as. Spoolers that assign job IDs only once the
+// document is complete, such as IPP printers, fill in the Job ID when
+// EndDocument returns.
+func (p *Printer) StartDocument(name, datatype string) (*Job, error) {
+	id, err := p.h.StartDocument(name, datatype)
 	if err != nil {
-		return
+		return nil, err
 	}
+	return p.startJob(id, name, datatype, ""), nil
+}
 
-	err = DocumentProperties(0, p.h, pDeviceName, devMode, devMode, DM_MODIFY)
-	return
+func (p *Printer) startJob(id uint32, name, datatype, path string) *Job {
+	p.doc = &Job{ID: id, Name: name, DataType: datatype, OutputFile: path, p: p}
+	return p.doc
+}
+
+// StartDocumentToFile starts a document whose output is written to the file
+// at path rather than to the printer, such as the PDF produced by
+// "Microsoft Print to PDF" without its save-as dialog. The path is made
+// absolute, because the spooler resolves it in its own process, and its
+// directory must exist. The final path is stored in Job.OutputFile.
+func (p *Printer) StartDocumentToFile(name, datatype, path string) (*Job, error) {
+	f, ok := p.h.(FileDocumentStarter)
+	if !ok {
+		return nil, &UnsupportedError{Op: "StartDocumentToFile"}
+	}
+	path, err := outputPath(path)
+	if err != nil {
+		return nil, err
+	}
+	id, err := f.StartFileDocument(name, datatype, path)
+	if err != nil {
+		return nil, err
+	}
+	return p.startJob(id, name, datatype, path), nil
 }
 
-func (p *Printer) GetDataType() (dataType string, err error) {
-	var ptr2 *PRINTER_INFO_2
-	if ptr2, err = p.GetPrinter2(); err != nil {
-		return
+// outputPath checks that documents can be printed to the file at path and
+// returns its absolute path.
+func outputPath(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("winprinters: empty output file path")
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("winprinters: output file %q: %w", path, err)
+	}
+	dir, err := os.Stat(filepath.Dir(abs))
+	if err != nil {
+		return "", fmt.Errorf("winprinters: output file %q: %w", path, err)
 	}
-	dataType = ptr2.GetDataType()
-	return
+	if !dir.IsDir() {
+		return "", fmt.Errorf("winprinters: output file %q: %s is not a directory", path, filepath.Dir(abs))
+	}
+	if fi, err := os.Stat(abs); err == nil && fi.IsDir() {
+		return "", fmt.Errorf("winprinters: output file %q is a directory", path)
+	}
+	return abs, nil
 }
 
-func (p *Printer) StartDocument(name, datatype string) error {
-	docName, _ := windows.UTF16FromString(name)
-	dataType, _ := windows.UTF16FromString(datatype)
-	d := DOC_INFO_1{
-		DocName:    &(docName)[0],
-		OutputFile: nil,
-		Datatype:   &(dataType)[0],
+// truncate shortens s to at most n bytes without splitting a UTF-8
+// sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
 	}
-	return StartDocPrinter(p.h, 1, &d)
+	return s[:n]
 }
 
 // StartRawDocument calls StartDocument and passes either "RAW" or "XPS_PASS"
 // as a document type, depending on if printer driver is XPS-based or not.
-func (p *Printer) StartRawDocument(name string) error {
+func (p *Printer) StartRawDocument(name string) (*Job, error) {
 	di, err := p.DriverInfo()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// See https://support.microsoft.com/en-us/help/2779300/v4-print-drivers-using-raw-mode-to-send-pcl-postscript-directly-to-the
 	// for details.
@@ -686,26 +475,28 @@ func (p *Printer) StartRawDocument(name string) error {
 }
 
 func (p *Printer) Write(b []byte) (int, error) {
-	var written uint32
-	err := WritePrinter(p.h, &b[0], uint32(len(b)), &written)
-	if err != nil {
-		return 0, err
-	}
-	return int(written), nil
+	return p.h.Write(b)
 }
 
 func (p *Printer) EndDocument() error {
-	return EndDocPrinter(p.h)
+	err := p.h.EndDocument()
+	if doc := p.doc; doc != nil {
+		p.doc = nil
+		if r, ok := p.h.(JobIDReporter); ok && err == nil && doc.ID == 0 {
+			doc.ID = r.LastJobID()
+		}
+	}
+	return err
 }
 
 func (p *Printer) StartPage() error {
-	return StartPagePrinter(p.h)
+	return p.h.StartPage()
 }
 
 func (p *Printer) EndPage() error {
-	return EndPagePrinter(p.h)
+	return p.h.EndPage()
 }
 
 func (p *Printer) Close() error {
-	return ClosePrinter(p.h)
+	return p.h.Close()
 }
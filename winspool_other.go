@@ -0,0 +1,50 @@
+//go:build !windows
+// +build !windows
+
+package winprinters
+
+// unsupportedSpooler is the default Spooler where winspool is not available.
+type unsupportedSpooler struct{}
+
+func newPlatformSpooler() Spooler {
+	return unsupportedSpooler{}
+}
+
+func (unsupportedSpooler) GetDefault() (string, error) {
+	return "", &UnsupportedError{Op: "GetDefault"}
+}
+
+func (unsupportedSpooler) SetDefault(string) error {
+	return &UnsupportedError{Op: "SetDefault"}
+}
+
+func (unsupportedSpooler) ReadNames() ([]string, error) {
+	return nil, &UnsupportedError{Op: "ReadNames"}
+}
+
+func (unsupportedSpooler) Open(string) (PrinterHandle, error) {
+	return nil, &UnsupportedError{Op: "Open"}
+}
+
+// OpenWithDefaults needs winspool and always fails outside Windows.
+func OpenWithDefaults(string, *PrinterDefaults) (*Printer, error) {
+	return nil, &UnsupportedError{Op: "OpenWithDefaults"}
+}
+
+// AddCustomPaperSize needs winspool and always fails outside Windows.
+func AddCustomPaperSize(string, string, uint32, uint32, uint32, uint32) error {
+	return &UnsupportedError{Op: "AddCustomPaperSize"}
+}
+
+// DeleteCustomPaperSize needs winspool and always fails outside Windows.
+func DeleteCustomPaperSize(string, string) error {
+	return &UnsupportedError{Op: "DeleteCustomPaperSize"}
+}
+
+func (p *Printer) DocumentPropertiesGet(string) (*DevMode, error) {
+	return nil, &UnsupportedError{Op: "DocumentPropertiesGet"}
+}
+
+func (p *Printer) DocumentPropertiesSet(string, *DevMode) error {
+	return &UnsupportedError{Op: "DocumentPropertiesSet"}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package winprinters
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUnsupportedSpooler(t *testing.T) {
+	if _, err := GetDefault(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("GetDefault() error = %v, want %v", err, ErrUnsupported)
+	}
+	if _, err := Open("Microsoft Print To PDF"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Open() error = %v, want %v", err, ErrUnsupported)
+	}
+	var ue *UnsupportedError
+	if err := AddCustomPaperSize("Label", "_Custom.100x200mm", 100, 200, 0, 0); !errors.As(err, &ue) || ue.Op != "AddCustomPaperSize" {
+		t.Errorf("AddCustomPaperSize() error = %v, want *UnsupportedError", err)
+	}
+}
@@ -0,0 +1,786 @@
+package winprinters
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+//go:generate go run cmd/mksyscall/mksyscall_windows.go -output zapi_windows.go winspool_windows.go
+
+//sys	GetDefaultPrinter(buf *uint16, bufN *uint32) (err error) = winspool.GetDefaultPrinterW
+//sys	SetDefaultPrinter(name *uint16) (err error) = winspool.SetDefaultPrinterW
+//sys	ClosePrinter(h syscall.Handle) (err error) = winspool.ClosePrinter
+//sys	OpenPrinter(name *uint16, h *syscall.Handle, defaults *PrinterDefaults) (err error) = winspool.OpenPrinterW
+//sys	StartDocPrinter(h syscall.Handle, level uint32, docInfo *DOC_INFO_1) (jobID uint32, err error) = winspool.StartDocPrinterW
+//sys	EndDocPrinter(h syscall.Handle) (err error) = winspool.EndDocPrinter
+//sys	WritePrinter(h syscall.Handle, buf *byte, bufN uint32, written *uint32) (err error) = winspool.WritePrinter
+//sys	StartPagePrinter(h syscall.Handle) (err error) = winspool.StartPagePrinter
+//sys	EndPagePrinter(h syscall.Handle) (err error) = winspool.EndPagePrinter
+//sys	EnumPrinters(flags uint32, name *uint16, level uint32, buf *byte, bufN uint32, needed *uint32, returned *uint32) (err error) = winspool.EnumPrintersW
+//sys	GetPrinterDriver(h syscall.Handle, env *uint16, level uint32, di *byte, n uint32, needed *uint32) (err error) = winspool.GetPrinterDriverW
+//sys	EnumJobs(h syscall.Handle, firstJob uint32, noJobs uint32, level uint32, buf *byte, bufN uint32, bytesNeeded *uint32, jobsReturned *uint32) (err error) = winspool.EnumJobsW
+//sys	DocumentProperties(hWnd uint32, h syscall.Handle, pDeviceName *uint16, devModeOut *DevMode, devModeIn *DevMode, fMode uint32) (err error) = winspool.DocumentPropertiesW
+//sys	GetPrinter(h syscall.Handle, level uint32, buf *byte, bufN uint32, needed *uint32) (err error) = winspool.GetPrinterW
+//sys	SetPrinter(h syscall.Handle, level uint32, buf *byte, command uint32) (err error) = winspool.SetPrinterW
+//sys	AddForm(h syscall.Handle, level uint32, form *FORM_INFO_1) (err error) = winspool.AddFormW
+//sys	DeleteForm(h syscall.Handle, pFormName *uint16) (err error) = winspool.DeleteFormW
+//sys	EnumForms(h syscall.Handle, level uint32, pForm *byte, cbBuf uint32, pcbNeeded *uint32, pcReturned *uint32) (err error) = winspool.EnumFormsW
+//sys	GetJob(h syscall.Handle, jobId uint32, level uint32, buf *byte, bufN uint32, needed *uint32) (err error) = winspool.GetJobW
+//sys	SetJob(h syscall.Handle, jobId uint32, level uint32, buf *byte, command uint32) (err error) = winspool.SetJobW
+//sys	FindFirstPrinterChangeNotification(h syscall.Handle, filter uint32, options uint32, notifyOptions *PRINTER_NOTIFY_OPTIONS) (change syscall.Handle, err error) [failretval==syscall.InvalidHandle] = winspool.FindFirstPrinterChangeNotification
+//sys	FindNextPrinterChangeNotification(change syscall.Handle, changed *uint32, notifyOptions *PRINTER_NOTIFY_OPTIONS, info **PRINTER_NOTIFY_INFO) (err error) = winspool.FindNextPrinterChangeNotification
+//sys	FindClosePrinterChangeNotification(change syscall.Handle) (err error) = winspool.FindClosePrinterChangeNotification
+//sys	FreePrinterNotifyInfo(info *PRINTER_NOTIFY_INFO) (err error) = winspool.FreePrinterNotifyInfo
+//sys	addPrinter(server *uint16, level uint32, printer *byte) (h syscall.Handle, err error) = winspool.AddPrinterW
+//sys	deletePrinter(h syscall.Handle) (err error) = winspool.DeletePrinter
+//sys	EnumPorts(name *uint16, level uint32, buf *byte, bufN uint32, needed *uint32, returned *uint32) (err error) = winspool.EnumPortsW
+//sys	XcvData(xcv syscall.Handle, dataName *uint16, input *byte, inputN uint32, output *byte, outputN uint32, outputNeeded *uint32, status *uint32) (err error) = winspool.XcvDataW
+//sys	EnumPrinterDrivers(name *uint16, env *uint16, level uint32, buf *byte, bufN uint32, needed *uint32, returned *uint32) (err error) = winspool.EnumPrinterDriversW
+//sys	EnumPrintProcessors(name *uint16, env *uint16, level uint32, buf *byte, bufN uint32, needed *uint32, returned *uint32) (err error) = winspool.EnumPrintProcessorsW
+//sys	EnumPrintProcessorDatatypes(name *uint16, processor *uint16, level uint32, buf *byte, bufN uint32, needed *uint32, returned *uint32) (err error) = winspool.EnumPrintProcessorDatatypesW
+//sys	addPrinterConnection(name *uint16) (err error) = winspool.AddPrinterConnectionW
+//sys	deletePrinterConnection(name *uint16) (err error) = winspool.DeletePrinterConnectionW
+//sys	getPrinterData(h syscall.Handle, valueName *uint16, valueType *uint32, data *byte, dataN uint32, needed *uint32) (status uint32) = winspool.GetPrinterDataW
+//sys	DeviceCapabilities(device *uint16, port *uint16, capability uint16, output *byte, devMode *DevMode) (n int32, err error) [failretval==-1] = winspool.DeviceCapabilitiesW
+
+//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
+type DOC_INFO_1 struct {
+	/*
+	  LPTSTR pDocName;
+	  LPTSTR pOutputFile;
+	  LPTSTR pDatatype;
+	*/
+	DocName    *uint16
+	OutputFile *uint16
+	Datatype   *uint16
+}
+
+//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
+type FORM_INFO_1 struct {
+	/*
+	  DWORD  Flags;
+	  LPTSTR pName;
+	  SIZEL  Size;
+	  RECTL  ImageableArea;
+	*/
+	Flags         uint32
+	pName         *uint16
+	Size          SIZE
+	ImageableArea Rect
+}
+
+//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
+type PRINTER_INFO_9 struct {
+	/*
+	  LPDEVMODE pDevMode;
+	*/
+	pDevMode *DevMode
+}
+
+//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
+type PRINTER_INFO_2 struct {
+	/*
+	  LPTSTR               pServerName;
+	  LPTSTR               pPrinterName;
+	  LPTSTR               pShareName;
+	  LPTSTR               pPortName;
+	  LPTSTR               pDriverName;
+	  LPTSTR               pComment;
+	  LPTSTR               pLocation;
+	  LPDEVMODE            pDevMode;
+	  LPTSTR               pSepFile;
+	  LPTSTR               pPrintProcessor;
+	  LPTSTR               pDatatype;
+	  LPTSTR               pParameters;
+	  PSECURITY_DESCRIPTOR pSecurityDescriptor;
+	  DWORD                Attributes;
+	  DWORD                Priority;
+	  DWORD                DefaultPriority;
+	  DWORD                StartTime;
+	  DWORD                UntilTime;
+	  DWORD                Status;
+	  DWORD                cJobs;
+	  DWORD                AveragePPM;
+	*/
+	pServerName         *uint16
+	pPrinterName        *uint16
+	pShareName          *uint16
+	pPortName           *uint16
+	pDriverName         *uint16
+	pComment            *uint16
+	pLocation           *uint16
+	pDevMode            *DevMode
+	pSepFile            *uint16
+	pPrintProcessor     *uint16
+	pDatatype           *uint16
+	pParameters         *uint16
+	pSecurityDescriptor uintptr
+	attributes          uint32
+	priority            uint32
+	defaultPriority     uint32
+	startTime           uint32
+	untilTime           uint32
+	status              uint32
+	cJobs               uint32
+	averagePPM          uint32
+}
+
+func (pi *PRINTER_INFO_2) GetDataType() string {
+	return utf16PtrToString(pi.pDatatype)
+}
+
+//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
+type PRINTER_INFO_5 struct {
+	/*
+	  LPTSTR pPrinterName;
+	  LPTSTR pPortName;
+	  DWORD  Attributes;
+	  DWORD  DeviceNotSelectedTimeout;
+	  DWORD  TransmissionRetryTimeout;
+	*/
+	PrinterName              *uint16
+	PortName                 *uint16
+	Attributes               uint32
+	DeviceNotSelectedTimeout uint32
+	TransmissionRetryTimeout uint32
+}
+
+// DRIVER_INFO_8 is the driver information of GetPrinterDriver level 8.
+//
+// Deprecated: the Go layout of DRIVER_INFO_8 misplaces the fields after
+// ftDriverDate on 386. Use Printer.DriverInfo, which decodes them all.
+//
+//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
+type DRIVER_INFO_8 struct {
+	/*
+	  DWORD     cVersion;
+	  LPTSTR    pName;
+	  LPTSTR    pEnvironment;
+	  LPTSTR    pDriverPath;
+	  LPTSTR    pDataFile;
+	  LPTSTR    pConfigFile;
+	  LPTSTR    pHelpFile;
+	  LPTSTR    pDependentFiles;
+	  LPTSTR    pMonitorName;
+	  LPTSTR    pDefaultDataType;
+	  LPTSTR    pszzPreviousNames;
+	  FILETIME  ftDriverDate;
+	  DWORDLONG dwlDriverVersion;
+	  LPTSTR    pszMfgName;
+	  LPTSTR    pszOEMUrl;
+	  LPTSTR    pszHardwareID;
+	  LPTSTR    pszProvider;
+	  LPTSTR    pszPrintProcessor;
+	  LPTSTR    pszVendorSetup;
+	  LPTSTR    pszzColorProfiles;
+	  LPTSTR    pszInfPath;
+	  DWORD     dwPrinterDriverAttributes;
+	  LPTSTR    pszzCoreDriverDependencies;
+	  FILETIME  ftMinInboxDriverVerDate;
+	  DWORDLONG dwlMinInboxDriverVerVersion;
+	*/
+	Version                  uint32
+	Name                     *uint16
+	Environment              *uint16
+	DriverPath               *uint16
+	DataFile                 *uint16
+	ConfigFile               *uint16
+	HelpFile                 *uint16
+	DependentFiles           *uint16
+	MonitorName              *uint16
+	DefaultDataType          *uint16
+	PreviousNames            *uint16
+	DriverDate               windows.Filetime
+	DriverVersion            uint64
+	MfgName                  *uint16
+	OEMUrl                   *uint16
+	HardwareID               *uint16
+	Provider                 *uint16
+	PrintProcessor           *uint16
+	VendorSetup              *uint16
+	ColorProfiles            *uint16
+	InfPath                  *uint16
+	PrinterDriverAttributes  uint32
+	CoreDriverDependencies   *uint16
+	MinInboxDriverVerDate    windows.Filetime
+	MinInboxDriverVerVersion uint32
+}
+
+//goland:noinspection GoSnakeCaseUsage,SpellCheckingInspection
+type JOB_INFO_1 struct {
+	/*
+	  DWORD      JobId;
+	  LPTSTR     pPrinterName;
+	  LPTSTR     pMachineName;
+	  LPTSTR     pUserName;
+	  LPTSTR     pDocument;
+	  LPTSTR     pDatatype;
+	  LPTSTR     pStatus;
+	  DWORD      Status;
+	  DWORD      Priority;
+	  DWORD      Position;
+	  DWORD      TotalPages;
+	  DWORD      PagesPrinted;
+	  SYSTEMTIME Submitted;
+	*/
+	JobID        uint32
+	PrinterName  *uint16
+	MachineName  *uint16
+	UserName     *uint16
+	Document     *uint16
+	DataType     *uint16
+	Status       *uint16
+	StatusCode   uint32
+	Priority     uint32
+	Position     uint32
+	TotalPages   uint32
+	PagesPrinted uint32
+	Submitted    windows.Systemtime
+}
+
+// winspool is the Spooler backed by the Windows print spooler.
+type winspool struct{}
+
+func newPlatformSpooler() Spooler {
+	return winspool{}
+}
+
+func (winspool) GetDefault() (printer string, err error) {
+	b := make([]uint16, 3)
+	n := uint32(len(b))
+	err = GetDefaultPrinter(&b[0], &n)
+	if err != nil {
+		if err != windows.ERROR_INSUFFICIENT_BUFFER {
+			return
+		}
+		b = make([]uint16, n)
+		err = GetDefaultPrinter(&b[0], &n)
+		if err != nil {
+			return
+		}
+	}
+	printer = windows.UTF16ToString(b)
+	return
+}
+
+func (winspool) SetDefault(printer string) (err error) {
+	docName, _ := windows.UTF16FromString(printer)
+	err = SetDefaultPrinter(&(docName)[0])
+	return
+}
+
+func (winspool) ReadNames() ([]string, error) {
+	const flags = PRINTER_ENUM_LOCAL | PRINTER_ENUM_CONNECTIONS
+	var needed, returned uint32
+	buf := make([]byte, 1)
+	err := EnumPrinters(flags, nil, 5, &buf[0], uint32(len(buf)), &needed, &returned)
+	if err != nil {
+		if err != windows.ERROR_INSUFFICIENT_BUFFER {
+			return nil, err
+		}
+		buf = make([]byte, needed)
+		err = EnumPrinters(flags, nil, 5, &buf[0], uint32(len(buf)), &needed, &returned)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ps := (*[1024]PRINTER_INFO_5)(unsafe.Pointer(&buf[0]))[:returned:returned]
+	names := make([]string, 0, returned)
+	for _, p := range ps {
+		names = append(names, windows.UTF16PtrToString(p.PrinterName))
+	}
+	return names, nil
+}
+
+func (winspool) Open(name string) (PrinterHandle, error) {
+	p, err := openWinspool(name, nil)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// winspoolPrinter is a PrinterHandle holding an OpenPrinter handle.
+type winspoolPrinter struct {
+	h syscall.Handle
+}
+
+func openWinspool(name string, defaults *PrinterDefaults) (*winspoolPrinter, error) {
+	var p winspoolPrinter
+	docName, _ := windows.UTF16FromString(name)
+	err := OpenPrinter(&(docName)[0], &p.h, defaults)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (p *winspoolPrinter) Forms() (forms []FormInfo, err error) {
+	var bytesNeeded, formsReturned uint32
+	buf := make([]byte, 1)
+	for {
+		err = EnumForms(p.h, 1, &buf[0], uint32(len(buf)), &bytesNeeded, &formsReturned)
+		if err == nil {
+			break
+		}
+		if err != windows.ERROR_INSUFFICIENT_BUFFER {
+			return
+		}
+		if bytesNeeded <= uint32(len(buf)) {
+			return
+		}
+		buf = make([]byte, bytesNeeded)
+	}
+	if formsReturned <= 0 {
+		return
+	}
+	forms = make([]FormInfo, 0, formsReturned)
+	formsInfo := (*[2048]FORM_INFO_1)(unsafe.Pointer(&buf[0]))[:formsReturned:formsReturned]
+	for _, form := range formsInfo {
+		formInfo := FormInfo{
+			Flags:         form.Flags,
+			Size:          form.Size,
+			ImageableArea: form.ImageableArea,
+		}
+		if form.pName != nil {
+			formInfo.Name = windows.UTF16PtrToString(form.pName)
+		}
+		forms = append(forms, formInfo)
+	}
+	return
+}
+
+// Jobs enumerates the whole queue.
+func (p *winspoolPrinter) Jobs() ([]JobInfo, error) {
+	return p.JobsPage(0, allJobs)
+}
+
+// JobsPage enumerates JOB_INFO_1 for at most n jobs from the zero-based
+// queue index first.
+func (p *winspoolPrinter) JobsPage(first, n uint32) ([]JobInfo, error) {
+	sb, count, err := p.enumJobs(1, first, n)
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return decodeJobInfo1(sb, count)
+}
+
+// JobsDetailed enumerates JOB_INFO_4, falling back to JOB_INFO_2 where the
+// spooler does not know level 4.
+func (p *winspoolPrinter) JobsDetailed() ([]JobDetails, error) {
+	level := uint32(4)
+	sb, count, err := p.enumJobs(level, 0, allJobs)
+	if err == windows.ERROR_INVALID_LEVEL {
+		level = 2
+		sb, count, err = p.enumJobs(level, 0, allJobs)
+	}
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return decodeJobInfo(sb, int(level), count)
+}
+
+// allJobs asks EnumJobs for every job from the first one on.
+const allJobs = 0xFFFFFFFF
+
+// enumJobs calls EnumJobs with a buffer large enough for n jobs from the
+// queue index first, and returns it with the number of jobs it holds.
+func (p *winspoolPrinter) enumJobs(level, first, n uint32) (spoolBuffer, int, error) {
+	var bytesNeeded, jobsReturned uint32
+	buf := make([]byte, 1)
+	for {
+		err := EnumJobs(p.h, first, n, level, &buf[0], uint32(len(buf)), &bytesNeeded, &jobsReturned)
+		if err == nil {
+			break
+		}
+		if err != windows.ERROR_INSUFFICIENT_BUFFER || bytesNeeded <= uint32(len(buf)) {
+			return spoolBuffer{}, 0, err
+		}
+		buf = make([]byte, bytesNeeded)
+	}
+	return newSpoolBuffer(buf), int(jobsReturned), nil
+}
+
+func (p *winspoolPrinter) Job(jobID uint32) (*JobDetails, error) {
+	j, err := p.getJob(jobID, 4)
+	if err == windows.ERROR_INVALID_LEVEL {
+		j, err = p.getJob(jobID, 2)
+	}
+	return j, winspoolJobError(err)
+}
+
+func (p *winspoolPrinter) getJob(jobID, level uint32) (*JobDetails, error) {
+	var needed uint32
+	buf := make([]byte, 1)
+	for {
+		err := GetJob(p.h, jobID, level, &buf[0], uint32(len(buf)), &needed)
+		if err == nil {
+			break
+		}
+		if err != windows.ERROR_INSUFFICIENT_BUFFER || needed <= uint32(len(buf)) {
+			return nil, err
+		}
+		buf = make([]byte, needed)
+	}
+	jobs, err := decodeJobInfo(newSpoolBuffer(buf), int(level), 1)
+	if err != nil {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+func (p *winspoolPrinter) PrinterInfo() (*PrinterInfo, error) {
+	var needed uint32
+	buf := make([]byte, 1)
+	for {
+		err := GetPrinter(p.h, 2, &buf[0], uint32(len(buf)), &needed)
+		if err == nil {
+			break
+		}
+		if err != windows.ERROR_INSUFFICIENT_BUFFER || needed <= uint32(len(buf)) {
+			return nil, err
+		}
+		buf = make([]byte, needed)
+	}
+	infos, err := decodePrinterInfo2(newSpoolBuffer(buf), 1)
+	if err != nil {
+		return nil, err
+	}
+	return &infos[0], nil
+}
+
+// UpdatePrinter writes info with SetPrinter level 2. Handles opened for
+// printing cannot change the printer, so on ERROR_ACCESS_DENIED it opens
+// the printer again to administer it.
+func (p *winspoolPrinter) UpdatePrinter(info *PrinterInfo) error {
+	pi, err := newPrinterInfo2(info)
+	if err != nil {
+		return err
+	}
+	err = SetPrinter(p.h, 2, (*byte)(unsafe.Pointer(pi)), 0)
+	if err != windows.ERROR_ACCESS_DENIED {
+		return err
+	}
+	cur, err := p.PrinterInfo()
+	if err != nil {
+		return err
+	}
+	admin, err := openWinspool(cur.Name, &PrinterDefaults{DesiredAccess: PRINTER_ALL_ACCESS})
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	return SetPrinter(admin.h, 2, (*byte)(unsafe.Pointer(pi)), 0)
+}
+
+// newPrinterInfo2 returns info as a PRINTER_INFO_2 whose strings and
+// DEVMODE live in Go memory. The security descriptor is null, which
+// SetPrinter leaves unchanged.
+func newPrinterInfo2(info *PrinterInfo) (*PRINTER_INFO_2, error) {
+	pi := &PRINTER_INFO_2{
+		attributes:      uint32(info.Attributes),
+		priority:        info.Priority,
+		defaultPriority: info.DefaultPriority,
+		startTime:       uint32(info.StartTime / time.Minute),
+		untilTime:       uint32(info.UntilTime / time.Minute),
+	}
+	strs := []struct {
+		dst **uint16
+		s   string
+	}{
+		{&pi.pServerName, info.ServerName},
+		{&pi.pPrinterName, info.Name},
+		{&pi.pShareName, info.ShareName},
+		{&pi.pPortName, info.PortName},
+		{&pi.pDriverName, info.DriverName},
+		{&pi.pComment, info.Comment},
+		{&pi.pLocation, info.Location},
+		{&pi.pSepFile, info.SepFile},
+		{&pi.pPrintProcessor, info.PrintProcessor},
+		{&pi.pDatatype, info.DataType},
+		{&pi.pParameters, info.Parameters},
+	}
+	for _, f := range strs {
+		if f.s == "" && f.dst == &pi.pServerName {
+			continue // a local printer
+		}
+		u, err := windows.UTF16PtrFromString(f.s)
+		if err != nil {
+			return nil, err
+		}
+		*f.dst = u
+	}
+	if info.DevMode != nil {
+		dm := encodeDevMode(info.DevMode, info.devModeExtra)
+		pi.pDevMode = (*DevMode)(unsafe.Pointer(&dm[:cap(dm)][0]))
+	}
+	return pi, nil
+}
+
+func (p *winspoolPrinter) DriverInfo() (*DriverInfo, error) {
+	var needed uint32
+	b := make([]byte, 1024*10)
+	for {
+		err := GetPrinterDriver(p.h, nil, 8, &b[0], uint32(len(b)), &needed)
+		if err == nil {
+			break
+		}
+		if err != windows.ERROR_INSUFFICIENT_BUFFER {
+			return nil, err
+		}
+		if needed <= uint32(len(b)) {
+			return nil, err
+		}
+		b = make([]byte, needed)
+	}
+	drivers, err := decodeDriverInfo8(newSpoolBuffer(b), 1)
+	if err != nil {
+		return nil, err
+	}
+	return &drivers[0], nil
+}
+
+// CancelJob deletes the job: Microsoft documents JOB_CONTROL_CANCEL as not
+// to be used and JOB_CONTROL_DELETE in its place.
+func (p *winspoolPrinter) CancelJob(jobID uint32) error {
+	return p.ControlJob(jobID, JOB_CONTROL_DELETE)
+}
+
+func (p *winspoolPrinter) ControlJob(jobID, command uint32) error {
+	return winspoolJobError(SetJob(p.h, jobID, 0, nil, command))
+}
+
+func (p *winspoolPrinter) SetJobPriority(jobID, priority uint32) error {
+	return p.setJob(jobID, func(j *JOB_INFO_1) {
+		j.Priority = priority
+	})
+}
+
+func (p *winspoolPrinter) SetJobPosition(jobID, position uint32) error {
+	return p.setJob(jobID, func(j *JOB_INFO_1) {
+		j.Position = position
+	})
+}
+
+// setJob reads the JOB_INFO_1 of a job, lets change modify it and writes it
+// back. The position is left alone unless change sets it.
+func (p *winspoolPrinter) setJob(jobID uint32, change func(*JOB_INFO_1)) error {
+	var needed uint32
+	buf := make([]byte, 1)
+	for {
+		err := GetJob(p.h, jobID, 1, &buf[0], uint32(len(buf)), &needed)
+		if err == nil {
+			break
+		}
+		if err != windows.ERROR_INSUFFICIENT_BUFFER || needed <= uint32(len(buf)) {
+			return winspoolJobError(err)
+		}
+		buf = make([]byte, needed)
+	}
+	j := (*JOB_INFO_1)(unsafe.Pointer(&buf[0]))
+	j.Position = JOB_POSITION_UNSPECIFIED
+	change(j)
+	return winspoolJobError(SetJob(p.h, jobID, 1, &buf[0], 0))
+}
+
+// winspoolJobError maps the error winspool reports for job IDs it does not
+// know to ErrJobNotFound.
+func winspoolJobError(err error) error {
+	if err == windows.ERROR_INVALID_PARAMETER {
+		return ErrJobNotFound
+	}
+	return err
+}
+
+func (p *winspoolPrinter) StartDocument(name, datatype string) (uint32, error) {
+	return p.StartFileDocument(name, datatype, "")
+}
+
+// StartFileDocument passes path as DOC_INFO_1.OutputFile, so the spooler
+// writes the output there instead of sending it to the port.
+func (p *winspoolPrinter) StartFileDocument(name, datatype, path string) (uint32, error) {
+	docName, _ := windows.UTF16FromString(name)
+	dataType, _ := windows.UTF16FromString(datatype)
+	d := DOC_INFO_1{
+		DocName:  &(docName)[0],
+		Datatype: &(dataType)[0],
+	}
+	if path != "" {
+		outputFile, err := windows.UTF16PtrFromString(path)
+		if err != nil {
+			return 0, err
+		}
+		d.OutputFile = outputFile
+	}
+	return StartDocPrinter(p.h, 1, &d)
+}
+
+func (p *winspoolPrinter) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	var written uint32
+	err := WritePrinter(p.h, &b[0], uint32(len(b)), &written)
+	if err != nil {
+		return 0, err
+	}
+	return int(written), nil
+}
+
+func (p *winspoolPrinter) EndDocument() error {
+	return EndDocPrinter(p.h)
+}
+
+func (p *winspoolPrinter) StartPage() error {
+	return StartPagePrinter(p.h)
+}
+
+func (p *winspoolPrinter) EndPage() error {
+	return EndPagePrinter(p.h)
+}
+
+func (p *winspoolPrinter) Close() error {
+	return ClosePrinter(p.h)
+}
+
+// OpenWithDefaults opens the named printer with winspool directly, passing
+// defaults to OpenPrinter. It ignores the Spooler installed by SetSpooler.
+func OpenWithDefaults(name string, defaults *PrinterDefaults) (*Printer, error) {
+	h, err := openWinspool(name, defaults)
+	if err != nil {
+		return nil, err
+	}
+	return &Printer{h: h}, nil
+}
+
+// DeleteJob deletes the job with the given ID from the queue of the printer
+// opened as h.
+func DeleteJob(h syscall.Handle, jobId uint32) error {
+	return SetJob(h, jobId, 0, nil, JOB_CONTROL_DELETE)
+}
+
+// winspoolHandle returns the winspool handle behind p, or an
+// *UnsupportedError for op when p was opened by another Spooler.
+func (p *Printer) winspoolHandle(op string) (syscall.Handle, error) {
+	if wp, ok := p.h.(*winspoolPrinter); ok {
+		return wp.h, nil
+	}
+	return 0, &UnsupportedError{Op: op}
+}
+
+// GetPrinter2 get Printer Info 2
+//
+// Deprecated: use Info, whose PrinterInfo holds no pointers into the
+// spooler buffer.
+func (p *Printer) GetPrinter2() (printerInfo *PRINTER_INFO_2, err error) {
+	var h syscall.Handle
+	if h, err = p.winspoolHandle("GetPrinter2"); err != nil {
+		return
+	}
+	var needed uint32
+	var buf = make([]byte, 1)
+
+	var r1 uintptr
+	r1, _, err = procGetPrinterW.Call(uintptr(h), 2, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), uintptr(unsafe.Pointer(&needed)))
+	if r1 == 0 {
+		var newBuf = make([]byte, int(needed))
+		var newLen = uint32(len(newBuf))
+		err = GetPrinter(h, 2, &newBuf[0], newLen, &needed)
+		if err != nil {
+			//fmt.Println("Failed: ", err)
+			return
+		}
+		printerInfo = (*PRINTER_INFO_2)(unsafe.Pointer(&newBuf[0]))
+		//fmt.Println("Get Printer Info 2 Duplex Setting: ", printerInfo.pDevMode.dmDuplex)
+	}
+	return
+}
+
+// SetPrinter2 set Printer Info 2
+//
+// Deprecated: use Update, which serializes the strings and the DevMode
+// for the spooler.
+func (p *Printer) SetPrinter2(printerInfo *PRINTER_INFO_2) (err error) {
+	var h syscall.Handle
+	if h, err = p.winspoolHandle("SetPrinter2"); err != nil {
+		return
+	}
+	err = SetPrinter(h, 2, (*byte)(unsafe.Pointer(printerInfo)), 0)
+	return
+}
+
+// GetPrinter9 get Printer Info 9
+func (p *Printer) GetPrinter9() (printerInfo *PRINTER_INFO_9, err error) {
+	var h syscall.Handle
+	if h, err = p.winspoolHandle("GetPrinter9"); err != nil {
+		return
+	}
+	var needed uint32
+	var buf = make([]byte, 1)
+
+	err = GetPrinter(h, 9, &buf[0], uint32(len(buf)), &needed)
+	if err != nil {
+		var newBuf = make([]byte, int(needed))
+		err = GetPrinter(h, 9, &newBuf[0], uint32(len(newBuf)), &needed)
+		if err != nil {
+			//fmt.Println("Failed: ", err)
+			return
+		}
+		printerInfo = (*PRINTER_INFO_9)(unsafe.Pointer(&newBuf[0]))
+		//fmt.Println("Get Printer Info 9 Duplex Setting: ", printerInfo.pDevMode.dmDuplex)
+	}
+	return
+}
+
+func (p *Printer) SetPrinter9(printerInfo *PRINTER_INFO_9) (err error) {
+	var h syscall.Handle
+	if h, err = p.winspoolHandle("SetPrinter9"); err != nil {
+		return
+	}
+	err = SetPrinter(h, 9, (*byte)(unsafe.Pointer(printerInfo)), 0)
+	return
+}
+
+func (p *Printer) DocumentPropertiesGet(deviceName string) (devMode *DevMode, err error) {
+	var h syscall.Handle
+	if h, err = p.winspoolHandle("DocumentPropertiesGet"); err != nil {
+		return
+	}
+	var pDeviceName *uint16
+	if pDeviceName, err = windows.UTF16PtrFromString(deviceName); err != nil {
+		return
+	}
+
+	var r1 uintptr
+	r1, _, err = procDocumentPropertiesW.Call(0, uintptr(h), uintptr(unsafe.Pointer(pDeviceName)), 0, 0, 0)
+	iDevModeSize := int32(r1)
+	if iDevModeSize < 0 {
+		return
+	}
+
+	devMode = new(DevMode)
+	//devMode.dmSize = uint16(iDevModeSize)
+	//devMode.dmSpecVersion = DM_SPECVERSION
+	err = DocumentProperties(0, h, pDeviceName, devMode, new(DevMode), DM_COPY)
+
+	//fmt.Println("From get:", devMode.dmDuplex)
+	return
+}
+
+func (p *Printer) DocumentPropertiesSet(deviceName string, devMode *DevMode) (err error) {
+	var h syscall.Handle
+	if h, err = p.winspoolHandle("DocumentPropertiesSet"); err != nil {
+		return
+	}
+	var pDeviceName *uint16
+	pDeviceName, err = windows.UTF16PtrFromString(deviceName)
+	if err != nil {
+		return
+	}
+
+	err = DocumentProperties(0, h, pDeviceName, devMode, devMode, DM_MODIFY)
+	return
+}
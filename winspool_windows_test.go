@@ -25,7 +25,7 @@ func TestPrinter(t *testing.T) {
 		_ = p.Close()
 	}(p)
 
-	err = p.StartDocument("my document", "RAW")
+	_, err = p.StartDocument("my document", "RAW")
 	if err != nil {
 		t.Fatalf("StartDocument failed: %v", err)
 	}
@@ -118,13 +118,6 @@ func TestJobs(t *testing.T) {
 	}
 }
 
-func closePrinter(p *Printer) {
-	if p == nil {
-		return
-	}
-	_ = p.Close()
-}
-
 func TestSetDefault(t *testing.T) {
 	type args struct {
 		printer string
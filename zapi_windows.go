@@ -0,0 +1,468 @@
+// MACHINE GENERATED BY 'go generate' COMMAND; DO NOT EDIT
+
+package winprinters
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var _ unsafe.Pointer
+
+var (
+	winspoolMod = syscall.NewLazyDLL("winspool.drv")
+
+	procGetDefaultPrinterW                 = winspoolMod.NewProc("GetDefaultPrinterW")
+	procSetDefaultPrinterW                 = winspoolMod.NewProc("SetDefaultPrinterW")
+	procClosePrinter                       = winspoolMod.NewProc("ClosePrinter")
+	procOpenPrinterW                       = winspoolMod.NewProc("OpenPrinterW")
+	procStartDocPrinterW                   = winspoolMod.NewProc("StartDocPrinterW")
+	procEndDocPrinter                      = winspoolMod.NewProc("EndDocPrinter")
+	procWritePrinter                       = winspoolMod.NewProc("WritePrinter")
+	procStartPagePrinter                   = winspoolMod.NewProc("StartPagePrinter")
+	procEndPagePrinter                     = winspoolMod.NewProc("EndPagePrinter")
+	procEnumPrintersW                      = winspoolMod.NewProc("EnumPrintersW")
+	procGetPrinterDriverW                  = winspoolMod.NewProc("GetPrinterDriverW")
+	procEnumJobsW                          = winspoolMod.NewProc("EnumJobsW")
+	procDocumentPropertiesW                = winspoolMod.NewProc("DocumentPropertiesW")
+	procGetPrinterW                        = winspoolMod.NewProc("GetPrinterW")
+	procSetPrinterW                        = winspoolMod.NewProc("SetPrinterW")
+	procAddFormW                           = winspoolMod.NewProc("AddFormW")
+	procDeleteFormW                        = winspoolMod.NewProc("DeleteFormW")
+	procEnumFormsW                         = winspoolMod.NewProc("EnumFormsW")
+	procGetJobW                            = winspoolMod.NewProc("GetJobW")
+	procSetJobW                            = winspoolMod.NewProc("SetJobW")
+	procFindFirstPrinterChangeNotification = winspoolMod.NewProc("FindFirstPrinterChangeNotification")
+	procFindNextPrinterChangeNotification  = winspoolMod.NewProc("FindNextPrinterChangeNotification")
+	procFindClosePrinterChangeNotification = winspoolMod.NewProc("FindClosePrinterChangeNotification")
+	procFreePrinterNotifyInfo              = winspoolMod.NewProc("FreePrinterNotifyInfo")
+	procAddPrinterW                        = winspoolMod.NewProc("AddPrinterW")
+	procDeletePrinter                      = winspoolMod.NewProc("DeletePrinter")
+	procEnumPortsW                         = winspoolMod.NewProc("EnumPortsW")
+	procXcvDataW                           = winspoolMod.NewProc("XcvDataW")
+	procEnumPrinterDriversW                = winspoolMod.NewProc("EnumPrinterDriversW")
+	procEnumPrintProcessorsW               = winspoolMod.NewProc("EnumPrintProcessorsW")
+	procEnumPrintProcessorDatatypesW       = winspoolMod.NewProc("EnumPrintProcessorDatatypesW")
+	procAddPrinterConnectionW              = winspoolMod.NewProc("AddPrinterConnectionW")
+	procDeletePrinterConnectionW           = winspoolMod.NewProc("DeletePrinterConnectionW")
+	procGetPrinterDataW                    = winspoolMod.NewProc("GetPrinterDataW")
+	procDeviceCapabilitiesW                = winspoolMod.NewProc("DeviceCapabilitiesW")
+)
+
+func GetDefaultPrinter(buf *uint16, bufN *uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procGetDefaultPrinterW.Addr(), uintptr(unsafe.Pointer(buf)), uintptr(unsafe.Pointer(bufN)), 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func SetDefaultPrinter(name *uint16) (err error) {
+	r1, _, e1 := syscall.SyscallN(procSetDefaultPrinterW.Addr(), uintptr(unsafe.Pointer(name)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func ClosePrinter(h syscall.Handle) (err error) {
+	r1, _, e1 := syscall.SyscallN(procClosePrinter.Addr(), uintptr(h), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func OpenPrinter(name *uint16, h *syscall.Handle, defaults *PrinterDefaults) (err error) {
+	r1, _, e1 := syscall.SyscallN(procOpenPrinterW.Addr(), uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(h)), uintptr(unsafe.Pointer(defaults)))
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func StartDocPrinter(h syscall.Handle, level uint32, docInfo *DOC_INFO_1) (jobID uint32, err error) {
+	r0, _, e1 := syscall.SyscallN(procStartDocPrinterW.Addr(), uintptr(h), uintptr(level), uintptr(unsafe.Pointer(docInfo)))
+	jobID = uint32(r0)
+	if jobID == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func EndDocPrinter(h syscall.Handle) (err error) {
+	r1, _, e1 := syscall.SyscallN(procEndDocPrinter.Addr(), uintptr(h), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func WritePrinter(h syscall.Handle, buf *byte, bufN uint32, written *uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procWritePrinter.Addr(), uintptr(h), uintptr(unsafe.Pointer(buf)), uintptr(bufN), uintptr(unsafe.Pointer(written)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func StartPagePrinter(h syscall.Handle) (err error) {
+	r1, _, e1 := syscall.SyscallN(procStartPagePrinter.Addr(), uintptr(h), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func EndPagePrinter(h syscall.Handle) (err error) {
+	r1, _, e1 := syscall.SyscallN(procEndPagePrinter.Addr(), uintptr(h), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func EnumPrinters(flags uint32, name *uint16, level uint32, buf *byte, bufN uint32, needed *uint32, returned *uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procEnumPrintersW.Addr(), uintptr(flags), uintptr(unsafe.Pointer(name)), uintptr(level), uintptr(unsafe.Pointer(buf)), uintptr(bufN), uintptr(unsafe.Pointer(needed)), uintptr(unsafe.Pointer(returned)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func GetPrinterDriver(h syscall.Handle, env *uint16, level uint32, di *byte, n uint32, needed *uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procGetPrinterDriverW.Addr(), uintptr(h), uintptr(unsafe.Pointer(env)), uintptr(level), uintptr(unsafe.Pointer(di)), uintptr(n), uintptr(unsafe.Pointer(needed)))
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func EnumJobs(h syscall.Handle, firstJob uint32, noJobs uint32, level uint32, buf *byte, bufN uint32, bytesNeeded *uint32, jobsReturned *uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procEnumJobsW.Addr(), uintptr(h), uintptr(firstJob), uintptr(noJobs), uintptr(level), uintptr(unsafe.Pointer(buf)), uintptr(bufN), uintptr(unsafe.Pointer(bytesNeeded)), uintptr(unsafe.Pointer(jobsReturned)), 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func DocumentProperties(hWnd uint32, h syscall.Handle, pDeviceName *uint16, devModeOut *DevMode, devModeIn *DevMode, fMode uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procDocumentPropertiesW.Addr(), uintptr(hWnd), uintptr(h), uintptr(unsafe.Pointer(pDeviceName)), uintptr(unsafe.Pointer(devModeOut)), uintptr(unsafe.Pointer(devModeIn)), uintptr(fMode))
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func GetPrinter(h syscall.Handle, level uint32, buf *byte, bufN uint32, needed *uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procGetPrinterW.Addr(), uintptr(h), uintptr(level), uintptr(unsafe.Pointer(buf)), uintptr(bufN), uintptr(unsafe.Pointer(needed)), 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func SetPrinter(h syscall.Handle, level uint32, buf *byte, command uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procSetPrinterW.Addr(), uintptr(h), uintptr(level), uintptr(unsafe.Pointer(buf)), uintptr(command), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func AddForm(h syscall.Handle, level uint32, form *FORM_INFO_1) (err error) {
+	r1, _, e1 := syscall.SyscallN(procAddFormW.Addr(), uintptr(h), uintptr(level), uintptr(unsafe.Pointer(form)))
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func DeleteForm(h syscall.Handle, pFormName *uint16) (err error) {
+	r1, _, e1 := syscall.SyscallN(procDeleteFormW.Addr(), uintptr(h), uintptr(unsafe.Pointer(pFormName)), 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func EnumForms(h syscall.Handle, level uint32, pForm *byte, cbBuf uint32, pcbNeeded *uint32, pcReturned *uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procEnumFormsW.Addr(), uintptr(h), uintptr(level), uintptr(unsafe.Pointer(pForm)), uintptr(cbBuf), uintptr(unsafe.Pointer(pcbNeeded)), uintptr(unsafe.Pointer(pcReturned)))
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func GetJob(h syscall.Handle, jobId uint32, level uint32, buf *byte, bufN uint32, needed *uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procGetJobW.Addr(), uintptr(h), uintptr(jobId), uintptr(level), uintptr(unsafe.Pointer(buf)), uintptr(bufN), uintptr(unsafe.Pointer(needed)))
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func SetJob(h syscall.Handle, jobId uint32, level uint32, buf *byte, command uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procSetJobW.Addr(), uintptr(h), uintptr(jobId), uintptr(level), uintptr(unsafe.Pointer(buf)), uintptr(command), 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func FindFirstPrinterChangeNotification(h syscall.Handle, filter uint32, options uint32, notifyOptions *PRINTER_NOTIFY_OPTIONS) (change syscall.Handle, err error) {
+	r0, _, e1 := syscall.SyscallN(procFindFirstPrinterChangeNotification.Addr(), uintptr(h), uintptr(filter), uintptr(options), uintptr(unsafe.Pointer(notifyOptions)), 0, 0)
+	change = syscall.Handle(r0)
+	if change == syscall.InvalidHandle {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func FindNextPrinterChangeNotification(change syscall.Handle, changed *uint32, notifyOptions *PRINTER_NOTIFY_OPTIONS, info **PRINTER_NOTIFY_INFO) (err error) {
+	r1, _, e1 := syscall.SyscallN(procFindNextPrinterChangeNotification.Addr(), uintptr(change), uintptr(unsafe.Pointer(changed)), uintptr(unsafe.Pointer(notifyOptions)), uintptr(unsafe.Pointer(info)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func FindClosePrinterChangeNotification(change syscall.Handle) (err error) {
+	r1, _, e1 := syscall.SyscallN(procFindClosePrinterChangeNotification.Addr(), uintptr(change), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func FreePrinterNotifyInfo(info *PRINTER_NOTIFY_INFO) (err error) {
+	r1, _, e1 := syscall.SyscallN(procFreePrinterNotifyInfo.Addr(), uintptr(unsafe.Pointer(info)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func addPrinter(server *uint16, level uint32, printer *byte) (h syscall.Handle, err error) {
+	r0, _, e1 := syscall.SyscallN(procAddPrinterW.Addr(), uintptr(unsafe.Pointer(server)), uintptr(level), uintptr(unsafe.Pointer(printer)))
+	h = syscall.Handle(r0)
+	if h == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func deletePrinter(h syscall.Handle) (err error) {
+	r1, _, e1 := syscall.SyscallN(procDeletePrinter.Addr(), uintptr(h), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func EnumPorts(name *uint16, level uint32, buf *byte, bufN uint32, needed *uint32, returned *uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procEnumPortsW.Addr(), uintptr(unsafe.Pointer(name)), uintptr(level), uintptr(unsafe.Pointer(buf)), uintptr(bufN), uintptr(unsafe.Pointer(needed)), uintptr(unsafe.Pointer(returned)))
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func XcvData(xcv syscall.Handle, dataName *uint16, input *byte, inputN uint32, output *byte, outputN uint32, outputNeeded *uint32, status *uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procXcvDataW.Addr(), uintptr(xcv), uintptr(unsafe.Pointer(dataName)), uintptr(unsafe.Pointer(input)), uintptr(inputN), uintptr(unsafe.Pointer(output)), uintptr(outputN), uintptr(unsafe.Pointer(outputNeeded)), uintptr(unsafe.Pointer(status)), 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func EnumPrinterDrivers(name *uint16, env *uint16, level uint32, buf *byte, bufN uint32, needed *uint32, returned *uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procEnumPrinterDriversW.Addr(), uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(env)), uintptr(level), uintptr(unsafe.Pointer(buf)), uintptr(bufN), uintptr(unsafe.Pointer(needed)), uintptr(unsafe.Pointer(returned)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func EnumPrintProcessors(name *uint16, env *uint16, level uint32, buf *byte, bufN uint32, needed *uint32, returned *uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procEnumPrintProcessorsW.Addr(), uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(env)), uintptr(level), uintptr(unsafe.Pointer(buf)), uintptr(bufN), uintptr(unsafe.Pointer(needed)), uintptr(unsafe.Pointer(returned)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func EnumPrintProcessorDatatypes(name *uint16, processor *uint16, level uint32, buf *byte, bufN uint32, needed *uint32, returned *uint32) (err error) {
+	r1, _, e1 := syscall.SyscallN(procEnumPrintProcessorDatatypesW.Addr(), uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(processor)), uintptr(level), uintptr(unsafe.Pointer(buf)), uintptr(bufN), uintptr(unsafe.Pointer(needed)), uintptr(unsafe.Pointer(returned)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func addPrinterConnection(name *uint16) (err error) {
+	r1, _, e1 := syscall.SyscallN(procAddPrinterConnectionW.Addr(), uintptr(unsafe.Pointer(name)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func deletePrinterConnection(name *uint16) (err error) {
+	r1, _, e1 := syscall.SyscallN(procDeletePrinterConnectionW.Addr(), uintptr(unsafe.Pointer(name)), 0, 0)
+	if r1 == 0 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func getPrinterData(h syscall.Handle, valueName *uint16, valueType *uint32, data *byte, dataN uint32, needed *uint32) (status uint32) {
+	r0, _, _ := syscall.SyscallN(procGetPrinterDataW.Addr(), uintptr(h), uintptr(unsafe.Pointer(valueName)), uintptr(unsafe.Pointer(valueType)), uintptr(unsafe.Pointer(data)), uintptr(dataN), uintptr(unsafe.Pointer(needed)))
+	status = uint32(r0)
+	return
+}
+
+func DeviceCapabilities(device *uint16, port *uint16, capability uint16, output *byte, devMode *DevMode) (n int32, err error) {
+	r0, _, e1 := syscall.SyscallN(procDeviceCapabilitiesW.Addr(), uintptr(unsafe.Pointer(device)), uintptr(unsafe.Pointer(port)), uintptr(capability), uintptr(unsafe.Pointer(output)), uintptr(unsafe.Pointer(devMode)), 0)
+	n = int32(r0)
+	if n == -1 {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}